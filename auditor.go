@@ -0,0 +1,101 @@
+package truemail
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// Auditor result structure. Includes current host ip address, warnings of
+// failed audit checks by audit type and configuration
+type AuditorResult struct {
+	CurrentHostIp string
+	Warnings      map[string]string
+	Configuration *Configuration
+}
+
+// AuditorResult methods
+
+// Addes warning to auditor result warnings dictionary
+func (auditorResult *AuditorResult) addWarning(key, value string) {
+	if auditorResult.Warnings == nil {
+		auditorResult.Warnings = map[string]string{}
+	}
+	auditorResult.Warnings[key] = value
+}
+
+// HostAudit checks that current host is ready for SMTP validation: A record of verifier domain
+// refers to current host ip address and PTR record of current host ip address refers to verifier
+// domain. Current host ip address is detected when it is not specified: first SMTP source address
+// is used when specified, otherwise local address of outbound connection to DNS server
+func HostAudit(ctx context.Context, configuration *Configuration, currentHostIp string) *AuditorResult {
+	configuration = copyConfigurationByPointer(configuration)
+	configuration.ctx = ctx
+	auditorResult := &AuditorResult{CurrentHostIp: currentHostIp, Configuration: configuration}
+	if auditorResult.CurrentHostIp == emptyString {
+		auditorResult.CurrentHostIp = detectCurrentHostIp(ctx, configuration)
+	}
+	if auditorResult.CurrentHostIp == emptyString {
+		auditorResult.addWarning(auditTypeIp, auditIpWarning)
+		return auditorResult
+	}
+
+	dnsResolver := newDnsResolver(configuration)
+	ipAddresses, err := dnsResolver.aRecords(configuration.VerifierDomain)
+	if err != nil || !isIncluded(ipAddresses, auditorResult.CurrentHostIp) {
+		auditorResult.addWarning(auditTypeDns, auditDnsWarning)
+	}
+	hostNames, err := dnsResolver.ptrRecords(auditorResult.CurrentHostIp)
+	if err != nil || !isIncluded(hostNames, configuration.VerifierDomain) {
+		auditorResult.addWarning(auditTypePtr, auditPtrWarning)
+	}
+
+	return auditorResult
+}
+
+// Returns current host ip address: first SMTP source address when specified, otherwise local
+// address of outbound UDP connection to probe address, no packets are sent. Returns empty
+// string when current host ip address can't be detected
+func detectCurrentHostIp(ctx context.Context, configuration *Configuration) string {
+	if len(configuration.SmtpSourceAddresses) > 0 {
+		return configuration.SmtpSourceAddresses[0].IpAddress
+	}
+
+	connection, err := new(net.Dialer).DialContext(contextOrBackground(ctx), auditIpProbeTransport, currentHostIpProbeAddress(configuration))
+	if err != nil {
+		return emptyString
+	}
+	defer connection.Close()
+
+	if localAddress, ok := connection.LocalAddr().(*net.UDPAddr); ok {
+		return localAddress.IP.String()
+	}
+
+	return emptyString
+}
+
+// Returns probe address for current host ip address detection: first DNS server from
+// configuration, host and port of DNS-over-HTTPS URL for DNS-over-HTTPS transport. Returns
+// default probe address when custom resolver is specified or system DNS server is used
+func currentHostIpProbeAddress(configuration *Configuration) string {
+	dnsServers := configuration.dnsServers()
+	if configuration.Resolver != nil || len(dnsServers) == 0 {
+		return auditIpProbeAddress
+	}
+
+	dnsServer := dnsServers[0]
+	if configuration.DnsTransport != dnsTransportHttps {
+		return dnsServer
+	}
+
+	dnsUrl, err := url.Parse(dnsServer)
+	if err != nil {
+		return auditIpProbeAddress
+	}
+	if dnsUrl.Port() != emptyString {
+		return dnsUrl.Host
+	}
+
+	return net.JoinHostPort(dnsUrl.Hostname(), strconv.Itoa(defaultDnsOverHttpsPort))
+}
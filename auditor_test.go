@@ -0,0 +1,129 @@
+package truemail
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostAudit(t *testing.T) {
+	currentHostIp, reverseCurrentHostIp := "1.2.3.4", "4.3.2.1.in-addr.arpa."
+
+	t.Run("when A and PTR records refer to current host", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver = &mockdns.Resolver{
+			Zones: map[string]mockdns.Zone{
+				toDnsHostName(configuration.VerifierDomain): {A: []string{currentHostIp}},
+				reverseCurrentHostIp:                        {PTR: []string{toDnsHostName(configuration.VerifierDomain)}},
+			},
+		}
+		auditorResult := HostAudit(context.Background(), configuration, currentHostIp)
+
+		assert.Equal(t, currentHostIp, auditorResult.CurrentHostIp)
+		assert.Nil(t, auditorResult.Warnings)
+		assert.NotSame(t, configuration, auditorResult.Configuration)
+		assert.Equal(t, configuration.VerifierDomain, auditorResult.Configuration.VerifierDomain)
+	})
+
+	t.Run("when A and PTR records do not refer to current host", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver = &mockdns.Resolver{
+			Zones: map[string]mockdns.Zone{
+				toDnsHostName(configuration.VerifierDomain): {A: []string{randomIpAddress()}},
+				reverseCurrentHostIp:                        {PTR: []string{toDnsHostName(randomDomain())}},
+			},
+		}
+		auditorResult := HostAudit(context.Background(), configuration, currentHostIp)
+
+		assert.Equal(t, map[string]string{auditTypeDns: auditDnsWarning, auditTypePtr: auditPtrWarning}, auditorResult.Warnings)
+	})
+
+	t.Run("when DNS records not found", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver = &mockdns.Resolver{}
+		auditorResult := HostAudit(context.Background(), configuration, currentHostIp)
+
+		assert.Equal(t, map[string]string{auditTypeDns: auditDnsWarning, auditTypePtr: auditPtrWarning}, auditorResult.Warnings)
+	})
+
+	t.Run("uses first SMTP source address as current host ip address", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver = &mockdns.Resolver{}
+		configuration.SmtpSourceAddresses = []SmtpSourceAddress{{IpAddress: currentHostIp}, {IpAddress: randomIpAddress()}}
+
+		assert.Equal(t, currentHostIp, HostAudit(context.Background(), configuration, emptyString).CurrentHostIp)
+	})
+
+	t.Run("when current host ip address can't be detected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		auditorResult := HostAudit(ctx, createConfiguration(), emptyString)
+
+		assert.Empty(t, auditorResult.CurrentHostIp)
+		assert.Equal(t, map[string]string{auditTypeIp: auditIpWarning}, auditorResult.Warnings)
+	})
+}
+
+func TestDetectCurrentHostIp(t *testing.T) {
+	t.Run("returns local address of outbound connection to DNS server", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Dns = localhostIPv4Address + ":53"
+
+		assert.Equal(t, localhostIPv4Address, detectCurrentHostIp(context.Background(), configuration))
+	})
+
+	t.Run("returns local address of outbound connection", func(t *testing.T) {
+		currentHostIp := detectCurrentHostIp(context.Background(), createConfiguration())
+		if currentHostIp == emptyString {
+			t.Skip("network is unreachable")
+		}
+
+		assert.NotNil(t, net.ParseIP(currentHostIp))
+	})
+}
+
+func TestCurrentHostIpProbeAddress(t *testing.T) {
+	t.Run("when system DNS server is used", func(t *testing.T) {
+		assert.Equal(t, auditIpProbeAddress, currentHostIpProbeAddress(createConfiguration()))
+	})
+
+	t.Run("when custom resolver specified", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver, configuration.Dns = &mockdns.Resolver{}, randomDnsServer()
+
+		assert.Equal(t, auditIpProbeAddress, currentHostIpProbeAddress(configuration))
+	})
+
+	t.Run("when DNS servers specified", func(t *testing.T) {
+		dnsServer, otherDnsServer := randomDnsServer(), randomDnsServer()
+		configuration := createConfiguration()
+		configuration.DnsServers = []string{dnsServer, otherDnsServer}
+
+		assert.Equal(t, dnsServer, currentHostIpProbeAddress(configuration))
+	})
+
+	t.Run("when DNS gateway and DNS servers specified", func(t *testing.T) {
+		dnsServer := randomDnsServer()
+		configuration := createConfiguration()
+		configuration.Dns, configuration.DnsServers = dnsServer, []string{randomDnsServer()}
+
+		assert.Equal(t, dnsServer, currentHostIpProbeAddress(configuration))
+	})
+
+	t.Run("when DNS-over-HTTPS URL without port number specified", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportHttps, "https://1.1.1.1/dns-query"
+
+		assert.Equal(t, "1.1.1.1:443", currentHostIpProbeAddress(configuration))
+	})
+
+	t.Run("when DNS-over-HTTPS URL with port number specified", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportHttps, "https://[2606:4700:4700::1111]:8443/dns-query"
+
+		assert.Equal(t, "[2606:4700:4700::1111]:8443", currentHostIpProbeAddress(configuration))
+	})
+}
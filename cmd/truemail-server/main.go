@@ -0,0 +1,116 @@
+// Command truemail-server runs HTTP JSON validation server, Go counterpart to truemail-rack.
+//
+// Configuration is built from command-line flags, TRUEMAIL_* environment variables
+// and JSON config file. Server shuts down gracefully on SIGINT or SIGTERM.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/truemail-rb/truemail-go"
+	"github.com/truemail-rb/truemail-go/internal/options"
+	"github.com/truemail-rb/truemail-go/server"
+)
+
+const (
+	commandName           = "truemail-server"
+	optionAddress         = "address"
+	optionAccessTokens    = "access-tokens"
+	optionRequestTimeout  = "request-timeout"
+	optionShutdownTimeout = "shutdown-timeout"
+	exitCodeSuccess       = 0
+	exitCodeError         = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stderr, os.LookupEnv))
+}
+
+// Runs validation server until context is done. Writes errors into output. Returns exit code
+func run(ctx context.Context, args []string, output io.Writer, lookupEnv func(string) (string, bool)) int {
+	serverAttr, err := parseServerAttr(args, lookupEnv, output)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitCodeSuccess
+	}
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return exitCodeError
+	}
+
+	validationServer, err := server.NewServer(serverAttr)
+	if err == nil {
+		fmt.Fprintf(output, "%s is listening on %s\n", commandName, validationServer.Address())
+		err = validationServer.ListenAndServe(ctx)
+		serverAttr.Configuration.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return exitCodeError
+	}
+
+	return exitCodeSuccess
+}
+
+// Returns command-line options: server options and configuration attributes options
+func availableOptions() []*options.Option {
+	return append(
+		[]*options.Option{
+			{Name: optionAddress, Usage: "server address (default :9292)"},
+			{Name: optionAccessTokens, Usage: "comma separated access tokens"},
+			{Name: optionRequestTimeout, Usage: "validation request timeout in seconds (default 30)"},
+			{Name: optionShutdownTimeout, Usage: "graceful shutdown timeout in seconds (default 10)"},
+		},
+		options.ConfigurationOptions()...,
+	)
+}
+
+// Parses command-line flags, environment variables and config file. Returns server attributes
+// with shared configuration or error if parsing fails. Returns flag.ErrHelp when help is requested
+func parseServerAttr(args []string, lookupEnv func(string) (string, bool), output io.Writer) (server.ServerAttr, error) {
+	var serverAttr server.ServerAttr
+	availableOptions := availableOptions()
+	flagSet := flag.NewFlagSet(commandName, flag.ContinueOnError)
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags]\n\n", commandName)
+		fmt.Fprintf(output, "Runs HTTP JSON validation server.\n")
+		fmt.Fprintf(output, "Each flag can be set with TRUEMAIL_<FLAG> environment variable or config file key.\n\n")
+		flagSet.PrintDefaults()
+	}
+
+	values, err := options.Parse(flagSet, availableOptions, args, lookupEnv)
+	if err != nil {
+		return serverAttr, err
+	}
+
+	configurationAttr, err := values.ConfigurationAttr(availableOptions)
+	if err != nil {
+		return serverAttr, err
+	}
+	if serverAttr.Configuration, err = truemail.NewConfiguration(configurationAttr); err != nil {
+		return serverAttr, err
+	}
+	if serverAttr.RequestTimeout, err = values.Int(optionRequestTimeout, 0); err != nil {
+		return serverAttr, err
+	}
+	if serverAttr.ShutdownTimeout, err = values.Int(optionShutdownTimeout, 0); err != nil {
+		return serverAttr, err
+	}
+	serverAttr.Address = values[optionAddress]
+	if accessTokens, ok := values[optionAccessTokens]; ok {
+		serverAttr.AccessTokens = strings.Split(accessTokens, ",")
+	}
+
+	return serverAttr, nil
+}
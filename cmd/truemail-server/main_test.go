@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns environment lookup function of environment variables
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestRun(t *testing.T) {
+	env := map[string]string{
+		"TRUEMAIL_VERIFIER_EMAIL": "verifier@example.com",
+		"TRUEMAIL_ACCESS_TOKENS":  "access-token",
+		"TRUEMAIL_ADDRESS":        "127.0.0.1:0",
+	}
+
+	t.Run("runs validation server until context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		output := new(bytes.Buffer)
+
+		assert.Equal(t, exitCodeSuccess, run(ctx, nil, output, lookupEnv(env)))
+		assert.Equal(t, "truemail-server is listening on 127.0.0.1:0\n", output.String())
+	})
+
+	t.Run("when help is requested", func(t *testing.T) {
+		output := new(bytes.Buffer)
+
+		assert.Equal(t, exitCodeSuccess, run(context.Background(), []string{"-h"}, output, lookupEnv(nil)))
+		assert.Contains(t, output.String(), "Usage: truemail-server [flags]")
+	})
+
+	t.Run("when access tokens are not specified", func(t *testing.T) {
+		output := new(bytes.Buffer)
+
+		assert.Equal(t, exitCodeError, run(context.Background(), []string{"-verifier-email", "verifier@example.com"}, output, lookupEnv(nil)))
+		assert.Equal(t, "at least one access token is required\n", output.String())
+	})
+}
+
+func TestParseServerAttr(t *testing.T) {
+	t.Run("returns server attributes with shared configuration", func(t *testing.T) {
+		serverAttr, err := parseServerAttr(
+			[]string{
+				"-verifier-email", "verifier@example.com",
+				"-validation-type", "mx",
+				"-access-tokens", "token1,token2",
+				"-address", ":8080",
+				"-request-timeout", "5",
+				"-shutdown-timeout", "1",
+			},
+			lookupEnv(nil),
+			new(bytes.Buffer),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "verifier@example.com", serverAttr.Configuration.VerifierEmail)
+		assert.Equal(t, "mx", serverAttr.Configuration.ValidationTypeDefault)
+		assert.Equal(t, []string{"token1", "token2"}, serverAttr.AccessTokens)
+		assert.Equal(t, ":8080", serverAttr.Address)
+		assert.Equal(t, 5, serverAttr.RequestTimeout)
+		assert.Equal(t, 1, serverAttr.ShutdownTimeout)
+	})
+
+	t.Run("when configuration is invalid", func(t *testing.T) {
+		_, err := parseServerAttr(nil, lookupEnv(nil), new(bytes.Buffer))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("when request timeout is invalid", func(t *testing.T) {
+		_, err := parseServerAttr(
+			[]string{"-verifier-email", "verifier@example.com", "-request-timeout", "random"},
+			lookupEnv(nil),
+			new(bytes.Buffer),
+		)
+
+		assert.EqualError(t, err, "random is invalid value of request-timeout option: integer is expected")
+	})
+}
@@ -0,0 +1,92 @@
+// Command truemail validates email addresses from the terminal and scripts.
+//
+// Emails are passed as arguments or via stdin, one per line. Configuration is built
+// from command-line flags, TRUEMAIL_* environment variables and JSON config file.
+// Exit code is 0 when all emails are valid, 1 when at least one email is invalid
+// and 2 when configuration or input is invalid.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/truemail-rb/truemail-go"
+)
+
+const (
+	commandName    = "truemail"
+	emptyString    = ""
+	optionFormat   = "format"
+	formatText     = "text"
+	formatJson     = "json"
+	stdinArgument  = "-"
+	exitCodeValid  = 0
+	exitCodeFailed = 1
+	exitCodeError  = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.LookupEnv))
+}
+
+// Runs truemail command-line tool. Validates emails from command-line arguments or stdin
+// and writes validation results into stdout, errors into stderr. Returns exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) int {
+	cli, err := parseCli(args, lookupEnv, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitCodeValid
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitCodeError
+	}
+
+	configuration, err := truemail.NewConfiguration(cli.configurationAttr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitCodeError
+	}
+
+	emails := cli.emails
+	if len(emails) == 0 || (len(emails) == 1 && emails[0] == stdinArgument) {
+		if emails, err = readEmails(stdin); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitCodeError
+		}
+	}
+
+	exitCode, writeResult := exitCodeValid, newResultWriter(cli.format, stdout)
+	for _, email := range emails {
+		validatorResult, err := truemail.Validate(email, configuration)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitCodeError
+		}
+		if err := writeResult(validatorResult); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitCodeError
+		}
+		if !validatorResult.Success {
+			exitCode = exitCodeFailed
+		}
+	}
+
+	return exitCode
+}
+
+// Reads emails from reader, one per line. Blank lines are skipped
+func readEmails(reader io.Reader) (emails []string, err error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if email := strings.TrimSpace(scanner.Text()); email != emptyString {
+			emails = append(emails, email)
+		}
+	}
+
+	return emails, scanner.Err()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/truemail-rb/truemail-go"
+)
+
+// Returns environment lookup function of environment variables
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+// Runs truemail command-line tool. Returns exit code, stdout and stderr
+func runCommand(args []string, stdin string, env map[string]string) (int, string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	exitCode := run(args, strings.NewReader(stdin), stdout, stderr, lookupEnv(env))
+	return exitCode, stdout.String(), stderr.String()
+}
+
+// Writes config file into temporary directory. Returns config file path
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "truemail.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestRun(t *testing.T) {
+	verifierEmail := "verifier@example.com"
+
+	t.Run("validates emails passed as arguments", func(t *testing.T) {
+		exitCode, stdout, stderr := runCommand(
+			[]string{"-verifier-email", verifierEmail, "-validation-type", "regex", "email@example.com", "email@other.com"},
+			"",
+			nil,
+		)
+
+		assert.Equal(t, exitCodeValid, exitCode)
+		assert.Equal(t, "email@example.com: valid (regex)\nemail@other.com: valid (regex)\n", stdout)
+		assert.Empty(t, stderr)
+	})
+
+	t.Run("validates emails passed via stdin, skips blank lines", func(t *testing.T) {
+		exitCode, stdout, _ := runCommand(
+			[]string{"-verifier-email", verifierEmail, "-validation-type", "regex", stdinArgument},
+			"email@example.com\n\n  invalid-email  \n",
+			nil,
+		)
+
+		assert.Equal(t, exitCodeFailed, exitCode)
+		assert.Equal(
+			t,
+			"email@example.com: valid (regex)\ninvalid-email: invalid (regex) - regex: email does not match the regular expression\n",
+			stdout,
+		)
+	})
+
+	t.Run("prints validation results in JSON format", func(t *testing.T) {
+		exitCode, stdout, _ := runCommand(
+			[]string{"-verifier-email", verifierEmail, "-validation-type", "regex", "-format", "json", "email@example.com", "invalid-email"},
+			"",
+			nil,
+		)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		var validResult, invalidResult truemail.ValidatorResultJson
+
+		assert.Equal(t, exitCodeFailed, exitCode)
+		assert.Len(t, lines, 2)
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &validResult))
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &invalidResult))
+		assert.True(t, validResult.Success)
+		assert.Equal(t, "email@example.com", validResult.Email)
+		assert.False(t, invalidResult.Success)
+		assert.Contains(t, invalidResult.Errors, "regex")
+	})
+
+	t.Run("builds configuration from environment variables", func(t *testing.T) {
+		exitCode, stdout, _ := runCommand(
+			[]string{"email@example.com"},
+			"",
+			map[string]string{"TRUEMAIL_VERIFIER_EMAIL": verifierEmail, "TRUEMAIL_VALIDATION_TYPE": "regex"},
+		)
+
+		assert.Equal(t, exitCodeValid, exitCode)
+		assert.Equal(t, "email@example.com: valid (regex)\n", stdout)
+	})
+
+	t.Run("builds configuration from config file", func(t *testing.T) {
+		configPath := writeConfigFile(
+			t,
+			`{"verifier_email": "verifier@example.com", "validation_type": "regex", "blacklisted_domains": ["example.com"]}`,
+		)
+		exitCode, stdout, _ := runCommand([]string{"-config", configPath, "email@example.com"}, "", nil)
+
+		assert.Equal(t, exitCodeFailed, exitCode)
+		assert.Equal(t, "email@example.com: invalid (blacklist) - domain_list_match: blacklisted email\n", stdout)
+	})
+
+	t.Run("flags take precedence over environment variables and config file", func(t *testing.T) {
+		configPath := writeConfigFile(t, `{"verifier_email": "verifier@example.com", "validation_type": "mx"}`)
+		exitCode, stdout, _ := runCommand(
+			[]string{"-validation-type", "regex", "email@example.com"},
+			"",
+			map[string]string{"TRUEMAIL_CONFIG": configPath, "TRUEMAIL_VALIDATION_TYPE": "smtp"},
+		)
+
+		assert.Equal(t, exitCodeValid, exitCode)
+		assert.Equal(t, "email@example.com: valid (regex)\n", stdout)
+	})
+
+	t.Run("when help is requested", func(t *testing.T) {
+		exitCode, stdout, stderr := runCommand([]string{"-h"}, "", nil)
+
+		assert.Equal(t, exitCodeValid, exitCode)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Usage: truemail [flags] [email ...]")
+	})
+
+	t.Run("when configuration is invalid", func(t *testing.T) {
+		exitCode, stdout, stderr := runCommand([]string{"email@example.com"}, "", nil)
+
+		assert.Equal(t, exitCodeError, exitCode)
+		assert.Empty(t, stdout)
+		assert.NotEmpty(t, stderr)
+	})
+
+	t.Run("when validation type is invalid", func(t *testing.T) {
+		exitCode, _, stderr := runCommand([]string{"-verifier-email", verifierEmail, "-validation-type", "random", "email@example.com"}, "", nil)
+
+		assert.Equal(t, exitCodeError, exitCode)
+		assert.NotEmpty(t, stderr)
+	})
+}
+
+func TestParseCli(t *testing.T) {
+	t.Run("assigns configuration attributes of options", func(t *testing.T) {
+		cli, err := parseCli(
+			[]string{
+				"-verifier-email", "verifier@example.com",
+				"-connection-timeout", "5",
+				"-whitelisted-domains", "example.com, other.com",
+				"-smtp-fail-fast",
+				"email@example.com",
+			},
+			lookupEnv(nil),
+			new(bytes.Buffer),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "verifier@example.com", cli.configurationAttr.VerifierEmail)
+		assert.Equal(t, 5, cli.configurationAttr.ConnectionTimeout)
+		assert.Equal(t, []string{"example.com", "other.com"}, cli.configurationAttr.WhitelistedDomains)
+		assert.True(t, cli.configurationAttr.SmtpFailFast)
+		assert.Equal(t, formatText, cli.format)
+		assert.Equal(t, []string{"email@example.com"}, cli.emails)
+	})
+
+	t.Run("when option value is invalid", func(t *testing.T) {
+		_, err := parseCli([]string{"-smtp-port", "port"}, lookupEnv(nil), new(bytes.Buffer))
+
+		assert.EqualError(t, err, "port is invalid value of smtp-port option: integer is expected")
+	})
+
+	t.Run("when output format is invalid", func(t *testing.T) {
+		_, err := parseCli([]string{"-format", "xml"}, lookupEnv(nil), new(bytes.Buffer))
+
+		assert.EqualError(t, err, "xml is invalid output format, use one of these: text, json")
+	})
+}
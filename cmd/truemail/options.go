@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/truemail-rb/truemail-go"
+	"github.com/truemail-rb/truemail-go/internal/options"
+)
+
+// Parsed command-line structure. Includes configuration attributes, output format and
+// emails passed as command-line arguments
+type cli struct {
+	configurationAttr truemail.ConfigurationAttr
+	format            string
+	emails            []string
+}
+
+// Returns command-line options: output format and configuration attributes options
+func availableOptions() []*options.Option {
+	return append(
+		[]*options.Option{{Name: optionFormat, Usage: "output format: text, json (default text)"}},
+		options.ConfigurationOptions()...,
+	)
+}
+
+// Parses command-line flags, environment variables and config file. Returns parsed
+// command-line or error if parsing fails. Returns flag.ErrHelp when help is requested
+func parseCli(args []string, lookupEnv func(string) (string, bool), output io.Writer) (*cli, error) {
+	availableOptions := availableOptions()
+	flagSet := flag.NewFlagSet(commandName, flag.ContinueOnError)
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags] [email ...]\n\n", commandName)
+		fmt.Fprintf(output, "Validates emails passed as arguments or via stdin, one per line.\n")
+		fmt.Fprintf(output, "Each flag can be set with TRUEMAIL_<FLAG> environment variable or config file key.\n\n")
+		flagSet.PrintDefaults()
+	}
+
+	values, err := options.Parse(flagSet, availableOptions, args, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := &cli{format: formatText, emails: flagSet.Args()}
+	if format, ok := values[optionFormat]; ok {
+		cli.format = format
+	}
+	if cli.format != formatText && cli.format != formatJson {
+		return nil, fmt.Errorf("%s is invalid output format, use one of these: %s, %s", cli.format, formatText, formatJson)
+	}
+
+	if cli.configurationAttr, err = values.ConfigurationAttr(availableOptions); err != nil {
+		return nil, err
+	}
+
+	return cli, nil
+}
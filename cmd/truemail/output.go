@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/truemail-rb/truemail-go"
+)
+
+// Returns writer of validator results with output format: JSON writer writes
+// one JSON object per line, text writer writes one human-readable line per email
+func newResultWriter(format string, output io.Writer) func(*truemail.ValidatorResult) error {
+	if format == formatJson {
+		encoder := json.NewEncoder(output)
+		return func(validatorResult *truemail.ValidatorResult) error {
+			return encoder.Encode(truemail.NewValidatorResultJson(validatorResult))
+		}
+	}
+
+	return func(validatorResult *truemail.ValidatorResult) error {
+		_, err := fmt.Fprintln(output, textResult(validatorResult))
+		return err
+	}
+}
+
+// Returns human-readable validator result: email, verdict and validation
+// type, followed by validation errors sorted by validation layer
+func textResult(validatorResult *truemail.ValidatorResult) string {
+	verdict := "valid"
+	if !validatorResult.Success {
+		verdict = "invalid"
+	}
+
+	result := fmt.Sprintf("%s: %s (%s)", validatorResult.Email, verdict, validatorResult.ValidationType)
+	if len(validatorResult.Errors) == 0 {
+		return result
+	}
+
+	layers := make([]string, 0, len(validatorResult.Errors))
+	for layer := range validatorResult.Errors {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+
+	errors := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		errors = append(errors, layer+": "+validatorResult.Errors[layer])
+	}
+
+	return result + " - " + strings.Join(errors, ", ")
+}
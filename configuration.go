@@ -2,18 +2,64 @@ package truemail
 
 import (
 	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
 	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Configuration structure
 type Configuration struct {
 	ctx                                                                  context.Context
+	eventLogBuffer                                                       *eventLogBuffer
 	VerifierEmail, VerifierDomain, ValidationTypeDefault, Dns            string
+	DnsTransport, DnsTlsServerName, DnsStrategy, DnsClient               string
+	DnsServers                                                           []string
+	DnsTlsConfig                                                         *tls.Config
 	ConnectionTimeout, ResponseTimeout, ConnectionAttempts, SmtpPort     int
 	WhitelistedDomains, BlacklistedDomains, BlacklistedMxIpAddresses     []string
 	ValidationTypeByDomain                                               map[string]string
 	WhitelistValidation, NotRfcMxLookupFlow, SmtpFailFast, SmtpSafeCheck bool
 	EmailPattern, SmtpErrorBodyPattern                                   *regexp.Regexp
+	DnsCache                                                             DnsCache
+	DnsCacheMinTtl, DnsCacheMaxTtl, DnsCacheNegativeTtl                  int
+	Resolver                                                             Resolver
+	MtaStsCheck                                                          bool
+	MtaStsHttpClient                                                     HttpClient
+	DaneCheck                                                            bool
+	SmtpTlsPolicy                                                        string
+	SmtpTlsConfig                                                        *tls.Config
+	SmtpProxy                                                            string
+	SmtpSourceAddresses                                                  []SmtpSourceAddress
+	SmtpSourceRotation                                                   string
+	SmtpRateLimitPerHost, SmtpRateLimitPerDomain                         SmtpRateLimit
+	SmtpRateLimitByProvider                                              map[string]SmtpRateLimit
+	SmtpRateLimitMaxWait                                                 int
+	SmtpGreylistingPattern                                               *regexp.Regexp
+	SmtpGreylistingRetryDelay, SmtpGreylistingRetryAttempts              int
+	SmtpGreylistingCallback                                              func(*ValidatorResult)
+	SmtpProviderProfiles                                                 []SmtpProviderProfile
+	SmtpParallelProbing                                                  bool
+	SmtpParallelProbingLimit                                             int
+	SmtpConnectionPool                                                   bool
+	SmtpConnectionPoolIdleTtl, SmtpConnectionPoolMaxRcpt                 int
+	SmtpConnectionPoolSize                                               int
+	ResultCache                                                          ResultCache
+	ResultCacheValidTtl, ResultCacheInvalidTtl, ResultCacheUnknownTtl    int
+	MetricsCollector                                                     MetricsCollector
+	TracerProvider                                                       trace.TracerProvider
+	Logger                                                               *slog.Logger
+	LogLevel                                                             slog.Level
+	LogFailedValidationsOnly                                             bool
+	LogEmailRedaction                                                    string
+	dnsServersHealth                                                     *dnsServersHealth
+	smtpSourceRotator                                                    *smtpSourceRotator
+	smtpRateLimiter                                                      *smtpRateLimiter
+	smtpSessionPool                                                      *smtpSessionPool
+	mtaStsPolicyCache                                                    *mtaStsPolicyCache
 }
 
 // NewConfiguration returns new valid newConfiguration structure
@@ -26,25 +72,246 @@ func NewConfiguration(config ConfigurationAttr) (*Configuration, error) {
 	}
 
 	newConfiguration := Configuration{
-		ctx:                      config.ctx,
-		VerifierEmail:            config.VerifierEmail,
-		VerifierDomain:           config.VerifierDomain,
-		ValidationTypeDefault:    config.ValidationTypeDefault,
-		ConnectionTimeout:        config.ConnectionTimeout,
-		ResponseTimeout:          config.ResponseTimeout,
-		ConnectionAttempts:       config.ConnectionAttempts,
-		WhitelistedDomains:       config.WhitelistedDomains,
-		BlacklistedDomains:       config.BlacklistedDomains,
-		BlacklistedMxIpAddresses: config.BlacklistedMxIpAddresses,
-		Dns:                      config.Dns,
-		ValidationTypeByDomain:   config.ValidationTypeByDomain,
-		WhitelistValidation:      config.WhitelistValidation,
-		NotRfcMxLookupFlow:       config.NotRfcMxLookupFlow,
-		SmtpPort:                 config.SmtpPort,
-		SmtpFailFast:             config.SmtpFailFast,
-		SmtpSafeCheck:            config.SmtpSafeCheck,
-		EmailPattern:             config.RegexEmail,
-		SmtpErrorBodyPattern:     config.RegexSmtpErrorBody,
+		ctx:                          config.ctx,
+		VerifierEmail:                config.VerifierEmail,
+		VerifierDomain:               config.VerifierDomain,
+		ValidationTypeDefault:        config.ValidationTypeDefault,
+		ConnectionTimeout:            config.ConnectionTimeout,
+		ResponseTimeout:              config.ResponseTimeout,
+		ConnectionAttempts:           config.ConnectionAttempts,
+		WhitelistedDomains:           config.WhitelistedDomains,
+		BlacklistedDomains:           config.BlacklistedDomains,
+		BlacklistedMxIpAddresses:     config.BlacklistedMxIpAddresses,
+		Dns:                          config.Dns,
+		DnsTransport:                 config.DnsTransport,
+		DnsTlsServerName:             config.DnsTlsServerName,
+		DnsTlsConfig:                 config.DnsTlsConfig,
+		DnsServers:                   config.DnsServers,
+		DnsStrategy:                  config.DnsStrategy,
+		DnsClient:                    config.DnsClient,
+		Resolver:                     config.Resolver,
+		MtaStsCheck:                  config.MtaStsCheck,
+		MtaStsHttpClient:             config.buildMtaStsHttpClient(config.MtaStsHttpClient),
+		DaneCheck:                    config.DaneCheck,
+		dnsServersHealth:             newDnsServersHealth(),
+		mtaStsPolicyCache:            newMtaStsPolicyCache(),
+		ValidationTypeByDomain:       config.ValidationTypeByDomain,
+		WhitelistValidation:          config.WhitelistValidation,
+		NotRfcMxLookupFlow:           config.NotRfcMxLookupFlow,
+		SmtpPort:                     config.SmtpPort,
+		SmtpFailFast:                 config.SmtpFailFast,
+		SmtpSafeCheck:                config.SmtpSafeCheck,
+		SmtpTlsPolicy:                config.SmtpTlsPolicy,
+		SmtpTlsConfig:                config.SmtpTlsConfig,
+		SmtpProxy:                    config.SmtpProxy,
+		SmtpSourceAddresses:          config.SmtpSourceAddresses,
+		SmtpSourceRotation:           config.SmtpSourceRotation,
+		smtpSourceRotator:            newSmtpSourceRotator(),
+		SmtpRateLimitPerHost:         config.SmtpRateLimitPerHost,
+		SmtpRateLimitPerDomain:       config.SmtpRateLimitPerDomain,
+		SmtpRateLimitByProvider:      config.SmtpRateLimitByProvider,
+		SmtpRateLimitMaxWait:         config.SmtpRateLimitMaxWait,
+		smtpRateLimiter:              newSmtpRateLimiter(),
+		SmtpGreylistingPattern:       config.RegexSmtpGreylisting,
+		SmtpGreylistingRetryDelay:    config.SmtpGreylistingRetryDelay,
+		SmtpGreylistingRetryAttempts: config.SmtpGreylistingRetryAttempts,
+		SmtpGreylistingCallback:      config.SmtpGreylistingCallback,
+		SmtpProviderProfiles:         config.buildSmtpProviderProfiles(config.SmtpProviderProfiles),
+		SmtpParallelProbing:          config.SmtpParallelProbing,
+		SmtpParallelProbingLimit:     config.SmtpParallelProbingLimit,
+		SmtpConnectionPool:           config.SmtpConnectionPool,
+		SmtpConnectionPoolIdleTtl:    config.SmtpConnectionPoolIdleTtl,
+		SmtpConnectionPoolMaxRcpt:    config.SmtpConnectionPoolMaxRcpt,
+		SmtpConnectionPoolSize:       config.SmtpConnectionPoolSize,
+		smtpSessionPool:              config.buildSmtpSessionPool(),
+		ResultCache:                  config.ResultCache,
+		ResultCacheValidTtl:          config.ResultCacheValidTtl,
+		ResultCacheInvalidTtl:        config.ResultCacheInvalidTtl,
+		ResultCacheUnknownTtl:        config.ResultCacheUnknownTtl,
+		MetricsCollector:             config.MetricsCollector,
+		TracerProvider:               config.TracerProvider,
+		Logger:                       config.Logger,
+		LogLevel:                     config.LogLevel,
+		LogFailedValidationsOnly:     config.LogFailedValidationsOnly,
+		LogEmailRedaction:            config.LogEmailRedaction,
+		EmailPattern:                 config.RegexEmail,
+		SmtpErrorBodyPattern:         config.RegexSmtpErrorBody,
+		DnsCache:                     config.DnsCache,
+		DnsCacheMinTtl:               config.DnsCacheMinTtl,
+		DnsCacheMaxTtl:               config.DnsCacheMaxTtl,
+		DnsCacheNegativeTtl:          config.DnsCacheNegativeTtl,
 	}
 	return &newConfiguration, err
 }
+
+// Configuration methods
+
+// Returns copy of DNS TLS config with TLS server name. For DNS-over-TLS transport
+// TLS server name is equal to DNS server host when it is not specified
+func (configuration *Configuration) dnsTlsConfig(dnsServer string) *tls.Config {
+	tlsConfig := new(tls.Config)
+	if configuration.DnsTlsConfig != nil {
+		tlsConfig = configuration.DnsTlsConfig.Clone()
+	}
+
+	if configuration.DnsTlsServerName != emptyString {
+		tlsConfig.ServerName = configuration.DnsTlsServerName
+	} else if tlsConfig.ServerName == emptyString && configuration.DnsTransport == dnsTransportTls {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(dnsServer)
+	}
+
+	return tlsConfig
+}
+
+// Returns copy of SMTP source address for target domain rotated round-robin or per
+// target domain. Returns nil when SMTP source addresses are not specified
+func (configuration *Configuration) smtpSourceAddress(domain string) *SmtpSourceAddress {
+	sourceAddressesCount := len(configuration.SmtpSourceAddresses)
+	if sourceAddressesCount == 0 {
+		return nil
+	}
+
+	position := 0
+	switch configuration.SmtpSourceRotation {
+	case smtpSourceRotationPerDomain:
+		position = smtpSourcePositionByDomain(domain, sourceAddressesCount)
+	default:
+		position = configuration.smtpSourceRotator.nextPosition() % sourceAddressesCount
+	}
+	sourceAddress := configuration.SmtpSourceAddresses[position]
+
+	return &sourceAddress
+}
+
+// Takes SMTP rate limit token of recipient domain. Returns false when recipient domain
+// is rate limited or validation context is done while waiting for token
+func (configuration *Configuration) takeSmtpDomainToken(domain string) bool {
+	return configuration.smtpRateLimiter.take(
+		configuration.ctx,
+		smtpRateLimitDomainKeyPrefix+domain,
+		configuration.smtpRateLimit(configuration.SmtpRateLimitPerDomain, domain),
+		time.Duration(configuration.SmtpRateLimitMaxWait)*time.Second,
+	)
+}
+
+// Takes SMTP rate limit token of MX host ip address. Provider rate limit is matched by
+// MX host name, rate limit of SMTP provider profile overrides per host rate limit.
+// Returns false when MX host ip address is rate limited or validation context is done
+// while waiting for token
+func (configuration *Configuration) takeSmtpHostToken(ipAddress, hostName string) bool {
+	rateLimit := configuration.SmtpRateLimitPerHost
+	if profile := configuration.smtpProviderProfile(hostName); profile != nil && profile.RateLimit.isEnabled() {
+		rateLimit = profile.RateLimit
+	}
+
+	return configuration.smtpRateLimiter.take(
+		configuration.ctx,
+		smtpRateLimitHostKeyPrefix+ipAddress,
+		configuration.smtpRateLimit(rateLimit, hostName),
+		time.Duration(configuration.SmtpRateLimitMaxWait)*time.Second,
+	)
+}
+
+// Returns result cache TTL of validator result verdict: valid, invalid or unknown
+func (configuration *Configuration) resultCacheTtl(validatorResult *ValidatorResult) time.Duration {
+	ttl := configuration.ResultCacheInvalidTtl
+	switch {
+	case validatorResult.Success:
+		ttl = configuration.ResultCacheValidTtl
+	case validatorResult.isUnknown():
+		ttl = configuration.ResultCacheUnknownTtl
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// Returns copy of first SMTP provider profile which matches MX host name.
+// Returns nil when MX host name does not match SMTP provider profiles
+func (configuration *Configuration) smtpProviderProfile(hostName string) *SmtpProviderProfile {
+	if hostName == emptyString {
+		return nil
+	}
+
+	for _, profile := range configuration.SmtpProviderProfiles {
+		if profile.isMatched(hostName) {
+			return &profile
+		}
+	}
+
+	return nil
+}
+
+// Returns provider SMTP rate limit when domain is equal to provider domain or its
+// subdomain, the most specific provider domain wins. Otherwise returns default rate limit
+func (configuration *Configuration) smtpRateLimit(defaultRateLimit SmtpRateLimit, domain string) SmtpRateLimit {
+	rateLimit, matchedProvider := defaultRateLimit, emptyString
+	for provider, providerRateLimit := range configuration.SmtpRateLimitByProvider {
+		if !isDomainOrSubdomain(domain, provider) || len(provider) <= len(matchedProvider) {
+			continue
+		}
+		rateLimit, matchedProvider = providerRateLimit, provider
+	}
+
+	return rateLimit
+}
+
+// Returns unique DNS servers: DNS gateway followed by DNS servers list.
+// Returns empty slice when system DNS server should be used
+func (configuration *Configuration) dnsServers() []string {
+	dnsServers := configuration.DnsServers
+	if configuration.Dns != emptyString {
+		dnsServers = append([]string{configuration.Dns}, dnsServers...)
+	}
+
+	return uniqStrings(dnsServers)
+}
+
+// Closes SMTP connection pool of configuration: idle SMTP sessions are ended gracefully
+// with QUIT. Configuration can be used after close, SMTP sessions are not pooled anymore.
+// Flushes pending changes of result cache which implements Flush, flush error is logged
+func (configuration *Configuration) Close() {
+	if configuration.smtpSessionPool != nil {
+		configuration.smtpSessionPool.Close()
+	}
+	if resultCache, ok := configuration.ResultCache.(interface{ Flush() error }); ok {
+		configuration.logResultCacheError(resultCache.Flush())
+	}
+}
+
+// Returns event logger with logger, log level, log email redaction and event log buffer
+// of validation from configuration, returns nil when logger is not specified
+func (configuration *Configuration) eventLogger() *eventLogger {
+	eventLogger := newEventLogger(configuration.Logger, configuration.LogLevel)
+	if eventLogger != nil {
+		eventLogger.emailRedaction, eventLogger.buffer = configuration.LogEmailRedaction, configuration.eventLogBuffer
+	}
+
+	return eventLogger
+}
+
+// Initializes event log buffer of validation when only failed validations should be logged,
+// so DNS and SMTP events of validation are logged only when validation outcome is known
+func (configuration *Configuration) initEventLogBuffer() {
+	if configuration.Logger != nil && configuration.LogFailedValidationsOnly {
+		configuration.eventLogBuffer = new(eventLogBuffer)
+	}
+}
+
+// Logs result cache error with configuration context and logger. Event is not held by
+// event log buffer of validation, because it is not related to validation outcome.
+// Does nothing when error is nil
+func (configuration *Configuration) logResultCacheError(err error) {
+	if err != nil {
+		newEventLogger(configuration.Logger, configuration.LogLevel).log(configuration.ctx, slog.LevelWarn, logMessageResultCacheError, errorLogAttr(err))
+	}
+}
+
+// Logs structured event with configuration context and logger
+func (configuration *Configuration) logEvent(level slog.Level, message string, attributes ...slog.Attr) {
+	configuration.eventLogger().log(configuration.ctx, level, message, attributes...)
+}
+
+// Returns email log attribute, email is redacted with log email redaction
+func (configuration *Configuration) emailLogAttr(email string) slog.Attr {
+	return slog.String(logAttrEmail, redactEmail(email, configuration.LogEmailRedaction))
+}
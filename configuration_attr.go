@@ -2,19 +2,61 @@ package truemail
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ConfigurationAttr kwargs structure for configuration builder
 type ConfigurationAttr struct {
 	ctx                                                                                           context.Context
 	VerifierEmail, VerifierDomain, ValidationTypeDefault, EmailPattern, SmtpErrorBodyPattern, Dns string
+	DnsTransport, DnsTlsServerName, DnsStrategy, DnsClient                                        string
+	DnsServers                                                                                    []string
+	DnsTlsConfig                                                                                  *tls.Config
 	ConnectionTimeout, ResponseTimeout, ConnectionAttempts, SmtpPort                              int
 	WhitelistedDomains, BlacklistedDomains, BlacklistedMxIpAddresses                              []string
 	ValidationTypeByDomain                                                                        map[string]string
 	WhitelistValidation, NotRfcMxLookupFlow, SmtpFailFast, SmtpSafeCheck                          bool
 	RegexEmail, RegexSmtpErrorBody                                                                *regexp.Regexp
+	DnsCache                                                                                      DnsCache
+	DnsCacheSize, DnsCacheMinTtl, DnsCacheMaxTtl, DnsCacheNegativeTtl                             int
+	DnsCacheDisabled                                                                              bool
+	Resolver                                                                                      Resolver
+	MtaStsCheck                                                                                   bool
+	MtaStsHttpClient                                                                              HttpClient
+	DaneCheck                                                                                     bool
+	SmtpTlsPolicy                                                                                 string
+	SmtpTlsConfig                                                                                 *tls.Config
+	SmtpProxy                                                                                     string
+	SmtpSourceAddresses                                                                           []SmtpSourceAddress
+	SmtpSourceRotation                                                                            string
+	SmtpRateLimitPerHost, SmtpRateLimitPerDomain                                                  SmtpRateLimit
+	SmtpRateLimitByProvider                                                                       map[string]SmtpRateLimit
+	SmtpRateLimitMaxWait                                                                          int
+	SmtpGreylistingPattern                                                                        string
+	RegexSmtpGreylisting                                                                          *regexp.Regexp
+	SmtpGreylistingRetryDelay, SmtpGreylistingRetryAttempts                                       int
+	SmtpGreylistingCallback                                                                       func(*ValidatorResult)
+	SmtpProviderProfiles                                                                          []SmtpProviderProfile
+	SmtpParallelProbing                                                                           bool
+	SmtpParallelProbingLimit                                                                      int
+	SmtpConnectionPool                                                                            bool
+	SmtpConnectionPoolIdleTtl, SmtpConnectionPoolMaxRcpt, SmtpConnectionPoolSize                  int
+	ResultCache                                                                                   ResultCache
+	ResultCacheSize, ResultCacheValidTtl, ResultCacheInvalidTtl, ResultCacheUnknownTtl            int
+	MetricsCollector                                                                              MetricsCollector
+	TracerProvider                                                                                trace.TracerProvider
+	Logger                                                                                        *slog.Logger
+	LogLevel                                                                                      slog.Level
+	LogFailedValidationsOnly                                                                      bool
+	LogEmailRedaction                                                                             string
 }
 
 // ConfigurationAttr methods
@@ -43,6 +85,60 @@ func (config *ConfigurationAttr) assignDefaultValues() {
 	if config.SmtpPort == 0 {
 		config.SmtpPort = defaultSmtpPort
 	}
+	if config.SmtpTlsPolicy == emptyString {
+		config.SmtpTlsPolicy = smtpTlsPolicyNone
+	}
+	if config.SmtpGreylistingPattern == emptyString {
+		config.SmtpGreylistingPattern = regexSmtpGreylistingPattern
+	}
+	if config.SmtpGreylistingRetryAttempts == 0 {
+		config.SmtpGreylistingRetryAttempts = defaultSmtpGreylistingRetryAttempts
+	}
+	if config.SmtpConnectionPoolIdleTtl == 0 {
+		config.SmtpConnectionPoolIdleTtl = defaultSmtpConnectionPoolIdleTtl
+	}
+	if config.SmtpConnectionPoolMaxRcpt == 0 {
+		config.SmtpConnectionPoolMaxRcpt = defaultSmtpConnectionPoolMaxRcpt
+	}
+	if config.SmtpConnectionPoolSize == 0 {
+		config.SmtpConnectionPoolSize = defaultSmtpConnectionPoolSize
+	}
+	if config.SmtpSourceRotation == emptyString {
+		config.SmtpSourceRotation = smtpSourceRotationRoundRobin
+	}
+	if config.DnsTransport == emptyString {
+		config.DnsTransport = dnsTransportPlain
+	}
+	if config.DnsStrategy == emptyString {
+		config.DnsStrategy = dnsStrategyFailover
+	}
+	if config.DnsClient == emptyString {
+		config.DnsClient = dnsClientNet
+	}
+	if config.DnsCacheSize == 0 {
+		config.DnsCacheSize = defaultDnsCacheSize
+	}
+	if config.DnsCacheMinTtl == 0 {
+		config.DnsCacheMinTtl = defaultDnsCacheMinTtl
+	}
+	if config.DnsCacheMaxTtl == 0 {
+		config.DnsCacheMaxTtl = defaultDnsCacheMaxTtl
+	}
+	if config.DnsCacheNegativeTtl == 0 {
+		config.DnsCacheNegativeTtl = defaultDnsCacheNegativeTtl
+	}
+	if config.ResultCacheValidTtl == 0 {
+		config.ResultCacheValidTtl = defaultResultCacheValidTtl
+	}
+	if config.ResultCacheInvalidTtl == 0 {
+		config.ResultCacheInvalidTtl = defaultResultCacheInvalidTtl
+	}
+	if config.ResultCacheUnknownTtl == 0 {
+		config.ResultCacheUnknownTtl = defaultResultCacheUnknownTtl
+	}
+	if config.LogEmailRedaction == emptyString {
+		config.LogEmailRedaction = logEmailRedactionNone
+	}
 }
 
 // validates and coerces ConfigurationAttr fields context
@@ -97,13 +193,68 @@ func (config *ConfigurationAttr) validate() error {
 		return err
 	}
 
-	dns, err := config.validateWithFormatDnsServerContext(config.Dns)
+	err = config.validateDnsTransportContext(config.DnsTransport)
+	if err != nil {
+		return err
+	}
+
+	dns, err := config.validateWithFormatDnsServerByTransportContext(config.DnsTransport, config.Dns)
 	if err != nil {
 		return err
 	}
 
 	config.Dns = dns
 
+	config.DnsServers, err = config.validateWithFormatDnsServersContext(config.DnsTransport, config.DnsServers)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateDnsStrategyContext(config.DnsStrategy)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateDnsClientContext(config.DnsClient)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateDaneCheckContext()
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpTlsPolicyContext(config.SmtpTlsPolicy)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpProxyContext(config.SmtpProxy)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpSourceAddressesContext(config.SmtpSourceAddresses)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpSourceRotationContext(config.SmtpSourceRotation)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpRateLimitsContext()
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpProviderProfilesContext(config.SmtpProviderProfiles)
+	if err != nil {
+		return err
+	}
+
 	err = config.validateTypeByDomainContext(config.ValidationTypeByDomain)
 	if err != nil {
 		return err
@@ -119,9 +270,63 @@ func (config *ConfigurationAttr) validate() error {
 		return err
 	}
 
+	config.RegexSmtpGreylisting, err = newRegex(config.SmtpGreylistingPattern)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateIntegerNonNegative(config.SmtpGreylistingRetryDelay)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateIntegerNonNegative(config.SmtpGreylistingRetryAttempts)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateIntegerNonNegative(config.SmtpParallelProbingLimit)
+	if err != nil {
+		return err
+	}
+
+	err = config.validateSmtpConnectionPoolContext()
+	if err != nil {
+		return err
+	}
+
+	err = config.validateDnsCacheContext()
+	if err != nil {
+		return err
+	}
+
+	config.DnsCache = config.buildDnsCache(config.DnsCache, config.DnsCacheSize, config.DnsCacheDisabled)
+
+	err = config.validateResultCacheContext()
+	if err != nil {
+		return err
+	}
+
+	config.ResultCache = config.buildResultCache(config.ResultCache, config.ResultCacheSize)
+
+	err = config.validateLogEmailRedactionContext(config.LogEmailRedaction)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Returns custom MTA-STS HTTP client when specified. Otherwise returns
+// default MTA-STS HTTP client with connection timeout when MTA-STS check is enabled
+func (config *ConfigurationAttr) buildMtaStsHttpClient(httpClient HttpClient) HttpClient {
+	if httpClient != nil || !config.MtaStsCheck {
+		return httpClient
+	}
+
+	return newMtaStsHttpClient(time.Duration(config.ConnectionTimeout) * time.Second)
+}
+
 // Validates verifier email. Returns error if validation fails
 func (config *ConfigurationAttr) validateVerifierEmail(verifierEmail string) error {
 	if matchRegex(verifierEmail, emailCharsSize) && matchRegex(verifierEmail, regexEmailPattern) {
@@ -168,6 +373,14 @@ func (config *ConfigurationAttr) validateIntegerPositive(integer int) error {
 	return fmt.Errorf("%v should be a positive integer", integer)
 }
 
+// Validates is integer is a non-negative. Returns error if validation fails
+func (config *ConfigurationAttr) validateIntegerNonNegative(integer int) error {
+	if integer >= 0 {
+		return nil
+	}
+	return fmt.Errorf("%v should be a non-negative integer", integer)
+}
+
 // Validates is string matches to regex pattern. Returns error if validation fails
 func (config *ConfigurationAttr) validateStringContext(target, regexPattern, msg string) error {
 	if matchRegex(target, regexPattern) {
@@ -260,3 +473,349 @@ func (config *ConfigurationAttr) validateWithFormatDnsServerContext(dnsGateway s
 
 	return config.formatDns(dnsGateway), nil
 }
+
+// Validates DNS transport. Returns error if validation fails
+func (config *ConfigurationAttr) validateDnsTransportContext(dnsTransport string) error {
+	if dnsTransport == emptyString || isIncluded(availableDnsTransports(), dnsTransport) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid dns transport, use one of these: %s",
+		dnsTransport,
+		availableDnsTransports(),
+	)
+}
+
+// Validates DNS-over-TLS server context and returns formatted DNS server with
+// default DNS-over-TLS port number for cases when port number is not specified
+func (config *ConfigurationAttr) validateWithFormatDnsOverTlsServerContext(dnsGateway string) (string, error) {
+	err := config.validateStringContext(dnsGateway, regexDnsOverTlsServerPattern, "dns over tls server")
+	if err != nil {
+		return dnsGateway, err
+	}
+
+	if _, _, err = net.SplitHostPort(dnsGateway); err == nil {
+		return dnsGateway, nil
+	}
+
+	return serverWithPortNumber(dnsGateway, defaultDnsOverTlsPort), nil
+}
+
+// Validates DNS-over-HTTPS URL context. Returns error if validation fails
+func (config *ConfigurationAttr) validateDnsOverHttpsUrlContext(dnsGateway string) (string, error) {
+	dnsUrl, err := url.Parse(dnsGateway)
+	if err != nil || dnsUrl.Scheme != dnsTransportHttps || dnsUrl.Host == emptyString {
+		return dnsGateway, fmt.Errorf("%s is invalid dns over https url", dnsGateway)
+	}
+
+	return dnsGateway, nil
+}
+
+// Validates DNS gateway context depending on DNS transport. DNS gateway is required
+// for DNS-over-TLS and DNS-over-HTTPS transports
+func (config *ConfigurationAttr) validateWithFormatDnsServerByTransportContext(dnsTransport, dnsGateway string) (string, error) {
+	switch dnsTransport {
+	case dnsTransportTls:
+		return config.validateWithFormatDnsOverTlsServerContext(dnsGateway)
+	case dnsTransportHttps:
+		return config.validateDnsOverHttpsUrlContext(dnsGateway)
+	}
+
+	return config.validateWithFormatDnsServerContext(dnsGateway)
+}
+
+// Validates each DNS server from slice depending on DNS transport. Returns formatted
+// DNS servers or error if at least one of DNS server validations fails
+func (config *ConfigurationAttr) validateWithFormatDnsServersContext(dnsTransport string, dnsServers []string) ([]string, error) {
+	var formattedDnsServers []string
+	for _, dnsServer := range dnsServers {
+		if dnsServer == emptyString {
+			return dnsServers, fmt.Errorf("%s is invalid dns server", dnsServer)
+		}
+
+		formattedDnsServer, err := config.validateWithFormatDnsServerByTransportContext(dnsTransport, dnsServer)
+		if err != nil {
+			return dnsServers, err
+		}
+		formattedDnsServers = append(formattedDnsServers, formattedDnsServer)
+	}
+
+	return formattedDnsServers, nil
+}
+
+// Validates DNS servers strategy. Returns error if validation fails
+func (config *ConfigurationAttr) validateDnsStrategyContext(dnsStrategy string) error {
+	if dnsStrategy == emptyString || isIncluded(availableDnsStrategies(), dnsStrategy) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid dns strategy, use one of these: %s",
+		dnsStrategy,
+		availableDnsStrategies(),
+	)
+}
+
+// Validates DNS client. Returns error if validation fails
+func (config *ConfigurationAttr) validateDnsClientContext(dnsClient string) error {
+	if dnsClient == emptyString || isIncluded(availableDnsClients(), dnsClient) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid dns client, use one of these: %s",
+		dnsClient,
+		availableDnsClients(),
+	)
+}
+
+// Validates DANE check context. DANE check requires raw DNS client, because TLSA
+// lookups and DNSSEC authenticated data flag are not available via net.Resolver
+// and custom Resolver. Returns error if validation fails
+func (config *ConfigurationAttr) validateDaneCheckContext() error {
+	if !config.DaneCheck || (config.Resolver == nil && config.DnsClient == dnsClientRaw) {
+		return nil
+	}
+
+	return fmt.Errorf("dane check requires %s dns client without custom resolver", dnsClientRaw)
+}
+
+// Validates SMTP TLS policy. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpTlsPolicyContext(smtpTlsPolicy string) error {
+	if smtpTlsPolicy == emptyString || isIncluded(availableSmtpTlsPolicies(), smtpTlsPolicy) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid smtp tls policy, use one of these: %s",
+		smtpTlsPolicy,
+		availableSmtpTlsPolicies(),
+	)
+}
+
+// Validates SMTP proxy URL context. SOCKS5 and HTTP CONNECT proxy URLs with
+// host and port number are supported. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpProxyContext(smtpProxy string) error {
+	if smtpProxy == emptyString {
+		return nil
+	}
+
+	proxyUrl, err := url.Parse(smtpProxy)
+	if err != nil ||
+		!isIncluded(availableSmtpProxySchemes(), proxyUrl.Scheme) ||
+		proxyUrl.Hostname() == emptyString ||
+		proxyUrl.Port() == emptyString {
+		return fmt.Errorf(
+			"%s is invalid smtp proxy url, use one of these schemes: %s",
+			smtpProxy,
+			availableSmtpProxySchemes(),
+		)
+	}
+
+	return nil
+}
+
+// Validates SMTP source addresses context: IP address of each source address and its
+// HELO domain when it is specified. Returns error if at least one of validations fails
+func (config *ConfigurationAttr) validateSmtpSourceAddressesContext(sourceAddresses []SmtpSourceAddress) error {
+	for _, sourceAddress := range sourceAddresses {
+		err := config.validateIpAddressContext(sourceAddress.IpAddress)
+		if err != nil {
+			return err
+		}
+
+		if sourceAddress.HeloDomain == emptyString {
+			continue
+		}
+
+		err = config.validateDomainContext(sourceAddress.HeloDomain)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validates SMTP source addresses rotation. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpSourceRotationContext(smtpSourceRotation string) error {
+	if smtpSourceRotation == emptyString || isIncluded(availableSmtpSourceRotations(), smtpSourceRotation) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid smtp source rotation, use one of these: %s",
+		smtpSourceRotation,
+		availableSmtpSourceRotations(),
+	)
+}
+
+// Validates SMTP rate limit. Rate and burst should be non-negative. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpRateLimitContext(rateLimit SmtpRateLimit) error {
+	if rateLimit.Rate >= 0 && rateLimit.Burst >= 0 {
+		return nil
+	}
+	return fmt.Errorf("%+v is invalid smtp rate limit, rate and burst should be non-negative", rateLimit)
+}
+
+// Validates SMTP rate limits context: per host, per domain and providers rate limits,
+// providers domains and max wait. Returns error if at least one of validations fails
+func (config *ConfigurationAttr) validateSmtpRateLimitsContext() error {
+	for _, rateLimit := range []SmtpRateLimit{config.SmtpRateLimitPerHost, config.SmtpRateLimitPerDomain} {
+		err := config.validateSmtpRateLimitContext(rateLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	for provider, rateLimit := range config.SmtpRateLimitByProvider {
+		err := config.validateDomainContext(provider)
+		if err != nil {
+			return err
+		}
+
+		err = config.validateSmtpRateLimitContext(rateLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return config.validateIntegerNonNegative(config.SmtpRateLimitMaxWait)
+}
+
+// Validates SMTP provider profiles context: profile name and MX host names should be
+// specified, MX host names should be valid domains. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpProviderProfilesContext(profiles []SmtpProviderProfile) error {
+	for _, profile := range profiles {
+		if profile.Name == emptyString || len(profile.MxHostNames) == 0 {
+			return fmt.Errorf("%+v is invalid smtp provider profile, name and mx host names should be specified", profile)
+		}
+
+		err := config.validateDomainsContext(profile.MxHostNames)
+		if err != nil {
+			return err
+		}
+
+		err = config.validateSmtpRateLimitContext(profile.RateLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns SMTP provider profiles: user-defined profiles followed by bundled profiles
+// which were not overridden by user-defined profiles with the same name
+func (config *ConfigurationAttr) buildSmtpProviderProfiles(profiles []SmtpProviderProfile) []SmtpProviderProfile {
+	smtpProviderProfiles := append([]SmtpProviderProfile(nil), profiles...)
+	for _, defaultProfile := range defaultSmtpProviderProfiles() {
+		isOverridden := false
+		for _, profile := range profiles {
+			isOverridden = isOverridden || profile.Name == defaultProfile.Name
+		}
+
+		if !isOverridden {
+			smtpProviderProfiles = append(smtpProviderProfiles, defaultProfile)
+		}
+	}
+
+	return smtpProviderProfiles
+}
+
+// Validates SMTP connection pool idle TTL, max recipients
+// per session and pool size context. Returns error if validation fails
+func (config *ConfigurationAttr) validateSmtpConnectionPoolContext() error {
+	for _, value := range []int{
+		config.SmtpConnectionPoolIdleTtl,
+		config.SmtpConnectionPoolMaxRcpt,
+		config.SmtpConnectionPoolSize,
+	} {
+		err := config.validateIntegerNonNegative(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns SMTP session pool when SMTP connection pool is enabled, otherwise returns nil
+func (config *ConfigurationAttr) buildSmtpSessionPool() *smtpSessionPool {
+	if !config.SmtpConnectionPool {
+		return nil
+	}
+
+	return newSmtpSessionPool(
+		config.SmtpConnectionPoolIdleTtl,
+		config.SmtpConnectionPoolMaxRcpt,
+		config.SmtpConnectionPoolSize,
+	)
+}
+
+// Validates result cache size and TTLs context. Returns error if validation fails
+func (config *ConfigurationAttr) validateResultCacheContext() error {
+	for _, integer := range []int{
+		config.ResultCacheSize,
+		config.ResultCacheValidTtl,
+		config.ResultCacheInvalidTtl,
+		config.ResultCacheUnknownTtl,
+	} {
+		err := config.validateIntegerNonNegative(integer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns custom result cache when specified. Otherwise returns in-memory result cache
+// for case when result cache size is positive, or nil (result cache disabled)
+func (config *ConfigurationAttr) buildResultCache(resultCache ResultCache, resultCacheSize int) ResultCache {
+	if resultCache != nil || resultCacheSize == 0 {
+		return resultCache
+	}
+
+	return NewResultMemoryCache(resultCacheSize)
+}
+
+// Validates log email redaction. Returns error if validation fails
+func (config *ConfigurationAttr) validateLogEmailRedactionContext(logEmailRedaction string) error {
+	if logEmailRedaction == emptyString || isIncluded(availableLogEmailRedactions(), logEmailRedaction) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s is invalid log email redaction, use one of these: %s",
+		logEmailRedaction,
+		availableLogEmailRedactions(),
+	)
+}
+
+// Validates DNS cache size and TTLs context. Returns error if validation fails
+func (config *ConfigurationAttr) validateDnsCacheContext() error {
+	for _, integer := range []int{config.DnsCacheSize, config.DnsCacheMinTtl, config.DnsCacheMaxTtl, config.DnsCacheNegativeTtl} {
+		err := config.validateIntegerNonNegative(integer)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.DnsCacheMinTtl > config.DnsCacheMaxTtl {
+		return fmt.Errorf(
+			"%v dns cache min ttl should be less than or equal to dns cache max ttl %v",
+			config.DnsCacheMinTtl,
+			config.DnsCacheMaxTtl,
+		)
+	}
+
+	return nil
+}
+
+// Returns nil when DNS cache is disabled, custom DNS cache when specified.
+// Otherwise returns in-memory DNS cache with specified size
+func (config *ConfigurationAttr) buildDnsCache(dnsCache DnsCache, dnsCacheSize int, dnsCacheDisabled bool) DnsCache {
+	if dnsCacheDisabled {
+		return nil
+	}
+	if dnsCache != nil {
+		return dnsCache
+	}
+
+	return NewDnsMemoryCache(dnsCacheSize)
+}
@@ -1,5 +1,7 @@
 package truemail
 
+import "time"
+
 const (
 	// network configuration options
 
@@ -7,9 +9,212 @@ const (
 	defaultResponseTimeout    = 2
 	defaultConnectionAttempts = 2
 	defaultDnsPort            = 53
+	defaultDnsOverTlsPort     = 853
+	defaultDnsOverHttpsPort   = 443
 	defaultSmtpPort           = 25
 	tcpTransportLayer         = "tcp"
 
+	// DNS transports
+
+	dnsTransportPlain   = "plain"
+	dnsTransportTls     = "tls"
+	dnsTransportHttps   = "https"
+	dnsMessageMediaType = "application/dns-message"
+	dnsMessageMaxSize   = 65535
+
+	// DNS clients
+
+	dnsClientNet           = "net"
+	dnsClientRaw           = "raw"
+	dnsClientUdpSize       = 4096
+	udpTransportLayer      = "udp"
+	systemDnsConfigPath    = "/etc/resolv.conf"
+	defaultSystemDnsServer = "127.0.0.1:53"
+
+	// MTA-STS options
+
+	mtaStsTxtRecordPrefix   = "_mta-sts."
+	mtaStsTxtRecordVersion  = "v=STSv1"
+	mtaStsPolicyVersion     = "STSv1"
+	mtaStsPolicyUrl         = "https://mta-sts.%s/.well-known/mta-sts.txt"
+	mtaStsPolicyMediaType   = "text/plain"
+	mtaStsPolicyMaxSize     = 64 * 1024
+	mtaStsPolicyMaxAgeLimit = 31557600
+	mtaStsModeEnforce       = "enforce"
+	mtaStsModeTesting       = "testing"
+	mtaStsModeNone          = "none"
+	mtaStsPolicyCacheSize   = 10000
+
+	// DANE options (RFC 6698, RFC 7672)
+
+	daneTlsaRecordName      = "_%d._tcp.%s"
+	tlsaUsagePkixTa         = 0
+	tlsaUsagePkixEe         = 1
+	tlsaUsageDaneTa         = 2
+	tlsaUsageDaneEe         = 3
+	tlsaSelectorCertificate = 0
+	tlsaSelectorPublicKey   = 1
+	tlsaMatchingTypeFull    = 0
+	tlsaMatchingTypeSha256  = 1
+	tlsaMatchingTypeSha512  = 2
+
+	// SMTP TLS policies
+
+	smtpTlsPolicyNone          = "none"
+	smtpTlsPolicyOpportunistic = "opportunistic"
+	smtpTlsPolicyRequired      = "required"
+
+	// SMTP proxy schemes
+
+	smtpProxySchemeSocks5  = "socks5"
+	smtpProxySchemeSocks5h = "socks5h"
+	smtpProxySchemeHttp    = "http"
+
+	// SMTP source addresses rotations
+
+	smtpSourceRotationRoundRobin = "round_robin"
+	smtpSourceRotationPerDomain  = "per_domain"
+
+	// SMTP rate limits
+
+	smtpRateLimitHostKeyPrefix   = "host:"
+	smtpRateLimitDomainKeyPrefix = "domain:"
+	smtpRateLimiterMaxBuckets    = 10000
+
+	// SMTP greylisting options
+
+	defaultSmtpGreylistingRetryAttempts = 1
+	defaultSmtpConnectionPoolIdleTtl    = 30
+	defaultSmtpConnectionPoolMaxRcpt    = 20
+	defaultSmtpConnectionPoolSize       = 2
+
+	// SMTP provider profiles
+
+	smtpProviderGmail     = "gmail"
+	smtpProviderYahoo     = "yahoo"
+	smtpProviderMicrosoft = "microsoft"
+
+	// SMTP reply classifications
+
+	smtpReplyMailboxUnknown    = "mailbox_unknown"
+	smtpReplyMailboxFull       = "mailbox_full"
+	smtpReplyPolicyBlock       = "policy_block"
+	smtpReplyIpReputationBlock = "ip_reputation_block"
+	smtpReplyTemporaryFailure  = "temporary_failure"
+
+	// DNS servers strategies
+
+	dnsStrategyFailover   = "failover"
+	dnsStrategyRoundRobin = "round_robin"
+	dnsStrategyRace       = "race"
+	dnsServerMaxFailures  = 3
+	dnsServerCooldown     = 30 * time.Second
+
+	// DNS cache options, in seconds
+
+	defaultDnsCacheSize        = 10000
+	defaultDnsCacheMinTtl      = 30
+	defaultDnsCacheMaxTtl      = 3600
+	defaultDnsCacheNegativeTtl = 60
+
+	// result cache options, in seconds
+
+	defaultResultCacheValidTtl   = 86400
+	defaultResultCacheInvalidTtl = 3600
+	defaultResultCacheUnknownTtl = 300
+
+	// metrics labels
+
+	metricsOutcomeValid   = "valid"
+	metricsOutcomeInvalid = "invalid"
+	metricsOutcomeUnknown = "unknown"
+	metricsStatusSuccess  = "success"
+	metricsStatusFailure  = "failure"
+	smtpCommandConnect    = "connect"
+	smtpCommandGreeting   = "greeting"
+	smtpCommandHelo       = "helo"
+	smtpCommandStartTls   = "starttls"
+	smtpCommandMailFrom   = "mail"
+	smtpCommandRcptTo     = "rcpt"
+	smtpCommandNoop       = "noop"
+	smtpCommandRset       = "rset"
+	smtpCommandQuit       = "quit"
+	smtpCommandData       = "data"
+
+	// tracing
+
+	tracerName                     = "github.com/truemail-rb/truemail-go"
+	spanNamePrefix                 = "truemail."
+	spanNameValidate               = "truemail.Validate"
+	spanNameDnsQuery               = "truemail.dns.query"
+	spanNameSmtpSession            = "truemail.smtp.session"
+	spanNameSmtpCommand            = "truemail.smtp.command"
+	attributeValidationType        = "truemail.validation_type"
+	attributeDomain                = "truemail.domain"
+	attributeSuccess               = "truemail.success"
+	attributeFromCache             = "truemail.from_cache"
+	attributeError                 = "truemail.error"
+	attributeMailServers           = "truemail.mail_servers"
+	attributeDnsQueryType          = "dns.query.type"
+	attributeDnsQueryName          = "dns.query.name"
+	attributeDnsServer             = "dns.server"
+	attributeDnsRcode              = "dns.rcode"
+	attributeDnsCached             = "dns.cached"
+	attributeServerAddress         = "server.address"
+	attributeServerPort            = "server.port"
+	attributeSmtpMxHost            = "smtp.mx_host"
+	attributeSmtpCommand           = "smtp.command"
+	attributeSmtpReplyCode         = "smtp.reply.code"
+	attributeSmtpReplyEnhancedCode = "smtp.reply.enhanced_code"
+
+	// logging
+
+	logEmailRedactionNone          = "none"
+	logEmailRedactionMask          = "mask"
+	logEmailRedactionHash          = "hash"
+	logEmailMask                   = "***"
+	logMessageValidationStarted    = "truemail validation started"
+	logMessageValidationFinished   = "truemail validation finished"
+	logMessageDnsQuery             = "truemail dns query"
+	logMessageSmtpCommand          = "truemail smtp command"
+	logMessageSmtpSessionRetry     = "truemail smtp session retry"
+	logMessageSmtpRateLimited      = "truemail smtp rate limited"
+	logMessageSmtpGreylistingRetry = "truemail smtp greylisting retry scheduled"
+	logMessageResultCacheError     = "truemail result cache error"
+	logAttrEmail                   = "email"
+	logAttrDomain                  = "domain"
+	logAttrValidationType          = "validation_type"
+	logAttrSuccess                 = "success"
+	logAttrOutcome                 = "outcome"
+	logAttrLayer                   = "layer"
+	logAttrErrors                  = "errors"
+	logAttrFromCache               = "from_cache"
+	logAttrDuration                = "duration"
+	logAttrError                   = "error"
+	logAttrQueryType               = "query_type"
+	logAttrQueryName               = "query_name"
+	logAttrDnsServer               = "dns_server"
+	logAttrRcode                   = "rcode"
+	logAttrCached                  = "cached"
+	logAttrHost                    = "host"
+	logAttrServerAddress           = "server_address"
+	logAttrCommand                 = "command"
+	logAttrReplyCode               = "reply_code"
+	logAttrReplyEnhancedCode       = "reply_enhanced_code"
+	logAttrAttemptsLeft            = "attempts_left"
+	logAttrRetryDelay              = "retry_delay"
+
+	// host audit
+
+	auditTypeIp           = "ip"
+	auditTypeDns          = "dns"
+	auditTypePtr          = "ptr"
+	auditIpWarning        = "impossible to detect current host address"
+	auditDnsWarning       = "A-record of verifier domain not refers to current host ip address"
+	auditPtrWarning       = "ptr record does not reference to current verifier domain"
+	auditIpProbeAddress   = "8.8.8.8:53"
+	auditIpProbeTransport = "udp"
+
 	// validation types
 
 	validationTypeDomainListMatch = "domain_list_match"
@@ -21,16 +226,23 @@ const (
 
 	// regex patterns
 
-	domainCharsSize              = `\A.{4,255}\z`
-	emailCharsSize               = `\A.{6,255}\z`
-	regexDomainPattern           = `(?i)[\p{L}0-9]+([\-.]{1}[\p{L}0-9]+)*\.\p{L}{2,63}`
-	regexEmailPattern            = `(\A([\p{L}0-9]+[\W\w]*)@(` + regexDomainPattern + `)\z)`
-	regexDomainFromEmail         = `\A.+@(.+)\z`
-	regexSMTPErrorBodyPattern    = `(?i).*550{1}.*(user|account|customer|mailbox).*`
-	regexPortNumber              = `(6553[0-5]|655[0-2]\d|65[0-4](\d){2}|6[0-4](\d){3}|[1-5](\d){4}|[1-9](\d){0,3})`
-	regexIpAddress               = `((\d|[1-9]\d|1\d{2}|2[0-4]\d|25[0-5])\.){3}(\d|[1-9]\d|1\d{2}|2[0-4]\d|25[0-5])`
-	regexIpAddressPattern        = `\A` + regexIpAddress + `\z`
-	regexDNSServerAddressPattern = `\A` + regexIpAddress + `(:` + regexPortNumber + `)?\z`
+	domainCharsSize                     = `\A.{4,255}\z`
+	emailCharsSize                      = `\A.{6,255}\z`
+	regexDomainPattern                  = `(?i)[\p{L}0-9]+([\-.]{1}[\p{L}0-9]+)*\.\p{L}{2,63}`
+	regexEmailPattern                   = `(\A([\p{L}0-9]+[\W\w]*)@(` + regexDomainPattern + `)\z)`
+	regexDomainFromEmail                = `\A.+@(.+)\z`
+	regexSMTPErrorBodyPattern           = `(?i).*550{1}.*(user|account|customer|mailbox).*`
+	regexSmtpEnhancedCodePattern        = `\A([245]\.\d{1,3}\.\d{1,3})(\s|\z)`
+	regexSmtpMailboxUnknownPattern      = `(?i)(no such|unknown|invalid|nonexistent|non-existent) (user|mailbox|recipient|account|address)|(user|mailbox|recipient|account|address).*(unknown|not found|does not exist|doesn't exist|disabled|unavailable)`
+	regexSmtpIpReputationBlockPattern   = `(?i)spamhaus|spamcop|barracuda|\b(rbl|dnsbl|blocklist|blacklist|block list|black list)|reputation`
+	regexGmailMailboxUnknownPattern     = `(?i)the email account that you tried to reach does not exist|NoSuchUser`
+	regexMicrosoftMailboxUnknownPattern = `(?i)5\.4\.1 recipient address rejected: access denied|RecipNotFound|recipient not found`
+	regexSmtpGreylistingPattern         = `(?i)(grey|gray)[ -]?list|try again later|temporar(il)?y (rejected|deferred|unavailable)`
+	regexPortNumber                     = `(6553[0-5]|655[0-2]\d|65[0-4](\d){2}|6[0-4](\d){3}|[1-5](\d){4}|[1-9](\d){0,3})`
+	regexIpAddress                      = `((\d|[1-9]\d|1\d{2}|2[0-4]\d|25[0-5])\.){3}(\d|[1-9]\d|1\d{2}|2[0-4]\d|25[0-5])`
+	regexIpAddressPattern               = `\A` + regexIpAddress + `\z`
+	regexDNSServerAddressPattern        = `\A` + regexIpAddress + `(:` + regexPortNumber + `)?\z`
+	regexDnsOverTlsServerPattern        = `\A(` + regexIpAddress + `|` + regexDomainPattern + `)(:` + regexPortNumber + `)?\z`
 
 	// shortcuts
 
@@ -52,9 +264,15 @@ const (
 
 	// validationMx
 
-	mxErrorContext = "target host(s) not found"
+	mxErrorContext           = "target host(s) not found"
+	mxDnsFailureErrorContext = "dns lookup failed"
+	mxMtaStsErrorContext     = "mx hosts do not match mta-sts policy"
 
 	// validatorSmtp
 
-	smtpErrorContext = "smtp error"
+	smtpErrorContext             = "smtp error"
+	smtpRateLimitedErrorContext  = "rate limited, retry later"
+	smtpCancelledErrorContext    = "smtp session cancelled, definitive answer received from other mail server"
+	smtpGreylistedErrorContext   = "greylisted, retry later"
+	smtpNotConfirmedErrorContext = "email existence is not confirmed by mail server"
 )
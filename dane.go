@@ -0,0 +1,170 @@
+package truemail
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+)
+
+// TLSA record structure (RFC 6698). Includes certificate usage, selector,
+// matching type and certificate association data in lower case hex
+type TlsaRecord struct {
+	Usage, Selector, MatchingType uint8
+	Certificate                   string
+}
+
+// DANE result structure (RFC 7672). Includes MX host name, its TLSA records, DNSSEC
+// authenticated data flag of TLSA answer, certificate verification outcome and error
+// of TLSA lookup or certificate verification
+type DaneResult struct {
+	HostName                string
+	TlsaRecords             []*TlsaRecord
+	Authenticated, Verified bool
+	Err                     error
+}
+
+// TLSA resolver interface. Implemented by raw DNS client gateway,
+// net.Resolver and custom Resolver can't lookup TLSA records
+type tlsaResolver interface {
+	lookupTLSA(ctx context.Context, name string) ([]*TlsaRecord, error)
+}
+
+// Looks up TLSA records by name via DNS gateway. Returns error when
+// DNS gateway does not support TLSA lookups
+func lookupTlsaRecords(ctx context.Context, dnsGateway Resolver, name string) ([]*TlsaRecord, error) {
+	tlsaGateway, ok := dnsGateway.(tlsaResolver)
+	if !ok {
+		return nil, fmt.Errorf("lookup %s: dns gateway does not support tlsa lookups", name)
+	}
+
+	return tlsaGateway.lookupTLSA(ctx, name)
+}
+
+// Returns TLSA record name of MX host for SMTP port number, for example _25._tcp.mx.example.com
+func daneTlsaName(smtpPortNumber int, hostName string) string {
+	return fmt.Sprintf(daneTlsaRecordName, smtpPortNumber, hostName)
+}
+
+// Creates TLS connection verifier, which checks certificate chain presented by target
+// server against TLSA records. Should be used with InsecureSkipVerify TLS config option,
+// because DANE-EE and DANE-TA records replace PKIX verification. Uses system root CAs
+// for PKIX-TA and PKIX-EE records when root CAs are not specified
+func newDaneVerifier(tlsaRecords []*TlsaRecord, hostName string, rootCAs *x509.CertPool) func(tls.ConnectionState) error {
+	return func(connectionState tls.ConnectionState) error {
+		for _, tlsaRecord := range tlsaRecords {
+			if tlsaRecord.verify(connectionState.PeerCertificates, hostName, rootCAs) {
+				return nil
+			}
+		}
+
+		return &daneVerificationError{hostName: hostName}
+	}
+}
+
+// TlsaRecord methods
+
+// Returns true if presented certificate chain is verified by TLSA record. Certificate
+// chain is expected in TLS handshake order: server certificate first
+func (tlsaRecord *TlsaRecord) verify(certificates []*x509.Certificate, hostName string, rootCAs *x509.CertPool) bool {
+	if len(certificates) == 0 {
+		return false
+	}
+
+	serverCertificate, intermediates := certificates[0], x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	switch tlsaRecord.Usage {
+	case tlsaUsageDaneEe:
+		return tlsaRecord.isMatched(serverCertificate)
+	case tlsaUsageDaneTa:
+		for _, certificate := range certificates {
+			if !tlsaRecord.isMatched(certificate) {
+				continue
+			}
+
+			trustAnchor := x509.NewCertPool()
+			trustAnchor.AddCert(certificate)
+			_, err := serverCertificate.Verify(
+				x509.VerifyOptions{DNSName: hostName, Roots: trustAnchor, Intermediates: intermediates},
+			)
+			return err == nil
+		}
+	case tlsaUsagePkixEe, tlsaUsagePkixTa:
+		chains, err := serverCertificate.Verify(
+			x509.VerifyOptions{DNSName: hostName, Roots: rootCAs, Intermediates: intermediates},
+		)
+		if err != nil {
+			return false
+		}
+		if tlsaRecord.Usage == tlsaUsagePkixEe {
+			return tlsaRecord.isMatched(serverCertificate)
+		}
+
+		for _, chain := range chains {
+			for _, certificate := range chain {
+				if certificate.IsCA && tlsaRecord.isMatched(certificate) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns true if certificate association data of TLSA record matches certificate
+// by TLSA record selector and matching type
+func (tlsaRecord *TlsaRecord) isMatched(certificate *x509.Certificate) bool {
+	var data []byte
+	switch tlsaRecord.Selector {
+	case tlsaSelectorCertificate:
+		data = certificate.Raw
+	case tlsaSelectorPublicKey:
+		data = certificate.RawSubjectPublicKeyInfo
+	default:
+		return false
+	}
+
+	switch tlsaRecord.MatchingType {
+	case tlsaMatchingTypeFull:
+	case tlsaMatchingTypeSha256:
+		digest := sha256.Sum256(data)
+		data = digest[:]
+	case tlsaMatchingTypeSha512:
+		digest := sha512.Sum512(data)
+		data = digest[:]
+	default:
+		return false
+	}
+
+	return hex.EncodeToString(data) == tlsaRecord.Certificate
+}
+
+// DaneResult methods
+
+// Returns true if DANE TLSA records can be used for certificate verification.
+// TLSA records without DNSSEC authenticated answer are not usable (RFC 7672)
+func (daneResult *DaneResult) isUsable() bool {
+	return daneResult != nil && daneResult.Authenticated && len(daneResult.TlsaRecords) > 0
+}
+
+// Records certificate verification outcome of SMTP session with usable TLSA records.
+// SMTP session which passed STARTTLS is verified, STARTTLS or certificate failure is not verified
+func (daneResult *DaneResult) recordSession(sessionError *SmtpClientError) {
+	if !daneResult.isUsable() {
+		return
+	}
+
+	switch {
+	case sessionError == nil || sessionError.isMailFrom || sessionError.isRecptTo:
+		daneResult.Verified, daneResult.Err = true, nil
+	case sessionError.isStartTls || sessionError.isCertificate:
+		daneResult.Verified, daneResult.Err = false, sessionError
+	}
+}
@@ -0,0 +1,226 @@
+package truemail
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupTlsaRecords(t *testing.T) {
+	name := daneTlsaName(defaultSmtpPort, randomDomain())
+
+	t.Run("when DNS gateway supports TLSA lookups", func(t *testing.T) {
+		tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256}}
+		dnsGateway := &tlsaResolverStandIn{tlsaRecords: tlsaRecords}
+		resolvedTlsaRecords, err := lookupTlsaRecords(context.Background(), dnsGateway, name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tlsaRecords, resolvedTlsaRecords)
+		assert.Equal(t, []string{name}, dnsGateway.names)
+	})
+
+	t.Run("when DNS gateway does not support TLSA lookups", func(t *testing.T) {
+		_, err := lookupTlsaRecords(context.Background(), new(net.Resolver), name)
+
+		assert.EqualError(t, err, "lookup "+name+": dns gateway does not support tlsa lookups")
+	})
+}
+
+func TestDaneTlsaName(t *testing.T) {
+	t.Run("returns TLSA record name of MX host", func(t *testing.T) {
+		hostName := randomDomain()
+
+		assert.Equal(t, "_25._tcp."+hostName, daneTlsaName(defaultSmtpPort, hostName))
+	})
+}
+
+func TestNewDaneVerifier(t *testing.T) {
+	tlsConfig, rootCAs := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+	connectionState := tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+	hostName := "example.com"
+
+	t.Run("when one of TLSA records matches certificate chain", func(t *testing.T) {
+		tlsaRecords := []*TlsaRecord{
+			{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256, Certificate: "42"},
+			createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeSha256),
+		}
+
+		assert.NoError(t, newDaneVerifier(tlsaRecords, hostName, nil)(connectionState))
+	})
+
+	t.Run("when TLSA records do not match certificate chain", func(t *testing.T) {
+		tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256, Certificate: "42"}}
+		err := newDaneVerifier(tlsaRecords, hostName, rootCAs)(connectionState)
+
+		assert.True(t, isDaneVerificationError(err))
+		assert.EqualError(t, err, hostName+" certificate does not match dane tlsa records")
+	})
+}
+
+func TestTlsaRecordVerify(t *testing.T) {
+	tlsConfig, rootCAs := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+	certificates, hostName := []*x509.Certificate{certificate}, "example.com"
+
+	t.Run("DANE-EE, ignores host name and trust anchors", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorCertificate, tlsaMatchingTypeSha512)
+
+		assert.True(t, tlsaRecord.verify(certificates, randomDomain(), nil))
+	})
+
+	t.Run("DANE-TA, when trust anchor matches and host name is valid", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneTa, tlsaSelectorPublicKey, tlsaMatchingTypeSha256)
+
+		assert.True(t, tlsaRecord.verify(certificates, hostName, nil))
+	})
+
+	t.Run("DANE-TA, when host name is invalid", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneTa, tlsaSelectorPublicKey, tlsaMatchingTypeSha256)
+
+		assert.False(t, tlsaRecord.verify(certificates, randomDomain(), nil))
+	})
+
+	t.Run("PKIX-EE, when certificate matches and PKIX verification passes", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsagePkixEe, tlsaSelectorCertificate, tlsaMatchingTypeFull)
+
+		assert.True(t, tlsaRecord.verify(certificates, hostName, rootCAs))
+	})
+
+	t.Run("PKIX-EE, when PKIX verification fails", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsagePkixEe, tlsaSelectorCertificate, tlsaMatchingTypeFull)
+
+		assert.False(t, tlsaRecord.verify(certificates, hostName, x509.NewCertPool()))
+	})
+
+	t.Run("PKIX-TA, when trust anchor of verified chain matches", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsagePkixTa, tlsaSelectorPublicKey, tlsaMatchingTypeSha512)
+
+		assert.True(t, tlsaRecord.verify(certificates, hostName, rootCAs))
+	})
+
+	t.Run("when empty certificate chain", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeSha256)
+
+		assert.False(t, tlsaRecord.verify([]*x509.Certificate{}, hostName, nil))
+	})
+
+	t.Run("when unknown certificate usage", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, 4, tlsaSelectorPublicKey, tlsaMatchingTypeSha256)
+
+		assert.False(t, tlsaRecord.verify(certificates, hostName, rootCAs))
+	})
+}
+
+func TestTlsaRecordIsMatched(t *testing.T) {
+	tlsConfig, _ := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+
+	for _, selector := range []uint8{tlsaSelectorCertificate, tlsaSelectorPublicKey} {
+		for _, matchingType := range []uint8{tlsaMatchingTypeFull, tlsaMatchingTypeSha256, tlsaMatchingTypeSha512} {
+			t.Run("when certificate matches TLSA record", func(t *testing.T) {
+				assert.True(t, createTlsaRecord(certificate, tlsaUsageDaneEe, selector, matchingType).isMatched(certificate))
+			})
+		}
+	}
+
+	t.Run("when certificate does not match TLSA record", func(t *testing.T) {
+		tlsaRecord := &TlsaRecord{Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256, Certificate: strings.Repeat("0", 64)}
+
+		assert.False(t, tlsaRecord.isMatched(certificate))
+	})
+
+	t.Run("when unknown selector", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeSha256)
+		tlsaRecord.Selector = 2
+
+		assert.False(t, tlsaRecord.isMatched(certificate))
+	})
+
+	t.Run("when unknown matching type", func(t *testing.T) {
+		tlsaRecord := createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeFull)
+		tlsaRecord.MatchingType = 3
+
+		assert.False(t, tlsaRecord.isMatched(certificate))
+	})
+}
+
+func TestDaneResultIsUsable(t *testing.T) {
+	tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe}}
+
+	t.Run("when authenticated TLSA records", func(t *testing.T) {
+		assert.True(t, (&DaneResult{TlsaRecords: tlsaRecords, Authenticated: true}).isUsable())
+	})
+
+	t.Run("when not authenticated TLSA records", func(t *testing.T) {
+		assert.False(t, (&DaneResult{TlsaRecords: tlsaRecords}).isUsable())
+	})
+
+	t.Run("when TLSA records not exist", func(t *testing.T) {
+		assert.False(t, (&DaneResult{Authenticated: true}).isUsable())
+	})
+
+	t.Run("when DANE result not exists", func(t *testing.T) {
+		var daneResult *DaneResult
+
+		assert.False(t, daneResult.isUsable())
+	})
+}
+
+func TestDaneResultRecordSession(t *testing.T) {
+	createDaneResult := func() *DaneResult {
+		return &DaneResult{TlsaRecords: []*TlsaRecord{{Usage: tlsaUsageDaneEe}}, Authenticated: true}
+	}
+
+	t.Run("when successful session", func(t *testing.T) {
+		daneResult := createDaneResult()
+		daneResult.recordSession(nil)
+
+		assert.True(t, daneResult.Verified)
+		assert.NoError(t, daneResult.Err)
+	})
+
+	t.Run("when session failed after STARTTLS", func(t *testing.T) {
+		daneResult := createDaneResult()
+		daneResult.recordSession(&SmtpClientError{isRecptTo: true})
+
+		assert.True(t, daneResult.Verified)
+	})
+
+	t.Run("when certificate verification failed", func(t *testing.T) {
+		daneResult, sessionError := createDaneResult(), &SmtpClientError{isCertificate: true, isDane: true, err: errors.New("error")}
+		daneResult.recordSession(sessionError)
+
+		assert.False(t, daneResult.Verified)
+		assert.Equal(t, sessionError, daneResult.Err)
+	})
+
+	t.Run("when STARTTLS failed", func(t *testing.T) {
+		daneResult, sessionError := createDaneResult(), &SmtpClientError{isStartTls: true, err: errors.New("error")}
+		daneResult.recordSession(sessionError)
+
+		assert.False(t, daneResult.Verified)
+		assert.Equal(t, sessionError, daneResult.Err)
+	})
+
+	t.Run("when session failed before STARTTLS", func(t *testing.T) {
+		daneResult := createDaneResult()
+		daneResult.recordSession(&SmtpClientError{isConnection: true})
+
+		assert.False(t, daneResult.Verified)
+		assert.NoError(t, daneResult.Err)
+	})
+
+	t.Run("when DANE result is not usable", func(t *testing.T) {
+		daneResult := &DaneResult{TlsaRecords: []*TlsaRecord{{Usage: tlsaUsageDaneEe}}}
+		daneResult.recordSession(nil)
+
+		assert.False(t, daneResult.Verified)
+	})
+}
@@ -0,0 +1,418 @@
+package truemail
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DnsCache interface. Provides storage for DNS lookup results shared
+// across validations which use the same configuration
+type DnsCache interface {
+	Get(key string) (DnsCacheEntry, bool)
+	Set(key string, entry DnsCacheEntry, ttl time.Duration)
+}
+
+// DNS cache entry structure. Includes lookup result value
+// or lookup error for negative cache entries
+type DnsCacheEntry struct {
+	Value any
+	Err   error
+}
+
+// In-memory DNS cache structure. Evicts least recently used
+// entries when capacity is reached and expired entries on read
+type DnsMemoryCache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+// In-memory DNS cache item, stored as list element value
+type dnsMemoryCacheItem struct {
+	key       string
+	entry     DnsCacheEntry
+	expiresAt time.Time
+}
+
+// NewDnsMemoryCache returns in-memory LRU DNS cache with given capacity
+func NewDnsMemoryCache(capacity int) *DnsMemoryCache {
+	return &DnsMemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// DnsMemoryCache methods
+
+// Get returns not expired cache entry by key
+func (cache *DnsMemoryCache) Get(key string) (DnsCacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return DnsCacheEntry{}, false
+	}
+
+	item := element.Value.(*dnsMemoryCacheItem)
+	if !cache.now().Before(item.expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return DnsCacheEntry{}, false
+	}
+
+	cache.order.MoveToFront(element)
+	return item.entry, true
+}
+
+// Set stores cache entry by key for ttl duration
+func (cache *DnsMemoryCache) Set(key string, entry DnsCacheEntry, ttl time.Duration) {
+	if ttl <= 0 || cache.capacity <= 0 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	item := &dnsMemoryCacheItem{key: key, entry: entry, expiresAt: cache.now().Add(ttl)}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = item
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(item)
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*dnsMemoryCacheItem).key)
+	}
+}
+
+// Len returns count of stored cache entries
+func (cache *DnsMemoryCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.order.Len()
+}
+
+// Caching gateway structure. Wraps DNS gateway, consults DNS cache
+// before each lookup and stores lookup results with clamped record TTL
+type cachingGateway struct {
+	gateway                     Resolver
+	cache                       DnsCache
+	minTtl, maxTtl, negativeTtl time.Duration
+}
+
+// cachingGateway builder. Creates caching gateway with DNS cache settings from configuration
+func newCachingGateway(dnsGateway Resolver, configuration *Configuration) *cachingGateway {
+	return &cachingGateway{
+		gateway:     dnsGateway,
+		cache:       configuration.DnsCache,
+		minTtl:      time.Duration(configuration.DnsCacheMinTtl) * time.Second,
+		maxTtl:      time.Duration(configuration.DnsCacheMaxTtl) * time.Second,
+		negativeTtl: time.Duration(configuration.DnsCacheNegativeTtl) * time.Second,
+	}
+}
+
+// interface implementation
+
+func (cachingGateway *cachingGateway) LookupHost(ctx context.Context, hostName string) ([]string, error) {
+	value, err := cachingGateway.lookup(ctx, "A", hostName, func(ctx context.Context) (any, error) {
+		return cachingGateway.gateway.LookupHost(ctx, hostName)
+	})
+	ipAddresses, _ := value.([]string)
+
+	return copyStrings(ipAddresses), err
+}
+
+func (cachingGateway *cachingGateway) LookupCNAME(ctx context.Context, hostName string) (string, error) {
+	value, err := cachingGateway.lookup(ctx, "CNAME", hostName, func(ctx context.Context) (any, error) {
+		return cachingGateway.gateway.LookupCNAME(ctx, hostName)
+	})
+	cName, _ := value.(string)
+
+	return cName, err
+}
+
+func (cachingGateway *cachingGateway) LookupMX(ctx context.Context, hostName string) ([]*net.MX, error) {
+	value, err := cachingGateway.lookup(ctx, "MX", hostName, func(ctx context.Context) (any, error) {
+		return cachingGateway.gateway.LookupMX(ctx, hostName)
+	})
+	mxRecords, _ := value.([]*net.MX)
+
+	return copyMxRecords(mxRecords), err
+}
+
+func (cachingGateway *cachingGateway) LookupAddr(ctx context.Context, hostAddress string) ([]string, error) {
+	value, err := cachingGateway.lookup(ctx, "PTR", hostAddress, func(ctx context.Context) (any, error) {
+		return cachingGateway.gateway.LookupAddr(ctx, hostAddress)
+	})
+	hostNames, _ := value.([]string)
+
+	return copyStrings(hostNames), err
+}
+
+func (cachingGateway *cachingGateway) LookupTXT(ctx context.Context, hostName string) ([]string, error) {
+	value, err := cachingGateway.lookup(ctx, "TXT", hostName, func(ctx context.Context) (any, error) {
+		return cachingGateway.gateway.LookupTXT(ctx, hostName)
+	})
+	txtRecords, _ := value.([]string)
+
+	return copyStrings(txtRecords), err
+}
+
+// tlsaResolver interface implementation
+
+// TLSA lookups bypass DNS cache, because DNSSEC authenticated data flag of answer is not cached
+func (cachingGateway *cachingGateway) lookupTLSA(ctx context.Context, name string) ([]*TlsaRecord, error) {
+	return lookupTlsaRecords(ctx, cachingGateway.gateway, name)
+}
+
+// cachingGateway methods
+
+// Returns cached lookup result by query type and name. Otherwise runs lookup query
+// and caches successful result with record TTL, NXDOMAIN result with negative TTL.
+// Empty answers (NODATA) and not found errors of custom resolvers are not cached,
+// because the name may still have records of other types
+func (cachingGateway *cachingGateway) lookup(
+	ctx context.Context,
+	queryType, name string,
+	query func(context.Context) (any, error),
+) (any, error) {
+	key := dnsCacheKey(queryType, name)
+	if entry, ok := cachingGateway.cache.Get(key); ok {
+		dnsQueryFromContext(ctx).recordCached()
+		return entry.Value, entry.Err
+	}
+
+	recorder := new(dnsTtlRecorder)
+	value, err := query(withDnsTtlRecorder(ctx, recorder))
+	switch {
+	case err == nil:
+		cachingGateway.cache.Set(key, DnsCacheEntry{Value: value}, cachingGateway.ttl(recorder))
+	case isNxDomainError(err) && recorder.isNxDomain():
+		cachingGateway.cache.Set(key, DnsCacheEntry{Value: value, Err: err}, cachingGateway.negativeTtl)
+	}
+
+	return value, err
+}
+
+// Returns record TTL clamped by min and max cache TTL. Uses min cache TTL
+// when record TTL is unknown, for example for hosts file answers
+func (cachingGateway *cachingGateway) ttl(recorder *dnsTtlRecorder) time.Duration {
+	ttl, ok := recorder.value()
+	if !ok || ttl < cachingGateway.minTtl {
+		return cachingGateway.minTtl
+	}
+	if ttl > cachingGateway.maxTtl {
+		return cachingGateway.maxTtl
+	}
+
+	return ttl
+}
+
+// Returns DNS cache key follows {queryType}:{name} pattern. Name is lowercased fully
+// qualified domain name, so case and trailing dot variants of name share cache entry
+func dnsCacheKey(queryType, name string) string {
+	return queryType + ":" + strings.ToLower(dns.Fqdn(name))
+}
+
+// Returns true if error is a DNS not found error, NXDOMAIN or NODATA
+func isNxDomainError(err error) bool {
+	e, ok := err.(*net.DNSError)
+	return ok && e.IsNotFound
+}
+
+// Returns copy of strings slice, so cached slices can't be mutated by callers
+func copyStrings(strSlice []string) []string {
+	if strSlice == nil {
+		return nil
+	}
+
+	return append([]string{}, strSlice...)
+}
+
+// Returns deep copy of MX records slice, so cached records can't be mutated by callers
+func copyMxRecords(mxRecords []*net.MX) (copiedMxRecords []*net.MX) {
+	for _, mxRecord := range mxRecords {
+		copiedMxRecord := *mxRecord
+		copiedMxRecords = append(copiedMxRecords, &copiedMxRecord)
+	}
+
+	return copiedMxRecords
+}
+
+// DNS TTL recorder structure. Collects min TTL of answer records
+// and response codes of DNS messages received during one lookup
+type dnsTtlRecorder struct {
+	ttl               uint32
+	found             bool
+	nxDomain, noError bool
+	mutex             sync.Mutex
+}
+
+type dnsTtlRecorderKey struct{}
+
+// Returns context with DNS TTL recorder
+func withDnsTtlRecorder(ctx context.Context, recorder *dnsTtlRecorder) context.Context {
+	return context.WithValue(ctx, dnsTtlRecorderKey{}, recorder)
+}
+
+// Returns DNS TTL recorder from context if exists
+func dnsTtlRecorderFromContext(ctx context.Context) (*dnsTtlRecorder, bool) {
+	recorder, ok := ctx.Value(dnsTtlRecorderKey{}).(*dnsTtlRecorder)
+	return recorder, ok
+}
+
+// dnsTtlRecorder methods
+
+// Records answer TTL, keeps min of recorded TTLs
+func (recorder *dnsTtlRecorder) record(ttl uint32) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if !recorder.found || ttl < recorder.ttl {
+		recorder.ttl, recorder.found = ttl, true
+	}
+}
+
+// Returns recorded TTL duration and true if at least one TTL was recorded
+func (recorder *dnsTtlRecorder) value() (time.Duration, bool) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return time.Duration(recorder.ttl) * time.Second, recorder.found
+}
+
+// Records DNS message response code, NXDOMAIN and NOERROR are tracked only
+func (recorder *dnsTtlRecorder) recordRcode(rcode dnsmessage.RCode) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	switch rcode {
+	case dnsmessage.RCodeNameError:
+		recorder.nxDomain = true
+	case dnsmessage.RCodeSuccess:
+		recorder.noError = true
+	}
+}
+
+// Returns true if at least one NXDOMAIN response and none NOERROR
+// responses were recorded, so name does not exist for any query type
+func (recorder *dnsTtlRecorder) isNxDomain() bool {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return recorder.nxDomain && !recorder.noError
+}
+
+// Records response code and answers TTLs from raw DNS message
+func (recorder *dnsTtlRecorder) recordMessage(message []byte) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(message)
+	if err != nil {
+		return
+	}
+	recorder.recordRcode(header.RCode)
+	if err := parser.SkipAllQuestions(); err != nil {
+		return
+	}
+
+	for {
+		header, err := parser.AnswerHeader()
+		if err != nil {
+			return
+		}
+		recorder.record(header.TTL)
+		if err = parser.SkipAnswer(); err != nil {
+			return
+		}
+	}
+}
+
+// DNS TTL connection structure. Wraps DNS connection
+// and records TTLs of received DNS messages
+type dnsTtlConn struct {
+	net.Conn
+	recorder *dnsTtlRecorder
+	isStream bool
+	buffer   []byte
+}
+
+// Wraps DNS connection with DNS TTL connection when context includes DNS TTL recorder
+func wrapDnsTtlConn(ctx context.Context, connection net.Conn) net.Conn {
+	recorder, ok := dnsTtlRecorderFromContext(ctx)
+	if !ok {
+		return connection
+	}
+	if packetConnection, ok := connection.(net.PacketConn); ok {
+		return &dnsTtlPacketConn{dnsTtlConn: &dnsTtlConn{Conn: connection, recorder: recorder}, packetConn: packetConnection}
+	}
+
+	return &dnsTtlConn{Conn: connection, recorder: recorder, isStream: true}
+}
+
+// net.Conn interface implementation
+func (connection *dnsTtlConn) Read(bytes []byte) (int, error) {
+	n, err := connection.Conn.Read(bytes)
+	if n > 0 {
+		connection.inspect(bytes[:n])
+	}
+
+	return n, err
+}
+
+// Records TTLs from received bytes. Stream connection messages are
+// prefixed with two bytes message length, so assembles them first
+func (connection *dnsTtlConn) inspect(bytes []byte) {
+	if !connection.isStream {
+		connection.recorder.recordMessage(bytes)
+		return
+	}
+
+	connection.buffer = append(connection.buffer, bytes...)
+	for len(connection.buffer) >= 2 {
+		messageLength := int(binary.BigEndian.Uint16(connection.buffer))
+		if len(connection.buffer) < messageLength+2 {
+			return
+		}
+		connection.recorder.recordMessage(connection.buffer[2 : messageLength+2])
+		connection.buffer = connection.buffer[messageLength+2:]
+	}
+}
+
+// DNS TTL packet connection structure. Keeps net.PacketConn interface
+// of wrapped connection, so resolver uses datagram messages exchange
+type dnsTtlPacketConn struct {
+	*dnsTtlConn
+	packetConn net.PacketConn
+}
+
+// net.PacketConn interface implementation
+
+func (connection *dnsTtlPacketConn) ReadFrom(bytes []byte) (int, net.Addr, error) {
+	n, address, err := connection.packetConn.ReadFrom(bytes)
+	if n > 0 {
+		connection.inspect(bytes[:n])
+	}
+
+	return n, address, err
+}
+
+func (connection *dnsTtlPacketConn) WriteTo(bytes []byte, address net.Addr) (int, error) {
+	return connection.packetConn.WriteTo(bytes, address)
+}
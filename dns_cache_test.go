@@ -0,0 +1,473 @@
+package truemail
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNewDnsMemoryCache(t *testing.T) {
+	t.Run("creates empty in-memory DNS cache", func(t *testing.T) {
+		capacity := randomPositiveNumber()
+		cache := NewDnsMemoryCache(capacity)
+
+		assert.Equal(t, capacity, cache.capacity)
+		assert.Empty(t, cache.entries)
+		assert.Equal(t, 0, cache.Len())
+		assert.NotNil(t, cache.now)
+	})
+}
+
+func TestDnsMemoryCacheGet(t *testing.T) {
+	key, entry := dnsCacheKey("A", randomDomain()), DnsCacheEntry{Value: []string{randomIpAddress()}}
+
+	t.Run("when entry not found", func(t *testing.T) {
+		_, ok := NewDnsMemoryCache(1).Get(key)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when entry found and not expired", func(t *testing.T) {
+		cache := NewDnsMemoryCache(1)
+		cache.Set(key, entry, time.Minute)
+		cachedEntry, ok := cache.Get(key)
+
+		assert.True(t, ok)
+		assert.Equal(t, entry, cachedEntry)
+	})
+
+	t.Run("when entry found and expired", func(t *testing.T) {
+		currentTime, cache := time.Now(), NewDnsMemoryCache(1)
+		cache.now = func() time.Time { return currentTime }
+		cache.Set(key, entry, time.Minute)
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		_, ok := cache.Get(key)
+
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestDnsMemoryCacheSet(t *testing.T) {
+	firstKey, secondKey, thirdKey := dnsCacheKey("A", "a.com"), dnsCacheKey("A", "b.com"), dnsCacheKey("A", "c.com")
+	entry := DnsCacheEntry{Value: []string{randomIpAddress()}}
+
+	t.Run("when ttl is not positive", func(t *testing.T) {
+		cache := NewDnsMemoryCache(1)
+		cache.Set(firstKey, entry, 0)
+
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("when entry already exists", func(t *testing.T) {
+		newEntry, cache := DnsCacheEntry{Value: []string{randomIpAddress()}}, NewDnsMemoryCache(1)
+		cache.Set(firstKey, entry, time.Minute)
+		cache.Set(firstKey, newEntry, time.Minute)
+		cachedEntry, _ := cache.Get(firstKey)
+
+		assert.Equal(t, 1, cache.Len())
+		assert.Equal(t, newEntry, cachedEntry)
+	})
+
+	t.Run("when capacity reached evicts least recently used entry", func(t *testing.T) {
+		cache := NewDnsMemoryCache(2)
+		cache.Set(firstKey, entry, time.Minute)
+		cache.Set(secondKey, entry, time.Minute)
+		cache.Get(firstKey)
+		cache.Set(thirdKey, entry, time.Minute)
+		_, isFirstKeyFound := cache.Get(firstKey)
+		_, isSecondKeyFound := cache.Get(secondKey)
+		_, isThirdKeyFound := cache.Get(thirdKey)
+
+		assert.Equal(t, 2, cache.Len())
+		assert.True(t, isFirstKeyFound)
+		assert.False(t, isSecondKeyFound)
+		assert.True(t, isThirdKeyFound)
+	})
+}
+
+func TestNewCachingGateway(t *testing.T) {
+	t.Run("creates caching gateway with DNS cache settings from configuration", func(t *testing.T) {
+		dnsGateway, configuration := new(gatewayMock), createConfiguration()
+		configuration.DnsCache, configuration.DnsCacheMinTtl, configuration.DnsCacheMaxTtl, configuration.DnsCacheNegativeTtl = NewDnsMemoryCache(1), 1, 2, 3
+		cachingGateway := newCachingGateway(dnsGateway, configuration)
+
+		assert.Equal(t, dnsGateway, cachingGateway.gateway)
+		assert.Equal(t, configuration.DnsCache, cachingGateway.cache)
+		assert.Equal(t, time.Second, cachingGateway.minTtl)
+		assert.Equal(t, 2*time.Second, cachingGateway.maxTtl)
+		assert.Equal(t, 3*time.Second, cachingGateway.negativeTtl)
+	})
+}
+
+func createCachingGateway(dnsGateway Resolver) *cachingGateway {
+	return &cachingGateway{
+		gateway:     dnsGateway,
+		cache:       NewDnsMemoryCache(42),
+		minTtl:      time.Minute,
+		maxTtl:      time.Hour,
+		negativeTtl: time.Minute,
+	}
+}
+
+// Runs raw DNS server which counts received DNS requests. Returns running server address,
+// requests counter and server stop function
+func startCountingRawDnsServer(handler dns.HandlerFunc) (string, *atomic.Int32, func()) {
+	requests := new(atomic.Int32)
+	dnsServer, stop := startRawDnsServer(func(writer dns.ResponseWriter, request *dns.Msg) {
+		requests.Add(1)
+		handler(writer, request)
+	})
+
+	return dnsServer, requests, stop
+}
+
+func TestCachingGatewayLookups(t *testing.T) {
+	hostName, hostAddress := randomDomain(), randomIpAddress()
+
+	t.Run("LookupHost, caches successful result and returns its copy", func(t *testing.T) {
+		dnsGateway := new(gatewayMock)
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupHost", hostName).Once().Return([]string{hostAddress}, nil)
+		firstResult, _ := cachingGateway.LookupHost(context.Background(), hostName)
+		firstResult[0] = emptyString
+		secondResult, err := cachingGateway.LookupHost(context.Background(), hostName)
+
+		dnsGateway.AssertExpectations(t)
+		assert.Equal(t, []string{hostAddress}, secondResult)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LookupHost, shares cached result across name case and trailing dot variants", func(t *testing.T) {
+		dnsGateway := new(gatewayMock)
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupHost", hostName).Once().Return([]string{hostAddress}, nil)
+		_, _ = cachingGateway.LookupHost(context.Background(), hostName)
+		result, err := cachingGateway.LookupHost(context.Background(), strings.ToUpper(hostName)+".")
+
+		dnsGateway.AssertExpectations(t)
+		assert.Equal(t, []string{hostAddress}, result)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LookupCNAME, caches NXDOMAIN result", func(t *testing.T) {
+		dnsServer, requests, stop := startCountingRawDnsServer(createRawDnsHandler(dns.RcodeNameError, false))
+		defer stop()
+		cachingGateway := createCachingGateway(createRawDnsGateway(dnsServer))
+		_, _ = cachingGateway.LookupCNAME(context.Background(), hostName)
+		cName, err := cachingGateway.LookupCNAME(context.Background(), hostName)
+
+		assert.Equal(t, int32(1), requests.Load())
+		assert.Empty(t, cName)
+		assert.True(t, isNxDomainError(err))
+	})
+
+	t.Run("LookupHost, caches NXDOMAIN result of net resolver", func(t *testing.T) {
+		dnsServer, requests, stop := startCountingRawDnsServer(createRawDnsHandler(dns.RcodeNameError, false))
+		defer stop()
+		configuration := createConfiguration()
+		configuration.ConnectionTimeout = 1
+		cachingGateway := createCachingGateway(newDnsGateway(configuration, dnsServer))
+		_, _ = cachingGateway.LookupHost(context.Background(), hostName)
+		sentRequests := requests.Load()
+		ipAddresses, err := cachingGateway.LookupHost(context.Background(), hostName)
+
+		assert.Equal(t, sentRequests, requests.Load())
+		assert.Empty(t, ipAddresses)
+		assert.True(t, isNxDomainError(err))
+	})
+
+	t.Run("LookupMX, does not cache NODATA result", func(t *testing.T) {
+		dnsServer, requests, stop := startCountingRawDnsServer(createRawDnsHandler(dns.RcodeSuccess, false))
+		defer stop()
+		cachingGateway := createCachingGateway(createRawDnsGateway(dnsServer))
+		_, _ = cachingGateway.LookupMX(context.Background(), hostName)
+		mxRecords, err := cachingGateway.LookupMX(context.Background(), hostName)
+
+		assert.Equal(t, int32(2), requests.Load())
+		assert.Empty(t, mxRecords)
+		assert.True(t, isNxDomainError(err))
+	})
+
+	t.Run("LookupHost, does not cache NODATA result of net resolver", func(t *testing.T) {
+		dnsServer, requests, stop := startCountingRawDnsServer(func(writer dns.ResponseWriter, request *dns.Msg) {
+			response := new(dns.Msg)
+			response.SetReply(request)
+			response.RecursionAvailable = true
+			_ = writer.WriteMsg(response)
+		})
+		defer stop()
+		configuration := createConfiguration()
+		configuration.ConnectionTimeout = 1
+		cachingGateway := createCachingGateway(newDnsGateway(configuration, dnsServer))
+		_, _ = cachingGateway.LookupHost(context.Background(), hostName)
+		sentRequests := requests.Load()
+		_, err := cachingGateway.LookupHost(context.Background(), hostName)
+
+		assert.Equal(t, 2*sentRequests, requests.Load())
+		assert.True(t, isNxDomainError(err))
+	})
+
+	t.Run("LookupCNAME, does not cache not found error of custom resolver", func(t *testing.T) {
+		dnsGateway, dnsError := new(gatewayMock), &net.DNSError{IsNotFound: true}
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupCNAME", hostName).Twice().Return(emptyString, dnsError)
+		_, _ = cachingGateway.LookupCNAME(context.Background(), hostName)
+		cName, err := cachingGateway.LookupCNAME(context.Background(), hostName)
+
+		dnsGateway.AssertExpectations(t)
+		assert.Empty(t, cName)
+		assert.Equal(t, dnsError, err)
+	})
+
+	t.Run("LookupMX, caches successful result and returns its copy", func(t *testing.T) {
+		dnsGateway, mxRecords := new(gatewayMock), []*net.MX{{Host: randomDnsHostName(), Pref: 10}}
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupMX", hostName).Once().Return(mxRecords, nil)
+		firstResult, _ := cachingGateway.LookupMX(context.Background(), hostName)
+		firstResult[0].Host = emptyString
+		secondResult, err := cachingGateway.LookupMX(context.Background(), hostName)
+
+		dnsGateway.AssertExpectations(t)
+		assert.Equal(t, mxRecords, secondResult)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LookupTXT, caches successful result and returns its copy", func(t *testing.T) {
+		dnsGateway, txtRecords := new(gatewayMock), []string{"v=STSv1; id=42"}
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupTXT", hostName).Once().Return(txtRecords, nil)
+		firstResult, _ := cachingGateway.LookupTXT(context.Background(), hostName)
+		firstResult[0] = emptyString
+		secondResult, err := cachingGateway.LookupTXT(context.Background(), hostName)
+
+		dnsGateway.AssertExpectations(t)
+		assert.Equal(t, []string{"v=STSv1; id=42"}, secondResult)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LookupAddr, does not cache temporary error", func(t *testing.T) {
+		dnsGateway, dnsError := new(gatewayMock), &net.DNSError{IsTemporary: true}
+		cachingGateway := createCachingGateway(dnsGateway)
+		dnsGateway.On("LookupAddr", hostAddress).Twice().Return([]string(nil), dnsError)
+		_, _ = cachingGateway.LookupAddr(context.Background(), hostAddress)
+		hostNames, err := cachingGateway.LookupAddr(context.Background(), hostAddress)
+
+		dnsGateway.AssertExpectations(t)
+		assert.Empty(t, hostNames)
+		assert.Equal(t, dnsError, err)
+	})
+}
+
+func TestCachingGatewayLookupTLSA(t *testing.T) {
+	t.Run("bypasses DNS cache", func(t *testing.T) {
+		name, tlsaRecords := randomDomain(), []*TlsaRecord{{Usage: tlsaUsageDaneEe}}
+		dnsGateway := &tlsaResolverStandIn{tlsaRecords: tlsaRecords}
+		cachingGateway := createCachingGateway(dnsGateway)
+		_, _ = cachingGateway.lookupTLSA(context.Background(), name)
+		resolvedTlsaRecords, err := cachingGateway.lookupTLSA(context.Background(), name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tlsaRecords, resolvedTlsaRecords)
+		assert.Equal(t, []string{name, name}, dnsGateway.names)
+	})
+}
+
+func TestCachingGatewayTtl(t *testing.T) {
+	cachingGateway := createCachingGateway(new(gatewayMock))
+
+	t.Run("when record TTL is unknown", func(t *testing.T) {
+		assert.Equal(t, time.Minute, cachingGateway.ttl(new(dnsTtlRecorder)))
+	})
+
+	t.Run("when record TTL less than min TTL", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.record(1)
+
+		assert.Equal(t, time.Minute, cachingGateway.ttl(recorder))
+	})
+
+	t.Run("when record TTL greater than max TTL", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.record(86400)
+
+		assert.Equal(t, time.Hour, cachingGateway.ttl(recorder))
+	})
+
+	t.Run("when record TTL in range", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.record(600)
+
+		assert.Equal(t, 10*time.Minute, cachingGateway.ttl(recorder))
+	})
+}
+
+func TestDnsCacheKey(t *testing.T) {
+	t.Run("returns key with query type and normalized name", func(t *testing.T) {
+		assert.Equal(t, "MX:example.com.", dnsCacheKey("MX", "Example.COM"))
+		assert.Equal(t, dnsCacheKey("MX", "example.com."), dnsCacheKey("MX", "EXAMPLE.com"))
+	})
+
+	t.Run("depends on query type", func(t *testing.T) {
+		assert.NotEqual(t, dnsCacheKey("A", "example.com"), dnsCacheKey("MX", "example.com"))
+	})
+}
+
+func TestIsNxDomainError(t *testing.T) {
+	t.Run("when DNS not found error", func(t *testing.T) {
+		assert.True(t, isNxDomainError(&net.DNSError{IsNotFound: true}))
+	})
+
+	t.Run("when other error", func(t *testing.T) {
+		assert.False(t, isNxDomainError(&net.DNSError{IsTimeout: true}))
+		assert.False(t, isNxDomainError(errors.New("error")))
+	})
+}
+
+func TestDnsTtlRecorderRecordMessage(t *testing.T) {
+	hostName := randomDomain()
+
+	t.Run("records min answer TTL", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordMessage(createDnsMessage(hostName, 300, 42, 600))
+		ttl, ok := recorder.value()
+
+		assert.True(t, ok)
+		assert.Equal(t, 42*time.Second, ttl)
+	})
+
+	t.Run("when message without answers", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordMessage(createDnsMessage(hostName))
+		_, ok := recorder.value()
+
+		assert.False(t, ok)
+	})
+
+	t.Run("records NXDOMAIN response code", func(t *testing.T) {
+		message := new(dns.Msg)
+		message.SetQuestion(toDnsHostName(hostName), dns.TypeA)
+		message.Response, message.Rcode = true, dns.RcodeNameError
+		packedMessage, _ := message.Pack()
+		recorder := new(dnsTtlRecorder)
+		recorder.recordMessage(packedMessage)
+
+		assert.True(t, recorder.isNxDomain())
+	})
+
+	t.Run("records NOERROR response code", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordMessage(createDnsMessage(hostName))
+
+		assert.False(t, recorder.isNxDomain())
+	})
+
+	t.Run("when message is malformed", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordMessage([]byte{42})
+		_, ok := recorder.value()
+
+		assert.False(t, ok)
+		assert.False(t, recorder.isNxDomain())
+	})
+}
+
+func TestDnsTtlRecorderIsNxDomain(t *testing.T) {
+	t.Run("when NXDOMAIN responses recorded only", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordRcode(dnsmessage.RCodeNameError)
+		recorder.recordRcode(dnsmessage.RCodeServerFailure)
+
+		assert.True(t, recorder.isNxDomain())
+	})
+
+	t.Run("when NXDOMAIN and NOERROR responses recorded", func(t *testing.T) {
+		recorder := new(dnsTtlRecorder)
+		recorder.recordRcode(dnsmessage.RCodeNameError)
+		recorder.recordRcode(dnsmessage.RCodeSuccess)
+
+		assert.False(t, recorder.isNxDomain())
+	})
+
+	t.Run("when responses not recorded", func(t *testing.T) {
+		assert.False(t, new(dnsTtlRecorder).isNxDomain())
+	})
+}
+
+func TestWrapDnsTtlConn(t *testing.T) {
+	t.Run("when context without DNS TTL recorder", func(t *testing.T) {
+		connection, _ := net.Pipe()
+
+		assert.Equal(t, connection, wrapDnsTtlConn(context.Background(), connection))
+	})
+
+	t.Run("when stream connection", func(t *testing.T) {
+		connection, _ := net.Pipe()
+		wrappedConnection := wrapDnsTtlConn(withDnsTtlRecorder(context.Background(), new(dnsTtlRecorder)), connection)
+
+		assert.IsType(t, new(dnsTtlConn), wrappedConnection)
+		assert.True(t, wrappedConnection.(*dnsTtlConn).isStream)
+	})
+
+	t.Run("when packet connection", func(t *testing.T) {
+		connection, _ := net.ListenPacket("udp", localhostIPv4Address+":0")
+		defer connection.Close()
+		wrappedConnection := wrapDnsTtlConn(withDnsTtlRecorder(context.Background(), new(dnsTtlRecorder)), connection.(net.Conn))
+		_, isPacketConnection := wrappedConnection.(net.PacketConn)
+
+		assert.True(t, isPacketConnection)
+	})
+}
+
+func TestDnsTtlConnInspect(t *testing.T) {
+	t.Run("assembles stream messages from chunks", func(t *testing.T) {
+		message, recorder := createDnsMessage(randomDomain(), 42), new(dnsTtlRecorder)
+		streamMessage := binary.BigEndian.AppendUint16(nil, uint16(len(message)))
+		streamMessage = append(streamMessage, message...)
+		connection := &dnsTtlConn{recorder: recorder, isStream: true}
+		connection.inspect(streamMessage[:3])
+		_, isRecordedBeforeMessageCompleted := recorder.value()
+		connection.inspect(streamMessage[3:])
+		ttl, ok := recorder.value()
+
+		assert.False(t, isRecordedBeforeMessageCompleted)
+		assert.True(t, ok)
+		assert.Equal(t, 42*time.Second, ttl)
+		assert.Empty(t, connection.buffer)
+	})
+}
+
+func TestDnsResolverWithDnsCache(t *testing.T) {
+	// Integration test with internal DNS request
+
+	t.Run("caches DNS answer with record TTL", func(t *testing.T) {
+		hostName, hostAddress := randomDomain(), randomIpAddress()
+		dns, stop := startMockDnsServer(map[string]mockdns.Zone{toDnsHostName(hostName): {A: []string{hostAddress}}})
+		currentTime, dnsCache, configuration := time.Now(), NewDnsMemoryCache(42), createConfiguration()
+		dnsCache.now = func() time.Time { return currentTime }
+		configuration.Dns, configuration.DnsCache, configuration.DnsCacheMaxTtl = dns, dnsCache, 86400
+		resolvedHostAddresses, err := newDnsResolver(configuration).aRecords(hostName)
+		stop()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, 1, dnsCache.Len())
+
+		item := dnsCache.entries[dnsCacheKey("A", hostName)].Value.(*dnsMemoryCacheItem)
+		assert.Equal(t, 9999*time.Second, item.expiresAt.Sub(currentTime))
+
+		resolvedHostAddresses, err = newDnsResolver(configuration).aRecords(hostName)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+	})
+}
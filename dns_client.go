@@ -0,0 +1,256 @@
+package truemail
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Raw DNS gateway structure. Sends DNS requests on wire level via DNS transport from
+// configuration. Unlike net.Resolver reports response codes, TTLs and DNSSEC authenticated
+// data flag, returns DnsResponseError for SERVFAIL, REFUSED and DNS server timeouts
+type rawDnsGateway struct {
+	dnsServer         string
+	connectionTimeout time.Duration
+	dial              dnsDialFunc
+}
+
+// rawDnsGateway builder. Uses system DNS server when DNS server is empty,
+// system DNS server is resolved once from resolv.conf
+func newRawDnsGateway(configuration *Configuration, dnsServer string) *rawDnsGateway {
+	rawDnsGateway := &rawDnsGateway{
+		dnsServer:         dnsServer,
+		connectionTimeout: time.Duration(configuration.ConnectionTimeout) * time.Second,
+		dial:              newDnsDialFunc(configuration, dnsServer),
+	}
+	if dnsServer == emptyString {
+		rawDnsGateway.dnsServer = systemDnsServer(systemDnsConfigPath)
+	}
+
+	return rawDnsGateway
+}
+
+// Returns first DNS server from resolv.conf by config path. Returns default
+// system DNS server when resolv.conf can not be read or includes no DNS servers
+func systemDnsServer(configPath string) string {
+	clientConfig, err := dns.ClientConfigFromFile(configPath)
+	if err != nil || len(clientConfig.Servers) == 0 {
+		return defaultSystemDnsServer
+	}
+
+	return net.JoinHostPort(clientConfig.Servers[0], clientConfig.Port)
+}
+
+// interface implementation
+
+// Resolves IPv4 addresses only, IPv6 addresses are rejected by dnsResolver anyway
+func (rawDnsGateway *rawDnsGateway) LookupHost(ctx context.Context, hostName string) (ipAddresses []string, err error) {
+	response, err := rawDnsGateway.lookup(ctx, hostName, dns.TypeA)
+	if err != nil {
+		return ipAddresses, err
+	}
+
+	for _, record := range response.Answer {
+		if aRecord, ok := record.(*dns.A); ok {
+			ipAddresses = append(ipAddresses, aRecord.A.String())
+		}
+	}
+
+	return ipAddresses, nil
+}
+
+func (rawDnsGateway *rawDnsGateway) LookupCNAME(ctx context.Context, hostName string) (string, error) {
+	response, err := rawDnsGateway.lookup(ctx, hostName, dns.TypeA)
+	if err != nil {
+		return emptyString, err
+	}
+
+	cName := dns.Fqdn(hostName)
+	for _, record := range response.Answer {
+		if cNameRecord, ok := record.(*dns.CNAME); ok {
+			cName = cNameRecord.Target
+		}
+	}
+
+	return cName, nil
+}
+
+func (rawDnsGateway *rawDnsGateway) LookupMX(ctx context.Context, hostName string) (mxRecords []*net.MX, err error) {
+	response, err := rawDnsGateway.lookup(ctx, hostName, dns.TypeMX)
+	if err != nil {
+		return mxRecords, err
+	}
+
+	for _, record := range response.Answer {
+		if mxRecord, ok := record.(*dns.MX); ok {
+			mxRecords = append(mxRecords, &net.MX{Host: mxRecord.Mx, Pref: mxRecord.Preference})
+		}
+	}
+
+	return mxRecords, nil
+}
+
+func (rawDnsGateway *rawDnsGateway) LookupAddr(ctx context.Context, hostAddress string) (hostNames []string, err error) {
+	reverseName, err := dns.ReverseAddr(hostAddress)
+	if err != nil {
+		return hostNames, &net.DNSError{Err: err.Error(), Name: hostAddress}
+	}
+
+	response, err := rawDnsGateway.lookup(ctx, reverseName, dns.TypePTR)
+	if err != nil {
+		return hostNames, err
+	}
+
+	for _, record := range response.Answer {
+		if ptrRecord, ok := record.(*dns.PTR); ok {
+			hostNames = append(hostNames, ptrRecord.Ptr)
+		}
+	}
+
+	return hostNames, nil
+}
+
+func (rawDnsGateway *rawDnsGateway) LookupTXT(ctx context.Context, hostName string) (txtRecords []string, err error) {
+	response, err := rawDnsGateway.lookup(ctx, hostName, dns.TypeTXT)
+	if err != nil {
+		return txtRecords, err
+	}
+
+	for _, record := range response.Answer {
+		if txtRecord, ok := record.(*dns.TXT); ok {
+			txtRecords = append(txtRecords, strings.Join(txtRecord.Txt, emptyString))
+		}
+	}
+
+	return txtRecords, nil
+}
+
+// tlsaResolver interface implementation
+
+func (rawDnsGateway *rawDnsGateway) lookupTLSA(ctx context.Context, name string) (tlsaRecords []*TlsaRecord, err error) {
+	response, err := rawDnsGateway.lookup(ctx, name, dns.TypeTLSA)
+	if err != nil {
+		return tlsaRecords, err
+	}
+
+	for _, record := range response.Answer {
+		if tlsaRecord, ok := record.(*dns.TLSA); ok {
+			tlsaRecords = append(
+				tlsaRecords,
+				&TlsaRecord{
+					Usage:        tlsaRecord.Usage,
+					Selector:     tlsaRecord.Selector,
+					MatchingType: tlsaRecord.MatchingType,
+					Certificate:  strings.ToLower(tlsaRecord.Certificate),
+				},
+			)
+		}
+	}
+
+	return tlsaRecords, nil
+}
+
+// rawDnsGateway methods
+
+// Sends DNS request and records DNS response into DNS query and DNS TTL recorder
+// from context. Returns net.DNSError with IsNotFound for NXDOMAIN and empty answers
+// of query type, DnsResponseError for failure response codes and transport errors
+func (rawDnsGateway *rawDnsGateway) lookup(ctx context.Context, name string, queryType uint16) (*dns.Msg, error) {
+	dnsServer := rawDnsGateway.dnsServer
+	dnsQueryFromContext(ctx).recordServer(dnsServer)
+
+	response, err := rawDnsGateway.exchange(ctx, dnsServer, rawDnsGateway.message(name, queryType))
+	if err != nil {
+		return nil, &DnsResponseError{Name: name, Server: dnsServer, Err: err}
+	}
+
+	ttl := rawDnsGateway.recordTtl(ctx, response)
+	rcode := dns.RcodeToString[response.Rcode]
+	dnsQueryFromContext(ctx).recordResponse(rcode, ttl, response.AuthenticatedData)
+
+	switch {
+	case response.Rcode == dns.RcodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: dnsServer, IsNotFound: true}
+	case response.Rcode != dns.RcodeSuccess:
+		return nil, &DnsResponseError{Name: name, Server: dnsServer, Rcode: rcode}
+	case !rawDnsGateway.isAnswered(response, queryType):
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: dnsServer, IsNotFound: true}
+	}
+
+	return response, nil
+}
+
+// Returns DNS request message with EDNS0 and DNSSEC authenticated data flag (RFC 6840)
+func (rawDnsGateway *rawDnsGateway) message(name string, queryType uint16) *dns.Msg {
+	message := new(dns.Msg)
+	message.SetQuestion(dns.Fqdn(name), queryType)
+	message.AuthenticatedData = true
+	message.SetEdns0(dnsClientUdpSize, false)
+
+	return message
+}
+
+// Sends DNS request message to DNS server. Retries request over TCP when UDP response
+// is truncated. Stream transports (DNS-over-TLS, DNS-over-HTTPS) never truncate responses
+func (rawDnsGateway *rawDnsGateway) exchange(ctx context.Context, dnsServer string, message *dns.Msg) (*dns.Msg, error) {
+	response, err := rawDnsGateway.exchangeVia(ctx, udpTransportLayer, dnsServer, message)
+	if err == nil && response.Truncated {
+		return rawDnsGateway.exchangeVia(ctx, tcpTransportLayer, dnsServer, message)
+	}
+
+	return response, err
+}
+
+// Sends DNS request message to DNS server via network protocol. Network protocol
+// is ignored by DNS-over-TLS and DNS-over-HTTPS dial functions
+func (rawDnsGateway *rawDnsGateway) exchangeVia(
+	ctx context.Context,
+	networkProtocol, dnsServer string,
+	message *dns.Msg,
+) (*dns.Msg, error) {
+	connection, err := rawDnsGateway.dial(ctx, networkProtocol, dnsServer)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+
+	client := &dns.Client{Timeout: rawDnsGateway.connectionTimeout}
+	response, _, err := client.ExchangeWithConnContext(ctx, message, &dns.Conn{Conn: connection})
+
+	return response, err
+}
+
+// Returns true if DNS response includes answer records of query type
+func (rawDnsGateway *rawDnsGateway) isAnswered(response *dns.Msg, queryType uint16) bool {
+	for _, record := range response.Answer {
+		if record.Header().Rrtype == queryType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Records response code and answer records TTLs into DNS TTL recorder from context.
+// Returns min answer TTL
+func (rawDnsGateway *rawDnsGateway) recordTtl(ctx context.Context, response *dns.Msg) (ttl uint32) {
+	recorder, isRecorderFound := dnsTtlRecorderFromContext(ctx)
+	if isRecorderFound {
+		recorder.recordRcode(dnsmessage.RCode(response.Rcode))
+	}
+	for index, record := range response.Answer {
+		recordTtl := record.Header().Ttl
+		if index == 0 || recordTtl < ttl {
+			ttl = recordTtl
+		}
+		if isRecorderFound {
+			recorder.record(recordTtl)
+		}
+	}
+
+	return ttl
+}
@@ -0,0 +1,294 @@
+package truemail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func createRawDnsGateway(dnsServer string) *rawDnsGateway {
+	configuration := createConfiguration()
+	configuration.DnsTransport, configuration.ConnectionTimeout = dnsTransportPlain, 1
+	return newRawDnsGateway(configuration, dnsServer)
+}
+
+func TestNewRawDnsGateway(t *testing.T) {
+	t.Run("creates raw DNS gateway", func(t *testing.T) {
+		configuration, dnsServer := createConfiguration(), randomDnsServer()
+		rawDnsGateway := newRawDnsGateway(configuration, dnsServer)
+
+		assert.Equal(t, dnsServer, rawDnsGateway.dnsServer)
+		assert.Equal(t, int64(configuration.ConnectionTimeout), int64(rawDnsGateway.connectionTimeout.Seconds()))
+		assert.NotNil(t, rawDnsGateway.dial)
+	})
+
+	t.Run("when DNS server not specified, uses system DNS server", func(t *testing.T) {
+		rawDnsGateway := newRawDnsGateway(createConfiguration(), emptyString)
+
+		assert.Equal(t, systemDnsServer(systemDnsConfigPath), rawDnsGateway.dnsServer)
+	})
+}
+
+func TestRawDnsGatewayLookupMX(t *testing.T) {
+	hostName := randomDomain()
+
+	t.Run("when DNSSEC authenticated answer", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(
+				dns.RcodeSuccess,
+				true,
+				createDnsRecord(toDnsHostName(hostName)+" 300 IN MX 10 mx1."+hostName+"."),
+				createDnsRecord(toDnsHostName(hostName)+" 120 IN MX 20 mx2."+hostName+"."),
+			),
+		)
+		defer stop()
+		dnsQuery := &DnsQuery{Type: "MX", Name: hostName}
+		mxRecords, err := createRawDnsGateway(dnsServer).LookupMX(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*net.MX{{Host: "mx1." + hostName + ".", Pref: 10}, {Host: "mx2." + hostName + ".", Pref: 20}}, mxRecords)
+		assert.Equal(t, dnsServer, dnsQuery.Server)
+		assert.Equal(t, "NOERROR", dnsQuery.Rcode)
+		assert.Equal(t, uint32(120), dnsQuery.Ttl)
+		assert.True(t, dnsQuery.Authenticated)
+	})
+
+	t.Run("when not authenticated answer, records TTLs into DNS TTL recorder", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(toDnsHostName(hostName)+" 42 IN MX 10 mx."+hostName+".")),
+		)
+		defer stop()
+		dnsQuery, recorder := new(DnsQuery), new(dnsTtlRecorder)
+		ctx := withDnsTtlRecorder(withDnsQuery(context.Background(), dnsQuery), recorder)
+		_, err := createRawDnsGateway(dnsServer).LookupMX(ctx, hostName)
+		ttl, ok := recorder.value()
+
+		assert.NoError(t, err)
+		assert.False(t, dnsQuery.Authenticated)
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), int64(ttl.Seconds()))
+	})
+
+	t.Run("when NXDOMAIN", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(createRawDnsHandler(dns.RcodeNameError, false))
+		defer stop()
+		dnsQuery := new(DnsQuery)
+		_, err := createRawDnsGateway(dnsServer).LookupMX(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		assert.True(t, isNxDomainError(err))
+		assert.Equal(t, "NXDOMAIN", dnsQuery.Rcode)
+	})
+
+	t.Run("when NOERROR without answers of query type", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(toDnsHostName(hostName)+" 42 IN A 127.0.0.1")),
+		)
+		defer stop()
+		_, err := createRawDnsGateway(dnsServer).LookupMX(context.Background(), hostName)
+
+		assert.True(t, isNxDomainError(err))
+	})
+
+	for _, rcode := range []int{dns.RcodeServerFailure, dns.RcodeRefused} {
+		t.Run("when "+dns.RcodeToString[rcode], func(t *testing.T) {
+			dnsServer, stop := startRawDnsServer(createRawDnsHandler(rcode, false))
+			defer stop()
+			dnsQuery := new(DnsQuery)
+			_, err := createRawDnsGateway(dnsServer).LookupMX(withDnsQuery(context.Background(), dnsQuery), hostName)
+			var dnsResponseError *DnsResponseError
+
+			assert.True(t, errors.As(err, &dnsResponseError))
+			assert.Equal(t, &DnsResponseError{Name: hostName, Server: dnsServer, Rcode: dns.RcodeToString[rcode]}, dnsResponseError)
+			assert.Equal(t, dns.RcodeToString[rcode], dnsQuery.Rcode)
+			assert.False(t, isNxDomainError(err))
+		})
+	}
+
+	t.Run("when DNS server timeout", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(func(dns.ResponseWriter, *dns.Msg) {})
+		defer stop()
+		_, err := createRawDnsGateway(dnsServer).LookupMX(context.Background(), hostName)
+		var dnsResponseError *DnsResponseError
+
+		assert.True(t, errors.As(err, &dnsResponseError))
+		assert.True(t, dnsResponseError.Timeout())
+		assert.Empty(t, dnsResponseError.Rcode)
+	})
+
+	t.Run("when truncated UDP response, retries request over TCP", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(func(writer dns.ResponseWriter, request *dns.Msg) {
+			response := new(dns.Msg)
+			response.SetReply(request)
+			if writer.RemoteAddr().Network() == udpTransportLayer {
+				response.Truncated = true
+			} else {
+				response.Answer = []dns.RR{createDnsRecord(toDnsHostName(hostName) + " 42 IN MX 10 mx." + hostName + ".")}
+			}
+			_ = writer.WriteMsg(response)
+		})
+		defer stop()
+		mxRecords, err := createRawDnsGateway(dnsServer).LookupMX(context.Background(), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*net.MX{{Host: "mx." + hostName + ".", Pref: 10}}, mxRecords)
+	})
+}
+
+func TestRawDnsGatewayLookups(t *testing.T) {
+	hostName, hostAddress, cName := randomDomain(), randomIpAddress(), randomDomain()
+
+	t.Run("LookupHost", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(toDnsHostName(hostName)+" 42 IN A "+hostAddress)),
+		)
+		defer stop()
+		ipAddresses, err := createRawDnsGateway(dnsServer).LookupHost(context.Background(), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, ipAddresses)
+	})
+
+	t.Run("LookupCNAME, when CNAME record exists", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(
+				dns.RcodeSuccess,
+				false,
+				createDnsRecord(toDnsHostName(hostName)+" 42 IN CNAME "+toDnsHostName(cName)),
+				createDnsRecord(toDnsHostName(cName)+" 42 IN A "+hostAddress),
+			),
+		)
+		defer stop()
+		resolvedCName, err := createRawDnsGateway(dnsServer).LookupCNAME(context.Background(), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, toDnsHostName(cName), resolvedCName)
+	})
+
+	t.Run("LookupCNAME, when CNAME record not exists", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(toDnsHostName(hostName)+" 42 IN A "+hostAddress)),
+		)
+		defer stop()
+		resolvedCName, err := createRawDnsGateway(dnsServer).LookupCNAME(context.Background(), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, toDnsHostName(hostName), resolvedCName)
+	})
+
+	t.Run("LookupAddr", func(t *testing.T) {
+		reverseName, _ := dns.ReverseAddr(hostAddress)
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(reverseName+" 42 IN PTR "+toDnsHostName(hostName))),
+		)
+		defer stop()
+		hostNames, err := createRawDnsGateway(dnsServer).LookupAddr(context.Background(), hostAddress)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{toDnsHostName(hostName)}, hostNames)
+	})
+
+	t.Run("LookupAddr, when invalid host address", func(t *testing.T) {
+		_, err := createRawDnsGateway(randomDnsServer()).LookupAddr(context.Background(), hostName)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("LookupTXT, joins TXT record strings", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, false, createDnsRecord(toDnsHostName(hostName)+` 42 IN TXT "v=STSv1; " "id=42"`)),
+		)
+		defer stop()
+		txtRecords, err := createRawDnsGateway(dnsServer).LookupTXT(context.Background(), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v=STSv1; id=42"}, txtRecords)
+	})
+}
+
+func TestRawDnsGatewayLookupTLSA(t *testing.T) {
+	name := daneTlsaName(defaultSmtpPort, randomDomain())
+
+	t.Run("when DNSSEC authenticated TLSA records", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, true, createDnsRecord(toDnsHostName(name)+" 42 IN TLSA 3 1 1 ABCDEF0123")),
+		)
+		defer stop()
+		dnsQuery := new(DnsQuery)
+		tlsaRecords, err := createRawDnsGateway(dnsServer).lookupTLSA(withDnsQuery(context.Background(), dnsQuery), name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*TlsaRecord{{Usage: 3, Selector: 1, MatchingType: 1, Certificate: "abcdef0123"}}, tlsaRecords)
+		assert.True(t, dnsQuery.Authenticated)
+	})
+
+	t.Run("when TLSA records not exist", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(createRawDnsHandler(dns.RcodeNameError, false))
+		defer stop()
+		_, err := createRawDnsGateway(dnsServer).lookupTLSA(context.Background(), name)
+
+		assert.True(t, isNxDomainError(err))
+	})
+}
+
+func TestRawDnsGatewayTransports(t *testing.T) {
+	// Integration tests with internal DNS request via in-process DNS-over-TLS/HTTPS stand-ins
+
+	domain, mxHostName := randomDomain(), randomDnsHostName()
+	dnsServer, stopDnsServer := startMockDnsServer(
+		map[string]mockdns.Zone{toDnsHostName(domain): {MX: []net.MX{{Host: mxHostName, Pref: 10}}}},
+	)
+	defer stopDnsServer()
+
+	t.Run("when DNS-over-TLS transport", func(t *testing.T) {
+		dnsOverTlsServer, rootCAs, stop := startDnsOverTlsStandIn(dnsServer)
+		defer stop()
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.DnsTlsServerName = dnsTransportTls, "example.com"
+		configuration.DnsTlsConfig = &tls.Config{RootCAs: rootCAs}
+		mxRecords, err := newRawDnsGateway(configuration, dnsOverTlsServer).LookupMX(context.Background(), domain)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*net.MX{{Host: mxHostName, Pref: 10}}, mxRecords)
+	})
+
+	t.Run("when DNS-over-HTTPS transport", func(t *testing.T) {
+		dnsOverHttpsServer := startDnsOverHttpsStandIn(dnsServer)
+		defer dnsOverHttpsServer.Close()
+		configuration := createConfiguration()
+		configuration.DnsTransport = dnsTransportHttps
+		configuration.DnsTlsConfig = dnsOverHttpsServer.Client().Transport.(*http.Transport).TLSClientConfig
+		mxRecords, err := newRawDnsGateway(configuration, dnsOverHttpsServer.URL).LookupMX(context.Background(), domain)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*net.MX{{Host: mxHostName, Pref: 10}}, mxRecords)
+	})
+}
+
+func TestSystemDnsServer(t *testing.T) {
+	t.Run("returns first DNS server from resolv.conf", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "resolv.conf")
+		_ = os.WriteFile(configPath, []byte("nameserver 10.0.0.1\nnameserver 10.0.0.2\n"), 0o600)
+
+		assert.Equal(t, "10.0.0.1:53", systemDnsServer(configPath))
+	})
+
+	t.Run("when resolv.conf includes no DNS servers", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "resolv.conf")
+		_ = os.WriteFile(configPath, []byte("options ndots:1\n"), 0o600)
+
+		assert.Equal(t, defaultSystemDnsServer, systemDnsServer(configPath))
+	})
+
+	t.Run("when resolv.conf can not be read", func(t *testing.T) {
+		assert.Equal(t, defaultSystemDnsServer, systemDnsServer(filepath.Join(t.TempDir(), "resolv.conf")))
+	})
+}
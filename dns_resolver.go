@@ -2,17 +2,123 @@ package truemail
 
 import (
 	"context"
+	"log/slog"
 	"net"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-type gateway interface {
-	LookupHost(context.Context, string) ([]string, error)
-	LookupCNAME(context.Context, string) (string, error)
-	LookupMX(context.Context, string) ([]*net.MX, error)
-	LookupAddr(context.Context, string) ([]string, error)
+// Resolver interface. Provides DNS lookups (A/AAAA, CNAME, MX, PTR, TXT) for DNS gateway.
+// It is implemented by *net.Resolver, which Truemail uses by default. Custom Resolver can
+// be specified in configuration to use in-house resolver service or static DNS fixtures
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNS query structure. Includes DNS query type, name, DNS server which
+// answered the query and DNS servers which failed to answer. Response code,
+// min answer TTL and DNSSEC authenticated data flag are recorded by raw DNS client only
+type DnsQuery struct {
+	Type, Name, Server, Rcode string
+	FailedServers             []string
+	Ttl                       uint32
+	Cached, Authenticated     bool
+	mutex                     sync.Mutex
+}
+
+type dnsQueryKey struct{}
+
+// Returns context with DNS query
+func withDnsQuery(ctx context.Context, dnsQuery *DnsQuery) context.Context {
+	return context.WithValue(ctx, dnsQueryKey{}, dnsQuery)
+}
+
+// Returns DNS query from context. Returns nil if context has no DNS query
+func dnsQueryFromContext(ctx context.Context) *DnsQuery {
+	dnsQuery, _ := ctx.Value(dnsQueryKey{}).(*DnsQuery)
+	return dnsQuery
+}
+
+// DnsQuery methods
+
+// Records DNS server which answered the query
+func (dnsQuery *DnsQuery) recordServer(dnsServer string) {
+	if dnsQuery == nil {
+		return
+	}
+
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+	dnsQuery.Server = dnsServer
+}
+
+// Records DNS servers which failed to answer the query
+func (dnsQuery *DnsQuery) recordFailedServers(dnsServers ...string) {
+	if dnsQuery == nil {
+		return
+	}
+
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+	dnsQuery.FailedServers = append(dnsQuery.FailedServers, dnsServers...)
+}
+
+// Records DNS response code, min answer TTL and DNSSEC authenticated data flag
+func (dnsQuery *DnsQuery) recordResponse(rcode string, ttl uint32, authenticated bool) {
+	if dnsQuery == nil {
+		return
+	}
+
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+	dnsQuery.Rcode, dnsQuery.Ttl, dnsQuery.Authenticated = rcode, ttl, authenticated
+}
+
+// Returns span attributes of the query: DNS server which answered the query,
+// response code and DNS cache marker
+func (dnsQuery *DnsQuery) attributes() []attribute.KeyValue {
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+
+	return []attribute.KeyValue{
+		attribute.String(attributeDnsServer, dnsQuery.Server),
+		attribute.String(attributeDnsRcode, dnsQuery.Rcode),
+		attribute.Bool(attributeDnsCached, dnsQuery.Cached),
+	}
+}
+
+// Returns log attributes of the query: DNS query type and name, DNS server which
+// answered the query, response code and DNS cache marker
+func (dnsQuery *DnsQuery) logAttributes() []slog.Attr {
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+
+	return []slog.Attr{
+		slog.String(logAttrQueryType, dnsQuery.Type),
+		slog.String(logAttrQueryName, dnsQuery.Name),
+		slog.String(logAttrDnsServer, dnsQuery.Server),
+		slog.String(logAttrRcode, dnsQuery.Rcode),
+		slog.Bool(logAttrCached, dnsQuery.Cached),
+	}
+}
+
+// Records that the query was answered from DNS cache
+func (dnsQuery *DnsQuery) recordCached() {
+	if dnsQuery == nil {
+		return
+	}
+
+	dnsQuery.mutex.Lock()
+	defer dnsQuery.mutex.Unlock()
+	dnsQuery.Cached = true
 }
 
 // dnsResolver structure. Provides possibility to send DNS requests
@@ -20,32 +126,144 @@ type gateway interface {
 type dnsResolver struct {
 	connectionTimeout int
 	dnsServer         string
-	gateway
+	queries           []*DnsQuery
+	gateway           Resolver
+	metricsCollector  MetricsCollector
+	eventLogger       *eventLogger
+	ctx               context.Context
 }
 
-// dnsResolver builder. Creates custom resolver with
-// connection timeout and DNS gateway from configuration
+// dnsResolver builder. Creates custom resolver with connection timeout and DNS
+// gateway. Uses custom Resolver from configuration when specified. Otherwise uses DNS
+// gateway dialed via DNS transport from configuration, multi server gateway when
+// more than one DNS server specified. Wraps DNS gateway with DNS cache when specified
 func newDnsResolver(configuration *Configuration) *dnsResolver {
-	connectionTimeout, dnsServer := configuration.ConnectionTimeout, configuration.Dns
+	var dnsGateway Resolver
+	dnsServers := configuration.dnsServers()
+
+	switch {
+	case configuration.Resolver != nil:
+		dnsGateway = configuration.Resolver
+	case len(dnsServers) == 0:
+		dnsGateway = newDnsGateway(configuration, emptyString)
+	case len(dnsServers) == 1:
+		dnsGateway = newDnsGateway(configuration, dnsServers[0])
+	default:
+		dnsGateway = newMultiServerGateway(configuration, dnsServers)
+	}
+
+	if configuration.DnsCache != nil {
+		dnsGateway = newCachingGateway(dnsGateway, configuration)
+	}
 
 	return &dnsResolver{
-		connectionTimeout: connectionTimeout,
-		dnsServer:         dnsServer,
-		gateway: &net.Resolver{
-			PreferGo: true,
-			Dial: func(ctx context.Context, networkProtocol, customDnsIpAddress string) (net.Conn, error) {
-				dialer := net.Dialer{Timeout: time.Duration(connectionTimeout) * time.Second}
-				if dnsServer != emptyString {
-					customDnsIpAddress = dnsServer
-				}
-				return dialer.DialContext(ctx, networkProtocol, customDnsIpAddress)
-			},
+		connectionTimeout: configuration.ConnectionTimeout,
+		dnsServer:         configuration.Dns,
+		gateway:           dnsGateway,
+		metricsCollector:  configuration.MetricsCollector,
+		eventLogger:       configuration.eventLogger(),
+		ctx:               configuration.ctx,
+	}
+}
+
+// DNS gateway builder. Creates raw DNS client gateway or net.Resolver gateway
+// depending on DNS client specified in configuration
+func newDnsGateway(configuration *Configuration, dnsServer string) Resolver {
+	if configuration.DnsClient == dnsClientRaw {
+		return newRawDnsGateway(configuration, dnsServer)
+	}
+
+	return newNetResolverGateway(configuration, dnsServer)
+}
+
+// net.Resolver gateway builder. Creates resolver which sends DNS requests to DNS server
+// via DNS transport from configuration. Uses system DNS server when DNS server is empty
+func newNetResolverGateway(configuration *Configuration, dnsServer string) *net.Resolver {
+	dial := newDnsDialFunc(configuration, dnsServer)
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, networkProtocol, dnsServerAddress string) (net.Conn, error) {
+			answeredDnsServer := dnsServer
+			if answeredDnsServer == emptyString {
+				answeredDnsServer = dnsServerAddress
+			}
+			dnsQueryFromContext(ctx).recordServer(answeredDnsServer)
+
+			connection, err := dial(ctx, networkProtocol, dnsServerAddress)
+			if err != nil {
+				return connection, err
+			}
+			return wrapDnsTtlConn(ctx, connection), nil
 		},
 	}
 }
 
 // dnsResolver methods
 
+// Returns context with new DNS query. Addes DNS query to resolver queries
+func (dnsResolver *dnsResolver) queryContext(ctx context.Context, queryType, name string) context.Context {
+	dnsQuery := &DnsQuery{Type: queryType, Name: name}
+	dnsResolver.queries = append(dnsResolver.queries, dnsQuery)
+
+	return withDnsQuery(contextOrBackground(ctx), dnsQuery)
+}
+
+// Starts DNS query within DNS query span, child of resolver context span. Returns context
+// with new DNS query and function which finishes DNS query: ends DNS query span with
+// attributes of DNS query, observes DNS query latency and logs DNS query result
+func (dnsResolver *dnsResolver) startQuery(queryType, name string) (context.Context, func(error)) {
+	startedAt := time.Now()
+	ctx, span := startSpan(
+		dnsResolver.ctx,
+		spanNameDnsQuery,
+		attribute.String(attributeDnsQueryType, queryType),
+		attribute.String(attributeDnsQueryName, name),
+	)
+	ctx = dnsResolver.queryContext(ctx, queryType, name)
+
+	return ctx, func(err error) {
+		span.SetAttributes(dnsQueryFromContext(ctx).attributes()...)
+		endSpan(span, err)
+		dnsResolver.observeQuery(queryType, startedAt, err)
+		dnsResolver.logQuery(ctx, startedAt, err)
+	}
+}
+
+// Logs DNS query result: successful and not found queries with debug level,
+// failed queries with warn level
+func (dnsResolver *dnsResolver) logQuery(ctx context.Context, startedAt time.Time, err error) {
+	if dnsResolver.eventLogger == nil {
+		return
+	}
+
+	level := slog.LevelDebug
+	if err != nil && !isNxDomainError(err) {
+		level = slog.LevelWarn
+	}
+
+	attributes := append(
+		dnsQueryFromContext(ctx).logAttributes(),
+		slog.Duration(logAttrDuration, time.Since(startedAt)),
+		errorLogAttr(err),
+	)
+	dnsResolver.eventLogger.log(ctx, level, logMessageDnsQuery, attributes...)
+}
+
+// Observes DNS query latency with metrics collector when specified
+func (dnsResolver *dnsResolver) observeQuery(queryType string, startedAt time.Time, err error) {
+	if dnsResolver.metricsCollector == nil {
+		return
+	}
+
+	dnsResolver.metricsCollector.ObserveDnsQuery(queryType, metricsStatus(err), time.Since(startedAt))
+}
+
+// Returns DNS queries sent by resolver
+func (dnsResolver *dnsResolver) dnsQueries() []*DnsQuery {
+	return dnsResolver.queries
+}
+
 // Helper method. Removes last dot from dns hostname representation, example.com. => example.com
 func (dnsResolver *dnsResolver) dnsNameToHostName(dnsName string) string {
 	return strings.TrimSuffix(dnsName, ".")
@@ -65,7 +283,9 @@ func (dnsResolver *dnsResolver) rejectIp6Addresses(ipAddresses []string) (ip4Add
 
 // Returns all A records by hostname
 func (dnsResolver *dnsResolver) aRecords(hostName string) ([]string, error) {
-	ipAddresses, err := dnsResolver.gateway.LookupHost(context.Background(), hostName)
+	ctx, finishQuery := dnsResolver.startQuery("A", hostName)
+	ipAddresses, err := dnsResolver.gateway.LookupHost(ctx, hostName)
+	finishQuery(err)
 	if err != nil {
 		return []string{}, wrapDnsError(err)
 	}
@@ -85,7 +305,9 @@ func (dnsResolver *dnsResolver) aRecord(hostName string) (string, error) {
 
 // Returns CNAME record by hostname for case when CNAME is different as hostname only
 func (dnsResolver *dnsResolver) cnameRecord(hostName string) (resolvedHostName string, err error) {
-	cName, err := dnsResolver.gateway.LookupCNAME(context.Background(), hostName)
+	ctx, finishQuery := dnsResolver.startQuery("CNAME", hostName)
+	cName, err := dnsResolver.gateway.LookupCNAME(ctx, hostName)
+	finishQuery(err)
 	if err != nil {
 		return resolvedHostName, wrapDnsError(err)
 	}
@@ -100,7 +322,9 @@ func (dnsResolver *dnsResolver) cnameRecord(hostName string) (resolvedHostName s
 
 // Returns MX records priorities and hostnames sorted by record priority
 func (dnsResolver *dnsResolver) mxRecords(hostName string) (priorities []uint16, hostNames []string, err error) {
-	mxRecords, err := dnsResolver.gateway.LookupMX(context.Background(), hostName)
+	ctx, finishQuery := dnsResolver.startQuery("MX", hostName)
+	mxRecords, err := dnsResolver.gateway.LookupMX(ctx, hostName)
+	finishQuery(err)
 	if err != nil {
 		return priorities, hostNames, wrapDnsError(err)
 	}
@@ -118,9 +342,35 @@ func (dnsResolver *dnsResolver) mxRecords(hostName string) (priorities []uint16,
 	return priorities, hostNames, nil
 }
 
+// Returns TXT records by hostname
+func (dnsResolver *dnsResolver) txtRecords(hostName string) ([]string, error) {
+	ctx, finishQuery := dnsResolver.startQuery("TXT", hostName)
+	txtRecords, err := dnsResolver.gateway.LookupTXT(ctx, hostName)
+	finishQuery(err)
+	if err != nil {
+		return txtRecords, wrapDnsError(err)
+	}
+
+	return txtRecords, nil
+}
+
+// Returns TLSA records by name and DNSSEC authenticated data flag of TLSA answer
+func (dnsResolver *dnsResolver) tlsaRecords(name string) ([]*TlsaRecord, bool, error) {
+	ctx, finishQuery := dnsResolver.startQuery("TLSA", name)
+	tlsaRecords, err := lookupTlsaRecords(ctx, dnsResolver.gateway, name)
+	finishQuery(err)
+	if err != nil {
+		return tlsaRecords, false, wrapDnsError(err)
+	}
+
+	return tlsaRecords, dnsQueryFromContext(ctx).Authenticated, nil
+}
+
 // Returns PTR records by host address
 func (dnsResolver *dnsResolver) ptrRecords(hostAddress string) (hostNames []string, err error) {
-	hostNames, err = dnsResolver.gateway.LookupAddr(context.Background(), hostAddress)
+	ctx, finishQuery := dnsResolver.startQuery("PTR", hostAddress)
+	hostNames, err = dnsResolver.gateway.LookupAddr(ctx, hostAddress)
+	finishQuery(err)
 	if err != nil {
 		return hostNames, wrapDnsError(err)
 	}
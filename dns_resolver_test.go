@@ -1,11 +1,19 @@
 package truemail
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func TestNewDnsResolver(t *testing.T) {
@@ -39,6 +47,132 @@ func TestNewDnsResolver(t *testing.T) {
 	})
 }
 
+func TestNewDnsResolverWithCustomResolver(t *testing.T) {
+	hostName, hostAddress := randomDomain(), randomIpAddress()
+	resolver := &mockdns.Resolver{Zones: map[string]mockdns.Zone{toDnsHostName(hostName): {A: []string{hostAddress}}}}
+
+	t.Run("uses custom resolver from configuration", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver, configuration.Dns, configuration.DnsCache = resolver, randomDnsServer(), nil
+		dnsResolver := newDnsResolver(configuration)
+		resolvedHostAddresses, err := dnsResolver.aRecords(hostName)
+
+		assert.Same(t, resolver, dnsResolver.gateway)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+	})
+
+	t.Run("wraps custom resolver with DNS cache", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.Resolver, configuration.DnsCache = resolver, NewDnsMemoryCache(42)
+		dnsResolver := newDnsResolver(configuration)
+
+		assert.IsType(t, new(cachingGateway), dnsResolver.gateway)
+		assert.Same(t, resolver, dnsResolver.gateway.(*cachingGateway).gateway)
+	})
+}
+
+func TestNewDnsResolverWithDnsServers(t *testing.T) {
+	// Integration test with internal DNS request
+
+	t.Run("when more than one DNS server specified uses multi server gateway", func(t *testing.T) {
+		hostName, hostAddress, unreachableDnsServer := randomDomain(), randomIpAddress(), localhostIPv4Address+":1"
+		dns, stop := startMockDnsServer(map[string]mockdns.Zone{toDnsHostName(hostName): {A: []string{hostAddress}}})
+		defer stop()
+		configuration := createConfiguration()
+		configuration.ConnectionTimeout, configuration.Dns, configuration.DnsServers = 1, unreachableDnsServer, []string{dns}
+		configuration.DnsCache = nil
+		dnsResolver := newDnsResolver(configuration)
+		resolvedHostAddresses, err := dnsResolver.aRecords(hostName)
+
+		assert.IsType(t, new(multiServerGateway), dnsResolver.gateway)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, dns, dnsResolver.dnsQueries()[0].Server)
+		assert.Equal(t, []string{unreachableDnsServer}, dnsResolver.dnsQueries()[0].FailedServers)
+	})
+
+	t.Run("when one DNS server specified records answered DNS server", func(t *testing.T) {
+		hostName, hostAddress := randomDomain(), randomIpAddress()
+		dns, stop := startMockDnsServer(map[string]mockdns.Zone{toDnsHostName(hostName): {A: []string{hostAddress}}})
+		defer stop()
+		configuration := createConfiguration()
+		configuration.DnsServers, configuration.DnsCache = []string{dns}, nil
+		dnsResolver := newDnsResolver(configuration)
+		_, err := dnsResolver.aRecords(hostName)
+
+		assert.IsType(t, new(net.Resolver), dnsResolver.gateway)
+		assert.NoError(t, err)
+		assert.Equal(t, &DnsQuery{Type: "A", Name: hostName, Server: dns}, dnsResolver.dnsQueries()[0])
+	})
+
+	t.Run("when raw DNS client specified, fails over DNS server responded with SERVFAIL", func(t *testing.T) {
+		hostName, hostAddress := randomDomain(), randomIpAddress()
+		failedDns, stopFailedDns := startRawDnsServer(createRawDnsHandler(dns.RcodeServerFailure, false))
+		defer stopFailedDns()
+		dnsServer, stop := startMockDnsServer(map[string]mockdns.Zone{toDnsHostName(hostName): {A: []string{hostAddress}}})
+		defer stop()
+		configuration := createConfiguration()
+		configuration.DnsClient, configuration.Dns, configuration.DnsServers = dnsClientRaw, failedDns, []string{dnsServer}
+		configuration.DnsCache = nil
+		dnsResolver := newDnsResolver(configuration)
+		resolvedHostAddresses, err := dnsResolver.aRecords(hostName)
+		dnsQuery := dnsResolver.dnsQueries()[0]
+
+		assert.IsType(t, new(rawDnsGateway), dnsResolver.gateway.(*multiServerGateway).servers[0].gateway)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, dnsServer, dnsQuery.Server)
+		assert.Equal(t, []string{failedDns}, dnsQuery.FailedServers)
+		assert.Equal(t, "NOERROR", dnsQuery.Rcode)
+	})
+
+	t.Run("when raw DNS client specified, wraps DNS failure", func(t *testing.T) {
+		failedDns, stopFailedDns := startRawDnsServer(createRawDnsHandler(dns.RcodeServerFailure, false))
+		defer stopFailedDns()
+		configuration := createConfiguration()
+		configuration.DnsClient, configuration.Dns, configuration.DnsCache = dnsClientRaw, failedDns, nil
+		dnsResolver := newDnsResolver(configuration)
+		_, _, err := dnsResolver.mxRecords(randomDomain())
+
+		assert.IsType(t, new(rawDnsGateway), dnsResolver.gateway)
+		assert.True(t, new(validationMx).isDnsFailureError(err))
+	})
+}
+
+func TestDnsResolverQueryContext(t *testing.T) {
+	t.Run("addes DNS query to resolver queries and to context", func(t *testing.T) {
+		hostName, dnsResolver := randomDomain(), new(dnsResolver)
+		ctx := dnsResolver.queryContext(context.Background(), "MX", hostName)
+
+		assert.Equal(t, []*DnsQuery{{Type: "MX", Name: hostName}}, dnsResolver.dnsQueries())
+		assert.Same(t, dnsResolver.dnsQueries()[0], dnsQueryFromContext(ctx))
+	})
+}
+
+func TestDnsQueryRecorders(t *testing.T) {
+	t.Run("records DNS query context", func(t *testing.T) {
+		dnsQuery, dnsServer, failedDnsServer := new(DnsQuery), randomDnsServer(), randomIpAddress()
+		dnsQuery.recordServer(dnsServer)
+		dnsQuery.recordFailedServers(failedDnsServer)
+		dnsQuery.recordCached()
+
+		assert.Equal(t, dnsServer, dnsQuery.Server)
+		assert.Equal(t, []string{failedDnsServer}, dnsQuery.FailedServers)
+		assert.True(t, dnsQuery.Cached)
+	})
+
+	t.Run("when DNS query is nil", func(t *testing.T) {
+		var dnsQuery *DnsQuery
+
+		assert.NotPanics(t, func() {
+			dnsQuery.recordServer(randomDnsServer())
+			dnsQuery.recordFailedServers(randomDnsServer())
+			dnsQuery.recordCached()
+		})
+	})
+}
+
 func TestDnsResolverDnsNameToHostName(t *testing.T) {
 	domain, dnsResolver := randomDomain(), new(dnsResolver)
 
@@ -202,3 +336,189 @@ func TestDnsResolverPtrRecords(t *testing.T) {
 		assert.True(t, isDnsNotFoundError(err))
 	})
 }
+
+func TestDnsResolverTxtRecords(t *testing.T) {
+	hostName, txtRecord := randomDomain(), "v=STSv1; id=42"
+
+	t.Run("when TXT records found", func(t *testing.T) {
+		dnsResolver := createDnsResolver(map[string]mockdns.Zone{toDnsHostName(hostName): {TXT: []string{txtRecord}}})
+		txtRecords, err := dnsResolver.txtRecords(hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{txtRecord}, txtRecords)
+		assert.Equal(t, "TXT", dnsResolver.dnsQueries()[0].Type)
+	})
+
+	t.Run("when TXT records not found", func(t *testing.T) {
+		_, err := createDnsResolverWithEpmtyRecords().txtRecords(hostName)
+
+		assert.True(t, isDnsNotFoundError(err))
+	})
+}
+
+func TestDnsResolverStartQuery(t *testing.T) {
+	domain := randomDomain()
+
+	t.Run("runs DNS query within DNS query span, child of resolver context span", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, validateSpan := startValidationSpan(context.Background(), tracerProvider)
+		dnsResolver := createDnsResolver(map[string]mockdns.Zone{toDnsHostName(domain): {TXT: []string{randomDomain()}}})
+		dnsResolver.ctx = ctx
+		_, err := dnsResolver.txtRecords(domain)
+		spans := endedSpans(spanRecorder, spanNameDnsQuery)
+
+		assert.NoError(t, err)
+		assert.Len(t, spans, 1)
+		assert.Equal(t, validateSpan.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, "TXT", spanAttribute(spans[0], attributeDnsQueryType).AsString())
+		assert.Equal(t, domain, spanAttribute(spans[0], attributeDnsQueryName).AsString())
+		assert.False(t, spanAttribute(spans[0], attributeDnsCached).AsBool())
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	})
+
+	t.Run("records DNS query error into DNS query span", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		dnsResolver := createDnsResolverWithEpmtyRecords()
+		dnsResolver.ctx, _ = startValidationSpan(context.Background(), tracerProvider)
+		_, err := dnsResolver.cnameRecord(domain)
+		spans := endedSpans(spanRecorder, spanNameDnsQuery)
+
+		assert.Error(t, err)
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "CNAME", spanAttribute(spans[0], attributeDnsQueryType).AsString())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+
+	t.Run("propagates resolver context to DNS query", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		dnsResolver := &dnsResolver{ctx: ctx}
+		queryContext, finishQuery := dnsResolver.startQuery("A", domain)
+		finishQuery(nil)
+
+		assert.ErrorIs(t, queryContext.Err(), context.Canceled)
+		assert.Equal(t, []*DnsQuery{{Type: "A", Name: domain}}, dnsResolver.dnsQueries())
+	})
+}
+
+func TestDnsResolverLogQuery(t *testing.T) {
+	domain := randomDomain()
+
+	t.Run("logs successful DNS query with debug level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		dnsResolver := createDnsResolver(map[string]mockdns.Zone{toDnsHostName(domain): {TXT: []string{randomDomain()}}})
+		dnsResolver.eventLogger = newEventLogger(logger, slog.LevelDebug)
+		_, err := dnsResolver.txtRecords(domain)
+		records := logRecords(buffer, logMessageDnsQuery)
+
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelDebug.String(), records[0][slog.LevelKey])
+		assert.Equal(t, "TXT", records[0][logAttrQueryType])
+		assert.Equal(t, domain, records[0][logAttrQueryName])
+		assert.Equal(t, false, records[0][logAttrCached])
+		assert.NotContains(t, records[0], logAttrError)
+	})
+
+	t.Run("logs failed DNS query with warn level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		dnsResolver := createDnsResolver(map[string]mockdns.Zone{toDnsHostName(domain): {Err: errors.New("dns server failure")}})
+		dnsResolver.eventLogger = newEventLogger(logger, slog.LevelDebug)
+		_, err := dnsResolver.aRecords(domain)
+		records := logRecords(buffer, logMessageDnsQuery)
+
+		assert.Error(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.Equal(t, "dns server failure", records[0][logAttrError])
+	})
+
+	t.Run("when event logger is not specified", func(t *testing.T) {
+		assert.NotPanics(t, func() { createDnsResolverWithEpmtyRecords().logQuery(context.Background(), time.Now(), nil) })
+	})
+}
+
+func TestDnsQueryAttributes(t *testing.T) {
+	t.Run("returns span attributes of DNS query", func(t *testing.T) {
+		dnsServer := randomDnsServer()
+		dnsQuery := &DnsQuery{Server: dnsServer, Rcode: "NOERROR", Cached: true}
+
+		assert.Equal(
+			t,
+			[]attribute.KeyValue{
+				attribute.String(attributeDnsServer, dnsServer),
+				attribute.String(attributeDnsRcode, "NOERROR"),
+				attribute.Bool(attributeDnsCached, true),
+			},
+			dnsQuery.attributes(),
+		)
+	})
+}
+
+func TestDnsResolverObserveQuery(t *testing.T) {
+	domain := randomDomain()
+
+	t.Run("observes successful DNS query latency with metrics collector", func(t *testing.T) {
+		metricsCollector := new(metricsCollectorMock)
+		dnsResolver := createDnsResolver(map[string]mockdns.Zone{toDnsHostName(domain): {TXT: []string{randomDomain()}}})
+		dnsResolver.metricsCollector = metricsCollector
+		metricsCollector.On("ObserveDnsQuery", "TXT", metricsStatusSuccess, mock.AnythingOfType("time.Duration")).Once()
+		_, err := dnsResolver.txtRecords(domain)
+
+		assert.NoError(t, err)
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("observes failed DNS query latency with metrics collector", func(t *testing.T) {
+		metricsCollector := new(metricsCollectorMock)
+		dnsResolver := createDnsResolverWithEpmtyRecords()
+		dnsResolver.metricsCollector = metricsCollector
+		metricsCollector.On("ObserveDnsQuery", "MX", metricsStatusFailure, mock.AnythingOfType("time.Duration")).Once()
+		_, _, err := dnsResolver.mxRecords(domain)
+
+		assert.Error(t, err)
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("when metrics collector is not specified", func(t *testing.T) {
+		assert.NotPanics(t, func() { createDnsResolverWithEpmtyRecords().observeQuery("A", time.Now(), nil) })
+	})
+}
+
+func TestDnsResolverTlsaRecords(t *testing.T) {
+	name := daneTlsaName(defaultSmtpPort, randomDomain())
+
+	t.Run("when DNSSEC authenticated TLSA records found", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(
+			createRawDnsHandler(dns.RcodeSuccess, true, createDnsRecord(toDnsHostName(name)+" 42 IN TLSA 3 1 1 abcdef")),
+		)
+		defer stop()
+		configuration := createConfiguration()
+		configuration.Dns, configuration.DnsClient = dnsServer, dnsClientRaw
+		dnsResolver := newDnsResolver(configuration)
+		tlsaRecords, authenticated, err := dnsResolver.tlsaRecords(name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*TlsaRecord{{Usage: 3, Selector: 1, MatchingType: 1, Certificate: "abcdef"}}, tlsaRecords)
+		assert.True(t, authenticated)
+		assert.Equal(t, "TLSA", dnsResolver.dnsQueries()[0].Type)
+	})
+
+	t.Run("when TLSA records not found", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(createRawDnsHandler(dns.RcodeNameError, false))
+		defer stop()
+		configuration := createConfiguration()
+		configuration.Dns, configuration.DnsClient = dnsServer, dnsClientRaw
+		_, authenticated, err := newDnsResolver(configuration).tlsaRecords(name)
+
+		assert.True(t, isDnsNotFoundError(err))
+		assert.False(t, authenticated)
+	})
+
+	t.Run("when DNS gateway does not support TLSA lookups", func(t *testing.T) {
+		_, _, err := createDnsResolverWithEpmtyRecords().tlsaRecords(name)
+
+		assert.Error(t, err)
+		assert.False(t, isDnsNotFoundError(err))
+	})
+}
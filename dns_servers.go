@@ -0,0 +1,282 @@
+package truemail
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNS servers health structure. Tracks consecutive failures per DNS server
+// and round-robin position, shared across validations which use the same configuration
+type dnsServersHealth struct {
+	failures       map[string]int
+	unhealthyUntil map[string]time.Time
+	position       int
+	now            func() time.Time
+	mutex          sync.Mutex
+}
+
+// dnsServersHealth builder
+func newDnsServersHealth() *dnsServersHealth {
+	return &dnsServersHealth{
+		failures:       make(map[string]int),
+		unhealthyUntil: make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// dnsServersHealth methods
+
+// Resets DNS server consecutive failures counter
+func (health *dnsServersHealth) recordSuccess(dnsServer string) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	delete(health.failures, dnsServer)
+	delete(health.unhealthyUntil, dnsServer)
+}
+
+// Increments DNS server consecutive failures counter. Marks DNS server as
+// unhealthy for cooldown period when failures limit is reached
+func (health *dnsServersHealth) recordFailure(dnsServer string) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.failures[dnsServer]++
+	if health.failures[dnsServer] >= dnsServerMaxFailures {
+		health.unhealthyUntil[dnsServer] = health.now().Add(dnsServerCooldown)
+	}
+}
+
+// Returns true if DNS server is not in cooldown period, otherwise returns false
+func (health *dnsServersHealth) isHealthy(dnsServer string) bool {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	return !health.now().Before(health.unhealthyUntil[dnsServer])
+}
+
+// Returns next round-robin position
+func (health *dnsServersHealth) nextPosition() int {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	position := health.position
+	health.position++
+	return position
+}
+
+// DNS server gateway structure. Includes DNS server address and its gateway
+type dnsServerGateway struct {
+	server  string
+	gateway Resolver
+}
+
+// Multi server gateway structure. Sends DNS requests to several
+// DNS servers using failover, round-robin or race strategy
+type multiServerGateway struct {
+	strategy string
+	servers  []*dnsServerGateway
+	health   *dnsServersHealth
+}
+
+// multiServerGateway builder. Creates gateway for each DNS server from configuration
+func newMultiServerGateway(configuration *Configuration, dnsServers []string) *multiServerGateway {
+	health := configuration.dnsServersHealth
+	if health == nil {
+		health = newDnsServersHealth()
+	}
+
+	multiServerGateway := &multiServerGateway{strategy: configuration.DnsStrategy, health: health}
+	for _, dnsServer := range dnsServers {
+		multiServerGateway.servers = append(
+			multiServerGateway.servers,
+			&dnsServerGateway{server: dnsServer, gateway: newDnsGateway(configuration, dnsServer)},
+		)
+	}
+
+	return multiServerGateway
+}
+
+// interface implementation
+
+func (multiServerGateway *multiServerGateway) LookupHost(ctx context.Context, hostName string) ([]string, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return dnsGateway.LookupHost(ctx, hostName)
+	})
+	ipAddresses, _ := value.([]string)
+
+	return ipAddresses, err
+}
+
+func (multiServerGateway *multiServerGateway) LookupCNAME(ctx context.Context, hostName string) (string, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return dnsGateway.LookupCNAME(ctx, hostName)
+	})
+	cName, _ := value.(string)
+
+	return cName, err
+}
+
+func (multiServerGateway *multiServerGateway) LookupMX(ctx context.Context, hostName string) ([]*net.MX, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return dnsGateway.LookupMX(ctx, hostName)
+	})
+	mxRecords, _ := value.([]*net.MX)
+
+	return mxRecords, err
+}
+
+func (multiServerGateway *multiServerGateway) LookupAddr(ctx context.Context, hostAddress string) ([]string, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return dnsGateway.LookupAddr(ctx, hostAddress)
+	})
+	hostNames, _ := value.([]string)
+
+	return hostNames, err
+}
+
+func (multiServerGateway *multiServerGateway) LookupTXT(ctx context.Context, hostName string) ([]string, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return dnsGateway.LookupTXT(ctx, hostName)
+	})
+	txtRecords, _ := value.([]string)
+
+	return txtRecords, err
+}
+
+// tlsaResolver interface implementation
+
+func (multiServerGateway *multiServerGateway) lookupTLSA(ctx context.Context, name string) ([]*TlsaRecord, error) {
+	value, err := multiServerGateway.lookup(ctx, func(ctx context.Context, dnsGateway Resolver) (any, error) {
+		return lookupTlsaRecords(ctx, dnsGateway, name)
+	})
+	tlsaRecords, _ := value.([]*TlsaRecord)
+
+	return tlsaRecords, err
+}
+
+// multiServerGateway methods
+
+// DNS server answer structure. Includes DNS query recorded by DNS server gateway
+type dnsServerAnswer struct {
+	server string
+	query  *DnsQuery
+	value  any
+	err    error
+}
+
+// Returns true if DNS server failed to answer. NXDOMAIN is a valid DNS answer
+func (answer *dnsServerAnswer) isFailed() bool {
+	return answer.err != nil && !isNxDomainError(answer.err)
+}
+
+// Runs lookup query via DNS servers by strategy. Records DNS server which answered,
+// its DNS response and DNS servers which failed into DNS query from context
+func (multiServerGateway *multiServerGateway) lookup(
+	ctx context.Context,
+	query func(context.Context, Resolver) (any, error),
+) (any, error) {
+	dnsQuery := dnsQueryFromContext(ctx)
+
+	var answer *dnsServerAnswer
+	var failedServers []string
+	if multiServerGateway.strategy == dnsStrategyRace {
+		answer, failedServers = multiServerGateway.race(ctx, query)
+	} else {
+		answer, failedServers = multiServerGateway.failover(ctx, query)
+	}
+
+	dnsQuery.recordFailedServers(failedServers...)
+	dnsQuery.recordResponse(answer.query.Rcode, answer.query.Ttl, answer.query.Authenticated)
+	if !answer.isFailed() {
+		dnsQuery.recordServer(answer.server)
+	}
+
+	return answer.value, answer.err
+}
+
+// Sends DNS request to DNS servers one by one until first not failed answer
+func (multiServerGateway *multiServerGateway) failover(
+	ctx context.Context,
+	query func(context.Context, Resolver) (any, error),
+) (answer *dnsServerAnswer, failedServers []string) {
+	for _, server := range multiServerGateway.orderedServers() {
+		answer = multiServerGateway.ask(ctx, server, query)
+		if !answer.isFailed() {
+			return answer, failedServers
+		}
+		failedServers = append(failedServers, answer.server)
+	}
+
+	return answer, failedServers
+}
+
+// Sends DNS request to all DNS servers concurrently, returns first not failed answer
+// and cancels other requests
+func (multiServerGateway *multiServerGateway) race(
+	ctx context.Context,
+	query func(context.Context, Resolver) (any, error),
+) (answer *dnsServerAnswer, failedServers []string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	servers := multiServerGateway.orderedServers()
+	answers := make(chan *dnsServerAnswer, len(servers))
+	for _, server := range servers {
+		go func() { answers <- multiServerGateway.ask(ctx, server, query) }()
+	}
+
+	for range servers {
+		answer = <-answers
+		if !answer.isFailed() {
+			return answer, failedServers
+		}
+		failedServers = append(failedServers, answer.server)
+	}
+
+	return answer, failedServers
+}
+
+// Sends DNS request to DNS server and records DNS server health. Uses own DNS query
+// for each DNS server, so DNS server gateways can't overwrite answered DNS server
+func (multiServerGateway *multiServerGateway) ask(
+	ctx context.Context,
+	server *dnsServerGateway,
+	query func(context.Context, Resolver) (any, error),
+) *dnsServerAnswer {
+	dnsQuery := new(DnsQuery)
+	value, err := query(withDnsQuery(ctx, dnsQuery), server.gateway)
+	answer := &dnsServerAnswer{server: server.server, query: dnsQuery, value: value, err: err}
+
+	switch {
+	case !answer.isFailed():
+		multiServerGateway.health.recordSuccess(answer.server)
+	case ctx.Err() == nil:
+		multiServerGateway.health.recordFailure(answer.server)
+	}
+
+	return answer
+}
+
+// Returns DNS servers ordered by strategy, healthy DNS servers first.
+// Unhealthy DNS servers are used as last resort
+func (multiServerGateway *multiServerGateway) orderedServers() []*dnsServerGateway {
+	servers := multiServerGateway.servers
+	if multiServerGateway.strategy == dnsStrategyRoundRobin {
+		offset := multiServerGateway.health.nextPosition() % len(servers)
+		servers = append(append([]*dnsServerGateway{}, servers[offset:]...), servers[:offset]...)
+	}
+
+	var healthyServers, unhealthyServers []*dnsServerGateway
+	for _, server := range servers {
+		if multiServerGateway.health.isHealthy(server.server) {
+			healthyServers = append(healthyServers, server)
+			continue
+		}
+		unhealthyServers = append(unhealthyServers, server)
+	}
+
+	return append(healthyServers, unhealthyServers...)
+}
@@ -0,0 +1,220 @@
+package truemail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDnsServersHealth(t *testing.T) {
+	dnsServer := randomDnsServer()
+
+	t.Run("marks DNS server as unhealthy when failures limit is reached", func(t *testing.T) {
+		health := newDnsServersHealth()
+		for failure := 1; failure < dnsServerMaxFailures; failure++ {
+			health.recordFailure(dnsServer)
+		}
+		isHealthyBeforeFailuresLimit := health.isHealthy(dnsServer)
+		health.recordFailure(dnsServer)
+
+		assert.True(t, isHealthyBeforeFailuresLimit)
+		assert.False(t, health.isHealthy(dnsServer))
+	})
+
+	t.Run("marks DNS server as healthy after cooldown period", func(t *testing.T) {
+		currentTime, health := time.Now(), newDnsServersHealth()
+		health.now = func() time.Time { return currentTime }
+		for failure := 0; failure < dnsServerMaxFailures; failure++ {
+			health.recordFailure(dnsServer)
+		}
+		health.now = func() time.Time { return currentTime.Add(dnsServerCooldown) }
+
+		assert.True(t, health.isHealthy(dnsServer))
+	})
+
+	t.Run("resets DNS server failures after success", func(t *testing.T) {
+		health := newDnsServersHealth()
+		for failure := 0; failure < dnsServerMaxFailures; failure++ {
+			health.recordFailure(dnsServer)
+		}
+		health.recordSuccess(dnsServer)
+
+		assert.True(t, health.isHealthy(dnsServer))
+		assert.Empty(t, health.failures)
+	})
+
+	t.Run("returns next round-robin position", func(t *testing.T) {
+		health := newDnsServersHealth()
+
+		assert.Equal(t, 0, health.nextPosition())
+		assert.Equal(t, 1, health.nextPosition())
+	})
+}
+
+func TestNewMultiServerGateway(t *testing.T) {
+	dnsServers := []string{randomDnsServer(), randomIpAddress() + ":53"}
+
+	t.Run("creates gateway for each DNS server with shared DNS servers health", func(t *testing.T) {
+		configuration, _ := NewConfiguration(ConfigurationAttr{VerifierEmail: randomEmail(), DnsStrategy: dnsStrategyRace})
+		multiServerGateway := newMultiServerGateway(configuration, dnsServers)
+
+		assert.Equal(t, dnsStrategyRace, multiServerGateway.strategy)
+		assert.Same(t, configuration.dnsServersHealth, multiServerGateway.health)
+		assert.Len(t, multiServerGateway.servers, 2)
+		assert.Equal(t, dnsServers[0], multiServerGateway.servers[0].server)
+		assert.Equal(t, dnsServers[1], multiServerGateway.servers[1].server)
+	})
+
+	t.Run("when configuration without DNS servers health", func(t *testing.T) {
+		multiServerGateway := newMultiServerGateway(new(Configuration), dnsServers)
+
+		assert.NotNil(t, multiServerGateway.health)
+	})
+}
+
+func createMultiServerGateway(strategy string, dnsGateways ...Resolver) (*multiServerGateway, []string) {
+	var dnsServers []string
+	multiServerGateway := &multiServerGateway{strategy: strategy, health: newDnsServersHealth()}
+	for index, dnsGateway := range dnsGateways {
+		dnsServer := serverWithPortNumber(localhostIPv4Address, index+1)
+		dnsServers = append(dnsServers, dnsServer)
+		multiServerGateway.servers = append(multiServerGateway.servers, &dnsServerGateway{server: dnsServer, gateway: dnsGateway})
+	}
+
+	return multiServerGateway, dnsServers
+}
+
+func TestMultiServerGatewayLookup(t *testing.T) {
+	hostName, hostAddress := randomDomain(), randomIpAddress()
+	temporaryError, notFoundError := &net.DNSError{IsTemporary: true}, &net.DNSError{IsNotFound: true}
+
+	t.Run("failover strategy, when first DNS server failed", func(t *testing.T) {
+		firstGateway, secondGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		firstGateway.On("LookupHost", hostName).Once().Return([]string(nil), temporaryError)
+		secondGateway.On("LookupHost", hostName).Once().Return([]string{hostAddress}, nil)
+		dnsQuery := new(DnsQuery)
+		resolvedHostAddresses, err := multiServerGateway.LookupHost(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		firstGateway.AssertExpectations(t)
+		secondGateway.AssertExpectations(t)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, dnsServers[1], dnsQuery.Server)
+		assert.Equal(t, []string{dnsServers[0]}, dnsQuery.FailedServers)
+		assert.Equal(t, 1, multiServerGateway.health.failures[dnsServers[0]])
+	})
+
+	t.Run("failover strategy, when first DNS server answered NXDOMAIN", func(t *testing.T) {
+		firstGateway, secondGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		firstGateway.On("LookupCNAME", hostName).Once().Return(emptyString, notFoundError)
+		dnsQuery := new(DnsQuery)
+		_, err := multiServerGateway.LookupCNAME(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		firstGateway.AssertExpectations(t)
+		secondGateway.AssertNotCalled(t, "LookupCNAME", hostName)
+		assert.Equal(t, notFoundError, err)
+		assert.Equal(t, dnsServers[0], dnsQuery.Server)
+		assert.Empty(t, dnsQuery.FailedServers)
+	})
+
+	t.Run("failover strategy, when all DNS servers failed", func(t *testing.T) {
+		firstGateway, secondGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		firstGateway.On("LookupAddr", hostAddress).Once().Return([]string(nil), temporaryError)
+		secondGateway.On("LookupAddr", hostAddress).Once().Return([]string(nil), temporaryError)
+		dnsQuery := new(DnsQuery)
+		hostNames, err := multiServerGateway.LookupAddr(withDnsQuery(context.Background(), dnsQuery), hostAddress)
+
+		assert.Empty(t, hostNames)
+		assert.Equal(t, temporaryError, err)
+		assert.Empty(t, dnsQuery.Server)
+		assert.Equal(t, dnsServers, dnsQuery.FailedServers)
+	})
+
+	t.Run("failover strategy, unhealthy DNS server is used as last resort", func(t *testing.T) {
+		firstGateway, secondGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		for failure := 0; failure < dnsServerMaxFailures; failure++ {
+			multiServerGateway.health.recordFailure(dnsServers[0])
+		}
+		secondGateway.On("LookupHost", hostName).Once().Return([]string{hostAddress}, nil)
+		_, err := multiServerGateway.LookupHost(context.Background(), hostName)
+
+		firstGateway.AssertNotCalled(t, "LookupHost", hostName)
+		assert.NoError(t, err)
+	})
+
+	t.Run("failover strategy, TXT lookup", func(t *testing.T) {
+		firstGateway, secondGateway, txtRecords := new(gatewayMock), new(gatewayMock), []string{"v=STSv1; id=42"}
+		multiServerGateway, _ := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		firstGateway.On("LookupTXT", hostName).Once().Return(txtRecords, nil)
+		resolvedTxtRecords, err := multiServerGateway.LookupTXT(context.Background(), hostName)
+
+		firstGateway.AssertExpectations(t)
+		assert.NoError(t, err)
+		assert.Equal(t, txtRecords, resolvedTxtRecords)
+	})
+
+	t.Run("failover strategy, TLSA lookup, when first DNS server does not support TLSA lookups", func(t *testing.T) {
+		tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256}}
+		firstGateway, secondGateway := new(gatewayMock), &tlsaResolverStandIn{tlsaRecords: tlsaRecords}
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyFailover, firstGateway, secondGateway)
+		dnsQuery := new(DnsQuery)
+		resolvedTlsaRecords, err := multiServerGateway.lookupTLSA(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tlsaRecords, resolvedTlsaRecords)
+		assert.Equal(t, []string{hostName}, secondGateway.names)
+		assert.Equal(t, dnsServers[1], dnsQuery.Server)
+		assert.Equal(t, []string{dnsServers[0]}, dnsQuery.FailedServers)
+	})
+
+	t.Run("round-robin strategy, rotates first DNS server", func(t *testing.T) {
+		firstGateway, secondGateway, mxRecords := new(gatewayMock), new(gatewayMock), []*net.MX{{Host: randomDnsHostName()}}
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyRoundRobin, firstGateway, secondGateway)
+		firstGateway.On("LookupMX", hostName).Once().Return(mxRecords, nil)
+		secondGateway.On("LookupMX", hostName).Once().Return(mxRecords, nil)
+		firstDnsQuery, secondDnsQuery := new(DnsQuery), new(DnsQuery)
+		_, _ = multiServerGateway.LookupMX(withDnsQuery(context.Background(), firstDnsQuery), hostName)
+		resolvedMxRecords, err := multiServerGateway.LookupMX(withDnsQuery(context.Background(), secondDnsQuery), hostName)
+
+		firstGateway.AssertExpectations(t)
+		secondGateway.AssertExpectations(t)
+		assert.NoError(t, err)
+		assert.Equal(t, mxRecords, resolvedMxRecords)
+		assert.Equal(t, dnsServers[0], firstDnsQuery.Server)
+		assert.Equal(t, dnsServers[1], secondDnsQuery.Server)
+	})
+
+	t.Run("race strategy, returns first answer", func(t *testing.T) {
+		slowGateway, fastGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyRace, slowGateway, fastGateway)
+		slowGateway.On("LookupHost", hostName).After(500*time.Millisecond).Return([]string{randomIpAddress()}, nil)
+		fastGateway.On("LookupHost", hostName).Once().Return([]string{hostAddress}, nil)
+		dnsQuery := new(DnsQuery)
+		resolvedHostAddresses, err := multiServerGateway.LookupHost(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, dnsServers[1], dnsQuery.Server)
+	})
+
+	t.Run("race strategy, skips failed answers", func(t *testing.T) {
+		failedGateway, slowGateway := new(gatewayMock), new(gatewayMock)
+		multiServerGateway, dnsServers := createMultiServerGateway(dnsStrategyRace, failedGateway, slowGateway)
+		failedGateway.On("LookupHost", hostName).Once().Return([]string(nil), temporaryError)
+		slowGateway.On("LookupHost", hostName).After(100*time.Millisecond).Return([]string{hostAddress}, nil)
+		dnsQuery := new(DnsQuery)
+		resolvedHostAddresses, err := multiServerGateway.LookupHost(withDnsQuery(context.Background(), dnsQuery), hostName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+		assert.Equal(t, dnsServers[1], dnsQuery.Server)
+		assert.Equal(t, []string{dnsServers[0]}, dnsQuery.FailedServers)
+	})
+}
@@ -0,0 +1,185 @@
+package truemail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DNS dial function, uses as net.Resolver.Dial
+type dnsDialFunc func(ctx context.Context, networkProtocol, dnsServerAddress string) (net.Conn, error)
+
+// DNS dial function builder. Returns dial function to DNS server for DNS transport
+// specified in configuration: plain UDP/TCP, DNS-over-TLS or DNS-over-HTTPS
+func newDnsDialFunc(configuration *Configuration, dnsServer string) dnsDialFunc {
+	connectionTimeout := time.Duration(configuration.ConnectionTimeout) * time.Second
+	tlsConfig := configuration.dnsTlsConfig(dnsServer)
+
+	switch configuration.DnsTransport {
+	case dnsTransportTls:
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialDnsOverTls(ctx, dnsServer, tlsConfig, connectionTimeout)
+		}
+	case dnsTransportHttps:
+		httpClient := newDnsOverHttpsClient(tlsConfig, connectionTimeout)
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return newDnsOverHttpsConn(ctx, dnsServer, httpClient), nil
+		}
+	}
+
+	return func(ctx context.Context, networkProtocol, customDnsIpAddress string) (net.Conn, error) {
+		dialer := net.Dialer{Timeout: connectionTimeout}
+		if dnsServer != emptyString {
+			customDnsIpAddress = dnsServer
+		}
+		return dialer.DialContext(ctx, networkProtocol, customDnsIpAddress)
+	}
+}
+
+// Dials DNS-over-TLS server (RFC 7858). Returns TLS connection after successful handshake.
+// Resolver uses TCP messages framing for this connection, because it is not a net.PacketConn
+func dialDnsOverTls(ctx context.Context, dnsServer string, tlsConfig *tls.Config, connectionTimeout time.Duration) (net.Conn, error) {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: connectionTimeout}, Config: tlsConfig}
+	return dialer.DialContext(ctx, tcpTransportLayer, dnsServer)
+}
+
+// DNS-over-HTTPS connection structure (RFC 8484). Emulates DNS stream connection: accepts
+// length-prefixed DNS query, sends it via HTTPS POST request and buffers length-prefixed
+// DNS response for reading
+type dnsOverHttpsConn struct {
+	ctx                 context.Context
+	url                 string
+	httpClient          *http.Client
+	request, response   bytes.Buffer
+	deadline            time.Time
+	localAddr, peerAddr net.Addr
+}
+
+// DNS-over-HTTPS client builder. Creates HTTP client configured with TLS config
+// and connection timeout, shared by DNS-over-HTTPS connections to the same server
+func newDnsOverHttpsClient(tlsConfig *tls.Config, connectionTimeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   connectionTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true},
+	}
+}
+
+// dnsOverHttpsConn builder. Creates DNS-over-HTTPS connection with HTTP client
+func newDnsOverHttpsConn(ctx context.Context, url string, httpClient *http.Client) *dnsOverHttpsConn {
+	return &dnsOverHttpsConn{
+		ctx:        ctx,
+		url:        url,
+		httpClient: httpClient,
+		localAddr:  dnsOverHttpsAddr(emptyString),
+		peerAddr:   dnsOverHttpsAddr(url),
+	}
+}
+
+// net.Conn interface implementation
+
+// Buffers DNS query bytes. Sends DNS query when length-prefixed message is completed
+func (connection *dnsOverHttpsConn) Write(bytes []byte) (int, error) {
+	connection.request.Write(bytes)
+
+	message := connection.request.Bytes()
+	if len(message) < 2 || len(message) < int(binary.BigEndian.Uint16(message))+2 {
+		return len(bytes), nil
+	}
+
+	query := append([]byte{}, message[2:int(binary.BigEndian.Uint16(message))+2]...)
+	connection.request.Reset()
+	if err := connection.exchange(query); err != nil {
+		return 0, err
+	}
+
+	return len(bytes), nil
+}
+
+// Reads buffered length-prefixed DNS response
+func (connection *dnsOverHttpsConn) Read(bytes []byte) (int, error) {
+	return connection.response.Read(bytes)
+}
+
+func (connection *dnsOverHttpsConn) Close() error {
+	return nil
+}
+
+func (connection *dnsOverHttpsConn) LocalAddr() net.Addr {
+	return connection.localAddr
+}
+
+func (connection *dnsOverHttpsConn) RemoteAddr() net.Addr {
+	return connection.peerAddr
+}
+
+func (connection *dnsOverHttpsConn) SetDeadline(deadline time.Time) error {
+	connection.deadline = deadline
+	return nil
+}
+
+func (connection *dnsOverHttpsConn) SetReadDeadline(deadline time.Time) error {
+	return nil
+}
+
+func (connection *dnsOverHttpsConn) SetWriteDeadline(deadline time.Time) error {
+	return nil
+}
+
+// dnsOverHttpsConn methods
+
+// Sends DNS query via HTTPS POST request, buffers length-prefixed DNS response
+func (connection *dnsOverHttpsConn) exchange(query []byte) error {
+	ctx := connection.ctx
+	if !connection.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, connection.deadline)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, connection.url, bytes.NewReader(query))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", dnsMessageMediaType)
+	request.Header.Set("Accept", dnsMessageMediaType)
+
+	response, err := connection.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("dns over https server responded with %s", response.Status)
+	}
+
+	message, err := io.ReadAll(io.LimitReader(response.Body, dnsMessageMaxSize+1))
+	if err != nil {
+		return err
+	}
+	if len(message) > dnsMessageMaxSize {
+		return fmt.Errorf("dns over https response exceeds %d bytes", dnsMessageMaxSize)
+	}
+
+	connection.response.Write(binary.BigEndian.AppendUint16(nil, uint16(len(message))))
+	connection.response.Write(message)
+
+	return nil
+}
+
+// DNS-over-HTTPS address, implements net.Addr interface
+type dnsOverHttpsAddr string
+
+func (address dnsOverHttpsAddr) Network() string {
+	return dnsTransportHttps
+}
+
+func (address dnsOverHttpsAddr) String() string {
+	return string(address)
+}
@@ -0,0 +1,128 @@
+package truemail
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDnsDialFunc(t *testing.T) {
+	// Integration tests with internal DNS request via in-process DNS-over-TLS/HTTPS stand-ins
+
+	domain, mxHostName, hostAddress := randomDomain(), randomDnsHostName(), randomIpAddress()
+	dnsRecords := map[string]mockdns.Zone{
+		toDnsHostName(domain): {MX: []net.MX{{Host: mxHostName, Pref: 10}}},
+		mxHostName:            {A: []string{hostAddress}},
+	}
+	dnsServer, stopDnsServer := startMockDnsServer(dnsRecords)
+	defer stopDnsServer()
+
+	t.Run("when DNS-over-TLS transport", func(t *testing.T) {
+		dnsOverTlsServer, rootCAs, stop := startDnsOverTlsStandIn(dnsServer)
+		defer stop()
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportTls, dnsOverTlsServer
+		configuration.DnsTlsServerName, configuration.DnsTlsConfig = "example.com", &tls.Config{RootCAs: rootCAs}
+		dnsResolver := newDnsResolver(configuration)
+		priorities, hostNames, err := dnsResolver.mxRecords(domain)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []uint16{10}, priorities)
+		assert.Equal(t, []string{dnsResolver.dnsNameToHostName(mxHostName)}, hostNames)
+	})
+
+	t.Run("when DNS-over-TLS transport, TLS server name mismatch", func(t *testing.T) {
+		dnsOverTlsServer, rootCAs, stop := startDnsOverTlsStandIn(dnsServer)
+		defer stop()
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportTls, dnsOverTlsServer
+		configuration.DnsTlsServerName, configuration.DnsTlsConfig = "other.com", &tls.Config{RootCAs: rootCAs}
+		_, _, err := newDnsResolver(configuration).mxRecords(domain)
+
+		assert.Error(t, err)
+		assert.False(t, isDnsNotFoundError(err))
+	})
+
+	t.Run("when DNS-over-HTTPS transport", func(t *testing.T) {
+		dnsOverHttpsServer := startDnsOverHttpsStandIn(dnsServer)
+		defer dnsOverHttpsServer.Close()
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportHttps, dnsOverHttpsServer.URL+"/dns-query"
+		configuration.DnsTlsConfig = dnsOverHttpsServer.Client().Transport.(*http.Transport).TLSClientConfig
+		resolvedHostAddresses, err := newDnsResolver(configuration).aRecords(strings.TrimSuffix(mxHostName, "."))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{hostAddress}, resolvedHostAddresses)
+	})
+
+	t.Run("when DNS-over-HTTPS transport, not found DNS record", func(t *testing.T) {
+		dnsOverHttpsServer := startDnsOverHttpsStandIn(dnsServer)
+		defer dnsOverHttpsServer.Close()
+		configuration := createConfiguration()
+		configuration.DnsTransport, configuration.Dns = dnsTransportHttps, dnsOverHttpsServer.URL+"/dns-query"
+		configuration.DnsTlsConfig = dnsOverHttpsServer.Client().Transport.(*http.Transport).TLSClientConfig
+		_, err := newDnsResolver(configuration).aRecords(randomDomain())
+
+		assert.True(t, isDnsNotFoundError(err))
+	})
+}
+
+func TestDnsOverHttpsConnWrite(t *testing.T) {
+	query := createDnsMessage(randomDomain())
+	streamQuery := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	streamQuery = append(streamQuery, query...)
+
+	t.Run("sends assembled DNS query and buffers length-prefixed DNS response", func(t *testing.T) {
+		response := createDnsMessage(randomDomain(), 42)
+		server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			body, _ := io.ReadAll(request.Body)
+			assert.Equal(t, query, body)
+			assert.Equal(t, dnsMessageMediaType, request.Header.Get("Accept"))
+			_, _ = writer.Write(response)
+		}))
+		defer server.Close()
+		connection := newDnsOverHttpsConn(context.Background(), server.URL, server.Client())
+		firstChunkLength, firstChunkErr := connection.Write(streamQuery[:1])
+		isSentAfterFirstChunk := connection.response.Len() > 0
+		secondChunkLength, secondChunkErr := connection.Write(streamQuery[1:])
+		receivedResponse, _ := io.ReadAll(connection)
+
+		assert.Equal(t, 1, firstChunkLength)
+		assert.NoError(t, firstChunkErr)
+		assert.False(t, isSentAfterFirstChunk)
+		assert.Equal(t, len(streamQuery)-1, secondChunkLength)
+		assert.NoError(t, secondChunkErr)
+		assert.Equal(t, uint16(len(response)), binary.BigEndian.Uint16(receivedResponse))
+		assert.Equal(t, response, receivedResponse[2:])
+	})
+
+	t.Run("when DNS-over-HTTPS server responded with non 200 status", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		connection := newDnsOverHttpsConn(context.Background(), server.URL, server.Client())
+		_, err := connection.Write(streamQuery)
+
+		assert.EqualError(t, err, "dns over https server responded with 400 Bad Request")
+	})
+}
+
+func TestDnsOverHttpsConnAddresses(t *testing.T) {
+	url := "https://dns.example.com/dns-query"
+	connection := newDnsOverHttpsConn(context.Background(), url, newDnsOverHttpsClient(nil, time.Second))
+
+	assert.Equal(t, dnsTransportHttps, connection.RemoteAddr().Network())
+	assert.Equal(t, url, connection.RemoteAddr().String())
+	assert.Equal(t, emptyString, connection.LocalAddr().String())
+}
@@ -1,11 +1,18 @@
 package truemail
 
-import "net"
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"regexp"
+)
 
 // Error wrapper
 type validationError struct {
-	isDnsNotFound, isNullMxFound bool
-	err                          error
+	isDnsNotFound, isDnsFailure, isNullMxFound bool
+	err                                        error
 }
 
 // error interface implementation
@@ -19,23 +26,143 @@ func wrapNullMxError(err error) *validationError {
 }
 
 // Wrappes DNSError in validationError with isDnsNotFound,
-// that depends on DNSError context
+// that depends on DNSError context. Wrappes DnsResponseError
+// in validationError with isDnsFailure
 func wrapDnsError(err error) *validationError {
 	e, ok := err.(*net.DNSError)
 	if ok && e.IsNotFound {
 		return &validationError{isDnsNotFound: true, err: err}
 	}
 
+	var dnsResponseError *DnsResponseError
+	if errors.As(err, &dnsResponseError) {
+		return &validationError{isDnsFailure: true, err: err}
+	}
+
 	return &validationError{err: err}
 }
 
+// DNS response error. Returned by raw DNS client when DNS server responded
+// with failure response code (SERVFAIL, REFUSED, etc.) or failed to respond
+type DnsResponseError struct {
+	Name, Server, Rcode string
+	Err                 error
+}
+
+// error interface implementation
+func (dnsResponseError *DnsResponseError) Error() string {
+	if dnsResponseError.Err != nil {
+		return fmt.Sprintf("lookup %s on %s: %v", dnsResponseError.Name, dnsResponseError.Server, dnsResponseError.Err)
+	}
+
+	return fmt.Sprintf(
+		"lookup %s on %s: dns server responded with %s",
+		dnsResponseError.Name,
+		dnsResponseError.Server,
+		dnsResponseError.Rcode,
+	)
+}
+
+// Unwrap returns DNS transport error
+func (dnsResponseError *DnsResponseError) Unwrap() error {
+	return dnsResponseError.Err
+}
+
+// Timeout returns true when DNS server failed to respond in time
+func (dnsResponseError *DnsResponseError) Timeout() bool {
+	var netError net.Error
+	return errors.As(dnsResponseError.Err, &netError) && netError.Timeout()
+}
+
 // SMTP client custom error wrapper
 type SmtpClientError struct {
-	isConnection, isResponseTimeout, isSmtpServiceReady, isHello, isMailFrom, isRecptTo bool
-	err                                                                                 error
+	isConnection, isResponseTimeout, isSmtpServiceReady, isHello, isStartTls, isCertificate, isDane bool
+	isMailFrom, isRecptTo, isData, isProxy, isRateLimited, isGreylisted, isCancelled                bool
+	err                                                                                             error
 }
 
 // error interface implementation
 func (smtpClientError *SmtpClientError) Error() string {
 	return smtpClientError.err.Error()
 }
+
+// Returns parsed SMTP reply of target server which caused SMTP client error: reply code,
+// enhanced status code, reply text and classification. Returns nil when error was not
+// caused by SMTP reply (connection, TLS or proxy errors)
+func (smtpClientError *SmtpClientError) Reply() *SmtpReply {
+	var replyError *textproto.Error
+	if !errors.As(smtpClientError.err, &replyError) {
+		return nil
+	}
+
+	return newSmtpReply(replyError.Code, replyError.Msg)
+}
+
+// Returns true if error is SMTP reply of target server with mailbox unknown classification
+// or error matches SMTP error body pattern, otherwise returns false
+func (smtpClientError *SmtpClientError) isMailboxUnknown(smtpErrorBodyPattern *regexp.Regexp) bool {
+	if smtpReply := smtpClientError.Reply(); smtpReply != nil && smtpReply.Classification == smtpReplyMailboxUnknown {
+		return true
+	}
+
+	return smtpErrorBodyPattern.MatchString(smtpClientError.Error())
+}
+
+// Returns SMTP client error with isRateLimited: true. Used when SMTP
+// connection with target server was not established because of rate limit
+func newSmtpRateLimitError() *SmtpClientError {
+	return &SmtpClientError{isRateLimited: true, err: errors.New(smtpRateLimitedErrorContext)}
+}
+
+// Returns SMTP client error with isCancelled: true. Used when SMTP session with target
+// server was cancelled because definitive answer was received from other mail server
+func newSmtpCancelledError() *SmtpClientError {
+	return &SmtpClientError{isCancelled: true, err: errors.New(smtpCancelledErrorContext)}
+}
+
+// SMTP proxy error. Returned when connection with target server via SMTP proxy
+// failed: proxy is unreachable, rejected authentication or target server connection
+type smtpProxyError struct {
+	proxy string
+	err   error
+}
+
+// error interface implementation
+func (smtpProxyError *smtpProxyError) Error() string {
+	return fmt.Sprintf("proxy %s: %v", smtpProxyError.proxy, smtpProxyError.err)
+}
+
+// Unwrap returns proxy dial error
+func (smtpProxyError *smtpProxyError) Unwrap() error {
+	return smtpProxyError.err
+}
+
+// Returns true if error is a SMTP proxy error
+func isSmtpProxyError(err error) bool {
+	var proxyError *smtpProxyError
+	return errors.As(err, &proxyError)
+}
+
+// DANE verification error. Returned by TLS handshake when certificate
+// chain presented by target server does not match DANE TLSA records
+type daneVerificationError struct {
+	hostName string
+}
+
+// error interface implementation
+func (daneVerificationError *daneVerificationError) Error() string {
+	return fmt.Sprintf("%s certificate does not match dane tlsa records", daneVerificationError.hostName)
+}
+
+// Returns true if error is a DANE verification error
+func isDaneVerificationError(err error) bool {
+	var daneError *daneVerificationError
+	return errors.As(err, &daneError)
+}
+
+// Returns true if error is a target server certificate verification error:
+// PKIX certificate verification error or DANE verification error
+func isCertificateVerificationError(err error) bool {
+	var certificateError *tls.CertificateVerificationError
+	return errors.As(err, &certificateError) || isDaneVerificationError(err)
+}
@@ -1,8 +1,12 @@
 package truemail
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"net/textproto"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,8 +45,39 @@ func TestWrapDnsError(t *testing.T) {
 		err := wrapDnsError(&net.DNSError{Name: hostname, Server: server, Err: errMessage, IsTimeout: true})
 
 		assert.False(t, err.isDnsNotFound)
+		assert.False(t, err.isDnsFailure)
 		assert.Equal(t, dnsErrorMessage(hostname), err.Error())
 	})
+
+	t.Run("when DnsResponseError error", func(t *testing.T) {
+		dnsResponseError := &DnsResponseError{Name: hostname, Server: server, Rcode: "SERVFAIL"}
+		err := wrapDnsError(dnsResponseError)
+
+		assert.False(t, err.isDnsNotFound)
+		assert.True(t, err.isDnsFailure)
+		assert.Equal(t, dnsResponseError.Error(), err.Error())
+	})
+}
+
+func TestDnsResponseErrorError(t *testing.T) {
+	hostname, server := randomDomain(), localhostIPv4Address+":53"
+
+	t.Run("when failure response code", func(t *testing.T) {
+		err := &DnsResponseError{Name: hostname, Server: server, Rcode: "REFUSED"}
+
+		assert.Equal(t, "lookup "+hostname+" on "+server+": dns server responded with REFUSED", err.Error())
+		assert.Nil(t, err.Unwrap())
+		assert.False(t, err.Timeout())
+	})
+
+	t.Run("when DNS transport error", func(t *testing.T) {
+		transportError := &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}
+		err := &DnsResponseError{Name: hostname, Server: server, Err: transportError}
+
+		assert.Equal(t, "lookup "+hostname+" on "+server+": "+transportError.Error(), err.Error())
+		assert.Equal(t, transportError, err.Unwrap())
+		assert.True(t, err.Timeout())
+	})
 }
 
 func TestSmtpClientErrorError(t *testing.T) {
@@ -53,3 +88,133 @@ func TestSmtpClientErrorError(t *testing.T) {
 		assert.Equal(t, errorMessage, customError.Error())
 	})
 }
+
+func TestSmtpClientErrorReply(t *testing.T) {
+	t.Run("when error was caused by SMTP reply", func(t *testing.T) {
+		customError := &SmtpClientError{err: fmt.Errorf("rcpt: %w", &textproto.Error{Code: 451, Msg: "4.7.1 try again later"})}
+
+		assert.Equal(
+			t,
+			&SmtpReply{Code: 451, EnhancedCode: "4.7.1", Text: "try again later", Classification: smtpReplyTemporaryFailure},
+			customError.Reply(),
+		)
+	})
+
+	t.Run("when error was not caused by SMTP reply", func(t *testing.T) {
+		assert.Nil(t, (&SmtpClientError{err: errors.New("error")}).Reply())
+	})
+}
+
+func TestSmtpClientErrorIsMailboxUnknown(t *testing.T) {
+	smtpErrorBodyPattern, _ := newRegex(regexSMTPErrorBodyPattern)
+
+	t.Run("when SMTP reply is classified as mailbox unknown", func(t *testing.T) {
+		customError := &SmtpClientError{err: &textproto.Error{Code: 550, Msg: "5.1.1 The email account that you tried to reach does not exist"}}
+
+		assert.True(t, customError.isMailboxUnknown(smtpErrorBodyPattern))
+	})
+
+	t.Run("when SMTP reply is classified as other than mailbox unknown", func(t *testing.T) {
+		customError := &SmtpClientError{err: &textproto.Error{Code: 550, Msg: "5.7.1 rejected by policy"}}
+
+		assert.False(t, customError.isMailboxUnknown(smtpErrorBodyPattern))
+	})
+
+	t.Run("when SMTP reply is classified as other than mailbox unknown, matches SMTP error body pattern", func(t *testing.T) {
+		customError := &SmtpClientError{err: &textproto.Error{Code: 550, Msg: "5.7.1 user unknown"}}
+
+		assert.True(t, customError.isMailboxUnknown(smtpErrorBodyPattern))
+	})
+
+	t.Run("when SMTP reply is not classified, matches SMTP error body pattern", func(t *testing.T) {
+		customError := &SmtpClientError{err: &textproto.Error{Code: 550, Msg: "customer rejected"}}
+
+		assert.True(t, customError.isMailboxUnknown(smtpErrorBodyPattern))
+	})
+
+	t.Run("when error was not caused by SMTP reply", func(t *testing.T) {
+		assert.False(t, (&SmtpClientError{err: errors.New("connection refused")}).isMailboxUnknown(smtpErrorBodyPattern))
+	})
+}
+
+func TestDaneVerificationErrorError(t *testing.T) {
+	t.Run("returns DANE verification error message", func(t *testing.T) {
+		hostName := randomDomain()
+
+		assert.Equal(t, hostName+" certificate does not match dane tlsa records", (&daneVerificationError{hostName: hostName}).Error())
+	})
+}
+
+func TestIsDaneVerificationError(t *testing.T) {
+	t.Run("when DANE verification error", func(t *testing.T) {
+		assert.True(t, isDaneVerificationError(&daneVerificationError{}))
+	})
+
+	t.Run("when wrapped DANE verification error", func(t *testing.T) {
+		assert.True(t, isDaneVerificationError(fmt.Errorf("handshake: %w", &daneVerificationError{})))
+	})
+
+	t.Run("when other error", func(t *testing.T) {
+		assert.False(t, isDaneVerificationError(errors.New("error")))
+	})
+}
+
+func TestIsCertificateVerificationError(t *testing.T) {
+	t.Run("when certificate verification error", func(t *testing.T) {
+		err := fmt.Errorf("tls: %w", &tls.CertificateVerificationError{Err: errors.New("error")})
+
+		assert.True(t, isCertificateVerificationError(err))
+	})
+
+	t.Run("when DANE verification error", func(t *testing.T) {
+		assert.True(t, isCertificateVerificationError(&daneVerificationError{}))
+	})
+
+	t.Run("when other error", func(t *testing.T) {
+		assert.False(t, isCertificateVerificationError(errors.New("error")))
+	})
+}
+
+func TestSmtpProxyErrorError(t *testing.T) {
+	t.Run("returns proxy error message", func(t *testing.T) {
+		err := &smtpProxyError{proxy: "socks5://127.0.0.1:1080", err: errors.New("connection refused")}
+
+		assert.EqualError(t, err, "proxy socks5://127.0.0.1:1080: connection refused")
+	})
+}
+
+func TestSmtpProxyErrorUnwrap(t *testing.T) {
+	t.Run("returns proxy dial error", func(t *testing.T) {
+		dialError := errors.New("error")
+
+		assert.Equal(t, dialError, (&smtpProxyError{err: dialError}).Unwrap())
+	})
+}
+
+func TestIsSmtpProxyError(t *testing.T) {
+	t.Run("when SMTP proxy error", func(t *testing.T) {
+		assert.True(t, isSmtpProxyError(fmt.Errorf("dial: %w", &smtpProxyError{err: errors.New("error")})))
+	})
+
+	t.Run("when other error", func(t *testing.T) {
+		assert.False(t, isSmtpProxyError(errors.New("error")))
+	})
+}
+
+func TestNewSmtpCancelledError(t *testing.T) {
+	t.Run("creates SMTP client error with isCancelled: true", func(t *testing.T) {
+		err := newSmtpCancelledError()
+
+		assert.True(t, err.isCancelled)
+		assert.EqualError(t, err, smtpCancelledErrorContext)
+	})
+}
+
+func TestNewSmtpRateLimitError(t *testing.T) {
+	t.Run("creates SMTP client error with isRateLimited: true", func(t *testing.T) {
+		err := newSmtpRateLimitError()
+
+		assert.True(t, err.isRateLimited)
+		assert.EqualError(t, err, smtpRateLimitedErrorContext)
+	})
+}
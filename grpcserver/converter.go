@@ -0,0 +1,115 @@
+package grpcserver
+
+import (
+	"github.com/truemail-rb/truemail-go"
+	truemailv1 "github.com/truemail-rb/truemail-go/proto/truemail/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Returns protobuf message of validator result
+func newValidatorResult(validatorResult *truemail.ValidatorResult) *truemailv1.ValidatorResult {
+	message := &truemailv1.ValidatorResult{
+		Success:        validatorResult.Success,
+		Email:          validatorResult.Email,
+		Domain:         validatorResult.Domain,
+		ValidationType: validatorResult.ValidationType,
+		MailServers:    validatorResult.MailServers,
+		Errors:         validatorResult.Errors,
+		FromCache:      validatorResult.FromCache,
+	}
+	for _, smtpRequest := range validatorResult.SmtpDebug {
+		message.SmtpDebug = append(message.SmtpDebug, newSmtpRequest(smtpRequest))
+	}
+
+	return message
+}
+
+// Returns protobuf message of SMTP request
+func newSmtpRequest(smtpRequest *truemail.SmtpRequest) *truemailv1.SmtpRequest {
+	message := &truemailv1.SmtpRequest{
+		Attempts: int32(smtpRequest.Attempts),
+		Email:    smtpRequest.Email,
+		Host:     smtpRequest.Host,
+		Response: newSmtpResponse(smtpRequest.Response),
+	}
+	if configuration := smtpRequest.Configuration; configuration != nil {
+		message.MxHost, message.Port = configuration.TargetServerName, int32(configuration.TargetServerPortNumber)
+	}
+
+	return message
+}
+
+// Returns protobuf message of SMTP response. Returns nil when SMTP response is not specified
+func newSmtpResponse(smtpResponse *truemail.SmtpResponse) *truemailv1.SmtpResponse {
+	if smtpResponse == nil {
+		return nil
+	}
+
+	message := &truemailv1.SmtpResponse{
+		Rcptto:        smtpResponse.Rcptto,
+		RcpttoTrusted: smtpResponse.RcpttoTrusted,
+		CatchAll:      smtpResponse.CatchAll,
+		Tls:           newTlsDetails(smtpResponse.Tls),
+	}
+	for _, smtpClientError := range smtpResponse.Errors {
+		if smtpClientError != nil {
+			message.Errors = append(message.Errors, newSmtpError(smtpClientError))
+		}
+	}
+	for _, transcriptEntry := range smtpResponse.Transcript {
+		message.Transcript = append(message.Transcript, newSmtpTranscriptEntry(transcriptEntry))
+	}
+
+	return message
+}
+
+// Returns protobuf message of SMTP client error with parsed SMTP reply
+func newSmtpError(smtpClientError *truemail.SmtpClientError) *truemailv1.SmtpError {
+	message := &truemailv1.SmtpError{Message: smtpClientError.Error()}
+	if smtpReply := smtpClientError.Reply(); smtpReply != nil {
+		message.Reply = &truemailv1.SmtpReply{
+			Code:           int32(smtpReply.Code),
+			EnhancedCode:   smtpReply.EnhancedCode,
+			Text:           smtpReply.Text,
+			Classification: smtpReply.Classification,
+		}
+	}
+
+	return message
+}
+
+// Returns protobuf message of TLS details. Returns nil when SMTP session was not upgraded
+// to TLS and did not fall back to plain text
+func newTlsDetails(tlsDetails *truemail.TlsDetails) *truemailv1.TlsDetails {
+	if tlsDetails == nil {
+		return nil
+	}
+
+	message := &truemailv1.TlsDetails{
+		Version:            tlsDetails.Version,
+		CipherSuite:        tlsDetails.CipherSuite,
+		CertificateSubject: tlsDetails.CertificateSubject,
+	}
+	if !tlsDetails.CertificateExpiry.IsZero() {
+		message.CertificateExpiry = timestamppb.New(tlsDetails.CertificateExpiry)
+	}
+	if tlsDetails.Err != nil {
+		message.Error = tlsDetails.Err.Error()
+	}
+
+	return message
+}
+
+// Returns protobuf message of SMTP transcript entry
+func newSmtpTranscriptEntry(transcriptEntry *truemail.SmtpTranscriptEntry) *truemailv1.SmtpTranscriptEntry {
+	return &truemailv1.SmtpTranscriptEntry{
+		Command:   transcriptEntry.Command,
+		Reply:     transcriptEntry.Reply,
+		Code:      int32(transcriptEntry.Code),
+		Tls:       transcriptEntry.Tls,
+		SentAt:    timestamppb.New(transcriptEntry.SentAt),
+		RepliedAt: timestamppb.New(transcriptEntry.RepliedAt),
+		Latency:   durationpb.New(transcriptEntry.Latency),
+	}
+}
@@ -0,0 +1,85 @@
+package grpcserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/truemail-rb/truemail-go"
+)
+
+func TestNewValidatorResult(t *testing.T) {
+	t.Run("converts validator result with SMTP debug", func(t *testing.T) {
+		sentAt := time.Now()
+		certificateExpiry := sentAt.Add(time.Hour)
+		validatorResult := &truemail.ValidatorResult{
+			Success:        true,
+			Email:          "email@example.com",
+			Domain:         "example.com",
+			ValidationType: "smtp",
+			MailServers:    []string{"127.0.0.1"},
+			SmtpDebug: []*truemail.SmtpRequest{
+				{
+					Attempts:      2,
+					Email:         "email@example.com",
+					Host:          "127.0.0.1",
+					Configuration: &truemail.SmtpRequestConfiguration{TargetServerName: "mx.example.com", TargetServerPortNumber: 25},
+					Response: &truemail.SmtpResponse{
+						Rcptto:        true,
+						RcpttoTrusted: true,
+						CatchAll:      true,
+						Errors:        []*truemail.SmtpClientError{nil},
+						Tls:           &truemail.TlsDetails{Version: "TLS 1.3", CertificateExpiry: certificateExpiry},
+						Transcript: truemail.SmtpTranscript{
+							{Command: "EHLO example.com", Reply: []string{"Hello"}, Code: 250, SentAt: sentAt, RepliedAt: sentAt.Add(time.Second), Latency: time.Second},
+						},
+					},
+				},
+			},
+		}
+		message := newValidatorResult(validatorResult)
+
+		assert.True(t, message.GetSuccess())
+		assert.Equal(t, validatorResult.MailServers, message.GetMailServers())
+		assert.Len(t, message.GetSmtpDebug(), 1)
+		smtpRequest := message.GetSmtpDebug()[0]
+		assert.Equal(t, int32(2), smtpRequest.GetAttempts())
+		assert.Equal(t, "mx.example.com", smtpRequest.GetMxHost())
+		assert.Equal(t, int32(25), smtpRequest.GetPort())
+		smtpResponse := smtpRequest.GetResponse()
+		assert.True(t, smtpResponse.GetRcptto())
+		assert.True(t, smtpResponse.GetRcpttoTrusted())
+		assert.True(t, smtpResponse.GetCatchAll())
+		assert.Empty(t, smtpResponse.GetErrors())
+		assert.Equal(t, "TLS 1.3", smtpResponse.GetTls().GetVersion())
+		assert.True(t, certificateExpiry.Equal(smtpResponse.GetTls().GetCertificateExpiry().AsTime()))
+		assert.Len(t, smtpResponse.GetTranscript(), 1)
+		transcriptEntry := smtpResponse.GetTranscript()[0]
+		assert.Equal(t, "EHLO example.com", transcriptEntry.GetCommand())
+		assert.Equal(t, int32(250), transcriptEntry.GetCode())
+		assert.True(t, sentAt.Equal(transcriptEntry.GetSentAt().AsTime()))
+		assert.Equal(t, time.Second, transcriptEntry.GetLatency().AsDuration())
+	})
+
+	t.Run("when SMTP session fell back to plain text", func(t *testing.T) {
+		message := newValidatorResult(
+			&truemail.ValidatorResult{
+				SmtpDebug: []*truemail.SmtpRequest{
+					{Response: &truemail.SmtpResponse{Tls: &truemail.TlsDetails{Err: errors.New("tls: handshake failure")}}},
+				},
+			},
+		)
+		tlsDetails := message.GetSmtpDebug()[0].GetResponse().GetTls()
+
+		assert.Equal(t, "tls: handshake failure", tlsDetails.GetError())
+		assert.Empty(t, tlsDetails.GetVersion())
+		assert.Nil(t, tlsDetails.GetCertificateExpiry())
+	})
+
+		t.Run("when SMTP response is not specified", func(t *testing.T) {
+		message := newValidatorResult(&truemail.ValidatorResult{SmtpDebug: []*truemail.SmtpRequest{{Host: "127.0.0.1"}}})
+
+		assert.Nil(t, message.GetSmtpDebug()[0].GetResponse())
+	})
+}
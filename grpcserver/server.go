@@ -0,0 +1,206 @@
+// Package grpcserver provides gRPC validation service. Service validates emails with shared
+// truemail configuration, streams validator results of bulk validation and audits current host.
+// Requests are authenticated with access tokens
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/truemail-rb/truemail-go"
+	truemailv1 "github.com/truemail-rb/truemail-go/proto/truemail/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	emptyString           = ""
+	metadataAuthorization = "authorization"
+	authorizationBearer   = "Bearer "
+)
+
+// ServerAttr kwargs structure for server builder
+type ServerAttr struct {
+	Configuration *truemail.Configuration
+	AccessTokens  []string
+}
+
+// Server structure. Implements truemailv1.ValidationServiceServer
+type Server struct {
+	truemailv1.UnimplementedValidationServiceServer
+	configuration *truemail.Configuration
+	accessTokens  []string
+}
+
+// NewServer returns gRPC validation service with shared configuration.
+// Returns error if server attributes are invalid
+func NewServer(attr ServerAttr) (*Server, error) {
+	if attr.Configuration == nil {
+		return nil, errors.New("configuration is required")
+	}
+
+	var accessTokens []string
+	for _, accessToken := range attr.AccessTokens {
+		if accessToken = strings.TrimSpace(accessToken); accessToken != emptyString {
+			accessTokens = append(accessTokens, accessToken)
+		}
+	}
+	if len(accessTokens) == 0 {
+		return nil, errors.New("at least one access token is required")
+	}
+
+	return &Server{configuration: attr.Configuration, accessTokens: accessTokens}, nil
+}
+
+// Server methods
+
+// Register registers validation service on gRPC server. Server should be created
+// with ServerOptions, otherwise requests are not authenticated
+func (server *Server) Register(grpcServer grpc.ServiceRegistrar) {
+	truemailv1.RegisterValidationServiceServer(grpcServer, server)
+}
+
+// ServerOptions returns gRPC server options with unary and stream interceptors, which
+// respond with unauthenticated status when authorization metadata of request does not
+// include one of access tokens
+func (server *Server) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(server.authenticateUnary),
+		grpc.ChainStreamInterceptor(server.authenticateStream),
+	}
+}
+
+// Serve serves validation service on listener until context is done,
+// then gracefully stops gRPC server. Requests are authenticated with access tokens
+func (server *Server) Serve(ctx context.Context, listener net.Listener, serverOptions ...grpc.ServerOption) error {
+	grpcServer := grpc.NewServer(append(server.ServerOptions(), serverOptions...)...)
+	server.Register(grpcServer)
+	serveError := make(chan error, 1)
+	go func() { serveError <- grpcServer.Serve(listener) }()
+
+	select {
+	case err := <-serveError:
+		return err
+	case <-ctx.Done():
+	}
+
+	grpcServer.GracefulStop()
+
+	return <-serveError
+}
+
+// Validate validates email. Responds with invalid argument status
+// when email is missing or validation type is invalid
+func (server *Server) Validate(ctx context.Context, request *truemailv1.ValidateRequest) (*truemailv1.ValidateResponse, error) {
+	return server.validate(ctx, request)
+}
+
+// ValidateStream validates emails from request stream, responds with validator result of
+// each email in order of requests. Stream is aborted with invalid argument status when
+// email is missing or validation type is invalid
+func (server *Server) ValidateStream(stream truemailv1.ValidationService_ValidateStreamServer) error {
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := server.validate(stream.Context(), request)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// HostAudit checks that A record of verifier domain and PTR record of current host
+// ip address refer to each other. Current host ip address is detected by server
+// when it is not specified
+func (server *Server) HostAudit(ctx context.Context, request *truemailv1.HostAuditRequest) (*truemailv1.HostAuditResponse, error) {
+	auditorResult := truemail.HostAudit(ctx, server.configuration, request.GetCurrentHostIp())
+
+	return &truemailv1.HostAuditResponse{CurrentHostIp: auditorResult.CurrentHostIp, Warnings: auditorResult.Warnings}, nil
+}
+
+// Authenticates unary request before calling handler
+func (server *Server) authenticateUnary(
+	ctx context.Context,
+	request any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := server.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, request)
+}
+
+// Authenticates stream before calling handler
+func (server *Server) authenticateStream(
+	srv any,
+	stream grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := server.authenticate(stream.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, stream)
+}
+
+// Returns unauthenticated status error when authorization metadata
+// from context does not include one of access tokens
+func (server *Server) authenticate(ctx context.Context) error {
+	requestMetadata, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range requestMetadata.Get(metadataAuthorization) {
+		if server.isAuthorized(authorization) {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// Checks authorization includes one of access tokens, with or without Bearer scheme
+func (server *Server) isAuthorized(authorization string) bool {
+	accessToken := []byte(strings.TrimPrefix(authorization, authorizationBearer))
+	for _, serverAccessToken := range server.accessTokens {
+		if subtle.ConstantTimeCompare(accessToken, []byte(serverAccessToken)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validates email from validate request with request context
+func (server *Server) validate(ctx context.Context, request *truemailv1.ValidateRequest) (*truemailv1.ValidateResponse, error) {
+	if request.GetEmail() == emptyString {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	var options []string
+	if validationType := request.GetValidationType(); validationType != emptyString {
+		options = append(options, validationType)
+	}
+
+	validatorResult, err := truemail.ValidateContext(ctx, request.GetEmail(), server.configuration, options...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &truemailv1.ValidateResponse{ValidatorResult: newValidatorResult(validatorResult)}, nil
+}
@@ -0,0 +1,288 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/stretchr/testify/assert"
+	"github.com/truemail-rb/truemail-go"
+	truemailv1 "github.com/truemail-rb/truemail-go/proto/truemail/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	bufferSize  = 1 << 20
+	accessToken = "access-token"
+)
+
+// Per RPC credentials stand-in, sends authorization metadata over insecure connection
+type authorizationCredentials string
+
+func (credentials authorizationCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{metadataAuthorization: string(credentials)}, nil
+}
+
+func (credentials authorizationCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// Creates configuration with regex validation type by default
+func createConfiguration(t *testing.T) *truemail.Configuration {
+	configuration, err := truemail.NewConfiguration(
+		truemail.ConfigurationAttr{VerifierEmail: "verifier@example.com", ValidationTypeDefault: "regex"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return configuration
+}
+
+// Runs validation service in-process with configuration. Returns generated client
+// connected to service with access token, service is stopped when test is finished
+func createClient(t *testing.T, configuration *truemail.Configuration) truemailv1.ValidationServiceClient {
+	return createClientWithAuthorization(t, configuration, authorizationBearer+accessToken)
+}
+
+// Runs validation service in-process with configuration. Returns generated client
+// connected to service, which sends authorization metadata when it is specified
+func createClientWithAuthorization(
+	t *testing.T,
+	configuration *truemail.Configuration,
+	authorization string,
+) truemailv1.ValidationServiceClient {
+	server, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{accessToken}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(bufferSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	serveError := make(chan error, 1)
+	go func() { serveError <- server.Serve(ctx, listener) }()
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if authorization != emptyString {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(authorizationCredentials(authorization)))
+	}
+	connection, err := grpc.NewClient("passthrough:///bufconn", dialOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		connection.Close()
+		cancel()
+		assert.NoError(t, <-serveError)
+	})
+
+	return truemailv1.NewValidationServiceClient(connection)
+}
+
+func TestNewServer(t *testing.T) {
+	configuration := createConfiguration(t)
+
+	t.Run("creates server with configuration and access tokens", func(t *testing.T) {
+		server, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{" " + accessToken + " ", ""}})
+
+		assert.NoError(t, err)
+		assert.Same(t, configuration, server.configuration)
+		assert.Equal(t, []string{accessToken}, server.accessTokens)
+	})
+
+	t.Run("when configuration is not specified", func(t *testing.T) {
+		_, err := NewServer(ServerAttr{AccessTokens: []string{accessToken}})
+
+		assert.EqualError(t, err, "configuration is required")
+	})
+
+	t.Run("when access tokens are not specified", func(t *testing.T) {
+		_, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{" "}})
+
+		assert.EqualError(t, err, "at least one access token is required")
+	})
+}
+
+func TestServerValidate(t *testing.T) {
+	client := createClient(t, createConfiguration(t))
+
+	t.Run("validates email with default validation type", func(t *testing.T) {
+		response, err := client.Validate(context.Background(), &truemailv1.ValidateRequest{Email: "email@example.com"})
+
+		assert.NoError(t, err)
+		validatorResult := response.GetValidatorResult()
+		assert.True(t, validatorResult.GetSuccess())
+		assert.Equal(t, "email@example.com", validatorResult.GetEmail())
+		assert.Equal(t, "example.com", validatorResult.GetDomain())
+		assert.Equal(t, "regex", validatorResult.GetValidationType())
+		assert.Empty(t, validatorResult.GetErrors())
+	})
+
+	t.Run("validates email with validation type from request", func(t *testing.T) {
+		response, err := client.Validate(
+			context.Background(),
+			&truemailv1.ValidateRequest{Email: "invalid-email", ValidationType: "regex"},
+		)
+
+		assert.NoError(t, err)
+		assert.False(t, response.GetValidatorResult().GetSuccess())
+		assert.Contains(t, response.GetValidatorResult().GetErrors(), "regex")
+	})
+
+	t.Run("when email is missing", func(t *testing.T) {
+		_, err := client.Validate(context.Background(), &truemailv1.ValidateRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "email is required", status.Convert(err).Message())
+	})
+
+	t.Run("when validation type is invalid", func(t *testing.T) {
+		_, err := client.Validate(
+			context.Background(),
+			&truemailv1.ValidateRequest{Email: "email@example.com", ValidationType: "random"},
+		)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServerValidateStream(t *testing.T) {
+	client := createClient(t, createConfiguration(t))
+
+	t.Run("responds with validator result of each email in order of requests", func(t *testing.T) {
+		emails := []string{"email@example.com", "invalid-email", "other@example.com"}
+		stream, err := client.ValidateStream(context.Background())
+		assert.NoError(t, err)
+		for _, email := range emails {
+			assert.NoError(t, stream.Send(&truemailv1.ValidateRequest{Email: email}))
+		}
+		assert.NoError(t, stream.CloseSend())
+
+		var validatorResults []*truemailv1.ValidatorResult
+		for {
+			response, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			validatorResults = append(validatorResults, response.GetValidatorResult())
+		}
+
+		assert.Len(t, validatorResults, len(emails))
+		for index, email := range emails {
+			assert.Equal(t, email, validatorResults[index].GetEmail())
+		}
+		assert.True(t, validatorResults[0].GetSuccess())
+		assert.False(t, validatorResults[1].GetSuccess())
+		assert.True(t, validatorResults[2].GetSuccess())
+	})
+
+	t.Run("when validation type is invalid, aborts stream", func(t *testing.T) {
+		stream, err := client.ValidateStream(context.Background())
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Send(&truemailv1.ValidateRequest{Email: "email@example.com"}))
+		assert.NoError(t, stream.Send(&truemailv1.ValidateRequest{Email: "email@example.com", ValidationType: "random"}))
+		assert.NoError(t, stream.CloseSend())
+
+		_, err = stream.Recv()
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServerHostAudit(t *testing.T) {
+	currentHostIp := "1.2.3.4"
+
+	t.Run("when A and PTR records refer to current host", func(t *testing.T) {
+		configuration := createConfiguration(t)
+		configuration.Resolver = &mockdns.Resolver{
+			Zones: map[string]mockdns.Zone{
+				"example.com.":          {A: []string{currentHostIp}},
+				"4.3.2.1.in-addr.arpa.": {PTR: []string{"example.com."}},
+			},
+		}
+		response, err := createClient(t, configuration).HostAudit(
+			context.Background(),
+			&truemailv1.HostAuditRequest{CurrentHostIp: currentHostIp},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, currentHostIp, response.GetCurrentHostIp())
+		assert.Empty(t, response.GetWarnings())
+	})
+
+	t.Run("when DNS records not found", func(t *testing.T) {
+		configuration := createConfiguration(t)
+		configuration.Resolver = &mockdns.Resolver{}
+		response, err := createClient(t, configuration).HostAudit(
+			context.Background(),
+			&truemailv1.HostAuditRequest{CurrentHostIp: currentHostIp},
+		)
+
+		assert.NoError(t, err)
+		assert.Contains(t, response.GetWarnings(), "dns")
+		assert.Contains(t, response.GetWarnings(), "ptr")
+	})
+}
+
+func TestServerAuthentication(t *testing.T) {
+	configuration := createConfiguration(t)
+	request := &truemailv1.ValidateRequest{Email: "email@example.com"}
+
+	t.Run("authenticates request with raw access token", func(t *testing.T) {
+		response, err := createClientWithAuthorization(t, configuration, accessToken).Validate(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.True(t, response.GetValidatorResult().GetSuccess())
+	})
+
+	t.Run("when access token is invalid", func(t *testing.T) {
+		_, err := createClientWithAuthorization(t, configuration, "random").Validate(context.Background(), request)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "unauthorized", status.Convert(err).Message())
+	})
+
+	t.Run("when authorization metadata is missing", func(t *testing.T) {
+		_, err := createClientWithAuthorization(t, configuration, emptyString).Validate(context.Background(), request)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("when authorization metadata is missing, aborts stream", func(t *testing.T) {
+		stream, err := createClientWithAuthorization(t, configuration, emptyString).ValidateStream(context.Background())
+		assert.NoError(t, err)
+		_ = stream.Send(request)
+		_, err = stream.Recv()
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("when service is registered on gRPC server with server options", func(t *testing.T) {
+		server, _ := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{accessToken}})
+		grpcServer := grpc.NewServer(server.ServerOptions()...)
+		server.Register(grpcServer)
+		listener := bufconn.Listen(bufferSize)
+		go func() { _ = grpcServer.Serve(listener) }()
+		defer grpcServer.Stop()
+		connection, _ := grpc.NewClient(
+			"passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		defer connection.Close()
+		_, err := truemailv1.NewValidationServiceClient(connection).Validate(context.Background(), request)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
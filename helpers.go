@@ -1,8 +1,11 @@
 package truemail
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // package helpers functions
@@ -51,6 +54,54 @@ func availableValidationTypes() []string {
 	return []string{validationTypeRegex, validationTypeMx, validationTypeMxBlacklist, validationTypeSmtp}
 }
 
+// Returns slice of available DNS transports
+func availableDnsTransports() []string {
+	return []string{dnsTransportPlain, dnsTransportTls, dnsTransportHttps}
+}
+
+// Returns slice of available DNS clients
+func availableDnsClients() []string {
+	return []string{dnsClientNet, dnsClientRaw}
+}
+
+// Returns slice of available SMTP TLS policies
+func availableSmtpTlsPolicies() []string {
+	return []string{smtpTlsPolicyNone, smtpTlsPolicyOpportunistic, smtpTlsPolicyRequired}
+}
+
+// Returns slice of available log email redactions
+func availableLogEmailRedactions() []string {
+	return []string{logEmailRedactionNone, logEmailRedactionMask, logEmailRedactionHash}
+}
+
+// Returns slice of available SMTP proxy URL schemes
+func availableSmtpProxySchemes() []string {
+	return []string{smtpProxySchemeSocks5, smtpProxySchemeSocks5h, smtpProxySchemeHttp}
+}
+
+// Returns slice of available SMTP source addresses rotations
+func availableSmtpSourceRotations() []string {
+	return []string{smtpSourceRotationRoundRobin, smtpSourceRotationPerDomain}
+}
+
+// Returns SMTP reply classifications by RFC 3463 enhanced status code
+func smtpReplyClassificationsByEnhancedCode() map[string]string {
+	return map[string]string{
+		"5.1.0":  smtpReplyMailboxUnknown,
+		"5.1.1":  smtpReplyMailboxUnknown,
+		"5.1.6":  smtpReplyMailboxUnknown,
+		"5.2.1":  smtpReplyMailboxUnknown,
+		"4.2.2":  smtpReplyMailboxFull,
+		"5.2.2":  smtpReplyMailboxFull,
+		"5.7.25": smtpReplyIpReputationBlock,
+	}
+}
+
+// Returns slice of available DNS servers strategies
+func availableDnsStrategies() []string {
+	return []string{dnsStrategyFailover, dnsStrategyRoundRobin, dnsStrategyRace}
+}
+
 // Extracts and validates validation type from variadic argument
 func variadicValidationType(options []string, defaultValidationType string) (string, error) {
 	if len(options) == 0 {
@@ -123,7 +174,25 @@ func sliceDiff(slice, otherSlice []string) (diff []string) {
 	return diff
 }
 
+// Returns true if domain is equal to parent domain or its subdomain, case-insensitive.
+// Trailing dot of fully qualified domain name is ignored
+func isDomainOrSubdomain(domain, parentDomain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	parentDomain = strings.ToLower(parentDomain)
+
+	return domain == parentDomain || strings.HasSuffix(domain, "."+parentDomain)
+}
+
 // Returns server with port number follows {server}:{portNumber} pattern
 func serverWithPortNumber(server string, portNumber int) string {
 	return fmt.Sprintf("%s:%d", server, portNumber)
 }
+
+// Returns random email with the same domain as given email, random local part
+// is hex encoded random bytes. Uses as non-existent recipient of catch-all probe
+func randomSmtpRecipient(email string) string {
+	randomBytes := make([]byte, 8)
+	_, _ = rand.Read(randomBytes)
+
+	return hex.EncodeToString(randomBytes) + "@" + emailDomain(email)
+}
@@ -71,6 +71,34 @@ func TestAvailableValidationTypes(t *testing.T) {
 	})
 }
 
+func TestAvailableSmtpTlsPolicies(t *testing.T) {
+	t.Run("slice of available SMTP TLS policies", func(t *testing.T) {
+		assert.Equal(t, []string{"none", "opportunistic", "required"}, availableSmtpTlsPolicies())
+	})
+}
+
+func TestAvailableSmtpProxySchemes(t *testing.T) {
+	t.Run("slice of available SMTP proxy URL schemes", func(t *testing.T) {
+		assert.Equal(t, []string{"socks5", "socks5h", "http"}, availableSmtpProxySchemes())
+	})
+}
+
+func TestAvailableSmtpSourceRotations(t *testing.T) {
+	t.Run("slice of available SMTP source addresses rotations", func(t *testing.T) {
+		assert.Equal(t, []string{"round_robin", "per_domain"}, availableSmtpSourceRotations())
+	})
+}
+
+func TestSmtpReplyClassificationsByEnhancedCode(t *testing.T) {
+	t.Run("returns SMTP reply classifications by enhanced status code", func(t *testing.T) {
+		classifications := smtpReplyClassificationsByEnhancedCode()
+
+		assert.Equal(t, smtpReplyMailboxUnknown, classifications["5.1.1"])
+		assert.Equal(t, smtpReplyMailboxFull, classifications["4.2.2"])
+		assert.Equal(t, smtpReplyIpReputationBlock, classifications["5.7.25"])
+	})
+}
+
 func TestVariadicValidationType(t *testing.T) {
 	t.Run("without validation type", func(t *testing.T) {
 		result, err := variadicValidationType([]string{}, validationTypeMx)
@@ -166,6 +194,20 @@ func TestSliceDiff(t *testing.T) {
 	})
 }
 
+func TestIsDomainOrSubdomain(t *testing.T) {
+	t.Run("when domain is equal to parent domain", func(t *testing.T) {
+		assert.True(t, isDomainOrSubdomain("Gmail.com", "gmail.com"))
+	})
+
+	t.Run("when domain is subdomain of parent domain", func(t *testing.T) {
+		assert.True(t, isDomainOrSubdomain("gmail-smtp-in.l.google.com.", "google.com"))
+	})
+
+	t.Run("when domain is not subdomain of parent domain", func(t *testing.T) {
+		assert.False(t, isDomainOrSubdomain("notgoogle.com", "google.com"))
+	})
+}
+
 func TestServerWithPortNumber(t *testing.T) {
 	t.Run("returns server with port number", func(t *testing.T) {
 		server, portNumber := randomIpAddress(), randomPortNumber()
@@ -173,3 +215,14 @@ func TestServerWithPortNumber(t *testing.T) {
 		assert.Equal(t, server+":"+strconv.Itoa(portNumber), serverWithPortNumber(server, portNumber))
 	})
 }
+
+func TestRandomSmtpRecipient(t *testing.T) {
+	t.Run("returns random email with the same domain", func(t *testing.T) {
+		domain := randomDomain()
+		email := "user@" + domain
+		recipient := randomSmtpRecipient(email)
+
+		assert.Regexp(t, `\A[0-9a-f]{16}@`+regexp.QuoteMeta(domain)+`\z`, recipient)
+		assert.NotEqual(t, recipient, randomSmtpRecipient(email))
+	})
+}
@@ -0,0 +1,293 @@
+package options
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/truemail-rb/truemail-go"
+)
+
+const (
+	// OptionConfig is name of config file path option, registered for each command
+	OptionConfig = "config"
+
+	envPrefix   = "TRUEMAIL_"
+	emptyString = ""
+)
+
+// Option structure. Option value is resolved from command-line flag, then from
+// environment variable, then from config file key. Options of configuration
+// attributes assign resolved value into configuration attributes
+type Option struct {
+	Name, Usage string
+	IsBool      bool
+	Assign      func(*truemail.ConfigurationAttr, string) error
+}
+
+// Option methods
+
+// EnvName returns environment variable name of option, for example TRUEMAIL_VERIFIER_EMAIL
+func (option *Option) EnvName() string {
+	return envPrefix + strings.ToUpper(option.ConfigKey())
+}
+
+// ConfigKey returns config file key of option, for example verifier_email
+func (option *Option) ConfigKey() string {
+	return strings.ReplaceAll(option.Name, "-", "_")
+}
+
+// ConfigurationOptions returns options of truemail configuration attributes
+func ConfigurationOptions() []*Option {
+	return []*Option{
+		{Name: "verifier-email", Usage: "email on behalf of which verification will be performed", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.VerifierEmail })},
+		{Name: "verifier-domain", Usage: "domain on behalf of which verification will be performed", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.VerifierDomain })},
+		{Name: "validation-type", Usage: "validation type: regex, mx, mx_blacklist, smtp (default smtp)", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.ValidationTypeDefault })},
+		{Name: "email-pattern", Usage: "custom email regex pattern", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.EmailPattern })},
+		{Name: "smtp-error-body-pattern", Usage: "custom SMTP error body regex pattern", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.SmtpErrorBodyPattern })},
+		{Name: "dns", Usage: "DNS gateway, for example 10.0.0.1:5300", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.Dns })},
+		{Name: "dns-servers", Usage: "comma separated DNS servers", Assign: assignStrings(func(config *truemail.ConfigurationAttr) *[]string { return &config.DnsServers })},
+		{Name: "connection-timeout", Usage: "connection timeout in seconds (default 2)", Assign: assignInt(func(config *truemail.ConfigurationAttr) *int { return &config.ConnectionTimeout })},
+		{Name: "response-timeout", Usage: "response timeout in seconds (default 2)", Assign: assignInt(func(config *truemail.ConfigurationAttr) *int { return &config.ResponseTimeout })},
+		{Name: "connection-attempts", Usage: "total of connection attempts (default 2)", Assign: assignInt(func(config *truemail.ConfigurationAttr) *int { return &config.ConnectionAttempts })},
+		{Name: "smtp-port", Usage: "SMTP port number (default 25)", Assign: assignInt(func(config *truemail.ConfigurationAttr) *int { return &config.SmtpPort })},
+		{Name: "whitelisted-domains", Usage: "comma separated whitelisted domains", Assign: assignStrings(func(config *truemail.ConfigurationAttr) *[]string { return &config.WhitelistedDomains })},
+		{Name: "blacklisted-domains", Usage: "comma separated blacklisted domains", Assign: assignStrings(func(config *truemail.ConfigurationAttr) *[]string { return &config.BlacklistedDomains })},
+		{Name: "blacklisted-mx-ip-addresses", Usage: "comma separated blacklisted MX ip addresses", Assign: assignStrings(func(config *truemail.ConfigurationAttr) *[]string { return &config.BlacklistedMxIpAddresses })},
+		{Name: "whitelist-validation", Usage: "validate emails with whitelisted domains only", IsBool: true, Assign: assignBool(func(config *truemail.ConfigurationAttr) *bool { return &config.WhitelistValidation })},
+		{Name: "not-rfc-mx-lookup-flow", Usage: "check MX and Null MX records only", IsBool: true, Assign: assignBool(func(config *truemail.ConfigurationAttr) *bool { return &config.NotRfcMxLookupFlow })},
+		{Name: "smtp-fail-fast", Usage: "check first mail server only", IsBool: true, Assign: assignBool(func(config *truemail.ConfigurationAttr) *bool { return &config.SmtpFailFast })},
+		{Name: "smtp-safe-check", Usage: "fail SMTP validation only when mailbox is unknown", IsBool: true, Assign: assignBool(func(config *truemail.ConfigurationAttr) *bool { return &config.SmtpSafeCheck })},
+		{Name: "smtp-tls-policy", Usage: "STARTTLS policy: none, opportunistic, required (default none)", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.SmtpTlsPolicy })},
+		{Name: "smtp-proxy", Usage: "SMTP proxy URL, for example socks5://127.0.0.1:1080", Assign: assignString(func(config *truemail.ConfigurationAttr) *string { return &config.SmtpProxy })},
+	}
+}
+
+// Values is dictionary of resolved option values by option name
+type Values map[string]string
+
+// Parse registers options with config file path option in flag set and parses command-line
+// arguments. Returns resolved option values: flags take precedence over environment variables,
+// environment variables take precedence over config file. Returns flag.ErrHelp when help is requested
+func Parse(flagSet *flag.FlagSet, options []*Option, args []string, lookupEnv func(string) (string, bool)) (Values, error) {
+	options = append([]*Option{{Name: OptionConfig, Usage: "path to JSON config file"}}, options...)
+	flagValues := make(map[string]*flagValue, len(options))
+	for _, option := range options {
+		flagValues[option.Name] = &flagValue{isBool: option.IsBool}
+		flagSet.Var(flagValues[option.Name], option.Name, option.Usage)
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	values := make(Values, len(options))
+	for name, flagValue := range flagValues {
+		if flagValue.isSet {
+			values[name] = flagValue.value
+		}
+	}
+	for _, option := range options {
+		if _, ok := values[option.Name]; ok {
+			continue
+		}
+		if value, ok := lookupEnv(option.EnvName()); ok {
+			values[option.Name] = value
+		}
+	}
+
+	if configPath, ok := values[OptionConfig]; ok {
+		configValues, err := readConfigFile(configPath, options)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range configValues {
+			if _, ok := values[name]; !ok {
+				values[name] = value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// Values methods
+
+// ConfigurationAttr returns configuration attributes with resolved values of options.
+// Returns error if option value is invalid
+func (values Values) ConfigurationAttr(options []*Option) (truemail.ConfigurationAttr, error) {
+	var configurationAttr truemail.ConfigurationAttr
+	for _, option := range options {
+		value, ok := values[option.Name]
+		if !ok || option.Assign == nil {
+			continue
+		}
+		if err := option.Assign(&configurationAttr, value); err != nil {
+			return configurationAttr, fmt.Errorf("%s is invalid value of %s option: %w", value, option.Name, err)
+		}
+	}
+
+	return configurationAttr, nil
+}
+
+// Int returns resolved integer value of option or default value when option value
+// is not resolved. Returns error if option value is not an integer
+func (values Values) Int(name string, defaultValue int) (int, error) {
+	value, ok := values[name]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	integer, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s is invalid value of %s option: integer is expected", value, name)
+	}
+
+	return integer, nil
+}
+
+// Reads JSON config file. Returns option values by option name, list values
+// are joined with comma. Returns error if config file includes unknown key
+func readConfigFile(path string, options []*Option) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configValues := make(map[string]interface{})
+	if err := json.Unmarshal(data, &configValues); err != nil {
+		return nil, fmt.Errorf("%s is invalid config file: %w", path, err)
+	}
+
+	optionNames := make(map[string]string, len(options))
+	for _, option := range options {
+		optionNames[option.ConfigKey()] = option.Name
+	}
+
+	keys := make([]string, 0, len(configValues))
+	for key := range configValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]string, len(configValues))
+	for _, key := range keys {
+		name, ok := optionNames[key]
+		if !ok || name == OptionConfig {
+			return nil, fmt.Errorf("%s is unknown config file key", key)
+		}
+		value, ok := configValue(configValues[key])
+		if !ok {
+			return nil, fmt.Errorf("%v is invalid config file value of %s key", configValues[key], key)
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// Returns string representation of config file value: lists are joined with comma.
+// Returns false when config file value is not a string, boolean, number or list
+func configValue(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			itemValue, ok := configValue(item)
+			if !ok {
+				return emptyString, false
+			}
+			items = append(items, itemValue)
+		}
+		return strings.Join(items, ","), true
+	default:
+		return emptyString, false
+	}
+}
+
+// Command-line flag value, implements flag.Value interface. Records
+// whether flag was set, so unset flags do not override other sources
+type flagValue struct {
+	value         string
+	isSet, isBool bool
+}
+
+// flag.Value interface implementation
+
+func (flagValue *flagValue) String() string {
+	return flagValue.value
+}
+
+func (flagValue *flagValue) Set(value string) error {
+	if flagValue.isBool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("parse error")
+		}
+	}
+	flagValue.value, flagValue.isSet = value, true
+
+	return nil
+}
+
+func (flagValue *flagValue) IsBoolFlag() bool {
+	return flagValue.isBool
+}
+
+// Option assigners
+
+// Returns assigner of string configuration attribute
+func assignString(field func(*truemail.ConfigurationAttr) *string) func(*truemail.ConfigurationAttr, string) error {
+	return func(config *truemail.ConfigurationAttr, value string) error {
+		*field(config) = value
+		return nil
+	}
+}
+
+// Returns assigner of comma separated strings configuration attribute
+func assignStrings(field func(*truemail.ConfigurationAttr) *[]string) func(*truemail.ConfigurationAttr, string) error {
+	return func(config *truemail.ConfigurationAttr, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != emptyString {
+				items = append(items, item)
+			}
+		}
+		*field(config) = items
+		return nil
+	}
+}
+
+// Returns assigner of integer configuration attribute
+func assignInt(field func(*truemail.ConfigurationAttr) *int) func(*truemail.ConfigurationAttr, string) error {
+	return func(config *truemail.ConfigurationAttr, value string) error {
+		integer, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("integer is expected")
+		}
+		*field(config) = integer
+		return nil
+	}
+}
+
+// Returns assigner of boolean configuration attribute
+func assignBool(field func(*truemail.ConfigurationAttr) *bool) func(*truemail.ConfigurationAttr, string) error {
+	return func(config *truemail.ConfigurationAttr, value string) error {
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("boolean is expected")
+		}
+		*field(config) = boolean
+		return nil
+	}
+}
@@ -0,0 +1,183 @@
+package options
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns environment lookup function of environment variables
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+// Writes config file into temporary directory. Returns config file path
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "truemail.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Parses arguments and environment variables with configuration options
+func parse(args []string, env map[string]string) (Values, error) {
+	flagSet := flag.NewFlagSet("truemail", flag.ContinueOnError)
+	flagSet.SetOutput(new(bytes.Buffer))
+	return Parse(flagSet, ConfigurationOptions(), args, lookupEnv(env))
+}
+
+func TestOptionEnvName(t *testing.T) {
+	t.Run("returns environment variable name of option", func(t *testing.T) {
+		assert.Equal(t, "TRUEMAIL_VERIFIER_EMAIL", (&Option{Name: "verifier-email"}).EnvName())
+	})
+}
+
+func TestOptionConfigKey(t *testing.T) {
+	t.Run("returns config file key of option", func(t *testing.T) {
+		assert.Equal(t, "verifier_email", (&Option{Name: "verifier-email"}).ConfigKey())
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("resolves option values from flags, environment variables and config file", func(t *testing.T) {
+		configPath := writeConfigFile(t, `{"verifier_email": "config@example.com", "smtp_port": 2525, "dns_servers": ["1.1.1.1", "8.8.8.8"]}`)
+		values, err := parse(
+			[]string{"-config", configPath, "-smtp-fail-fast", "-verifier-email", "flag@example.com"},
+			map[string]string{"TRUEMAIL_VERIFIER_EMAIL": "env@example.com", "TRUEMAIL_SMTP_PORT": "587"},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			Values{
+				OptionConfig:     configPath,
+				"verifier-email": "flag@example.com",
+				"smtp-fail-fast": "true",
+				"smtp-port":      "587",
+				"dns-servers":    "1.1.1.1,8.8.8.8",
+			},
+			values,
+		)
+	})
+
+	t.Run("resolves config file path from environment variable", func(t *testing.T) {
+		configPath := writeConfigFile(t, `{"verifier_email": "config@example.com"}`)
+		values, err := parse(nil, map[string]string{"TRUEMAIL_CONFIG": configPath})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "config@example.com", values["verifier-email"])
+	})
+
+	t.Run("when help is requested", func(t *testing.T) {
+		_, err := parse([]string{"-h"}, nil)
+
+		assert.ErrorIs(t, err, flag.ErrHelp)
+	})
+
+	t.Run("when boolean flag value is invalid", func(t *testing.T) {
+		_, err := parse([]string{"-smtp-fail-fast=random"}, nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("when config file not exists", func(t *testing.T) {
+		_, err := parse([]string{"-config", filepath.Join(t.TempDir(), "random.json")}, nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("when config file is not JSON", func(t *testing.T) {
+		configPath := writeConfigFile(t, "verifier_email: config@example.com")
+		_, err := parse([]string{"-config", configPath}, nil)
+
+		assert.ErrorContains(t, err, configPath+" is invalid config file")
+	})
+
+	t.Run("when config file includes unknown key", func(t *testing.T) {
+		configPath := writeConfigFile(t, `{"random": "value"}`)
+		_, err := parse([]string{"-config", configPath}, nil)
+
+		assert.EqualError(t, err, "random is unknown config file key")
+	})
+
+	t.Run("when config file value is invalid", func(t *testing.T) {
+		configPath := writeConfigFile(t, `{"smtp_port": {"value": 25}}`)
+		_, err := parse([]string{"-config", configPath}, nil)
+
+		assert.EqualError(t, err, "map[value:25] is invalid config file value of smtp_port key")
+	})
+}
+
+func TestValuesConfigurationAttr(t *testing.T) {
+	t.Run("assigns configuration attributes of options", func(t *testing.T) {
+		configurationAttr, err := Values{
+			"verifier-email":      "verifier@example.com",
+			"connection-timeout":  "5",
+			"whitelisted-domains": "example.com, ,other.com",
+			"smtp-safe-check":     "true",
+		}.ConfigurationAttr(ConfigurationOptions())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "verifier@example.com", configurationAttr.VerifierEmail)
+		assert.Equal(t, 5, configurationAttr.ConnectionTimeout)
+		assert.Equal(t, []string{"example.com", "other.com"}, configurationAttr.WhitelistedDomains)
+		assert.True(t, configurationAttr.SmtpSafeCheck)
+	})
+
+	t.Run("when option value is invalid", func(t *testing.T) {
+		_, err := Values{"smtp-safe-check": "random"}.ConfigurationAttr(ConfigurationOptions())
+
+		assert.EqualError(t, err, "random is invalid value of smtp-safe-check option: boolean is expected")
+	})
+}
+
+func TestValuesInt(t *testing.T) {
+	t.Run("returns resolved integer value of option", func(t *testing.T) {
+		integer, err := Values{"timeout": "42"}.Int("timeout", 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 42, integer)
+	})
+
+	t.Run("returns default value when option value is not resolved", func(t *testing.T) {
+		integer, err := Values{}.Int("timeout", 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, integer)
+	})
+
+	t.Run("when option value is not an integer", func(t *testing.T) {
+		_, err := Values{"timeout": "random"}.Int("timeout", 1)
+
+		assert.EqualError(t, err, "random is invalid value of timeout option: integer is expected")
+	})
+}
+
+func TestConfigValue(t *testing.T) {
+	for _, testCase := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{true, "true"},
+		{float64(25), "25"},
+		{[]interface{}{"example.com", "other.com"}, "example.com,other.com"},
+	} {
+		value, ok := configValue(testCase.value)
+
+		assert.True(t, ok)
+		assert.Equal(t, testCase.expected, value)
+	}
+
+	_, ok := configValue(nil)
+	assert.False(t, ok)
+}
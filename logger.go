@@ -0,0 +1,149 @@
+package truemail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event logger structure. Logs structured events with logger when event level is not
+// lower than log level from configuration. Events are held by event log buffer when it
+// is specified. Email redaction is applied to emails echoed in error messages
+type eventLogger struct {
+	logger         *slog.Logger
+	level          slog.Level
+	emailRedaction string
+	buffer         *eventLogBuffer
+}
+
+// eventLogger builder. Returns nil when logger is not specified
+func newEventLogger(logger *slog.Logger, level slog.Level) *eventLogger {
+	if logger == nil {
+		return nil
+	}
+
+	return &eventLogger{logger: logger, level: level}
+}
+
+// eventLogger methods
+
+// Logs structured event with context, so logger handler can extract span of context.
+// Does nothing when event logger is nil or event level is lower than log level
+func (eventLogger *eventLogger) log(ctx context.Context, level slog.Level, message string, attributes ...slog.Attr) {
+	if eventLogger == nil || level < eventLogger.level {
+		return
+	}
+
+	if eventLogger.buffer != nil {
+		eventLogger.buffer.add(ctx, level, message, attributes)
+		return
+	}
+
+	eventLogger.logger.LogAttrs(contextOrBackground(ctx), level, message, attributes...)
+}
+
+// Event log buffer structure. Holds events of validation until validation outcome is
+// known, uses when only failed validations should be logged. Safe for concurrent use
+type eventLogBuffer struct {
+	mutex   sync.Mutex
+	records []eventLogRecord
+}
+
+// Event log record structure. Includes log record and its context
+type eventLogRecord struct {
+	ctx    context.Context
+	record slog.Record
+}
+
+// eventLogBuffer methods
+
+// Adds event to event log buffer, event time is time of adding
+func (buffer *eventLogBuffer) add(ctx context.Context, level slog.Level, message string, attributes []slog.Attr) {
+	record := slog.NewRecord(time.Now(), level, message, 0)
+	record.AddAttrs(attributes...)
+
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	buffer.records = append(buffer.records, eventLogRecord{ctx: contextOrBackground(ctx), record: record})
+}
+
+// Logs held events with logger in order of adding, keeps original event time.
+// Event log buffer is empty after flush
+func (buffer *eventLogBuffer) flush(logger *slog.Logger) {
+	for _, eventLogRecord := range buffer.reset() {
+		if handler := logger.Handler(); handler.Enabled(eventLogRecord.ctx, eventLogRecord.record.Level) {
+			_ = handler.Handle(eventLogRecord.ctx, eventLogRecord.record)
+		}
+	}
+}
+
+// Removes held events from event log buffer, returns removed events
+func (buffer *eventLogBuffer) reset() []eventLogRecord {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	records := buffer.records
+	buffer.records = nil
+
+	return records
+}
+
+// Returns email redacted with email redaction: masked email keeps first character of
+// local part and domain, hashed email is SHA-256 hex digest of normalized email
+func redactEmail(email, emailRedaction string) string {
+	switch emailRedaction {
+	case logEmailRedactionMask:
+		localPart, domain, found := strings.Cut(email, "@")
+		if !found || localPart == emptyString {
+			return logEmailMask
+		}
+		return localPart[:1] + logEmailMask + "@" + domain
+	case logEmailRedactionHash:
+		hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+		return hex.EncodeToString(hash[:])
+	default:
+		return email
+	}
+}
+
+// Returns text with email redacted with email redaction, email is matched case-insensitive.
+// SMTP reply text can echo recipient, so it should be redacted as email log attribute
+func redactEmailInText(text, email, emailRedaction string) string {
+	if email == emptyString || emailRedaction == emptyString || emailRedaction == logEmailRedactionNone {
+		return text
+	}
+
+	return regexp.MustCompile(`(?i)`+regexp.QuoteMeta(email)).ReplaceAllLiteralString(text, redactEmail(email, emailRedaction))
+}
+
+// Returns log level of error: failure level when error is not nil, otherwise success level
+func errorLogLevel(err error, successLevel, failureLevel slog.Level) slog.Level {
+	if err != nil {
+		return failureLevel
+	}
+
+	return successLevel
+}
+
+// Returns error log attribute. Returns empty attribute when error is nil, so it is not logged
+func errorLogAttr(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	return slog.String(logAttrError, err.Error())
+}
+
+// Returns error log attribute, email echoed in error message is redacted with email
+// redaction. Returns empty attribute when error is nil, so it is not logged
+func redactedErrorLogAttr(err error, email, emailRedaction string) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	return slog.String(logAttrError, redactEmailInText(err.Error(), email, emailRedaction))
+}
@@ -0,0 +1,156 @@
+package truemail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEventLogger(t *testing.T) {
+	t.Run("when logger is specified", func(t *testing.T) {
+		logger, _ := createLogger()
+
+		assert.Equal(t, &eventLogger{logger: logger, level: slog.LevelWarn}, newEventLogger(logger, slog.LevelWarn))
+	})
+
+	t.Run("when logger is not specified", func(t *testing.T) {
+		assert.Nil(t, newEventLogger(nil, slog.LevelInfo))
+	})
+}
+
+func TestEventLoggerLog(t *testing.T) {
+	message := "message"
+
+	t.Run("logs event with level not lower than log level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		newEventLogger(logger, slog.LevelInfo).log(context.Background(), slog.LevelWarn, message, slog.String(logAttrDomain, "example.com"))
+		records := logRecords(buffer, message)
+
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.Equal(t, "example.com", records[0][logAttrDomain])
+	})
+
+	t.Run("does not log event with level lower than log level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		newEventLogger(logger, slog.LevelInfo).log(context.Background(), slog.LevelDebug, message)
+
+		assert.Empty(t, logRecords(buffer, message))
+	})
+
+	t.Run("when context is nil", func(t *testing.T) {
+		var nilContext context.Context
+		logger, buffer := createLogger()
+		newEventLogger(logger, slog.LevelDebug).log(nilContext, slog.LevelDebug, message)
+
+		assert.Len(t, logRecords(buffer, message), 1)
+	})
+
+	t.Run("when event logger is nil", func(t *testing.T) {
+		var nilEventLogger *eventLogger
+
+		assert.NotPanics(t, func() { nilEventLogger.log(context.Background(), slog.LevelError, message) })
+	})
+}
+
+func TestRedactEmail(t *testing.T) {
+	email := "Email@example.com"
+
+	t.Run("when email redaction is none", func(t *testing.T) {
+		assert.Equal(t, email, redactEmail(email, logEmailRedactionNone))
+	})
+
+	t.Run("masks local part of email", func(t *testing.T) {
+		assert.Equal(t, "E***@example.com", redactEmail(email, logEmailRedactionMask))
+	})
+
+	t.Run("masks whole invalid email", func(t *testing.T) {
+		assert.Equal(t, logEmailMask, redactEmail("@example.com", logEmailRedactionMask))
+		assert.Equal(t, logEmailMask, redactEmail("email", logEmailRedactionMask))
+	})
+
+	t.Run("hashes normalized email", func(t *testing.T) {
+		hash := sha256.Sum256([]byte("email@example.com"))
+
+		assert.Equal(t, hex.EncodeToString(hash[:]), redactEmail(email, logEmailRedactionHash))
+		assert.Equal(t, redactEmail(email, logEmailRedactionHash), redactEmail(" email@EXAMPLE.com ", logEmailRedactionHash))
+	})
+}
+
+func TestErrorLogLevel(t *testing.T) {
+	t.Run("when error is nil", func(t *testing.T) {
+		assert.Equal(t, slog.LevelDebug, errorLogLevel(nil, slog.LevelDebug, slog.LevelWarn))
+	})
+
+	t.Run("when error is not nil", func(t *testing.T) {
+		assert.Equal(t, slog.LevelWarn, errorLogLevel(errors.New("error"), slog.LevelDebug, slog.LevelWarn))
+	})
+}
+
+func TestErrorLogAttr(t *testing.T) {
+	t.Run("when error is nil", func(t *testing.T) {
+		assert.True(t, errorLogAttr(nil).Equal(slog.Attr{}))
+	})
+
+	t.Run("when error is not nil", func(t *testing.T) {
+		assert.True(t, errorLogAttr(errors.New("error")).Equal(slog.String(logAttrError, "error")))
+	})
+}
+
+func TestEventLoggerLogWithEventLogBuffer(t *testing.T) {
+	t.Run("holds events in event log buffer until flush", func(t *testing.T) {
+		logger, buffer := createLogger()
+		eventLogger, message := newEventLogger(logger, slog.LevelInfo), "message"
+		eventLogger.buffer = new(eventLogBuffer)
+		eventLogger.log(context.Background(), slog.LevelWarn, message, slog.String(logAttrDomain, "first.com"))
+		eventLogger.log(context.Background(), slog.LevelDebug, message)
+		eventLogger.log(context.Background(), slog.LevelInfo, message, slog.String(logAttrDomain, "second.com"))
+
+		assert.Empty(t, logRecords(buffer, message))
+		assert.Len(t, eventLogger.buffer.records, 2)
+
+		eventLogger.buffer.flush(logger)
+		records := logRecords(buffer, message)
+
+		assert.Len(t, records, 2)
+		assert.Equal(t, "first.com", records[0][logAttrDomain])
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.Equal(t, "second.com", records[1][logAttrDomain])
+		assert.Empty(t, eventLogger.buffer.records)
+	})
+}
+
+func TestRedactEmailInText(t *testing.T) {
+	email, text := "John.Doe@example.com", "550 5.1.1 <john.doe@example.com>: user unknown"
+
+	t.Run("when email redaction is not specified", func(t *testing.T) {
+		assert.Equal(t, text, redactEmailInText(text, email, emptyString))
+		assert.Equal(t, text, redactEmailInText(text, email, logEmailRedactionNone))
+	})
+
+	t.Run("when email redaction is specified", func(t *testing.T) {
+		assert.Equal(t, "550 5.1.1 <J***@example.com>: user unknown", redactEmailInText(text, email, logEmailRedactionMask))
+		assert.Equal(t, "550 5.1.1 <"+redactEmail(email, logEmailRedactionHash)+">: user unknown", redactEmailInText(text, email, logEmailRedactionHash))
+	})
+
+	t.Run("when email is not specified", func(t *testing.T) {
+		assert.Equal(t, text, redactEmailInText(text, emptyString, logEmailRedactionMask))
+	})
+}
+
+func TestRedactedErrorLogAttr(t *testing.T) {
+	t.Run("when error is nil", func(t *testing.T) {
+		assert.Equal(t, slog.Attr{}, redactedErrorLogAttr(nil, randomEmail(), logEmailRedactionMask))
+	})
+
+	t.Run("when error is specified", func(t *testing.T) {
+		err := errors.New("550 <email@example.com> unknown")
+
+		assert.Equal(t, slog.String(logAttrError, "550 <e***@example.com> unknown"), redactedErrorLogAttr(err, "email@example.com", logEmailRedactionMask))
+	})
+}
@@ -0,0 +1,40 @@
+package truemail
+
+import "time"
+
+// MetricsCollector interface. Collects validation outcomes by validation type, validation
+// layer and result cache hit, DNS query and SMTP command latencies and number of SMTP sessions
+// in flight. Outcome is valid, invalid or unknown, status is success or failure. Metrics collector
+// is shared across all validations which use the same configuration, so implementation
+// should be safe for concurrent use
+type MetricsCollector interface {
+	ObserveValidation(validationType, layer, outcome string, fromCache bool)
+	ObserveDnsQuery(queryType, status string, duration time.Duration)
+	ObserveSmtpCommand(command, status string, duration time.Duration)
+	AddSmtpSessionsInFlight(delta float64)
+}
+
+// Observes validation outcome of validator result with metrics collector
+// from validator result configuration when metrics collector is specified
+func observeValidation(validatorResult *ValidatorResult) {
+	configuration := validatorResult.Configuration
+	if configuration == nil || configuration.MetricsCollector == nil {
+		return
+	}
+
+	configuration.MetricsCollector.ObserveValidation(
+		validatorResult.ValidationType,
+		validatorResult.validationLayer(),
+		validatorResult.validationOutcome(),
+		validatorResult.FromCache,
+	)
+}
+
+// Returns metrics status of operation by its error
+func metricsStatus(err error) string {
+	if err != nil {
+		return metricsStatusFailure
+	}
+
+	return metricsStatusSuccess
+}
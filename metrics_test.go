@@ -0,0 +1,40 @@
+package truemail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveValidation(t *testing.T) {
+	t.Run("observes validation outcome with metrics collector", func(t *testing.T) {
+		metricsCollector, configuration := new(metricsCollectorMock), createConfiguration()
+		configuration.MetricsCollector = metricsCollector
+		validatorResult := &ValidatorResult{
+			Success:         true,
+			ValidationType:  validationTypeMx,
+			Configuration:   configuration,
+			usedValidations: []string{validationTypeRegex, validationTypeMx},
+		}
+		metricsCollector.On("ObserveValidation", validationTypeMx, validationTypeMx, metricsOutcomeValid, false).Once()
+		observeValidation(validatorResult)
+
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("when metrics collector is not specified", func(t *testing.T) {
+		assert.NotPanics(t, func() { observeValidation(&ValidatorResult{Configuration: createConfiguration()}) })
+		assert.NotPanics(t, func() { observeValidation(new(ValidatorResult)) })
+	})
+}
+
+func TestMetricsStatus(t *testing.T) {
+	t.Run("when operation is successful", func(t *testing.T) {
+		assert.Equal(t, metricsStatusSuccess, metricsStatus(nil))
+	})
+
+	t.Run("when operation is failed", func(t *testing.T) {
+		assert.Equal(t, metricsStatusFailure, metricsStatus(errors.New("error")))
+	})
+}
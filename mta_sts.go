@@ -0,0 +1,249 @@
+package truemail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HttpClient interface. Sends HTTP requests, implemented by *http.Client.
+// Custom HttpClient can be specified in configuration to fetch MTA-STS policies
+type HttpClient interface {
+	Do(request *http.Request) (*http.Response, error)
+}
+
+// MTA-STS result structure (RFC 8461). Includes MTA-STS policy id from DNS TXT record,
+// policy mode, max age, mx patterns, resolved MX hosts which do not match policy
+// mx patterns and policy discovery error
+type MtaStsResult struct {
+	Id, Mode          string
+	MaxAge            int
+	Mx, MismatchedMxs []string
+	Err               error
+}
+
+// MTA-STS policy structure
+type mtaStsPolicy struct {
+	mode   string
+	maxAge int
+	mx     []string
+}
+
+// MTA-STS policy cache item structure. Includes MTA-STS policy id from DNS TXT
+// record, fetched MTA-STS policy and its expiration time by policy max age
+type mtaStsPolicyCacheItem struct {
+	id        string
+	policy    *mtaStsPolicy
+	expiresAt time.Time
+}
+
+// MTA-STS policy cache structure (RFC 8461, section 5.1). Fetched MTA-STS policies by domain,
+// shared across validations which use the same configuration. Cached policy is used until its
+// max age expires or domain publishes new policy id
+type mtaStsPolicyCache struct {
+	items map[string]*mtaStsPolicyCacheItem
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+// mtaStsPolicyCache builder
+func newMtaStsPolicyCache() *mtaStsPolicyCache {
+	return &mtaStsPolicyCache{items: map[string]*mtaStsPolicyCacheItem{}, now: time.Now}
+}
+
+// MTA-STS HTTP client builder. Creates HTTP client with connection timeout,
+// which does not follow redirects according to RFC 8461
+func newMtaStsHttpClient(connectionTimeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: connectionTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Returns MTA-STS policy id from DNS TXT records. Returns false when domain
+// publishes none or more than one MTA-STS TXT record
+func mtaStsPolicyId(txtRecords []string) (policyId string, ok bool) {
+	for _, txtRecord := range txtRecords {
+		fields := strings.Split(txtRecord, ";")
+		if strings.TrimSpace(fields[0]) != mtaStsTxtRecordVersion {
+			continue
+		}
+		if ok {
+			return emptyString, false
+		}
+
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			if key == "id" {
+				policyId, ok = value, value != emptyString
+			}
+		}
+	}
+
+	return policyId, ok
+}
+
+// Fetches MTA-STS policy from well-known policy host of domain via HTTP client,
+// request is canceled when context is done
+func fetchMtaStsPolicy(ctx context.Context, httpClient HttpClient, domain string) (*mtaStsPolicy, error) {
+	request, err := http.NewRequestWithContext(contextOrBackground(ctx), http.MethodGet, fmt.Sprintf(mtaStsPolicyUrl, domain), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mta-sts policy host responded with %s", response.Status)
+	}
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), mtaStsPolicyMediaType) {
+		return nil, fmt.Errorf("mta-sts policy has invalid content type %q", response.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, mtaStsPolicyMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > mtaStsPolicyMaxSize {
+		return nil, fmt.Errorf("mta-sts policy exceeds %d bytes", mtaStsPolicyMaxSize)
+	}
+
+	return parseMtaStsPolicy(body)
+}
+
+// Parses MTA-STS policy body. Returns error for invalid version, mode, max age
+// or when mx patterns are missing for not none policy mode
+func parseMtaStsPolicy(body []byte) (*mtaStsPolicy, error) {
+	policy, version, maxAge := new(mtaStsPolicy), emptyString, emptyString
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			policy.mode = value
+		case "max_age":
+			maxAge = value
+		case "mx":
+			policy.mx = append(policy.mx, strings.ToLower(value))
+		}
+	}
+
+	if version != mtaStsPolicyVersion {
+		return nil, fmt.Errorf("%s is invalid mta-sts policy version", version)
+	}
+	if !isIncluded([]string{mtaStsModeEnforce, mtaStsModeTesting, mtaStsModeNone}, policy.mode) {
+		return nil, fmt.Errorf("%s is invalid mta-sts policy mode", policy.mode)
+	}
+
+	var err error
+	policy.maxAge, err = strconv.Atoi(maxAge)
+	if err != nil || policy.maxAge < 0 || policy.maxAge > mtaStsPolicyMaxAgeLimit {
+		return nil, fmt.Errorf("%s is invalid mta-sts policy max age", maxAge)
+	}
+
+	if policy.mode != mtaStsModeNone && len(policy.mx) == 0 {
+		return nil, fmt.Errorf("mta-sts policy includes no mx patterns")
+	}
+
+	return policy, nil
+}
+
+// mtaStsPolicy methods
+
+// Returns true if MX host name matches one of policy mx patterns. Wildcard pattern
+// matches exactly one leftmost label, for example *.example.com matches mx.example.com
+func (policy *mtaStsPolicy) isMatched(mxHostName string) bool {
+	mxHostName = strings.ToLower(strings.TrimSuffix(mxHostName, "."))
+
+	for _, pattern := range policy.mx {
+		if pattern == mxHostName {
+			return true
+		}
+
+		if suffix, isWildcard := strings.CutPrefix(pattern, "*."); isWildcard {
+			label, domain, found := strings.Cut(mxHostName, ".")
+			if found && label != emptyString && domain == suffix {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns true if MTA-STS policy is in enforce mode
+func (policy *mtaStsPolicy) isEnforced() bool {
+	return policy.mode == mtaStsModeEnforce
+}
+
+// mtaStsPolicyCache methods
+
+// Returns cached MTA-STS policy of domain when it has the same policy id and its max age
+// is not expired, otherwise returns false. Returns false for nil MTA-STS policy cache
+func (cache *mtaStsPolicyCache) get(domain, policyId string) (*mtaStsPolicy, bool) {
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	item, ok := cache.items[domain]
+	if !ok || item.id != policyId || !cache.now().Before(item.expiresAt) {
+		return nil, false
+	}
+
+	return item.policy, true
+}
+
+// Stores MTA-STS policy of domain with policy id until its max age expires. When cache size
+// is reached removes expired policies. Does nothing for nil MTA-STS policy cache or zero max age
+func (cache *mtaStsPolicyCache) set(domain, policyId string, policy *mtaStsPolicy) {
+	if cache == nil || policy.maxAge == 0 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	if _, ok := cache.items[domain]; !ok && len(cache.items) >= mtaStsPolicyCacheSize {
+		cache.removeExpiredItems(now)
+	}
+
+	cache.items[domain] = &mtaStsPolicyCacheItem{
+		id:        policyId,
+		policy:    policy,
+		expiresAt: now.Add(time.Duration(policy.maxAge) * time.Second),
+	}
+}
+
+// Removes MTA-STS policies with expired max age
+func (cache *mtaStsPolicyCache) removeExpiredItems(now time.Time) {
+	for domain, item := range cache.items {
+		if !now.Before(item.expiresAt) {
+			delete(cache.items, domain)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package truemail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMtaStsHttpClient(t *testing.T) {
+	t.Run("creates HTTP client with connection timeout", func(t *testing.T) {
+		connectionTimeout := time.Duration(randomPositiveNumber()) * time.Second
+
+		assert.Equal(t, connectionTimeout, newMtaStsHttpClient(connectionTimeout).Timeout)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		server := httptest.NewServer(http.RedirectHandler("/other", http.StatusMovedPermanently))
+		defer server.Close()
+		response, err := newMtaStsHttpClient(time.Second).Get(server.URL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusMovedPermanently, response.StatusCode)
+	})
+}
+
+func TestMtaStsPolicyId(t *testing.T) {
+	t.Run("when MTA-STS TXT record exists", func(t *testing.T) {
+		policyId, ok := mtaStsPolicyId([]string{"v=spf1 -all", "v=STSv1; id=20160831085700Z;"})
+
+		assert.True(t, ok)
+		assert.Equal(t, "20160831085700Z", policyId)
+	})
+
+	t.Run("when MTA-STS TXT record not exists", func(t *testing.T) {
+		_, ok := mtaStsPolicyId([]string{"v=spf1 -all"})
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when MTA-STS TXT record without id", func(t *testing.T) {
+		_, ok := mtaStsPolicyId([]string{"v=STSv1;"})
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when more than one MTA-STS TXT record exists", func(t *testing.T) {
+		_, ok := mtaStsPolicyId([]string{"v=STSv1; id=1", "v=STSv1; id=2"})
+
+		assert.False(t, ok)
+	})
+}
+
+func TestFetchMtaStsPolicy(t *testing.T) {
+	domain := randomDomain()
+
+	t.Run("when valid MTA-STS policy", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "mx.example.com"))
+		defer stop()
+		policy, err := fetchMtaStsPolicy(context.Background(), httpClient, domain)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &mtaStsPolicy{mode: mtaStsModeEnforce, maxAge: 86400, mx: []string{"mx.example.com"}}, policy)
+		assert.Equal(t, []string{"https://mta-sts." + domain + "/.well-known/mta-sts.txt"}, httpClient.urls)
+	})
+
+	t.Run("when policy host responded with not 200 status", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		_, err := fetchMtaStsPolicy(context.Background(), &httpClientStandIn{server: server}, domain)
+
+		assert.EqualError(t, err, "mta-sts policy host responded with 404 Not Found")
+	})
+
+	t.Run("when policy has invalid content type", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
+		}))
+		defer server.Close()
+		_, err := fetchMtaStsPolicy(context.Background(), &httpClientStandIn{server: server}, domain)
+
+		assert.EqualError(t, err, `mta-sts policy has invalid content type "text/html"`)
+	})
+
+	t.Run("when policy exceeds max size", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(strings.Repeat("a", mtaStsPolicyMaxSize+1))
+		defer stop()
+		_, err := fetchMtaStsPolicy(context.Background(), httpClient, domain)
+
+		assert.EqualError(t, err, "mta-sts policy exceeds 65536 bytes")
+	})
+
+	t.Run("when context is done", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "mx.example.com"))
+		defer stop()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := fetchMtaStsPolicy(ctx, httpClient, domain)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("when policy host is unreachable", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		server.Close()
+		_, err := fetchMtaStsPolicy(context.Background(), &httpClientStandIn{server: server}, domain)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestParseMtaStsPolicy(t *testing.T) {
+	t.Run("when valid policy with LF line endings", func(t *testing.T) {
+		policy, err := parseMtaStsPolicy([]byte("version: STSv1\nmode: testing\nmx: MX1.example.com\nmx: *.example.net\nmax_age: 604800\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, &mtaStsPolicy{mode: mtaStsModeTesting, maxAge: 604800, mx: []string{"mx1.example.com", "*.example.net"}}, policy)
+	})
+
+	t.Run("when valid policy in none mode without mx patterns", func(t *testing.T) {
+		policy, err := parseMtaStsPolicy([]byte(createMtaStsPolicy(mtaStsModeNone)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, mtaStsModeNone, policy.mode)
+	})
+
+	t.Run("when invalid version", func(t *testing.T) {
+		_, err := parseMtaStsPolicy([]byte("version: STSv2\nmode: enforce\nmax_age: 1\nmx: mx.example.com"))
+
+		assert.EqualError(t, err, "STSv2 is invalid mta-sts policy version")
+	})
+
+	t.Run("when invalid mode", func(t *testing.T) {
+		_, err := parseMtaStsPolicy([]byte(createMtaStsPolicy("strict", "mx.example.com")))
+
+		assert.EqualError(t, err, "strict is invalid mta-sts policy mode")
+	})
+
+	t.Run("when invalid max age", func(t *testing.T) {
+		_, err := parseMtaStsPolicy([]byte("version: STSv1\nmode: enforce\nmax_age: 31557601\nmx: mx.example.com"))
+
+		assert.EqualError(t, err, "31557601 is invalid mta-sts policy max age")
+	})
+
+	t.Run("when mx patterns are missing", func(t *testing.T) {
+		_, err := parseMtaStsPolicy([]byte(createMtaStsPolicy(mtaStsModeEnforce)))
+
+		assert.EqualError(t, err, "mta-sts policy includes no mx patterns")
+	})
+}
+
+func TestMtaStsPolicyIsMatched(t *testing.T) {
+	policy := &mtaStsPolicy{mx: []string{"mx.example.com", "*.example.net"}}
+
+	t.Run("when MX host name matches exact pattern", func(t *testing.T) {
+		assert.True(t, policy.isMatched("MX.example.com."))
+	})
+
+	t.Run("when MX host name matches wildcard pattern", func(t *testing.T) {
+		assert.True(t, policy.isMatched("mx1.example.net"))
+	})
+
+	t.Run("when wildcard pattern matches more than one label", func(t *testing.T) {
+		assert.False(t, policy.isMatched("mx1.mail.example.net"))
+	})
+
+	t.Run("when MX host name does not match patterns", func(t *testing.T) {
+		assert.False(t, policy.isMatched("example.net"))
+		assert.False(t, policy.isMatched("mx.example.org"))
+	})
+}
+
+func TestMtaStsPolicyIsEnforced(t *testing.T) {
+	t.Run("when enforce mode", func(t *testing.T) {
+		assert.True(t, (&mtaStsPolicy{mode: mtaStsModeEnforce}).isEnforced())
+	})
+
+	t.Run("when other mode", func(t *testing.T) {
+		assert.False(t, (&mtaStsPolicy{mode: mtaStsModeTesting}).isEnforced())
+	})
+}
+
+func TestNewMtaStsPolicyCache(t *testing.T) {
+	t.Run("creates empty MTA-STS policy cache", func(t *testing.T) {
+		cache := newMtaStsPolicyCache()
+
+		assert.Empty(t, cache.items)
+		assert.NotNil(t, cache.now)
+	})
+}
+
+func TestMtaStsPolicyCacheGet(t *testing.T) {
+	domain, policy := randomDomain(), &mtaStsPolicy{mode: mtaStsModeEnforce, maxAge: 60, mx: []string{"mx.example.com"}}
+
+	t.Run("when policy found and not expired", func(t *testing.T) {
+		cache := newMtaStsPolicyCache()
+		cache.set(domain, "42", policy)
+		cachedPolicy, ok := cache.get(domain, "42")
+
+		assert.True(t, ok)
+		assert.Same(t, policy, cachedPolicy)
+	})
+
+	t.Run("when policy not found", func(t *testing.T) {
+		_, ok := newMtaStsPolicyCache().get(domain, "42")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when policy id is changed", func(t *testing.T) {
+		cache := newMtaStsPolicyCache()
+		cache.set(domain, "42", policy)
+		_, ok := cache.get(domain, "43")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when policy max age is expired", func(t *testing.T) {
+		currentTime, cache := time.Now(), newMtaStsPolicyCache()
+		cache.now = func() time.Time { return currentTime }
+		cache.set(domain, "42", policy)
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		_, ok := cache.get(domain, "42")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when MTA-STS policy cache is nil", func(t *testing.T) {
+		var cache *mtaStsPolicyCache
+		_, ok := cache.get(domain, "42")
+
+		assert.False(t, ok)
+	})
+}
+
+func TestMtaStsPolicyCacheSet(t *testing.T) {
+	domain, policy := randomDomain(), &mtaStsPolicy{mode: mtaStsModeEnforce, maxAge: 60, mx: []string{"mx.example.com"}}
+
+	t.Run("stores policy until its max age expires", func(t *testing.T) {
+		currentTime, cache := time.Now(), newMtaStsPolicyCache()
+		cache.now = func() time.Time { return currentTime }
+		cache.set(domain, "42", policy)
+
+		assert.Equal(t, &mtaStsPolicyCacheItem{id: "42", policy: policy, expiresAt: currentTime.Add(time.Minute)}, cache.items[domain])
+	})
+
+	t.Run("when policy max age is zero", func(t *testing.T) {
+		cache := newMtaStsPolicyCache()
+		cache.set(domain, "42", &mtaStsPolicy{mode: mtaStsModeNone})
+
+		assert.Empty(t, cache.items)
+	})
+
+	t.Run("when cache size reached removes expired policies", func(t *testing.T) {
+		currentTime, cache := time.Now(), newMtaStsPolicyCache()
+		cache.now = func() time.Time { return currentTime }
+		for index := range mtaStsPolicyCacheSize {
+			cache.set(strconv.Itoa(index), "42", policy)
+		}
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		cache.set(domain, "42", policy)
+
+		assert.Len(t, cache.items, 1)
+		assert.Contains(t, cache.items, domain)
+	})
+
+	t.Run("when MTA-STS policy cache is nil", func(t *testing.T) {
+		var cache *mtaStsPolicyCache
+
+		assert.NotPanics(t, func() { cache.set(domain, "42", policy) })
+	})
+}
@@ -13,11 +13,15 @@ type resolver interface {
 	cnameRecord(string) (string, error)
 	ptrRecords(string) ([]string, error)
 	mxRecords(string) ([]uint16, []string, error)
+	txtRecords(string) ([]string, error)
+	tlsaRecords(string) ([]*TlsaRecord, bool, error)
+	dnsQueries() []*DnsQuery
 }
 
 // DNS (MX) validation, second validation level
 type validationMx struct {
-	result *ValidatorResult
+	result       *ValidatorResult
+	isDnsFailure bool
 	resolver
 }
 
@@ -27,10 +31,13 @@ func (validation *validationMx) check(validatorResult *ValidatorResult) *Validat
 	validation.setValidatorResultPunycodeRepresentation()
 	validation.initDnsResolver()
 	validation.runMxLookup()
+	validation.runMtaStsCheck()
+	validation.runDaneCheck()
+	validation.setValidatorResultDnsDebug()
 
 	if validation.isMailServerNotFound() {
 		validatorResult.Success = false
-		validatorResult.addError(validationTypeMx, mxErrorContext)
+		validatorResult.addError(validationTypeMx, validation.errorContext())
 	}
 
 	return validatorResult
@@ -59,6 +66,24 @@ func (validation *validationMx) initDnsResolver() {
 	validation.resolver = newDnsResolver(validation.result.Configuration)
 }
 
+// Addes DNS queries sent by resolver to validatorResult.DnsDebug
+func (validation *validationMx) setValidatorResultDnsDebug() {
+	validation.result.DnsDebug = append(validation.result.DnsDebug, validation.resolver.dnsQueries()...)
+}
+
+// Returns MX validation error context. DNS failure error context is used when
+// DNS server failed to answer (SERVFAIL, REFUSED, timeout), so target domain existence is unknown
+func (validation *validationMx) errorContext() string {
+	if validation.isDnsFailure {
+		return mxDnsFailureErrorContext
+	}
+	if mtaSts := validation.result.MtaSts; mtaSts != nil && len(mtaSts.MismatchedMxs) > 0 {
+		return mxMtaStsErrorContext
+	}
+
+	return mxErrorContext
+}
+
 // Returns true if validatorResult contains no mail servers, otherwise returns false
 func (validation *validationMx) isMailServerNotFound() bool {
 	return len(validation.result.MailServers) == 0
@@ -86,6 +111,12 @@ func (validation *validationMx) isDnsNotFoundError(err error) bool {
 	return ok && e.isDnsNotFound
 }
 
+// Casts is wrapped error is an DnsFailure error
+func (validation *validationMx) isDnsFailureError(err error) bool {
+	e, ok := err.(*validationError)
+	return ok && e.isDnsFailure
+}
+
 // Casts is wrapped error is an NullMxError error
 func (validation *validationMx) isNullMxError(err error) bool {
 	e, ok := err.(*validationError)
@@ -137,12 +168,16 @@ func (validation *validationMx) hostsFromMxRecords(hostName string) (resolvedIpA
 	}
 
 	// Resolves host addresses by MX hostname
-	for _, hostName := range hostNames {
+	for index, hostName := range hostNames {
 		ipAddresses, err = validation.aRecords(hostName)
 		if err != nil {
 			continue
 		}
 
+		for _, ipAddress := range ipAddresses {
+			validation.result.addMailServerHostName(ipAddress, hostName)
+			validation.result.addMailServerPriority(ipAddress, priorities[index])
+		}
 		resolvedIpAddresses = append(resolvedIpAddresses, ipAddresses...)
 	}
 
@@ -250,6 +285,7 @@ func (validation *validationMx) runMxLookup() {
 	}
 
 	if validation.isNullMxError(err) || validation.result.Configuration.NotRfcMxLookupFlow {
+		validation.isDnsFailure = validation.isDnsFailureError(err)
 		return
 	}
 
@@ -266,4 +302,98 @@ func (validation *validationMx) runMxLookup() {
 		validation.fetchTargetHosts(hostAddress)
 		return
 	}
+
+	validation.isDnsFailure = validation.isDnsFailureError(err)
+}
+
+// Returns true if MTA-STS check is enabled, otherwise returns false
+func (validation *validationMx) isMtaStsCheckEnabled() bool {
+	return validation.result.Configuration.MtaStsCheck
+}
+
+// Discovers MTA-STS policy of target domain when MTA-STS check is enabled and mail servers
+// were found. Assigns MTA-STS result to validatorResult. Domain without MTA-STS TXT record
+// has no policy. Fetched policy is cached until its max age expires or policy id is changed.
+// For policy in enforce mode keeps only mail servers matched policy mx patterns
+func (validation *validationMx) runMtaStsCheck() {
+	if !validation.isMtaStsCheckEnabled() || validation.isMailServerNotFound() {
+		return
+	}
+
+	domain := validation.result.punycodeDomain
+	txtRecords, err := validation.resolver.txtRecords(mtaStsTxtRecordPrefix + domain)
+	if err != nil {
+		return
+	}
+
+	policyId, ok := mtaStsPolicyId(txtRecords)
+	if !ok {
+		return
+	}
+
+	mtaSts, configuration := &MtaStsResult{Id: policyId}, validation.result.Configuration
+	validation.result.MtaSts = mtaSts
+	policy, ok := configuration.mtaStsPolicyCache.get(domain, policyId)
+	if !ok {
+		policy, err = fetchMtaStsPolicy(configuration.ctx, configuration.MtaStsHttpClient, domain)
+		if err != nil {
+			mtaSts.Err = err
+			return
+		}
+		configuration.mtaStsPolicyCache.set(domain, policyId, policy)
+	}
+
+	mtaSts.Mode, mtaSts.MaxAge, mtaSts.Mx = policy.mode, policy.maxAge, copyStrings(policy.mx)
+	if policy.mode == mtaStsModeNone {
+		return
+	}
+
+	var matchedMailServers []string
+	for _, mailServer := range validation.result.MailServers {
+		hostName := validation.result.mailServerHostName(mailServer)
+		if hostName != emptyString && policy.isMatched(hostName) {
+			matchedMailServers = append(matchedMailServers, mailServer)
+			continue
+		}
+		if hostName == emptyString {
+			hostName = mailServer
+		}
+		mtaSts.MismatchedMxs = append(mtaSts.MismatchedMxs, sliceDiff([]string{hostName}, mtaSts.MismatchedMxs)...)
+	}
+
+	if policy.isEnforced() {
+		validation.result.MailServers = matchedMailServers
+	}
+}
+
+// Returns true if DANE check is enabled for SMTP validation, otherwise returns false
+func (validation *validationMx) isDaneCheckEnabled() bool {
+	return validation.result.Configuration.DaneCheck && validation.result.ValidationType == validationTypeSmtp
+}
+
+// Looks up DANE TLSA records for each MX host of mail servers when DANE check is enabled.
+// Assigns DANE result of each MX host to validatorResult. MX host without TLSA records
+// has DANE result without records, DNS failure is recorded as DANE result error
+func (validation *validationMx) runDaneCheck() {
+	if !validation.isDaneCheckEnabled() {
+		return
+	}
+
+	for _, mailServer := range validation.result.MailServers {
+		hostName := validation.result.mailServerHostName(mailServer)
+		if hostName == emptyString || validation.result.daneResults[hostName] != nil {
+			continue
+		}
+
+		daneResult := &DaneResult{HostName: hostName}
+		tlsaName := daneTlsaName(validation.result.Configuration.SmtpPort, hostName)
+		tlsaRecords, authenticated, err := validation.resolver.tlsaRecords(tlsaName)
+		switch {
+		case err == nil:
+			daneResult.TlsaRecords, daneResult.Authenticated = tlsaRecords, authenticated
+		case !validation.isDnsNotFoundError(err):
+			daneResult.Err = err
+		}
+		validation.result.addDaneResult(daneResult)
+	}
 }
@@ -1,18 +1,21 @@
 package truemail
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"testing"
 
 	"github.com/foxcpp/go-mockdns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestValidationMxCheck(t *testing.T) {
 	targetUserName, targetHostName := "niña@", "mañana.com"
 	targetEmail := targetUserName + targetHostName
 	configuration := createConfiguration()
+	configuration.DnsCache = nil
 
 	t.Run("MX validation: successful, servers extracted by MX records resolver", func(t *testing.T) {
 		mxHostnameFirst, mxHostnameSecond := randomDnsHostName(), randomDnsHostName()
@@ -46,6 +49,9 @@ func TestValidationMxCheck(t *testing.T) {
 		assert.Equal(t, punycodeDomain(targetHostName), validatorResult.punycodeDomain)
 		assert.Equal(t, targetUserName+punycodeDomain(targetHostName), validatorResult.punycodeEmail)
 		assert.Equal(t, []string{resolvedIpAddressFirst, resolvedIpAddressSecond}, validatorResult.MailServers)
+		assert.Len(t, validatorResult.DnsDebug, 3)
+		assert.Equal(t, "MX", validatorResult.DnsDebug[0].Type)
+		assert.Equal(t, punycodeDomain(targetHostName), validatorResult.DnsDebug[0].Name)
 	})
 
 	t.Run("MX validation: successful, servers extracted by CNAME record resolver", func(t *testing.T) {
@@ -206,6 +212,19 @@ func TestValidationMxInitDnsResolver(t *testing.T) {
 	})
 }
 
+func TestValidationMxSetValidatorResultDnsDebug(t *testing.T) {
+	t.Run("addes resolver DNS queries to validator result", func(t *testing.T) {
+		resolver, existingDnsQuery, dnsQuery := new(dnsResolverMock), &DnsQuery{Type: "MX"}, &DnsQuery{Type: "A"}
+		validatorResult := &ValidatorResult{DnsDebug: []*DnsQuery{existingDnsQuery}}
+		validation := &validationMx{result: validatorResult, resolver: resolver}
+		resolver.On("dnsQueries").Once().Return([]*DnsQuery{dnsQuery})
+		validation.setValidatorResultDnsDebug()
+
+		resolver.AssertExpectations(t)
+		assert.Equal(t, []*DnsQuery{existingDnsQuery, dnsQuery}, validatorResult.DnsDebug)
+	})
+}
+
 func TestValidationMxIsMailServerNotFound(t *testing.T) {
 	t.Run("when mail servers none", func(t *testing.T) {
 		validation := &validationMx{result: &ValidatorResult{}}
@@ -331,6 +350,8 @@ func TestValidationMxHostsFromMxRecords(t *testing.T) {
 		resolver.AssertExpectations(t)
 		assert.Equal(t, ipAddresses, resolvedIpAddresses)
 		assert.NoError(t, err)
+		assert.Equal(t, hostNames[0], validatorResult.mailServerHostName(ipAddresses[0]))
+		assert.Equal(t, priorities[0], validatorResult.mailServerPriority(ipAddresses[0]))
 	})
 
 	t.Run("when null MX records was found", func(t *testing.T) {
@@ -680,4 +701,259 @@ func TestValidationMxRunMxLookup(t *testing.T) {
 		resolver.AssertExpectations(t)
 		assert.Empty(t, validatorResult.MailServers)
 	})
+
+	t.Run("when mail servers not found because of DNS failure", func(t *testing.T) {
+		dnsFailureError := wrapDnsError(&DnsResponseError{Name: hostName, Rcode: "SERVFAIL"})
+		validatorResult, resolver := createSuccessfulValidatorResult(email, configuration), new(dnsResolverMock)
+		validatorResult.punycodeDomain = hostName
+		validation := &validationMx{result: validatorResult, resolver: resolver}
+
+		resolver.On("mxRecords", hostName).Return([]uint16{}, []string(nil), dnsFailureError)
+		resolver.On("cnameRecord", hostName).Return("", dnsFailureError)
+		resolver.On("aRecord", hostName).Return("", dnsFailureError)
+		validation.runMxLookup()
+		resolver.AssertExpectations(t)
+		assert.Empty(t, validatorResult.MailServers)
+		assert.True(t, validation.isDnsFailure)
+	})
+
+	t.Run("when mail servers not found by MX records because of DNS failure, not RFC MX lookup flow enabled", func(t *testing.T) {
+		otherConfiguration := copyConfigurationByPointer(configuration)
+		otherConfiguration.NotRfcMxLookupFlow = true
+		dnsFailureError := wrapDnsError(&DnsResponseError{Name: hostName, Rcode: "REFUSED"})
+		validatorResult, resolver := createSuccessfulValidatorResult(email, otherConfiguration), new(dnsResolverMock)
+		validatorResult.punycodeDomain = hostName
+		validation := &validationMx{result: validatorResult, resolver: resolver}
+
+		resolver.On("mxRecords", hostName).Return([]uint16{}, []string(nil), dnsFailureError)
+		validation.runMxLookup()
+		resolver.AssertExpectations(t)
+		assert.True(t, validation.isDnsFailure)
+	})
+}
+
+func TestValidationMxIsDnsFailureError(t *testing.T) {
+	t.Run("when DNS failure error", func(t *testing.T) {
+		assert.True(t, new(validationMx).isDnsFailureError(&validationError{isDnsFailure: true}))
+	})
+
+	t.Run("when another error", func(t *testing.T) {
+		assert.False(t, new(validationMx).isDnsFailureError(createDnsNotFoundError()))
+	})
+}
+
+func TestValidationMxErrorContext(t *testing.T) {
+	t.Run("when DNS failure", func(t *testing.T) {
+		assert.Equal(t, mxDnsFailureErrorContext, (&validationMx{isDnsFailure: true}).errorContext())
+	})
+
+	t.Run("when MX hosts do not match MTA-STS policy", func(t *testing.T) {
+		validatorResult := &ValidatorResult{MtaSts: &MtaStsResult{MismatchedMxs: []string{randomDomain()}}}
+
+		assert.Equal(t, mxMtaStsErrorContext, (&validationMx{result: validatorResult}).errorContext())
+	})
+
+	t.Run("when mail servers not found", func(t *testing.T) {
+		assert.Equal(t, mxErrorContext, (&validationMx{result: new(ValidatorResult)}).errorContext())
+	})
+}
+
+func TestValidationMxRunMtaStsCheck(t *testing.T) {
+	email := randomEmail()
+	domain := emailDomain(email)
+	matchedIpAddress, mismatchedIpAddress, fallbackIpAddress := randomIpAddress(), randomIpAddress(), randomIpAddress()
+	createValidation := func(httpClient HttpClient, resolver resolver) *validationMx {
+		configuration := createConfiguration()
+		configuration.MtaStsCheck, configuration.MtaStsHttpClient = true, httpClient
+		validatorResult := createSuccessfulValidatorResult(email, configuration)
+		validatorResult.punycodeDomain = domain
+		validatorResult.MailServers = []string{matchedIpAddress, mismatchedIpAddress, fallbackIpAddress}
+		validatorResult.addMailServerHostName(matchedIpAddress, "mx1."+domain)
+		validatorResult.addMailServerHostName(mismatchedIpAddress, "mx.other.com")
+
+		return &validationMx{result: validatorResult, resolver: resolver}
+	}
+
+	t.Run("when MTA-STS policy in enforce mode, keeps matched mail servers only", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "*."+domain))
+		defer stop()
+		resolver := new(dnsResolverMock)
+		validation := createValidation(httpClient, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=STSv1; id=42"}, nil)
+		validation.runMtaStsCheck()
+		resolver.AssertExpectations(t)
+		assert.Equal(t, []string{matchedIpAddress}, validation.result.MailServers)
+		assert.Equal(
+			t,
+			&MtaStsResult{
+				Id:            "42",
+				Mode:          mtaStsModeEnforce,
+				MaxAge:        86400,
+				Mx:            []string{"*." + domain},
+				MismatchedMxs: []string{"mx.other.com", fallbackIpAddress},
+			},
+			validation.result.MtaSts,
+		)
+		assert.True(t, validation.result.isStartTlsRequired())
+	})
+
+	t.Run("when MTA-STS policy in testing mode, reports mismatched MX hosts only", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeTesting, "*."+domain))
+		defer stop()
+		resolver := new(dnsResolverMock)
+		validation := createValidation(httpClient, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=STSv1; id=42"}, nil)
+		validation.runMtaStsCheck()
+		assert.Equal(t, []string{matchedIpAddress, mismatchedIpAddress, fallbackIpAddress}, validation.result.MailServers)
+		assert.Equal(t, []string{"mx.other.com", fallbackIpAddress}, validation.result.MtaSts.MismatchedMxs)
+		assert.False(t, validation.result.isStartTlsRequired())
+	})
+
+	t.Run("when MTA-STS policy in none mode", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeNone))
+		defer stop()
+		resolver := new(dnsResolverMock)
+		validation := createValidation(httpClient, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=STSv1; id=42"}, nil)
+		validation.runMtaStsCheck()
+		assert.Len(t, validation.result.MailServers, 3)
+		assert.Equal(t, mtaStsModeNone, validation.result.MtaSts.Mode)
+		assert.Empty(t, validation.result.MtaSts.MismatchedMxs)
+	})
+
+	t.Run("when MTA-STS policy fetching failed", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn("invalid policy")
+		defer stop()
+		resolver := new(dnsResolverMock)
+		validation := createValidation(httpClient, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=STSv1; id=42"}, nil)
+		validation.runMtaStsCheck()
+		assert.Len(t, validation.result.MailServers, 3)
+		assert.Equal(t, "42", validation.result.MtaSts.Id)
+		assert.EqualError(t, validation.result.MtaSts.Err, " is invalid mta-sts policy version")
+	})
+
+	t.Run("uses cached MTA-STS policy until policy id is changed", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "*."+domain))
+		defer stop()
+		resolver := new(dnsResolverMock)
+		validation := createValidation(httpClient, resolver)
+		configuration := validation.result.Configuration
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Twice().Return([]string{"v=STSv1; id=42"}, nil)
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=STSv1; id=43"}, nil)
+		validation.runMtaStsCheck()
+		cachedValidation := createValidation(httpClient, resolver)
+		cachedValidation.result.Configuration = configuration
+		cachedValidation.runMtaStsCheck()
+		changedValidation := createValidation(httpClient, resolver)
+		changedValidation.result.Configuration = configuration
+		changedValidation.runMtaStsCheck()
+		resolver.AssertExpectations(t)
+		assert.Len(t, httpClient.urls, 2)
+		assert.Equal(t, validation.result.MtaSts, cachedValidation.result.MtaSts)
+		assert.Equal(t, []string{matchedIpAddress}, cachedValidation.result.MailServers)
+		assert.Equal(t, "43", changedValidation.result.MtaSts.Id)
+	})
+
+	t.Run("when MTA-STS TXT record not found", func(t *testing.T) {
+		resolver := new(dnsResolverMock)
+		validation := createValidation(nil, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string(nil), createDnsNotFoundError())
+		validation.runMtaStsCheck()
+		resolver.AssertExpectations(t)
+		assert.Nil(t, validation.result.MtaSts)
+	})
+
+	t.Run("when TXT records include no MTA-STS record", func(t *testing.T) {
+		resolver := new(dnsResolverMock)
+		validation := createValidation(nil, resolver)
+
+		resolver.On("txtRecords", "_mta-sts."+domain).Once().Return([]string{"v=spf1 -all"}, nil)
+		validation.runMtaStsCheck()
+		assert.Nil(t, validation.result.MtaSts)
+	})
+
+	t.Run("when MTA-STS check is disabled", func(t *testing.T) {
+		resolver := new(dnsResolverMock)
+		validation := createValidation(nil, resolver)
+		validation.result.Configuration.MtaStsCheck = false
+
+		validation.runMtaStsCheck()
+		resolver.AssertNotCalled(t, "txtRecords", mock.Anything)
+		assert.Nil(t, validation.result.MtaSts)
+	})
+}
+
+func TestValidationMxIsDaneCheckEnabled(t *testing.T) {
+	createValidation := func(daneCheck bool, validationType string) *validationMx {
+		configuration := createConfiguration()
+		configuration.DaneCheck = daneCheck
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.ValidationType = validationType
+
+		return &validationMx{result: validatorResult}
+	}
+
+	t.Run("when DANE check enabled for SMTP validation", func(t *testing.T) {
+		assert.True(t, createValidation(true, validationTypeSmtp).isDaneCheckEnabled())
+	})
+
+	t.Run("when DANE check enabled for MX validation", func(t *testing.T) {
+		assert.False(t, createValidation(true, validationTypeMx).isDaneCheckEnabled())
+	})
+
+	t.Run("when DANE check disabled", func(t *testing.T) {
+		assert.False(t, createValidation(false, validationTypeSmtp).isDaneCheckEnabled())
+	})
+}
+
+func TestValidationMxRunDaneCheck(t *testing.T) {
+	domain := randomDomain()
+	firstHostName, secondHostName, thirdHostName := "mx1."+domain, "mx2."+domain, "mx3."+domain
+	firstIpAddress, secondIpAddress, thirdIpAddress, fallbackIpAddress := randomIpAddress(), randomIpAddress(), randomIpAddress(), randomIpAddress()
+	tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256}}
+	createValidation := func(resolver resolver) *validationMx {
+		configuration := createConfiguration()
+		configuration.DaneCheck = true
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.ValidationType = validationTypeSmtp
+		validatorResult.MailServers = []string{firstIpAddress, secondIpAddress, thirdIpAddress, fallbackIpAddress}
+		validatorResult.addMailServerHostName(firstIpAddress, firstHostName)
+		validatorResult.addMailServerHostName(secondIpAddress, secondHostName)
+		validatorResult.addMailServerHostName(thirdIpAddress, thirdHostName)
+
+		return &validationMx{result: validatorResult, resolver: resolver}
+	}
+
+	t.Run("assigns DANE result of each MX host", func(t *testing.T) {
+		resolver, dnsFailureError := new(dnsResolverMock), &validationError{isDnsFailure: true, err: errors.New("error")}
+		validation := createValidation(resolver)
+
+		resolver.On("tlsaRecords", "_25._tcp."+firstHostName).Once().Return(tlsaRecords, true, nil)
+		resolver.On("tlsaRecords", "_25._tcp."+secondHostName).Once().Return([]*TlsaRecord(nil), false, &validationError{isDnsNotFound: true})
+		resolver.On("tlsaRecords", "_25._tcp."+thirdHostName).Once().Return([]*TlsaRecord(nil), false, dnsFailureError)
+		validation.runDaneCheck()
+		resolver.AssertExpectations(t)
+
+		assert.Equal(t, &DaneResult{HostName: firstHostName, TlsaRecords: tlsaRecords, Authenticated: true}, validation.result.daneResult(firstIpAddress))
+		assert.Equal(t, &DaneResult{HostName: secondHostName}, validation.result.daneResult(secondIpAddress))
+		assert.Equal(t, &DaneResult{HostName: thirdHostName, Err: dnsFailureError}, validation.result.daneResult(thirdIpAddress))
+		assert.Nil(t, validation.result.daneResult(fallbackIpAddress))
+	})
+
+	t.Run("when DANE check disabled", func(t *testing.T) {
+		resolver := new(dnsResolverMock)
+		validation := createValidation(resolver)
+		validation.result.Configuration.DaneCheck = false
+		validation.runDaneCheck()
+
+		resolver.AssertNotCalled(t, "tlsaRecords", "_25._tcp."+firstHostName)
+		assert.Empty(t, validation.result.daneResults)
+	})
 }
@@ -0,0 +1,113 @@
+// Package prometheus provides Prometheus implementation of truemail.MetricsCollector.
+// It is kept out of truemail package, so Prometheus client is not required to use truemail
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "truemail"
+
+// Prometheus metrics collector structure. Exposes validations counter by validation
+// type, validation layer, outcome and result cache hit, DNS query latency histogram by query type and
+// status, SMTP command latency histogram by command and status and SMTP sessions
+// in flight gauge
+type MetricsCollector struct {
+	validations          *prometheus.CounterVec
+	dnsQueryDuration     *prometheus.HistogramVec
+	smtpCommandDuration  *prometheus.HistogramVec
+	smtpSessionsInFlight prometheus.Gauge
+}
+
+// NewMetricsCollector returns Prometheus metrics collector with metrics registered
+// in given registerer. Uses prometheus.DefaultRegisterer when registerer is nil. Returns
+// error when metrics can not be registered, for example when they are already registered
+func NewMetricsCollector(registerer prometheus.Registerer) (*MetricsCollector, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	metricsCollector := &MetricsCollector{
+		validations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "validations_total",
+				Help:      "Total number of email validations by validation type, validation layer, outcome and result cache hit.",
+			},
+			[]string{"validation_type", "layer", "outcome", "from_cache"},
+		),
+		dnsQueryDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "dns_query_duration_seconds",
+				Help:      "DNS query latency in seconds by query type and status.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"query_type", "status"},
+		),
+		smtpCommandDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "smtp_command_duration_seconds",
+				Help:      "SMTP command latency in seconds by command and status.",
+				Buckets:   prometheus.ExponentialBuckets(0.01, 2, 13),
+			},
+			[]string{"command", "status"},
+		),
+		smtpSessionsInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "smtp_sessions_in_flight",
+				Help:      "Number of SMTP sessions in flight.",
+			},
+		),
+	}
+
+	var registered []prometheus.Collector
+	for _, collector := range metricsCollector.collectors() {
+		if err := registerer.Register(collector); err != nil {
+			for _, registeredCollector := range registered {
+				registerer.Unregister(registeredCollector)
+			}
+			return nil, err
+		}
+		registered = append(registered, collector)
+	}
+
+	return metricsCollector, nil
+}
+
+// MetricsCollector methods
+
+// ObserveValidation increments validations counter
+func (metricsCollector *MetricsCollector) ObserveValidation(validationType, layer, outcome string, fromCache bool) {
+	metricsCollector.validations.WithLabelValues(validationType, layer, outcome, strconv.FormatBool(fromCache)).Inc()
+}
+
+// ObserveDnsQuery observes DNS query latency
+func (metricsCollector *MetricsCollector) ObserveDnsQuery(queryType, status string, duration time.Duration) {
+	metricsCollector.dnsQueryDuration.WithLabelValues(queryType, status).Observe(duration.Seconds())
+}
+
+// ObserveSmtpCommand observes SMTP command latency
+func (metricsCollector *MetricsCollector) ObserveSmtpCommand(command, status string, duration time.Duration) {
+	metricsCollector.smtpCommandDuration.WithLabelValues(command, status).Observe(duration.Seconds())
+}
+
+// AddSmtpSessionsInFlight adds delta to SMTP sessions in flight gauge
+func (metricsCollector *MetricsCollector) AddSmtpSessionsInFlight(delta float64) {
+	metricsCollector.smtpSessionsInFlight.Add(delta)
+}
+
+// Returns all Prometheus collectors of metrics collector
+func (metricsCollector *MetricsCollector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		metricsCollector.validations,
+		metricsCollector.dnsQueryDuration,
+		metricsCollector.smtpCommandDuration,
+		metricsCollector.smtpSessionsInFlight,
+	}
+}
@@ -0,0 +1,89 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/truemail-rb/truemail-go"
+)
+
+func TestNewMetricsCollector(t *testing.T) {
+	t.Run("registers metrics in registerer", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		metricsCollector, err := NewMetricsCollector(registry)
+
+		assert.NoError(t, err)
+		assert.Implements(t, (*truemail.MetricsCollector)(nil), metricsCollector)
+		for _, collector := range metricsCollector.collectors() {
+			assert.True(t, registry.Unregister(collector))
+		}
+	})
+
+	t.Run("uses default registerer when registerer is nil", func(t *testing.T) {
+		metricsCollector, err := NewMetricsCollector(nil)
+		defer func() {
+			for _, collector := range metricsCollector.collectors() {
+				prometheus.DefaultRegisterer.Unregister(collector)
+			}
+		}()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, metricsCollector)
+	})
+
+	t.Run("when metrics are already registered", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		_, _ = NewMetricsCollector(registry)
+		metricsCollector, err := NewMetricsCollector(registry)
+
+		assert.Nil(t, metricsCollector)
+		assert.IsType(t, prometheus.AlreadyRegisteredError{}, err)
+	})
+}
+
+func TestMetricsCollectorObserveValidation(t *testing.T) {
+	t.Run("increments validations counter", func(t *testing.T) {
+		metricsCollector, _ := NewMetricsCollector(prometheus.NewRegistry())
+		metricsCollector.ObserveValidation("smtp", "mx", "invalid", false)
+		metricsCollector.ObserveValidation("smtp", "mx", "invalid", false)
+		metricsCollector.ObserveValidation("smtp", "mx", "invalid", true)
+
+		counter := metricsCollector.validations.WithLabelValues("smtp", "mx", "invalid", "false")
+		assert.Equal(t, float64(2), testutil.ToFloat64(counter))
+		counter = metricsCollector.validations.WithLabelValues("smtp", "mx", "invalid", "true")
+		assert.Equal(t, float64(1), testutil.ToFloat64(counter))
+	})
+}
+
+func TestMetricsCollectorObserveDnsQuery(t *testing.T) {
+	t.Run("observes DNS query latency", func(t *testing.T) {
+		metricsCollector, _ := NewMetricsCollector(prometheus.NewRegistry())
+		metricsCollector.ObserveDnsQuery("MX", "success", 42*time.Millisecond)
+
+		assert.Equal(t, 1, testutil.CollectAndCount(metricsCollector.dnsQueryDuration, "truemail_dns_query_duration_seconds"))
+	})
+}
+
+func TestMetricsCollectorObserveSmtpCommand(t *testing.T) {
+	t.Run("observes SMTP command latency", func(t *testing.T) {
+		metricsCollector, _ := NewMetricsCollector(prometheus.NewRegistry())
+		metricsCollector.ObserveSmtpCommand("rcpt", "success", time.Second)
+		metricsCollector.ObserveSmtpCommand("mail", "failure", time.Second)
+
+		assert.Equal(t, 2, testutil.CollectAndCount(metricsCollector.smtpCommandDuration, "truemail_smtp_command_duration_seconds"))
+	})
+}
+
+func TestMetricsCollectorAddSmtpSessionsInFlight(t *testing.T) {
+	t.Run("adds delta to SMTP sessions in flight gauge", func(t *testing.T) {
+		metricsCollector, _ := NewMetricsCollector(prometheus.NewRegistry())
+		metricsCollector.AddSmtpSessionsInFlight(1)
+		metricsCollector.AddSmtpSessionsInFlight(1)
+		metricsCollector.AddSmtpSessionsInFlight(-1)
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(metricsCollector.smtpSessionsInFlight))
+	})
+}
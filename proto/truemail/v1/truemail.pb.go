@@ -0,0 +1,1187 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.3
+// source: truemail/v1/truemail.proto
+
+package truemailv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ValidateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Email string `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	// Available validation types: regex, mx, mx_blacklist, smtp. Default validation
+	// type of server configuration is used when validation type is not specified
+	ValidationType string `protobuf:"bytes,2,opt,name=validation_type,json=validationType,proto3" json:"validation_type,omitempty"`
+}
+
+func (x *ValidateRequest) Reset() {
+	*x = ValidateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRequest) ProtoMessage() {}
+
+func (x *ValidateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRequest.ProtoReflect.Descriptor instead.
+func (*ValidateRequest) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ValidateRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ValidateRequest) GetValidationType() string {
+	if x != nil {
+		return x.ValidationType
+	}
+	return ""
+}
+
+type ValidateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ValidatorResult *ValidatorResult `protobuf:"bytes,1,opt,name=validator_result,json=validatorResult,proto3" json:"validator_result,omitempty"`
+}
+
+func (x *ValidateResponse) Reset() {
+	*x = ValidateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateResponse) ProtoMessage() {}
+
+func (x *ValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateResponse.ProtoReflect.Descriptor instead.
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidateResponse) GetValidatorResult() *ValidatorResult {
+	if x != nil {
+		return x.ValidatorResult
+	}
+	return nil
+}
+
+// Mirrors truemail.ValidatorResult
+type ValidatorResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success        bool              `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Email          string            `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Domain         string            `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	ValidationType string            `protobuf:"bytes,4,opt,name=validation_type,json=validationType,proto3" json:"validation_type,omitempty"`
+	MailServers    []string          `protobuf:"bytes,5,rep,name=mail_servers,json=mailServers,proto3" json:"mail_servers,omitempty"`
+	Errors         map[string]string `protobuf:"bytes,6,rep,name=errors,proto3" json:"errors,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SmtpDebug      []*SmtpRequest    `protobuf:"bytes,7,rep,name=smtp_debug,json=smtpDebug,proto3" json:"smtp_debug,omitempty"`
+	FromCache      bool              `protobuf:"varint,8,opt,name=from_cache,json=fromCache,proto3" json:"from_cache,omitempty"`
+}
+
+func (x *ValidatorResult) Reset() {
+	*x = ValidatorResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidatorResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidatorResult) ProtoMessage() {}
+
+func (x *ValidatorResult) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidatorResult.ProtoReflect.Descriptor instead.
+func (*ValidatorResult) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ValidatorResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ValidatorResult) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ValidatorResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *ValidatorResult) GetValidationType() string {
+	if x != nil {
+		return x.ValidationType
+	}
+	return ""
+}
+
+func (x *ValidatorResult) GetMailServers() []string {
+	if x != nil {
+		return x.MailServers
+	}
+	return nil
+}
+
+func (x *ValidatorResult) GetErrors() map[string]string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *ValidatorResult) GetSmtpDebug() []*SmtpRequest {
+	if x != nil {
+		return x.SmtpDebug
+	}
+	return nil
+}
+
+func (x *ValidatorResult) GetFromCache() bool {
+	if x != nil {
+		return x.FromCache
+	}
+	return false
+}
+
+// Mirrors truemail.SmtpRequest
+type SmtpRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Attempts int32         `protobuf:"varint,1,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	Email    string        `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Host     string        `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	MxHost   string        `protobuf:"bytes,4,opt,name=mx_host,json=mxHost,proto3" json:"mx_host,omitempty"`
+	Port     int32         `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`
+	Response *SmtpResponse `protobuf:"bytes,6,opt,name=response,proto3" json:"response,omitempty"`
+}
+
+func (x *SmtpRequest) Reset() {
+	*x = SmtpRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SmtpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmtpRequest) ProtoMessage() {}
+
+func (x *SmtpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmtpRequest.ProtoReflect.Descriptor instead.
+func (*SmtpRequest) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SmtpRequest) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *SmtpRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *SmtpRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *SmtpRequest) GetMxHost() string {
+	if x != nil {
+		return x.MxHost
+	}
+	return ""
+}
+
+func (x *SmtpRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *SmtpRequest) GetResponse() *SmtpResponse {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+// Mirrors truemail.SmtpResponse
+type SmtpResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rcptto        bool                   `protobuf:"varint,1,opt,name=rcptto,proto3" json:"rcptto,omitempty"`
+	RcpttoTrusted bool                   `protobuf:"varint,2,opt,name=rcptto_trusted,json=rcpttoTrusted,proto3" json:"rcptto_trusted,omitempty"`
+	Errors        []*SmtpError           `protobuf:"bytes,3,rep,name=errors,proto3" json:"errors,omitempty"`
+	Tls           *TlsDetails            `protobuf:"bytes,4,opt,name=tls,proto3" json:"tls,omitempty"`
+	Transcript    []*SmtpTranscriptEntry `protobuf:"bytes,5,rep,name=transcript,proto3" json:"transcript,omitempty"`
+	CatchAll      bool                   `protobuf:"varint,6,opt,name=catch_all,json=catchAll,proto3" json:"catch_all,omitempty"`
+}
+
+func (x *SmtpResponse) Reset() {
+	*x = SmtpResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SmtpResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmtpResponse) ProtoMessage() {}
+
+func (x *SmtpResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmtpResponse.ProtoReflect.Descriptor instead.
+func (*SmtpResponse) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SmtpResponse) GetRcptto() bool {
+	if x != nil {
+		return x.Rcptto
+	}
+	return false
+}
+
+func (x *SmtpResponse) GetRcpttoTrusted() bool {
+	if x != nil {
+		return x.RcpttoTrusted
+	}
+	return false
+}
+
+func (x *SmtpResponse) GetErrors() []*SmtpError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *SmtpResponse) GetTls() *TlsDetails {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+func (x *SmtpResponse) GetTranscript() []*SmtpTranscriptEntry {
+	if x != nil {
+		return x.Transcript
+	}
+	return nil
+}
+
+func (x *SmtpResponse) GetCatchAll() bool {
+	if x != nil {
+		return x.CatchAll
+	}
+	return false
+}
+
+// Mirrors truemail.SmtpClientError, reply is not set when error was not caused by SMTP reply
+type SmtpError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Message string     `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Reply   *SmtpReply `protobuf:"bytes,2,opt,name=reply,proto3" json:"reply,omitempty"`
+}
+
+func (x *SmtpError) Reset() {
+	*x = SmtpError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SmtpError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmtpError) ProtoMessage() {}
+
+func (x *SmtpError) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmtpError.ProtoReflect.Descriptor instead.
+func (*SmtpError) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SmtpError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SmtpError) GetReply() *SmtpReply {
+	if x != nil {
+		return x.Reply
+	}
+	return nil
+}
+
+// Mirrors truemail.SmtpReply
+type SmtpReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code           int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	EnhancedCode   string `protobuf:"bytes,2,opt,name=enhanced_code,json=enhancedCode,proto3" json:"enhanced_code,omitempty"`
+	Text           string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Classification string `protobuf:"bytes,4,opt,name=classification,proto3" json:"classification,omitempty"`
+}
+
+func (x *SmtpReply) Reset() {
+	*x = SmtpReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SmtpReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmtpReply) ProtoMessage() {}
+
+func (x *SmtpReply) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmtpReply.ProtoReflect.Descriptor instead.
+func (*SmtpReply) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SmtpReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SmtpReply) GetEnhancedCode() string {
+	if x != nil {
+		return x.EnhancedCode
+	}
+	return ""
+}
+
+func (x *SmtpReply) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SmtpReply) GetClassification() string {
+	if x != nil {
+		return x.Classification
+	}
+	return ""
+}
+
+// Mirrors truemail.TlsDetails, error is set when session fell back to plain text
+type TlsDetails struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version            string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	CipherSuite        string                 `protobuf:"bytes,2,opt,name=cipher_suite,json=cipherSuite,proto3" json:"cipher_suite,omitempty"`
+	CertificateSubject string                 `protobuf:"bytes,3,opt,name=certificate_subject,json=certificateSubject,proto3" json:"certificate_subject,omitempty"`
+	CertificateExpiry  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=certificate_expiry,json=certificateExpiry,proto3" json:"certificate_expiry,omitempty"`
+	Error              string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *TlsDetails) Reset() {
+	*x = TlsDetails{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TlsDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TlsDetails) ProtoMessage() {}
+
+func (x *TlsDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TlsDetails.ProtoReflect.Descriptor instead.
+func (*TlsDetails) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TlsDetails) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *TlsDetails) GetCipherSuite() string {
+	if x != nil {
+		return x.CipherSuite
+	}
+	return ""
+}
+
+func (x *TlsDetails) GetCertificateSubject() string {
+	if x != nil {
+		return x.CertificateSubject
+	}
+	return ""
+}
+
+func (x *TlsDetails) GetCertificateExpiry() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CertificateExpiry
+	}
+	return nil
+}
+
+func (x *TlsDetails) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Mirrors truemail.SmtpTranscriptEntry
+type SmtpTranscriptEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Command   string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Reply     []string               `protobuf:"bytes,2,rep,name=reply,proto3" json:"reply,omitempty"`
+	Code      int32                  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Tls       bool                   `protobuf:"varint,4,opt,name=tls,proto3" json:"tls,omitempty"`
+	SentAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	RepliedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=replied_at,json=repliedAt,proto3" json:"replied_at,omitempty"`
+	Latency   *durationpb.Duration   `protobuf:"bytes,7,opt,name=latency,proto3" json:"latency,omitempty"`
+}
+
+func (x *SmtpTranscriptEntry) Reset() {
+	*x = SmtpTranscriptEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SmtpTranscriptEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SmtpTranscriptEntry) ProtoMessage() {}
+
+func (x *SmtpTranscriptEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SmtpTranscriptEntry.ProtoReflect.Descriptor instead.
+func (*SmtpTranscriptEntry) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SmtpTranscriptEntry) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *SmtpTranscriptEntry) GetReply() []string {
+	if x != nil {
+		return x.Reply
+	}
+	return nil
+}
+
+func (x *SmtpTranscriptEntry) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SmtpTranscriptEntry) GetTls() bool {
+	if x != nil {
+		return x.Tls
+	}
+	return false
+}
+
+func (x *SmtpTranscriptEntry) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+func (x *SmtpTranscriptEntry) GetRepliedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RepliedAt
+	}
+	return nil
+}
+
+func (x *SmtpTranscriptEntry) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+type HostAuditRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Current host ip address is detected by server when it is not specified
+	CurrentHostIp string `protobuf:"bytes,1,opt,name=current_host_ip,json=currentHostIp,proto3" json:"current_host_ip,omitempty"`
+}
+
+func (x *HostAuditRequest) Reset() {
+	*x = HostAuditRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HostAuditRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostAuditRequest) ProtoMessage() {}
+
+func (x *HostAuditRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostAuditRequest.ProtoReflect.Descriptor instead.
+func (*HostAuditRequest) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HostAuditRequest) GetCurrentHostIp() string {
+	if x != nil {
+		return x.CurrentHostIp
+	}
+	return ""
+}
+
+// Mirrors truemail.AuditorResult
+type HostAuditResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CurrentHostIp string            `protobuf:"bytes,1,opt,name=current_host_ip,json=currentHostIp,proto3" json:"current_host_ip,omitempty"`
+	Warnings      map[string]string `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *HostAuditResponse) Reset() {
+	*x = HostAuditResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_truemail_v1_truemail_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HostAuditResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostAuditResponse) ProtoMessage() {}
+
+func (x *HostAuditResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_truemail_v1_truemail_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostAuditResponse.ProtoReflect.Descriptor instead.
+func (*HostAuditResponse) Descriptor() ([]byte, []int) {
+	return file_truemail_v1_truemail_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HostAuditResponse) GetCurrentHostIp() string {
+	if x != nil {
+		return x.CurrentHostIp
+	}
+	return ""
+}
+
+func (x *HostAuditResponse) GetWarnings() map[string]string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+var File_truemail_v1_truemail_proto protoreflect.FileDescriptor
+
+var file_truemail_v1_truemail_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2f, 0x76, 0x31, 0x2f, 0x74, 0x72,
+	0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x74, 0x72,
+	0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x50, 0x0a, 0x0f, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x6d,
+	0x61, 0x69, 0x6c, 0x12, 0x27, 0x0a, 0x0f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x76, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x22, 0x5b, 0x0a, 0x10,
+	0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x47, 0x0a, 0x10, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x5f, 0x72, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x74, 0x72, 0x75,
+	0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x6f, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x0f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0xfa, 0x02, 0x0a, 0x0f, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x18, 0x0a,
+	0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x12, 0x16, 0x0a,
+	0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64,
+	0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x27, 0x0a, 0x0f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x21,
+	0x0a, 0x0c, 0x6d, 0x61, 0x69, 0x6c, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x18, 0x05,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x6d, 0x61, 0x69, 0x6c, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x73, 0x12, 0x40, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x28, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e,
+	0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x12, 0x37, 0x0a, 0x0a, 0x73, 0x6d, 0x74, 0x70, 0x5f, 0x64, 0x65, 0x62, 0x75,
+	0x67, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61,
+	0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6d, 0x74, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x52, 0x09, 0x73, 0x6d, 0x74, 0x70, 0x44, 0x65, 0x62, 0x75, 0x67, 0x12, 0x1d, 0x0a, 0x0a,
+	0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x09, 0x66, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x63, 0x68, 0x65, 0x1a, 0x39, 0x0a, 0x0b, 0x45,
+	0x72, 0x72, 0x6f, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xb7, 0x01, 0x0a, 0x0b, 0x53, 0x6d, 0x74, 0x70, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x74, 0x74, 0x65, 0x6d, 0x70,
+	0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x61, 0x74, 0x74, 0x65, 0x6d, 0x70,
+	0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07,
+	0x6d, 0x78, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d,
+	0x78, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x35, 0x0a, 0x08, 0x72, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x74, 0x72,
+	0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6d, 0x74, 0x70, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x87, 0x02, 0x0a, 0x0c, 0x53, 0x6d, 0x74, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x63, 0x70, 0x74, 0x74, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x06, 0x72, 0x63, 0x70, 0x74, 0x74, 0x6f, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x63, 0x70,
+	0x74, 0x74, 0x6f, 0x5f, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0d, 0x72, 0x63, 0x70, 0x74, 0x74, 0x6f, 0x54, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64,
+	0x12, 0x2e, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x6d, 0x74, 0x70, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73,
+	0x12, 0x29, 0x0a, 0x03, 0x74, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e,
+	0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x6c, 0x73, 0x44,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x03, 0x74, 0x6c, 0x73, 0x12, 0x40, 0x0a, 0x0a, 0x74,
+	0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x20, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6d,
+	0x74, 0x70, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x1b, 0x0a,
+	0x09, 0x63, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x61, 0x6c, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x63, 0x61, 0x74, 0x63, 0x68, 0x41, 0x6c, 0x6c, 0x22, 0x53, 0x0a, 0x09, 0x53, 0x6d,
+	0x74, 0x70, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x12, 0x2c, 0x0a, 0x05, 0x72, 0x65, 0x70, 0x6c, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x6d, 0x74, 0x70, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x52, 0x05, 0x72, 0x65, 0x70, 0x6c, 0x79, 0x22,
+	0x80, 0x01, 0x0a, 0x09, 0x53, 0x6d, 0x74, 0x70, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a,
+	0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64,
+	0x65, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x6e, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x64, 0x5f, 0x63, 0x6f,
+	0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x6e, 0x68, 0x61, 0x6e, 0x63,
+	0x65, 0x64, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x26, 0x0a, 0x0e, 0x63, 0x6c,
+	0x61, 0x73, 0x73, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0e, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x22, 0xdb, 0x01, 0x0a, 0x0a, 0x54, 0x6c, 0x73, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c,
+	0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x63,
+	0x69, 0x70, 0x68, 0x65, 0x72, 0x5f, 0x73, 0x75, 0x69, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x53, 0x75, 0x69, 0x74, 0x65, 0x12, 0x2f,
+	0x0a, 0x13, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x5f, 0x73, 0x75,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x63, 0x65, 0x72,
+	0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12,
+	0x49, 0x0a, 0x12, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x5f, 0x65,
+	0x78, 0x70, 0x69, 0x72, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x11, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0x90, 0x02, 0x0a, 0x13, 0x53, 0x6d, 0x74, 0x70, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72,
+	0x69, 0x70, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x65, 0x70, 0x6c, 0x79, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x05, 0x72, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03,
+	0x74, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x74, 0x6c, 0x73, 0x12, 0x33,
+	0x0a, 0x07, 0x73, 0x65, 0x6e, 0x74, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x06, 0x73, 0x65, 0x6e,
+	0x74, 0x41, 0x74, 0x12, 0x39, 0x0a, 0x0a, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x5f, 0x61,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x52, 0x09, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x41, 0x74, 0x12, 0x33,
+	0x0a, 0x07, 0x6c, 0x61, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x6c, 0x61, 0x74, 0x65,
+	0x6e, 0x63, 0x79, 0x22, 0x3a, 0x0a, 0x10, 0x48, 0x6f, 0x73, 0x74, 0x41, 0x75, 0x64, 0x69, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x63, 0x75, 0x72, 0x72, 0x65,
+	0x6e, 0x74, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0d, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x49, 0x70, 0x22,
+	0xc2, 0x01, 0x0a, 0x11, 0x48, 0x6f, 0x73, 0x74, 0x41, 0x75, 0x64, 0x69, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x0f, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x5f, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x49, 0x70, 0x12, 0x48, 0x0a,
+	0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x2c, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x6f,
+	0x73, 0x74, 0x41, 0x75, 0x64, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x77,
+	0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x73, 0x1a, 0x3b, 0x0a, 0x0d, 0x57, 0x61, 0x72, 0x6e, 0x69,
+	0x6e, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x32, 0xfb, 0x01, 0x0a, 0x11, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x47, 0x0a, 0x08, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x12, 0x1c, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69,
+	0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e,
+	0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x51, 0x0a, 0x0e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1c, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c,
+	0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76,
+	0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x4a, 0x0a, 0x09, 0x48, 0x6f, 0x73, 0x74, 0x41, 0x75,
+	0x64, 0x69, 0x74, 0x12, 0x1d, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76,
+	0x31, 0x2e, 0x48, 0x6f, 0x73, 0x74, 0x41, 0x75, 0x64, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2e, 0x76, 0x31,
+	0x2e, 0x48, 0x6f, 0x73, 0x74, 0x41, 0x75, 0x64, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x41, 0x5a, 0x3f, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x74, 0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2d, 0x72, 0x62, 0x2f, 0x74, 0x72, 0x75,
+	0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2d, 0x67, 0x6f, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74,
+	0x72, 0x75, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x2f, 0x76, 0x31, 0x3b, 0x74, 0x72, 0x75, 0x65, 0x6d,
+	0x61, 0x69, 0x6c, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_truemail_v1_truemail_proto_rawDescOnce sync.Once
+	file_truemail_v1_truemail_proto_rawDescData = file_truemail_v1_truemail_proto_rawDesc
+)
+
+func file_truemail_v1_truemail_proto_rawDescGZIP() []byte {
+	file_truemail_v1_truemail_proto_rawDescOnce.Do(func() {
+		file_truemail_v1_truemail_proto_rawDescData = protoimpl.X.CompressGZIP(file_truemail_v1_truemail_proto_rawDescData)
+	})
+	return file_truemail_v1_truemail_proto_rawDescData
+}
+
+var file_truemail_v1_truemail_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_truemail_v1_truemail_proto_goTypes = []any{
+	(*ValidateRequest)(nil),       // 0: truemail.v1.ValidateRequest
+	(*ValidateResponse)(nil),      // 1: truemail.v1.ValidateResponse
+	(*ValidatorResult)(nil),       // 2: truemail.v1.ValidatorResult
+	(*SmtpRequest)(nil),           // 3: truemail.v1.SmtpRequest
+	(*SmtpResponse)(nil),          // 4: truemail.v1.SmtpResponse
+	(*SmtpError)(nil),             // 5: truemail.v1.SmtpError
+	(*SmtpReply)(nil),             // 6: truemail.v1.SmtpReply
+	(*TlsDetails)(nil),            // 7: truemail.v1.TlsDetails
+	(*SmtpTranscriptEntry)(nil),   // 8: truemail.v1.SmtpTranscriptEntry
+	(*HostAuditRequest)(nil),      // 9: truemail.v1.HostAuditRequest
+	(*HostAuditResponse)(nil),     // 10: truemail.v1.HostAuditResponse
+	nil,                           // 11: truemail.v1.ValidatorResult.ErrorsEntry
+	nil,                           // 12: truemail.v1.HostAuditResponse.WarningsEntry
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 14: google.protobuf.Duration
+}
+var file_truemail_v1_truemail_proto_depIdxs = []int32{
+	2,  // 0: truemail.v1.ValidateResponse.validator_result:type_name -> truemail.v1.ValidatorResult
+	11, // 1: truemail.v1.ValidatorResult.errors:type_name -> truemail.v1.ValidatorResult.ErrorsEntry
+	3,  // 2: truemail.v1.ValidatorResult.smtp_debug:type_name -> truemail.v1.SmtpRequest
+	4,  // 3: truemail.v1.SmtpRequest.response:type_name -> truemail.v1.SmtpResponse
+	5,  // 4: truemail.v1.SmtpResponse.errors:type_name -> truemail.v1.SmtpError
+	7,  // 5: truemail.v1.SmtpResponse.tls:type_name -> truemail.v1.TlsDetails
+	8,  // 6: truemail.v1.SmtpResponse.transcript:type_name -> truemail.v1.SmtpTranscriptEntry
+	6,  // 7: truemail.v1.SmtpError.reply:type_name -> truemail.v1.SmtpReply
+	13, // 8: truemail.v1.TlsDetails.certificate_expiry:type_name -> google.protobuf.Timestamp
+	13, // 9: truemail.v1.SmtpTranscriptEntry.sent_at:type_name -> google.protobuf.Timestamp
+	13, // 10: truemail.v1.SmtpTranscriptEntry.replied_at:type_name -> google.protobuf.Timestamp
+	14, // 11: truemail.v1.SmtpTranscriptEntry.latency:type_name -> google.protobuf.Duration
+	12, // 12: truemail.v1.HostAuditResponse.warnings:type_name -> truemail.v1.HostAuditResponse.WarningsEntry
+	0,  // 13: truemail.v1.ValidationService.Validate:input_type -> truemail.v1.ValidateRequest
+	0,  // 14: truemail.v1.ValidationService.ValidateStream:input_type -> truemail.v1.ValidateRequest
+	9,  // 15: truemail.v1.ValidationService.HostAudit:input_type -> truemail.v1.HostAuditRequest
+	1,  // 16: truemail.v1.ValidationService.Validate:output_type -> truemail.v1.ValidateResponse
+	1,  // 17: truemail.v1.ValidationService.ValidateStream:output_type -> truemail.v1.ValidateResponse
+	10, // 18: truemail.v1.ValidationService.HostAudit:output_type -> truemail.v1.HostAuditResponse
+	16, // [16:19] is the sub-list for method output_type
+	13, // [13:16] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_truemail_v1_truemail_proto_init() }
+func file_truemail_v1_truemail_proto_init() {
+	if File_truemail_v1_truemail_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_truemail_v1_truemail_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidatorResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*SmtpRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*SmtpResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*SmtpError); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*SmtpReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*TlsDetails); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*SmtpTranscriptEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*HostAuditRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_truemail_v1_truemail_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*HostAuditResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_truemail_v1_truemail_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_truemail_v1_truemail_proto_goTypes,
+		DependencyIndexes: file_truemail_v1_truemail_proto_depIdxs,
+		MessageInfos:      file_truemail_v1_truemail_proto_msgTypes,
+	}.Build()
+	File_truemail_v1_truemail_proto = out.File
+	file_truemail_v1_truemail_proto_rawDesc = nil
+	file_truemail_v1_truemail_proto_goTypes = nil
+	file_truemail_v1_truemail_proto_depIdxs = nil
+}
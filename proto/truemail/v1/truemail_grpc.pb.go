@@ -0,0 +1,229 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.27.3
+// source: truemail/v1/truemail.proto
+
+package truemailv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	ValidationService_Validate_FullMethodName       = "/truemail.v1.ValidationService/Validate"
+	ValidationService_ValidateStream_FullMethodName = "/truemail.v1.ValidationService/ValidateStream"
+	ValidationService_HostAudit_FullMethodName      = "/truemail.v1.ValidationService/HostAudit"
+)
+
+// ValidationServiceClient is the client API for ValidationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Truemail validation service
+type ValidationServiceClient interface {
+	// Validates email
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+	// Validates emails from request stream, responds with validator result of each email
+	ValidateStream(ctx context.Context, opts ...grpc.CallOption) (ValidationService_ValidateStreamClient, error)
+	// Checks that A record of verifier domain and PTR record of current host refer to each other
+	HostAudit(ctx context.Context, in *HostAuditRequest, opts ...grpc.CallOption) (*HostAuditResponse, error)
+}
+
+type validationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewValidationServiceClient(cc grpc.ClientConnInterface) ValidationServiceClient {
+	return &validationServiceClient{cc}
+}
+
+func (c *validationServiceClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, ValidationService_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *validationServiceClient) ValidateStream(ctx context.Context, opts ...grpc.CallOption) (ValidationService_ValidateStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ValidationService_ServiceDesc.Streams[0], ValidationService_ValidateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &validationServiceValidateStreamClient{ClientStream: stream}
+	return x, nil
+}
+
+type ValidationService_ValidateStreamClient interface {
+	Send(*ValidateRequest) error
+	Recv() (*ValidateResponse, error)
+	grpc.ClientStream
+}
+
+type validationServiceValidateStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *validationServiceValidateStreamClient) Send(m *ValidateRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *validationServiceValidateStreamClient) Recv() (*ValidateResponse, error) {
+	m := new(ValidateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *validationServiceClient) HostAudit(ctx context.Context, in *HostAuditRequest, opts ...grpc.CallOption) (*HostAuditResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostAuditResponse)
+	err := c.cc.Invoke(ctx, ValidationService_HostAudit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ValidationServiceServer is the server API for ValidationService service.
+// All implementations must embed UnimplementedValidationServiceServer
+// for forward compatibility
+//
+// Truemail validation service
+type ValidationServiceServer interface {
+	// Validates email
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+	// Validates emails from request stream, responds with validator result of each email
+	ValidateStream(ValidationService_ValidateStreamServer) error
+	// Checks that A record of verifier domain and PTR record of current host refer to each other
+	HostAudit(context.Context, *HostAuditRequest) (*HostAuditResponse, error)
+	mustEmbedUnimplementedValidationServiceServer()
+}
+
+// UnimplementedValidationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedValidationServiceServer struct {
+}
+
+func (UnimplementedValidationServiceServer) Validate(context.Context, *ValidateRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedValidationServiceServer) ValidateStream(ValidationService_ValidateStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ValidateStream not implemented")
+}
+func (UnimplementedValidationServiceServer) HostAudit(context.Context, *HostAuditRequest) (*HostAuditResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HostAudit not implemented")
+}
+func (UnimplementedValidationServiceServer) mustEmbedUnimplementedValidationServiceServer() {}
+
+// UnsafeValidationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ValidationServiceServer will
+// result in compilation errors.
+type UnsafeValidationServiceServer interface {
+	mustEmbedUnimplementedValidationServiceServer()
+}
+
+func RegisterValidationServiceServer(s grpc.ServiceRegistrar, srv ValidationServiceServer) {
+	s.RegisterService(&ValidationService_ServiceDesc, srv)
+}
+
+func _ValidationService_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidationServiceServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ValidationService_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidationServiceServer).Validate(ctx, req.(*ValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ValidationService_ValidateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ValidationServiceServer).ValidateStream(&validationServiceValidateStreamServer{ServerStream: stream})
+}
+
+type ValidationService_ValidateStreamServer interface {
+	Send(*ValidateResponse) error
+	Recv() (*ValidateRequest, error)
+	grpc.ServerStream
+}
+
+type validationServiceValidateStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *validationServiceValidateStreamServer) Send(m *ValidateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *validationServiceValidateStreamServer) Recv() (*ValidateRequest, error) {
+	m := new(ValidateRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ValidationService_HostAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostAuditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ValidationServiceServer).HostAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ValidationService_HostAudit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ValidationServiceServer).HostAudit(ctx, req.(*HostAuditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ValidationService_ServiceDesc is the grpc.ServiceDesc for ValidationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ValidationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "truemail.v1.ValidationService",
+	HandlerType: (*ValidationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Validate",
+			Handler:    _ValidationService_Validate_Handler,
+		},
+		{
+			MethodName: "HostAudit",
+			Handler:    _ValidationService_HostAudit_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ValidateStream",
+			Handler:       _ValidationService_ValidateStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "truemail/v1/truemail.proto",
+}
@@ -0,0 +1,388 @@
+package truemail
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultCache interface. Provides storage for full validation results shared across
+// validations, consulted by Validate before running validation layers. Set error is
+// logged with configuration logger, validation result is not affected by it
+type ResultCache interface {
+	Get(key string) (ResultCacheEntry, bool)
+	Set(key string, entry ResultCacheEntry, ttl time.Duration) error
+}
+
+// Result cache entry structure. Includes validation outcome of validator result and
+// last used validation layer, email is not included because result cache key includes
+// normalized email. SMTP debug, DNS debug, MTA-STS result and greylisting retry are not cached
+type ResultCacheEntry struct {
+	Success        bool
+	Domain         string
+	ValidationType string
+	Layer          string
+	MailServers    []string
+	Errors         map[string]string
+}
+
+// Returns result cache entry of validator result
+func newResultCacheEntry(validatorResult *ValidatorResult) ResultCacheEntry {
+	return ResultCacheEntry{
+		Success:        validatorResult.Success,
+		Domain:         validatorResult.Domain,
+		ValidationType: validatorResult.ValidationType,
+		Layer:          validatorResult.validationLayer(),
+		MailServers:    copyStrings(validatorResult.MailServers),
+		Errors:         copyErrors(validatorResult.Errors),
+	}
+}
+
+// ResultCacheEntry methods
+
+// Returns validator result of email with validation outcome of result cache entry,
+// marked as result from cache. SMTP debug, DNS debug, MTA-STS result and greylisting
+// retry of original validation are not available in validator result from cache
+func (entry ResultCacheEntry) validatorResult(email string, configuration *Configuration) *ValidatorResult {
+	validatorResult := &ValidatorResult{
+		Success:        entry.Success,
+		Email:          email,
+		Domain:         entry.Domain,
+		ValidationType: entry.ValidationType,
+		MailServers:    copyStrings(entry.MailServers),
+		Errors:         copyErrors(entry.Errors),
+		Configuration:  configuration,
+		FromCache:      true,
+	}
+	if entry.Layer != emptyString {
+		validatorResult.usedValidations = []string{entry.Layer}
+	}
+
+	return validatorResult
+}
+
+// Returns copy of validator result errors dictionary
+func copyErrors(validationErrors map[string]string) map[string]string {
+	if validationErrors == nil {
+		return nil
+	}
+
+	copiedErrors := make(map[string]string, len(validationErrors))
+	for key, value := range validationErrors {
+		copiedErrors[key] = value
+	}
+
+	return copiedErrors
+}
+
+// Returns result cache key: normalized email, validation type and configuration fingerprint
+func resultCacheKey(email, validationType string, configuration *Configuration) string {
+	return strings.Join([]string{normalizeResultCacheEmail(email), validationType, newResultCacheFingerprint(configuration)}, "|")
+}
+
+// Returns email with trimmed spaces and lowercased domain part. Local
+// part is kept as is, because it can be case-sensitive (RFC 5321)
+func normalizeResultCacheEmail(email string) string {
+	email = strings.TrimSpace(email)
+	index := strings.LastIndex(email, "@")
+	if index < 0 {
+		return email
+	}
+
+	return email[:index] + strings.ToLower(email[index:])
+}
+
+// Returns configuration fingerprint: hash of configuration settings which affect
+// validation outcome, so results of different configurations are not mixed. Custom
+// resolver, TLS configs and MTA-STS HTTP client are compared by identity
+func newResultCacheFingerprint(configuration *Configuration) string {
+	settings := fmt.Sprintf(
+		"%v",
+		[]any{
+			configuration.VerifierEmail,
+			configuration.VerifierDomain,
+			configuration.EmailPattern,
+			configuration.SmtpErrorBodyPattern,
+			configuration.WhitelistedDomains,
+			configuration.BlacklistedDomains,
+			configuration.BlacklistedMxIpAddresses,
+			configuration.ValidationTypeByDomain,
+			configuration.WhitelistValidation,
+			configuration.NotRfcMxLookupFlow,
+			configuration.ConnectionTimeout,
+			configuration.ResponseTimeout,
+			configuration.ConnectionAttempts,
+			configuration.Dns,
+			configuration.DnsServers,
+			configuration.DnsStrategy,
+			configuration.DnsTransport,
+			configuration.DnsTlsServerName,
+			configuration.DnsTlsConfig,
+			configuration.DnsClient,
+			configuration.Resolver,
+			configuration.MtaStsCheck,
+			configuration.MtaStsHttpClient,
+			configuration.DaneCheck,
+			configuration.SmtpFailFast,
+			configuration.SmtpSafeCheck,
+			configuration.SmtpPort,
+			configuration.SmtpTlsPolicy,
+			configuration.SmtpTlsConfig,
+			configuration.SmtpProxy,
+			configuration.SmtpSourceAddresses,
+			configuration.SmtpSourceRotation,
+			configuration.SmtpGreylistingPattern,
+			configuration.SmtpProviderProfiles,
+			configuration.SmtpParallelProbing,
+			configuration.SmtpParallelProbingLimit,
+		},
+	)
+	hash := sha256.Sum256([]byte(settings))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// In-memory result cache structure. Evicts least recently used
+// entries when capacity is reached and expired entries on read
+type ResultMemoryCache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+// In-memory result cache item, stored as list element value
+type resultMemoryCacheItem struct {
+	key       string
+	entry     ResultCacheEntry
+	expiresAt time.Time
+}
+
+// NewResultMemoryCache returns in-memory LRU result cache with given capacity
+func NewResultMemoryCache(capacity int) *ResultMemoryCache {
+	return &ResultMemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// ResultMemoryCache methods
+
+// Get returns not expired cache entry by key
+func (cache *ResultMemoryCache) Get(key string) (ResultCacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return ResultCacheEntry{}, false
+	}
+
+	item := element.Value.(*resultMemoryCacheItem)
+	if !cache.now().Before(item.expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return ResultCacheEntry{}, false
+	}
+
+	cache.order.MoveToFront(element)
+	return item.entry, true
+}
+
+// Set stores cache entry by key for ttl duration
+func (cache *ResultMemoryCache) Set(key string, entry ResultCacheEntry, ttl time.Duration) error {
+	cache.set(key, entry, cache.now().Add(ttl), ttl)
+	return nil
+}
+
+// Len returns count of stored cache entries
+func (cache *ResultMemoryCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.order.Len()
+}
+
+// Stores cache entry by key until expiration time, evicts least recently used cache
+// entries when capacity is reached. Does nothing for not positive ttl or capacity
+func (cache *ResultMemoryCache) set(key string, entry ResultCacheEntry, expiresAt time.Time, ttl time.Duration) {
+	if ttl <= 0 || cache.capacity <= 0 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	item := &resultMemoryCacheItem{key: key, entry: entry, expiresAt: expiresAt}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = item
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(item)
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*resultMemoryCacheItem).key)
+	}
+}
+
+// Returns not expired cache entries ordered from least to most recently used
+func (cache *ResultMemoryCache) items() (items []*resultMemoryCacheItem) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	for element := cache.order.Back(); element != nil; element = element.Prev() {
+		if item := element.Value.(*resultMemoryCacheItem); now.Before(item.expiresAt) {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// File-backed result cache structure. Keeps cache entries in in-memory LRU result cache with
+// given capacity and persists them into JSON file, so cache entries survive process restart.
+// Changes are persisted at most once per flush interval, pending changes are persisted by Flush
+type ResultFileCache struct {
+	*ResultMemoryCache
+	path          string
+	flushInterval time.Duration
+	flushedAt     time.Time
+	isChanged     bool
+	fileMutex     sync.Mutex
+}
+
+// File-backed result cache item, stored in JSON file
+type resultFileCacheItem struct {
+	Entry     ResultCacheEntry `json:"entry"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// NewResultFileCache returns file-backed LRU result cache with given capacity persisted into
+// file by path. Changes are persisted at most once per flush interval, every change is persisted
+// when flush interval is zero. Loads not expired cache entries from existing file. Returns error
+// when capacity is not positive or file can not be read
+func NewResultFileCache(path string, capacity int, flushInterval time.Duration) (*ResultFileCache, error) {
+	if capacity <= 0 {
+		return nil, errors.New("result file cache capacity should be a positive integer")
+	}
+
+	cache := &ResultFileCache{ResultMemoryCache: NewResultMemoryCache(capacity), path: path, flushInterval: flushInterval}
+	cache.flushedAt = cache.now()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items map[string]resultFileCacheItem
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%s is invalid result cache file: %w", path, err)
+	}
+	cache.load(items)
+
+	return cache, nil
+}
+
+// ResultFileCache methods
+
+// Set stores cache entry by key for ttl duration. Persists cache entries into file when flush
+// interval is passed since last persisting. Returns error when file can not be written, cache
+// entries which were not persisted stay in memory and are persisted by following flush
+func (cache *ResultFileCache) Set(key string, entry ResultCacheEntry, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	_ = cache.ResultMemoryCache.Set(key, entry, ttl)
+
+	cache.fileMutex.Lock()
+	defer cache.fileMutex.Unlock()
+
+	cache.isChanged = true
+	if cache.flushInterval > 0 && cache.now().Sub(cache.flushedAt) < cache.flushInterval {
+		return nil
+	}
+
+	return cache.persist()
+}
+
+// Flush persists pending changes of cache entries into file. Returns error
+// when file can not be written. Does nothing when there are no pending changes
+func (cache *ResultFileCache) Flush() error {
+	cache.fileMutex.Lock()
+	defer cache.fileMutex.Unlock()
+
+	if !cache.isChanged {
+		return nil
+	}
+
+	return cache.persist()
+}
+
+// Loads not expired cache entries into memory, entries which expire later are
+// treated as more recently used
+func (cache *ResultFileCache) load(items map[string]resultFileCacheItem) {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return items[keys[i]].ExpiresAt.Before(items[keys[j]].ExpiresAt) })
+
+	now := cache.now()
+	for _, key := range keys {
+		item := items[key]
+		cache.set(key, item.Entry, item.ExpiresAt, item.ExpiresAt.Sub(now))
+	}
+}
+
+// Writes not expired cache entries into temporary file and renames it to cache file,
+// so cache file is never partially written. Marks cache entries as persisted
+func (cache *ResultFileCache) persist() error {
+	items := make(map[string]resultFileCacheItem)
+	for _, item := range cache.items() {
+		items[item.key] = resultFileCacheItem{Entry: item.entry, ExpiresAt: item.expiresAt}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(cache.path), filepath.Base(cache.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+
+	if _, err = temporaryFile.Write(data); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err = temporaryFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(temporaryFile.Name(), cache.path); err != nil {
+		return err
+	}
+	cache.isChanged, cache.flushedAt = false, cache.now()
+
+	return nil
+}
@@ -0,0 +1,421 @@
+package truemail
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/stretchr/testify/assert"
+)
+
+func createResultCacheEntry() ResultCacheEntry {
+	return ResultCacheEntry{
+		Success:        true,
+		Domain:         randomDomain(),
+		ValidationType: validationTypeMx,
+		MailServers:    []string{randomIpAddress()},
+	}
+}
+
+func TestNewResultCacheEntry(t *testing.T) {
+	t.Run("creates result cache entry with copied validation outcome", func(t *testing.T) {
+		validatorResult := &ValidatorResult{
+			Email:           randomEmail(),
+			Domain:          randomDomain(),
+			ValidationType:  validationTypeSmtp,
+			MailServers:     []string{randomIpAddress()},
+			Errors:          map[string]string{validationTypeSmtp: smtpErrorContext},
+			usedValidations: []string{validationTypeRegex, validationTypeMx, validationTypeSmtp},
+		}
+		entry := newResultCacheEntry(validatorResult)
+		validatorResult.MailServers[0], validatorResult.Errors[validationTypeSmtp] = randomIpAddress(), emptyString
+
+		assert.False(t, entry.Success)
+		assert.Equal(t, validatorResult.Domain, entry.Domain)
+		assert.Equal(t, validationTypeSmtp, entry.ValidationType)
+		assert.Equal(t, validationTypeSmtp, entry.Layer)
+		assert.NotEqual(t, validatorResult.MailServers, entry.MailServers)
+		assert.Equal(t, map[string]string{validationTypeSmtp: smtpErrorContext}, entry.Errors)
+	})
+}
+
+func TestResultCacheEntryValidatorResult(t *testing.T) {
+	t.Run("returns validator result marked as result from cache", func(t *testing.T) {
+		email, entry, configuration := randomEmail(), createResultCacheEntry(), createConfiguration()
+		validatorResult := entry.validatorResult(email, configuration)
+
+		assert.True(t, validatorResult.Success)
+		assert.True(t, validatorResult.FromCache)
+		assert.Equal(t, email, validatorResult.Email)
+		assert.Equal(t, entry.Domain, validatorResult.Domain)
+		assert.Equal(t, entry.ValidationType, validatorResult.ValidationType)
+		assert.Equal(t, entry.MailServers, validatorResult.MailServers)
+		assert.Nil(t, validatorResult.Errors)
+		assert.Same(t, configuration, validatorResult.Configuration)
+		assert.Empty(t, validatorResult.usedValidations)
+	})
+
+	t.Run("returns validator result with cached validation layer", func(t *testing.T) {
+		entry := createResultCacheEntry()
+		entry.Layer = validationTypeMx
+		validatorResult := entry.validatorResult(randomEmail(), createConfiguration())
+
+		assert.Equal(t, []string{validationTypeMx}, validatorResult.usedValidations)
+		assert.Equal(t, validationTypeMx, validatorResult.validationLayer())
+	})
+}
+
+func TestResultCacheKey(t *testing.T) {
+	configuration := createConfiguration()
+
+	t.Run("normalizes email", func(t *testing.T) {
+		assert.Equal(
+			t,
+			resultCacheKey("email@example.com", validationTypeSmtp, configuration),
+			resultCacheKey(" email@Example.COM ", validationTypeSmtp, configuration),
+		)
+	})
+
+	t.Run("keeps case of email local part", func(t *testing.T) {
+		assert.NotEqual(
+			t,
+			resultCacheKey("email@example.com", validationTypeSmtp, configuration),
+			resultCacheKey("Email@example.com", validationTypeSmtp, configuration),
+		)
+	})
+
+	t.Run("depends on validation type", func(t *testing.T) {
+		assert.NotEqual(
+			t,
+			resultCacheKey("email@example.com", validationTypeSmtp, configuration),
+			resultCacheKey("email@example.com", validationTypeMx, configuration),
+		)
+	})
+
+	t.Run("depends on configuration fingerprint", func(t *testing.T) {
+		otherConfiguration := copyConfigurationByPointer(configuration)
+		otherConfiguration.SmtpSafeCheck = !configuration.SmtpSafeCheck
+
+		assert.NotEqual(
+			t,
+			resultCacheKey("email@example.com", validationTypeSmtp, configuration),
+			resultCacheKey("email@example.com", validationTypeSmtp, otherConfiguration),
+		)
+	})
+}
+
+func TestNewResultCacheFingerprint(t *testing.T) {
+	t.Run("returns the same fingerprint for the same configuration settings", func(t *testing.T) {
+		configuration := createConfiguration()
+
+		assert.Len(t, newResultCacheFingerprint(configuration), 64)
+		assert.Equal(t, newResultCacheFingerprint(configuration), newResultCacheFingerprint(copyConfigurationByPointer(configuration)))
+	})
+
+	t.Run("depends on configuration settings which affect validation outcome", func(t *testing.T) {
+		configuration := createConfiguration()
+		for name, changeSetting := range map[string]func(*Configuration){
+			"Dns":           func(configuration *Configuration) { configuration.Dns = "10.0.0.1" },
+			"DnsServers":    func(configuration *Configuration) { configuration.DnsServers = []string{"10.0.0.1:53"} },
+			"DnsStrategy":   func(configuration *Configuration) { configuration.DnsStrategy = dnsStrategyRace },
+			"DnsTransport":  func(configuration *Configuration) { configuration.DnsTransport = dnsTransportTls },
+			"DnsClient":     func(configuration *Configuration) { configuration.DnsClient = dnsClientRaw },
+			"Resolver":      func(configuration *Configuration) { configuration.Resolver = &mockdns.Resolver{} },
+			"MtaStsCheck":   func(configuration *Configuration) { configuration.MtaStsCheck = true },
+			"DaneCheck":     func(configuration *Configuration) { configuration.DaneCheck = true },
+			"SmtpTlsPolicy": func(configuration *Configuration) { configuration.SmtpTlsPolicy = smtpTlsPolicyRequired },
+			"SmtpTlsConfig": func(configuration *Configuration) { configuration.SmtpTlsConfig = new(tls.Config) },
+			"SmtpProxy":     func(configuration *Configuration) { configuration.SmtpProxy = "socks5://127.0.0.1:1080" },
+			"SmtpSourceAddresses": func(configuration *Configuration) {
+				configuration.SmtpSourceAddresses = []SmtpSourceAddress{{IpAddress: "10.0.0.1"}}
+			},
+			"SmtpGreylistingPattern": func(configuration *Configuration) {
+				configuration.SmtpGreylistingPattern = regexp.MustCompile("greylisted")
+			},
+			"SmtpProviderProfiles": func(configuration *Configuration) { configuration.SmtpProviderProfiles = nil },
+			"SmtpParallelProbing":  func(configuration *Configuration) { configuration.SmtpParallelProbing = true },
+		} {
+			otherConfiguration := copyConfigurationByPointer(configuration)
+			changeSetting(otherConfiguration)
+
+			assert.NotEqual(t, newResultCacheFingerprint(configuration), newResultCacheFingerprint(otherConfiguration), name)
+		}
+	})
+}
+
+func TestNewResultMemoryCache(t *testing.T) {
+	t.Run("creates empty in-memory result cache", func(t *testing.T) {
+		capacity := randomPositiveNumber()
+		cache := NewResultMemoryCache(capacity)
+
+		assert.Equal(t, capacity, cache.capacity)
+		assert.Empty(t, cache.entries)
+		assert.Equal(t, 0, cache.Len())
+		assert.NotNil(t, cache.now)
+	})
+}
+
+func TestResultMemoryCacheGet(t *testing.T) {
+	key, entry := randomEmail(), createResultCacheEntry()
+
+	t.Run("when entry not found", func(t *testing.T) {
+		_, ok := NewResultMemoryCache(1).Get(key)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when entry found and not expired", func(t *testing.T) {
+		cache := NewResultMemoryCache(1)
+		cache.Set(key, entry, time.Minute)
+		cachedEntry, ok := cache.Get(key)
+
+		assert.True(t, ok)
+		assert.Equal(t, entry, cachedEntry)
+	})
+
+	t.Run("when entry found and expired", func(t *testing.T) {
+		currentTime, cache := time.Now(), NewResultMemoryCache(1)
+		cache.now = func() time.Time { return currentTime }
+		cache.Set(key, entry, time.Minute)
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		_, ok := cache.Get(key)
+
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestResultMemoryCacheSet(t *testing.T) {
+	firstKey, secondKey, thirdKey := "a@a.com", "b@b.com", "c@c.com"
+	entry := createResultCacheEntry()
+
+	t.Run("when ttl is not positive", func(t *testing.T) {
+		cache := NewResultMemoryCache(1)
+		cache.Set(firstKey, entry, 0)
+
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("when entry already exists", func(t *testing.T) {
+		newEntry, cache := createResultCacheEntry(), NewResultMemoryCache(1)
+		cache.Set(firstKey, entry, time.Minute)
+		cache.Set(firstKey, newEntry, time.Minute)
+		cachedEntry, _ := cache.Get(firstKey)
+
+		assert.Equal(t, 1, cache.Len())
+		assert.Equal(t, newEntry, cachedEntry)
+	})
+
+	t.Run("when capacity reached evicts least recently used entry", func(t *testing.T) {
+		cache := NewResultMemoryCache(2)
+		cache.Set(firstKey, entry, time.Minute)
+		cache.Set(secondKey, entry, time.Minute)
+		cache.Get(firstKey)
+		cache.Set(thirdKey, entry, time.Minute)
+		_, isFirstKeyFound := cache.Get(firstKey)
+		_, isSecondKeyFound := cache.Get(secondKey)
+		_, isThirdKeyFound := cache.Get(thirdKey)
+
+		assert.Equal(t, 2, cache.Len())
+		assert.True(t, isFirstKeyFound)
+		assert.False(t, isSecondKeyFound)
+		assert.True(t, isThirdKeyFound)
+	})
+}
+
+func TestNewResultFileCache(t *testing.T) {
+	t.Run("when cache file not exists", func(t *testing.T) {
+		path, capacity := filepath.Join(t.TempDir(), "results.json"), randomPositiveNumber()
+		cache, err := NewResultFileCache(path, capacity, time.Second)
+
+		assert.NoError(t, err)
+		assert.Equal(t, path, cache.path)
+		assert.Equal(t, capacity, cache.capacity)
+		assert.Equal(t, time.Second, cache.flushInterval)
+		assert.Equal(t, 0, cache.Len())
+		assert.NotNil(t, cache.now)
+	})
+
+	t.Run("loads not expired cache entries from existing cache file", func(t *testing.T) {
+		path, entry := filepath.Join(t.TempDir(), "results.json"), createResultCacheEntry()
+		currentTime := time.Now()
+		cache, _ := NewResultFileCache(path, 2, 0)
+		cache.now = func() time.Time { return currentTime.Add(-time.Hour) }
+		_ = cache.Set("expired", entry, time.Minute)
+		cache.now = time.Now
+		_ = cache.Set("actual", entry, time.Minute)
+		loadedCache, err := NewResultFileCache(path, 2, 0)
+		cachedEntry, ok := loadedCache.Get("actual")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, loadedCache.Len())
+		assert.True(t, ok)
+		assert.Equal(t, entry, cachedEntry)
+	})
+
+	t.Run("loads cache entries which expire later when capacity reached", func(t *testing.T) {
+		path, entry := filepath.Join(t.TempDir(), "results.json"), createResultCacheEntry()
+		cache, _ := NewResultFileCache(path, 2, 0)
+		_ = cache.Set("a@a.com", entry, time.Hour)
+		_ = cache.Set("b@b.com", entry, time.Minute)
+		loadedCache, err := NewResultFileCache(path, 1, 0)
+		_, isFirstKeyFound := loadedCache.Get("a@a.com")
+		_, isSecondKeyFound := loadedCache.Get("b@b.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, loadedCache.Len())
+		assert.True(t, isFirstKeyFound)
+		assert.False(t, isSecondKeyFound)
+	})
+
+	t.Run("when capacity is not positive", func(t *testing.T) {
+		_, err := NewResultFileCache(filepath.Join(t.TempDir(), "results.json"), 0, 0)
+
+		assert.EqualError(t, err, "result file cache capacity should be a positive integer")
+	})
+
+	t.Run("when cache file is invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		_ = os.WriteFile(path, []byte("invalid"), 0o600)
+		_, err := NewResultFileCache(path, 1, 0)
+
+		assert.ErrorContains(t, err, path+" is invalid result cache file")
+	})
+
+	t.Run("when cache file can not be read", func(t *testing.T) {
+		_, err := NewResultFileCache(t.TempDir(), 1, 0)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestResultFileCacheGet(t *testing.T) {
+	key, entry := randomEmail(), createResultCacheEntry()
+
+	t.Run("when entry not found", func(t *testing.T) {
+		cache, _ := NewResultFileCache(filepath.Join(t.TempDir(), "results.json"), 1, 0)
+		_, ok := cache.Get(key)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("when entry found and expired", func(t *testing.T) {
+		currentTime := time.Now()
+		cache, _ := NewResultFileCache(filepath.Join(t.TempDir(), "results.json"), 1, 0)
+		cache.now = func() time.Time { return currentTime }
+		_ = cache.Set(key, entry, time.Minute)
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		_, ok := cache.Get(key)
+
+		assert.False(t, ok)
+	})
+}
+
+func TestResultFileCacheSet(t *testing.T) {
+	key, entry := randomEmail(), createResultCacheEntry()
+
+	t.Run("when ttl is not positive", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		cache, _ := NewResultFileCache(path, 1, 0)
+
+		assert.NoError(t, cache.Set(key, entry, 0))
+		assert.Equal(t, 0, cache.Len())
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("persists cache entries into cache file", func(t *testing.T) {
+		directory := t.TempDir()
+		path := filepath.Join(directory, "results.json")
+		cache, _ := NewResultFileCache(path, 1, 0)
+		err := cache.Set(key, entry, time.Minute)
+		files, _ := os.ReadDir(directory)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, path)
+		assert.Len(t, files, 1)
+		assert.False(t, cache.isChanged)
+	})
+
+	t.Run("when capacity reached evicts least recently used entry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		cache, _ := NewResultFileCache(path, 1, 0)
+		_ = cache.Set("a@a.com", entry, time.Minute)
+		_ = cache.Set("b@b.com", entry, time.Minute)
+		loadedCache, _ := NewResultFileCache(path, 2, 0)
+		_, isFirstKeyFound := loadedCache.Get("a@a.com")
+		_, isSecondKeyFound := loadedCache.Get("b@b.com")
+
+		assert.Equal(t, 1, cache.Len())
+		assert.Equal(t, 1, loadedCache.Len())
+		assert.False(t, isFirstKeyFound)
+		assert.True(t, isSecondKeyFound)
+	})
+
+	t.Run("persists cache entries once per flush interval", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		cache, _ := NewResultFileCache(path, 2, time.Minute)
+		currentTime := cache.flushedAt
+		cache.now = func() time.Time { return currentTime }
+		_ = cache.Set("a@a.com", entry, time.Hour)
+
+		assert.NoFileExists(t, path)
+		assert.True(t, cache.isChanged)
+
+		cache.now = func() time.Time { return currentTime.Add(time.Minute) }
+		_ = cache.Set("b@b.com", entry, time.Hour)
+		loadedCache, _ := NewResultFileCache(path, 2, time.Minute)
+
+		assert.Equal(t, 2, loadedCache.Len())
+		assert.False(t, cache.isChanged)
+		assert.Equal(t, currentTime.Add(time.Minute), cache.flushedAt)
+	})
+
+	t.Run("when cache file can not be written keeps cache entries in memory", func(t *testing.T) {
+		cache, _ := NewResultFileCache(filepath.Join(t.TempDir(), "missing", "results.json"), 1, 0)
+		err := cache.Set(key, entry, time.Minute)
+		cachedEntry, ok := cache.Get(key)
+
+		assert.Error(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, entry, cachedEntry)
+		assert.True(t, cache.isChanged)
+	})
+}
+
+func TestResultFileCacheFlush(t *testing.T) {
+	key, entry := randomEmail(), createResultCacheEntry()
+
+	t.Run("persists pending changes into cache file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		cache, _ := NewResultFileCache(path, 1, time.Hour)
+		_ = cache.Set(key, entry, time.Minute)
+		err := cache.Flush()
+		loadedCache, _ := NewResultFileCache(path, 1, time.Hour)
+		cachedEntry, ok := loadedCache.Get(key)
+
+		assert.NoError(t, err)
+		assert.False(t, cache.isChanged)
+		assert.True(t, ok)
+		assert.Equal(t, entry, cachedEntry)
+	})
+
+	t.Run("when there are no pending changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "results.json")
+		cache, _ := NewResultFileCache(path, 1, time.Hour)
+
+		assert.NoError(t, cache.Flush())
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("when cache file can not be written", func(t *testing.T) {
+		cache, _ := NewResultFileCache(filepath.Join(t.TempDir(), "missing", "results.json"), 1, time.Hour)
+		_ = cache.Set(key, entry, time.Minute)
+
+		assert.Error(t, cache.Flush())
+		assert.True(t, cache.isChanged)
+	})
+}
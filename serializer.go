@@ -0,0 +1,118 @@
+package truemail
+
+import "time"
+
+// ValidatorResultJson structure. JSON representation of validator result,
+// compatible with validator JSON serializer of Ruby truemail gem
+type ValidatorResultJson struct {
+	Date           string             `json:"date"`
+	Email          string             `json:"email"`
+	Domain         string             `json:"domain"`
+	ValidationType string             `json:"validation_type"`
+	Success        bool               `json:"success"`
+	FromCache      bool               `json:"from_cache"`
+	MailServers    []string           `json:"mail_servers"`
+	Errors         map[string]string  `json:"errors"`
+	SmtpDebug      []*SmtpDebugJson   `json:"smtp_debug"`
+	Configuration  *ConfigurationJson `json:"configuration"`
+}
+
+// SmtpDebugJson structure. JSON representation of SMTP request: mail server
+// which was probed, RCPTTO acceptance and SMTP session errors
+type SmtpDebugJson struct {
+	MailHost string   `json:"mail_host"`
+	Rcptto   bool     `json:"rcptto"`
+	Errors   []string `json:"errors"`
+}
+
+// ConfigurationJson structure. JSON representation of configuration
+// options which have an effect on validation verdict
+type ConfigurationJson struct {
+	ValidationTypeByDomain   map[string]string `json:"validation_type_by_domain"`
+	WhitelistValidation      bool              `json:"whitelist_validation"`
+	WhitelistedDomains       []string          `json:"whitelisted_domains"`
+	BlacklistedDomains       []string          `json:"blacklisted_domains"`
+	BlacklistedMxIpAddresses []string          `json:"blacklisted_mx_ip_addresses"`
+	Dns                      []string          `json:"dns"`
+	NotRfcMxLookupFlow       bool              `json:"not_rfc_mx_lookup_flow"`
+	SmtpFailFast             bool              `json:"smtp_fail_fast"`
+	SmtpSafeCheck            bool              `json:"smtp_safe_check"`
+	EmailPattern             string            `json:"email_pattern"`
+	SmtpErrorBodyPattern     string            `json:"smtp_error_body_pattern"`
+}
+
+// NewValidatorResultJson returns JSON representation of validator result
+// with current date. Empty collections are represented as null
+func NewValidatorResultJson(validatorResult *ValidatorResult) *ValidatorResultJson {
+	validatorResultJson := &ValidatorResultJson{
+		Date:           time.Now().Format(time.RFC3339),
+		Email:          validatorResult.Email,
+		Domain:         validatorResult.Domain,
+		ValidationType: validatorResult.ValidationType,
+		Success:        validatorResult.Success,
+		FromCache:      validatorResult.FromCache,
+		MailServers:    nilIfEmptyStrings(validatorResult.MailServers),
+		Configuration:  newConfigurationJson(validatorResult.Configuration),
+	}
+	if len(validatorResult.Errors) > 0 {
+		validatorResultJson.Errors = validatorResult.Errors
+	}
+	for _, smtpRequest := range validatorResult.SmtpDebug {
+		validatorResultJson.SmtpDebug = append(validatorResultJson.SmtpDebug, newSmtpDebugJson(smtpRequest))
+	}
+
+	return validatorResultJson
+}
+
+// Returns JSON representation of SMTP request
+func newSmtpDebugJson(smtpRequest *SmtpRequest) *SmtpDebugJson {
+	smtpDebugJson := &SmtpDebugJson{MailHost: smtpRequest.Host}
+	if smtpResponse := smtpRequest.Response; smtpResponse != nil {
+		smtpDebugJson.Rcptto = smtpResponse.Rcptto
+		for _, smtpClientError := range smtpResponse.Errors {
+			if smtpClientError != nil && smtpClientError.err != nil {
+				smtpDebugJson.Errors = append(smtpDebugJson.Errors, smtpClientError.Error())
+			}
+		}
+	}
+
+	return smtpDebugJson
+}
+
+// Returns JSON representation of configuration. Returns nil when configuration is not specified
+func newConfigurationJson(configuration *Configuration) *ConfigurationJson {
+	if configuration == nil {
+		return nil
+	}
+
+	configurationJson := &ConfigurationJson{
+		WhitelistValidation:      configuration.WhitelistValidation,
+		WhitelistedDomains:       nilIfEmptyStrings(configuration.WhitelistedDomains),
+		BlacklistedDomains:       nilIfEmptyStrings(configuration.BlacklistedDomains),
+		BlacklistedMxIpAddresses: nilIfEmptyStrings(configuration.BlacklistedMxIpAddresses),
+		Dns:                      nilIfEmptyStrings(configuration.dnsServers()),
+		NotRfcMxLookupFlow:       configuration.NotRfcMxLookupFlow,
+		SmtpFailFast:             configuration.SmtpFailFast,
+		SmtpSafeCheck:            configuration.SmtpSafeCheck,
+	}
+	if len(configuration.ValidationTypeByDomain) > 0 {
+		configurationJson.ValidationTypeByDomain = configuration.ValidationTypeByDomain
+	}
+	if configuration.EmailPattern != nil {
+		configurationJson.EmailPattern = configuration.EmailPattern.String()
+	}
+	if configuration.SmtpErrorBodyPattern != nil {
+		configurationJson.SmtpErrorBodyPattern = configuration.SmtpErrorBodyPattern.String()
+	}
+
+	return configurationJson
+}
+
+// Returns nil when slice of strings is empty, otherwise returns slice of strings
+func nilIfEmptyStrings(strSlice []string) []string {
+	if len(strSlice) == 0 {
+		return nil
+	}
+
+	return strSlice
+}
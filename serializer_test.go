@@ -0,0 +1,79 @@
+package truemail
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidatorResultJson(t *testing.T) {
+	t.Run("returns JSON representation of validator result", func(t *testing.T) {
+		email, domain := pairRandomEmailDomain()
+		mailServer, configuration := randomIpAddress(), createConfiguration()
+		configuration.BlacklistedDomains, configuration.SmtpFailFast = []string{domain}, true
+		validatorResult := &ValidatorResult{
+			Email:          email,
+			Domain:         domain,
+			ValidationType: validationTypeSmtp,
+			MailServers:    []string{mailServer},
+			Errors:         map[string]string{validationTypeSmtp: smtpErrorContext},
+			Configuration:  configuration,
+			SmtpDebug: []*SmtpRequest{
+				{
+					Host: mailServer,
+					Response: &SmtpResponse{
+						Errors: []*SmtpClientError{{isRecptTo: true, err: errors.New("550 User unknown")}},
+					},
+				},
+			},
+		}
+		validatorResultJson := NewValidatorResultJson(validatorResult)
+		date, err := time.Parse(time.RFC3339, validatorResultJson.Date)
+
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), date, time.Minute)
+		assert.Equal(t, email, validatorResultJson.Email)
+		assert.Equal(t, domain, validatorResultJson.Domain)
+		assert.Equal(t, validationTypeSmtp, validatorResultJson.ValidationType)
+		assert.False(t, validatorResultJson.Success)
+		assert.Equal(t, []string{mailServer}, validatorResultJson.MailServers)
+		assert.Equal(t, validatorResult.Errors, validatorResultJson.Errors)
+		assert.Equal(t, []*SmtpDebugJson{{MailHost: mailServer, Errors: []string{"550 User unknown"}}}, validatorResultJson.SmtpDebug)
+		assert.Equal(t, []string{domain}, validatorResultJson.Configuration.BlacklistedDomains)
+		assert.True(t, validatorResultJson.Configuration.SmtpFailFast)
+		assert.Equal(t, regexEmailPattern, validatorResultJson.Configuration.EmailPattern)
+		assert.Equal(t, regexSMTPErrorBodyPattern, validatorResultJson.Configuration.SmtpErrorBodyPattern)
+	})
+
+	t.Run("represents empty collections as null", func(t *testing.T) {
+		validatorResult := &ValidatorResult{Success: true, Email: randomEmail(), Errors: map[string]string{}}
+		data, err := json.Marshal(NewValidatorResultJson(validatorResult))
+		decoded := make(map[string]interface{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Nil(t, decoded["errors"])
+		assert.Nil(t, decoded["mail_servers"])
+		assert.Nil(t, decoded["smtp_debug"])
+		assert.Nil(t, decoded["configuration"])
+		assert.Equal(t, true, decoded["success"])
+	})
+}
+
+func TestNewConfigurationJson(t *testing.T) {
+	t.Run("returns DNS servers of configuration", func(t *testing.T) {
+		dns, configuration := randomDnsServer(), createConfiguration()
+		configuration.Dns, configuration.ValidationTypeByDomain = dns, map[string]string{"example.com": validationTypeRegex}
+		configurationJson := newConfigurationJson(configuration)
+
+		assert.Equal(t, []string{dns}, configurationJson.Dns)
+		assert.Equal(t, configuration.ValidationTypeByDomain, configurationJson.ValidationTypeByDomain)
+	})
+
+	t.Run("when configuration is not specified", func(t *testing.T) {
+		assert.Nil(t, newConfigurationJson(nil))
+	})
+}
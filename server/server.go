@@ -0,0 +1,240 @@
+// Package server provides HTTP JSON validation server, Go counterpart to truemail-rack.
+// Server validates emails with shared truemail configuration and responds with JSON
+// representation of validator result. Requests are authenticated with access tokens
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/truemail-rb/truemail-go"
+)
+
+const (
+	defaultAddress           = ":9292"
+	defaultRequestTimeout    = 30
+	defaultShutdownTimeout   = 10
+	defaultReadHeaderTimeout = 5 * time.Second
+	maxRequestBodySize       = 1 << 20
+	headerAuthorization      = "Authorization"
+	headerContentType        = "Content-Type"
+	contentTypeJson          = "application/json"
+	authorizationBearer      = "Bearer "
+	paramEmail               = "email"
+	paramType                = "type"
+	emptyString              = ""
+)
+
+// ServerAttr kwargs structure for server builder
+type ServerAttr struct {
+	Configuration                   *truemail.Configuration
+	AccessTokens                    []string
+	Address                         string
+	RequestTimeout, ShutdownTimeout int
+}
+
+// Server structure. Validation server is http.Handler, it can be mounted into
+// existing HTTP server or run with ListenAndServe
+type Server struct {
+	configuration                   *truemail.Configuration
+	accessTokens                    []string
+	address                         string
+	requestTimeout, shutdownTimeout time.Duration
+	handler                         http.Handler
+}
+
+// Validation request structure, JSON body of POST request
+type validationRequest struct {
+	Email string `json:"email"`
+	Type  string `json:"type"`
+}
+
+// Error response structure
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns validation server with shared configuration. Address is equal to ":9292",
+// request timeout is equal to 30 seconds and shutdown timeout is equal to 10 seconds by default.
+// Returns error if server attributes are invalid
+func NewServer(attr ServerAttr) (*Server, error) {
+	if attr.Configuration == nil {
+		return nil, errors.New("configuration is required")
+	}
+
+	var accessTokens []string
+	for _, accessToken := range attr.AccessTokens {
+		if accessToken = strings.TrimSpace(accessToken); accessToken != emptyString {
+			accessTokens = append(accessTokens, accessToken)
+		}
+	}
+	if len(accessTokens) == 0 {
+		return nil, errors.New("at least one access token is required")
+	}
+
+	if attr.RequestTimeout < 0 || attr.ShutdownTimeout < 0 {
+		return nil, errors.New("timeout should be a positive integer")
+	}
+	if attr.Address == emptyString {
+		attr.Address = defaultAddress
+	}
+	if attr.RequestTimeout == 0 {
+		attr.RequestTimeout = defaultRequestTimeout
+	}
+	if attr.ShutdownTimeout == 0 {
+		attr.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	server := &Server{
+		configuration:   attr.Configuration,
+		accessTokens:    accessTokens,
+		address:         attr.Address,
+		requestTimeout:  time.Duration(attr.RequestTimeout) * time.Second,
+		shutdownTimeout: time.Duration(attr.ShutdownTimeout) * time.Second,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthcheck", server.healthcheck)
+	mux.HandleFunc("GET /{$}", server.authenticate(server.validate))
+	mux.HandleFunc("POST /{$}", server.authenticate(server.validate))
+	server.handler = mux
+
+	return server, nil
+}
+
+// Server methods
+
+// Address returns server address
+func (server *Server) Address() string {
+	return server.address
+}
+
+// ServeHTTP implements http.Handler interface
+func (server *Server) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	server.handler.ServeHTTP(responseWriter, request)
+}
+
+// ListenAndServe listens on server address and serves validation requests
+// until context is done, then gracefully shuts down the server
+func (server *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(ctx, listener)
+}
+
+// Serve serves validation requests on listener until context is done, then gracefully
+// shuts down the server: stops accepting new requests and waits for active requests
+// during shutdown timeout
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: defaultReadHeaderTimeout}
+	serveError := make(chan error, 1)
+	go func() { serveError <- httpServer.Serve(listener) }()
+
+	select {
+	case err := <-serveError:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownContext, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownContext); err != nil {
+		return err
+	}
+	if err := <-serveError; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Responds to health check request
+func (server *Server) healthcheck(responseWriter http.ResponseWriter, request *http.Request) {
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+// Returns handler which responds with unauthorized status when request
+// authorization header does not include valid access token
+func (server *Server) authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		if !server.isAuthorized(request.Header.Get(headerAuthorization)) {
+			writeJson(responseWriter, http.StatusUnauthorized, &errorResponse{Error: "unauthorized"})
+			return
+		}
+
+		handler(responseWriter, request)
+	}
+}
+
+// Checks authorization header includes one of access tokens, with or without Bearer scheme
+func (server *Server) isAuthorized(authorization string) bool {
+	accessToken := []byte(strings.TrimPrefix(authorization, authorizationBearer))
+	for _, serverAccessToken := range server.accessTokens {
+		if subtle.ConstantTimeCompare(accessToken, []byte(serverAccessToken)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validates email from query parameters of GET request or JSON body of POST request
+// within request timeout. Responds with JSON representation of validator result
+func (server *Server) validate(responseWriter http.ResponseWriter, request *http.Request) {
+	validationRequest, err := parseValidationRequest(responseWriter, request)
+	if err != nil {
+		writeJson(responseWriter, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	var options []string
+	if validationRequest.Type != emptyString {
+		options = append(options, validationRequest.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), server.requestTimeout)
+	defer cancel()
+	validatorResult, err := truemail.ValidateContext(ctx, validationRequest.Email, server.configuration, options...)
+	if err != nil {
+		writeJson(responseWriter, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJson(responseWriter, http.StatusOK, truemail.NewValidatorResultJson(validatorResult))
+}
+
+// Returns validation request from query parameters of GET request or JSON body
+// of POST request. Returns error if request body is invalid or email is missing
+func parseValidationRequest(responseWriter http.ResponseWriter, request *http.Request) (*validationRequest, error) {
+	validationRequest := &validationRequest{
+		Email: request.URL.Query().Get(paramEmail),
+		Type:  request.URL.Query().Get(paramType),
+	}
+	if request.Method == http.MethodPost {
+		body := http.MaxBytesReader(responseWriter, request.Body, maxRequestBodySize)
+		if err := json.NewDecoder(body).Decode(validationRequest); err != nil {
+			return nil, errors.New("request body should be a JSON object")
+		}
+	}
+
+	if validationRequest.Email = strings.TrimSpace(validationRequest.Email); validationRequest.Email == emptyString {
+		return nil, errors.New("email is required")
+	}
+
+	return validationRequest, nil
+}
+
+// Writes JSON response with status code
+func writeJson(responseWriter http.ResponseWriter, statusCode int, response interface{}) {
+	responseWriter.Header().Set(headerContentType, contentTypeJson)
+	responseWriter.WriteHeader(statusCode)
+	_ = json.NewEncoder(responseWriter).Encode(response)
+}
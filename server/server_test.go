@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/truemail-rb/truemail-go"
+)
+
+const accessToken = "access-token"
+
+// Creates validation server with regex validation type by default
+func createServer(t *testing.T) *Server {
+	configuration, err := truemail.NewConfiguration(
+		truemail.ConfigurationAttr{VerifierEmail: "verifier@example.com", ValidationTypeDefault: "regex"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{accessToken}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server
+}
+
+// Sends request to server. Returns recorded response
+func sendRequest(server *Server, method, target, body, authorization string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if authorization != emptyString {
+		request.Header.Set(headerAuthorization, authorization)
+	}
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+
+	return response
+}
+
+// Decodes JSON response body into value
+func decodeResponse(t *testing.T, response *httptest.ResponseRecorder, value interface{}) {
+	assert.Equal(t, contentTypeJson, response.Header().Get(headerContentType))
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(value))
+}
+
+func TestNewServer(t *testing.T) {
+	configuration, _ := truemail.NewConfiguration(truemail.ConfigurationAttr{VerifierEmail: "verifier@example.com"})
+
+	t.Run("creates server with default values", func(t *testing.T) {
+		server, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{" " + accessToken + " ", ""}})
+
+		assert.NoError(t, err)
+		assert.Same(t, configuration, server.configuration)
+		assert.Equal(t, []string{accessToken}, server.accessTokens)
+		assert.Equal(t, defaultAddress, server.Address())
+		assert.Equal(t, defaultRequestTimeout*time.Second, server.requestTimeout)
+		assert.Equal(t, defaultShutdownTimeout*time.Second, server.shutdownTimeout)
+	})
+
+	t.Run("creates server with custom values", func(t *testing.T) {
+		server, err := NewServer(
+			ServerAttr{
+				Configuration:   configuration,
+				AccessTokens:    []string{accessToken},
+				Address:         "127.0.0.1:8080",
+				RequestTimeout:  5,
+				ShutdownTimeout: 1,
+			},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:8080", server.address)
+		assert.Equal(t, 5*time.Second, server.requestTimeout)
+		assert.Equal(t, time.Second, server.shutdownTimeout)
+	})
+
+	t.Run("when configuration is not specified", func(t *testing.T) {
+		_, err := NewServer(ServerAttr{AccessTokens: []string{accessToken}})
+
+		assert.EqualError(t, err, "configuration is required")
+	})
+
+	t.Run("when access tokens are not specified", func(t *testing.T) {
+		_, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{" "}})
+
+		assert.EqualError(t, err, "at least one access token is required")
+	})
+
+	t.Run("when timeout is invalid", func(t *testing.T) {
+		_, err := NewServer(ServerAttr{Configuration: configuration, AccessTokens: []string{accessToken}, RequestTimeout: -1})
+
+		assert.EqualError(t, err, "timeout should be a positive integer")
+	})
+}
+
+func TestServerHealthcheck(t *testing.T) {
+	t.Run("responds with ok status without authorization", func(t *testing.T) {
+		response := sendRequest(createServer(t), http.MethodGet, "/healthcheck", emptyString, emptyString)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, response.Body.String())
+	})
+}
+
+func TestServerValidate(t *testing.T) {
+	server := createServer(t)
+
+	t.Run("validates email from query parameters of GET request", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/?email=email@example.com", emptyString, accessToken)
+		var validatorResultJson truemail.ValidatorResultJson
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		decodeResponse(t, response, &validatorResultJson)
+		assert.True(t, validatorResultJson.Success)
+		assert.Equal(t, "email@example.com", validatorResultJson.Email)
+		assert.Equal(t, "regex", validatorResultJson.ValidationType)
+	})
+
+	t.Run("validates email from JSON body of POST request", func(t *testing.T) {
+		response := sendRequest(server, http.MethodPost, "/", `{"email": "invalid-email", "type": "regex"}`, "Bearer "+accessToken)
+		var validatorResultJson truemail.ValidatorResultJson
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		decodeResponse(t, response, &validatorResultJson)
+		assert.False(t, validatorResultJson.Success)
+		assert.Contains(t, validatorResultJson.Errors, "regex")
+	})
+
+	t.Run("when access token is invalid", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/?email=email@example.com", emptyString, "random")
+		var errorResponse errorResponse
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		decodeResponse(t, response, &errorResponse)
+		assert.Equal(t, "unauthorized", errorResponse.Error)
+	})
+
+	t.Run("when authorization header is missing", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/?email=email@example.com", emptyString, emptyString)
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("when email is missing", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/", emptyString, accessToken)
+		var errorResponse errorResponse
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		decodeResponse(t, response, &errorResponse)
+		assert.Equal(t, "email is required", errorResponse.Error)
+	})
+
+	t.Run("when request body is invalid", func(t *testing.T) {
+		response := sendRequest(server, http.MethodPost, "/", "email=email@example.com", accessToken)
+		var errorResponse errorResponse
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		decodeResponse(t, response, &errorResponse)
+		assert.Equal(t, "request body should be a JSON object", errorResponse.Error)
+	})
+
+	t.Run("when validation type is invalid", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/?email=email@example.com&type=random", emptyString, accessToken)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("when method is not allowed", func(t *testing.T) {
+		response := sendRequest(server, http.MethodDelete, "/", emptyString, accessToken)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	})
+
+	t.Run("when path is not found", func(t *testing.T) {
+		response := sendRequest(server, http.MethodGet, "/random", emptyString, accessToken)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestServerServe(t *testing.T) {
+	t.Run("serves requests until context is done, then shuts down", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		serveError := make(chan error, 1)
+		go func() { serveError <- createServer(t).Serve(ctx, listener) }()
+
+		response, err := http.Get("http://" + listener.Addr().String() + "/healthcheck")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		response.Body.Close()
+
+		cancel()
+		assert.NoError(t, <-serveError)
+		_, err = http.Get("http://" + listener.Addr().String() + "/healthcheck")
+		assert.Error(t, err)
+	})
+
+	t.Run("when listener is closed", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.Close()
+
+		assert.Error(t, createServer(t).Serve(context.Background(), listener))
+	})
+}
+
+func TestServerListenAndServe(t *testing.T) {
+	t.Run("when address is invalid", func(t *testing.T) {
+		server := createServer(t)
+		server.address = "invalid-address"
+
+		assert.Error(t, server.ListenAndServe(context.Background()))
+	})
+}
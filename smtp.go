@@ -1,9 +1,16 @@
 package truemail
 
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
 // SMTP validation, fourth validation level
 type validationSmtp struct {
-	result      *ValidatorResult
-	smtpResults []*SmtpRequest
+	result        *ValidatorResult
+	smtpResults   []*SmtpRequest
+	isRateLimited atomic.Bool
 	builder
 }
 
@@ -13,11 +20,38 @@ func (validation *validationSmtp) check(validatorResult *ValidatorResult) *Valid
 	validation.initSmtpBuilder()
 	validation.run()
 
-	if validation.isIncludesSuccessfulSmtpResponse() {
+	// SMTP transcript, TLS details and DANE outcome are available for successful validation too
+	validation.result.SmtpDebug = validation.smtpResults
+
+	if validation.isRateLimitedOutcome() {
+		validatorResult.Success = false
+		validatorResult.addError(validationTypeSmtp, smtpRateLimitedErrorContext)
 		return validatorResult
 	}
 
-	validation.result.SmtpDebug = validation.smtpResults
+	// SMTP safe check scenario treats greylisted email as valid
+	if !validation.isSmtpSafeCheckEnabled() && validation.isGreylistedOutcome() {
+		validatorResult.Success = false
+		validatorResult.addError(validationTypeSmtp, smtpGreylistedErrorContext)
+		validation.scheduleGreylistingRetry()
+		return validatorResult
+	}
+
+	if validation.isIncludesTrustedSmtpResponse() {
+		return validatorResult
+	}
+
+	// Successful RCPT TO of provider which does not trust RCPT TO or of catch-all target
+	// server does not confirm email existence
+	if validation.isIncludesSuccessfulSmtpResponse() {
+		if validation.isSmtpSafeCheckEnabled() {
+			return validatorResult
+		}
+
+		validatorResult.Success = false
+		validatorResult.addError(validationTypeSmtp, smtpNotConfirmedErrorContext)
+		return validatorResult
+	}
 
 	if validation.isSmtpSafeCheckEnabled() && validation.isNotIncludeUserNotFoundErrors() {
 		return validatorResult
@@ -36,18 +70,84 @@ func (validation *validationSmtp) initSmtpBuilder() {
 	validation.builder = new(smtpBuilder)
 }
 
-// Runs SMTP session for each target server until receive successful session response
+// Runs SMTP session for each target server until receive successful session response.
+// Target servers are probed in parallel when SMTP parallel probing scenario is enabled.
+// SMTP sessions are not run when recipient domain is rate limited
 func (validation *validationSmtp) run() {
-	for _, targetHostAddress := range validation.filteredMailServersByFailFastScenario() {
+	configuration := validation.result.Configuration
+	if !configuration.takeSmtpDomainToken(validation.result.Domain) {
+		validation.isRateLimited.Store(true)
+		configuration.logEvent(
+			slog.LevelWarn,
+			logMessageSmtpRateLimited,
+			slog.String(logAttrDomain, validation.result.Domain),
+		)
+		return
+	}
+
+	mailServers := validation.filteredMailServersByFailFastScenario()
+	if validation.isParallelProbingScenario(mailServers) {
+		validation.runParallelProbing(mailServers)
+		return
+	}
+
+	for _, targetHostAddress := range mailServers {
 		if validation.runSmtpSession(targetHostAddress) {
 			break
 		}
 	}
 }
 
+// Runs SMTP sessions with mail servers of each MX priority group in parallel. Following
+// priority groups are not probed when definitive answer is received
+func (validation *validationSmtp) runParallelProbing(mailServers []string) {
+	for _, mailServersGroup := range validation.mailServerPriorityGroups(mailServers) {
+		if validation.runParallelProbe(mailServersGroup) {
+			break
+		}
+	}
+}
+
+// Runs SMTP sessions with mail servers in parallel, limited by SMTP parallel probing limit.
+// Running SMTP sessions are cancelled and SMTP sessions which are not started yet are
+// skipped once definitive answer is received. Returns true for definitive answer,
+// otherwise returns false
+func (validation *validationSmtp) runParallelProbe(mailServers []string) bool {
+	probe, waitGroup := newSmtpParallelProbe(), new(sync.WaitGroup)
+	semaphore := make(chan struct{}, validation.parallelProbingLimit(len(mailServers)))
+
+	for _, targetHostAddress := range mailServers {
+		semaphore <- struct{}{}
+		if probe.isFinished() {
+			<-semaphore
+			break
+		}
+
+		smtpRequest := validation.prepareSmtpRequest(targetHostAddress)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			validation.runSmtpRequest(smtpRequest, probe)
+			if validation.isDefinitiveSmtpResponse(smtpRequest.Response) {
+				probe.finish()
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	return probe.isFinished()
+}
+
 // Runs SMTP session for target mail server. Returns
 // true for successful session, otherwise returns false
 func (validation *validationSmtp) runSmtpSession(targetHostAddress string) bool {
+	return validation.runSmtpRequest(validation.prepareSmtpRequest(targetHostAddress), nil)
+}
+
+// Creates SMTP request for target mail server and addes it to SMTP results
+func (validation *validationSmtp) prepareSmtpRequest(targetHostAddress string) *SmtpRequest {
 	validatorResult, validatorBuilder := validation.result, validation.builder
 	smtpRequest := validatorBuilder.newSmtpRequest(
 		validation.attempts(),
@@ -55,24 +155,154 @@ func (validation *validationSmtp) runSmtpSession(targetHostAddress string) bool
 		targetHostAddress,
 		validatorResult.Configuration,
 	)
-	smtpResponse := smtpRequest.Response
+	smtpRequest.Configuration.TargetServerName = validatorResult.mailServerHostName(targetHostAddress)
+	smtpRequest.Provider = validatorResult.Configuration.smtpProviderProfile(smtpRequest.Configuration.TargetServerName)
+	if smtpRequest.Provider != nil {
+		smtpRequest.Configuration.CatchAllProbing = smtpRequest.Provider.CatchAllProbing
+		smtpRequest.Configuration.DataProbing = smtpRequest.Provider.DataProbing
+	}
+	if validatorResult.isStartTlsRequired() {
+		smtpRequest.Configuration.TlsPolicy = smtpTlsPolicyRequired
+	}
+	validation.assignDaneResult(smtpRequest)
+	validation.assignSourceAddress(smtpRequest)
 	validation.smtpResults = append(validation.smtpResults, smtpRequest)
 
+	return smtpRequest
+}
+
+// Runs SMTP session attempts of SMTP request. SMTP session is cancelled by SMTP parallel
+// probe when definitive answer was received from other mail server, probe is nil for
+// sequential SMTP sessions. Returns true for successful session, otherwise returns false
+func (validation *validationSmtp) runSmtpRequest(smtpRequest *SmtpRequest, probe *smtpParallelProbe) bool {
+	smtpResponse := smtpRequest.Response
+
 	for smtpRequest.Attempts > 0 {
-		smtpClient := validatorBuilder.newSmtpClient(smtpRequest.Configuration)
+		if !validation.takeSmtpHostToken(smtpRequest) {
+			smtpResponse.Errors = append(smtpResponse.Errors, newSmtpRateLimitError())
+			return false
+		}
+
+		smtpClient := validation.builder.newSmtpClient(smtpRequest.Configuration)
+		if !probe.register(smtpClient) {
+			smtpResponse.Errors = append(smtpResponse.Errors, newSmtpCancelledError())
+			return false
+		}
 		smtpRequest.Attempts -= 1
 
-		if smtpClient.runSession() {
+		isSuccessfulSession := smtpClient.runSession()
+		isCancelledSession := probe.unregister(smtpClient)
+		smtpResponse.Transcript = append(smtpResponse.Transcript, smtpClient.sessionTranscript()...)
+
+		if isSuccessfulSession {
 			smtpResponse.Rcptto = true
+			if smtpRequest.Configuration.CatchAllProbing {
+				smtpResponse.CatchAll = smtpClient.sessionCatchAll()
+			}
+			smtpResponse.RcpttoTrusted = !smtpRequest.Provider.isRcptUntrusted() && !smtpResponse.CatchAll
+			validation.assignSessionTls(smtpRequest, smtpClient)
+			smtpRequest.Dane.recordSession(nil)
 			return true
 		}
 
-		smtpResponse.Errors = append(smtpResponse.Errors, smtpClient.sessionError())
+		if isCancelledSession {
+			smtpResponse.Errors = append(smtpResponse.Errors, newSmtpCancelledError())
+			validation.assignSessionTls(smtpRequest, smtpClient)
+			return false
+		}
+
+		sessionError := smtpClient.sessionError()
+		sessionError.isGreylisted = validation.isGreylistingError(sessionError)
+		smtpResponse.Errors = append(smtpResponse.Errors, sessionError)
+		validation.assignSessionTls(smtpRequest, smtpClient)
+		smtpRequest.Dane.recordSession(sessionError)
+		validation.logSessionRetry(smtpRequest, sessionError)
+	}
+
+	return false
+}
+
+// Logs retry decision of failed SMTP session when SMTP request attempts are available.
+// Target email echoed in SMTP session error is redacted
+func (validation *validationSmtp) logSessionRetry(smtpRequest *SmtpRequest, sessionError *SmtpClientError) {
+	configuration := validation.result.Configuration
+	if configuration.Logger == nil || smtpRequest.Attempts == 0 {
+		return
+	}
+
+	var err error
+	if sessionError != nil {
+		err = sessionError.err
+	}
+
+	configuration.logEvent(
+		slog.LevelInfo,
+		logMessageSmtpSessionRetry,
+		slog.String(logAttrServerAddress, smtpRequest.Host),
+		slog.String(logAttrHost, smtpRequest.Configuration.TargetServerName),
+		slog.Int(logAttrAttemptsLeft, smtpRequest.Attempts),
+		redactedErrorLogAttr(err, smtpRequest.Email, configuration.LogEmailRedaction),
+	)
+}
+
+// Takes SMTP rate limit token of target host for SMTP connection. Returns false
+// and marks SMTP validation as rate limited when target host is rate limited
+func (validation *validationSmtp) takeSmtpHostToken(smtpRequest *SmtpRequest) bool {
+	if validation.result.Configuration.takeSmtpHostToken(smtpRequest.Host, smtpRequest.Configuration.TargetServerName) {
+		return true
 	}
 
+	validation.isRateLimited.Store(true)
+	validation.result.Configuration.logEvent(
+		slog.LevelWarn,
+		logMessageSmtpRateLimited,
+		slog.String(logAttrServerAddress, smtpRequest.Host),
+		slog.String(logAttrHost, smtpRequest.Configuration.TargetServerName),
+	)
 	return false
 }
 
+// Assigns SMTP source address to SMTP request. SMTP client binds source IP address
+// and uses its HELO domain instead of verifier domain when HELO domain is specified
+func (validation *validationSmtp) assignSourceAddress(smtpRequest *SmtpRequest) {
+	sourceAddress := validation.result.Configuration.smtpSourceAddress(validation.result.Domain)
+	if sourceAddress == nil {
+		return
+	}
+
+	smtpRequest.SourceAddress = sourceAddress
+	smtpRequest.Configuration.SourceIpAddress = sourceAddress.IpAddress
+	if sourceAddress.HeloDomain != emptyString {
+		smtpRequest.Configuration.VerifierDomain = sourceAddress.HeloDomain
+	}
+}
+
+// Assigns DANE result of target host to SMTP request. DANE TLSA records with DNSSEC
+// authenticated answer are used for certificate verification and require STARTTLS
+func (validation *validationSmtp) assignDaneResult(smtpRequest *SmtpRequest) {
+	daneResult := validation.result.daneResult(smtpRequest.Host)
+	smtpRequest.Dane = daneResult
+	if !daneResult.isUsable() {
+		return
+	}
+
+	smtpRequest.Configuration.TlsPolicy = smtpTlsPolicyRequired
+	smtpRequest.Configuration.TlsaRecords = daneResult.TlsaRecords
+}
+
+// Assigns TLS details of SMTP session to SMTP response when STARTTLS is enabled by TLS policy
+func (validation *validationSmtp) assignSessionTls(smtpRequest *SmtpRequest, smtpClient client) {
+	switch smtpRequest.Configuration.TlsPolicy {
+	case smtpTlsPolicyOpportunistic, smtpTlsPolicyRequired:
+		smtpRequest.Response.Tls = smtpClient.sessionTls()
+	}
+}
+
+// Returns true if DANE check is enabled, otherwise returns false
+func (validation *validationSmtp) isDaneCheckEnabled() bool {
+	return validation.result.Configuration.DaneCheck
+}
+
 // Returns true if SMTP fail fast scenario is enabled, otherwise returns false
 func (validation *validationSmtp) isFailFastScenario() bool {
 	return validation.result.Configuration.SmtpFailFast
@@ -90,6 +320,55 @@ func (validation *validationSmtp) filteredMailServersByFailFastScenario() []stri
 	return mailServers
 }
 
+// Returns true if SMTP parallel probing is enabled and there are more than one
+// mail server to probe, otherwise returns false
+func (validation *validationSmtp) isParallelProbingScenario(mailServers []string) bool {
+	return validation.result.Configuration.SmtpParallelProbing && len(mailServers) > 1
+}
+
+// Returns number of SMTP sessions which run in parallel for mail servers priority group.
+// Equal to number of mail servers when SMTP parallel probing limit is not specified
+func (validation *validationSmtp) parallelProbingLimit(mailServersCount int) int {
+	limit := validation.result.Configuration.SmtpParallelProbingLimit
+	if limit == 0 || limit > mailServersCount {
+		return mailServersCount
+	}
+
+	return limit
+}
+
+// Returns mail servers grouped by MX record priority. Mail servers are sorted
+// by priority, so each group includes consecutive mail servers with the same priority
+func (validation *validationSmtp) mailServerPriorityGroups(mailServers []string) (mailServersGroups [][]string) {
+	validatorResult := validation.result
+
+	for index, mailServer := range mailServers {
+		if index == 0 || validatorResult.mailServerPriority(mailServer) != validatorResult.mailServerPriority(mailServers[index-1]) {
+			mailServersGroups = append(mailServersGroups, nil)
+		}
+		lastIndex := len(mailServersGroups) - 1
+		mailServersGroups[lastIndex] = append(mailServersGroups[lastIndex], mailServer)
+	}
+
+	return mailServersGroups
+}
+
+// Returns true if SMTP response is definitive answer of target server: successful RCPT TO
+// or RCPT TO permanent failure, otherwise returns false
+func (validation *validationSmtp) isDefinitiveSmtpResponse(smtpResponse *SmtpResponse) bool {
+	if smtpResponse.Rcptto {
+		return true
+	}
+
+	for _, err := range smtpResponse.Errors {
+		if smtpReply := err.Reply(); err.isRecptTo && smtpReply != nil && smtpReply.IsPermanent() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Returns true for case when more than one mail server exists, otherwise returns false
 func (validation *validationSmtp) isMoreThanOneMailServer() bool {
 	return len(validation.result.MailServers) > 1
@@ -125,17 +404,101 @@ func (validation *validationSmtp) isIncludesSuccessfulSmtpResponse() (successful
 	return successfulSmtpResponse
 }
 
+// Returns true for empty SMTP results or when SMTP results contain successful SMTP response
+// trusted by SMTP provider profile and not received from catch-all target server,
+// otherwise returns false
+func (validation *validationSmtp) isIncludesTrustedSmtpResponse() bool {
+	smtpResults := validation.smtpResults
+	if len(smtpResults) == 0 {
+		return true
+	}
+
+	for _, smtpRequest := range smtpResults {
+		if smtpRequest.Response.RcpttoTrusted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if SMTP validation was rate limited and SMTP results do not contain
+// successful SMTP response or UserNotFound errors, so email existence is unknown
+func (validation *validationSmtp) isRateLimitedOutcome() bool {
+	if !validation.isRateLimited.Load() {
+		return false
+	}
+
+	return (len(validation.smtpResults) == 0 || !validation.isIncludesSuccessfulSmtpResponse()) &&
+		validation.isNotIncludeUserNotFoundErrors()
+}
+
+// Returns true if SMTP client error is greylisting: RCPT TO temporary failure with 450 or 451
+// reply code, or other temporary failure reply which matches SMTP greylisting pattern
+func (validation *validationSmtp) isGreylistingError(err *SmtpClientError) bool {
+	if !err.isRecptTo {
+		return false
+	}
+
+	smtpReply := err.Reply()
+	if smtpReply == nil || !smtpReply.IsTemporary() {
+		return false
+	}
+
+	if smtpReply.Code == 450 || smtpReply.Code == 451 {
+		return true
+	}
+
+	greylistingPattern := validation.result.Configuration.SmtpGreylistingPattern
+	return greylistingPattern != nil && greylistingPattern.MatchString(err.Error())
+}
+
+// Returns true if SMTP results contain greylisting errors and do not contain successful
+// SMTP response or UserNotFound errors, so target server should be asked again later
+func (validation *validationSmtp) isGreylistedOutcome() bool {
+	if validation.isIncludesSuccessfulSmtpResponse() || !validation.isNotIncludeUserNotFoundErrors() {
+		return false
+	}
+
+	for _, smtpRequest := range validation.smtpResults {
+		for _, err := range smtpRequest.Response.Errors {
+			if err.isGreylisted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Schedules SMTP validation retry of greylisted email when SMTP greylisting retry delay is
+// specified. Final validator result is delivered to ValidatorResult.GreylistingRetry channel
+func (validation *validationSmtp) scheduleGreylistingRetry() {
+	validatorResult := validation.result
+	if validatorResult.Configuration.SmtpGreylistingRetryDelay == 0 || validatorResult.isSmtpRetry {
+		return
+	}
+
+	retry := newSmtpGreylistingRetry(validatorResult)
+	validatorResult.GreylistingRetry = retry.results
+	retry.schedule()
+}
+
 // Returns true if SMTP safe check scenario is enabled, otherwise returns false
 func (validation *validationSmtp) isSmtpSafeCheckEnabled() bool {
 	return validation.result.Configuration.SmtpSafeCheck
 }
 
-// Returns true if SMTP results does not contain UserNotFound erros,
-// otherwise terminates iteration and returns false
+// Returns true if SMTP results does not contain UserNotFound erros: RCPT TO errors with
+// mailbox unknown SMTP reply classification or matched by SMTP provider profile mailbox
+// unknown pattern, otherwise terminates iteration and returns false
 func (validation *validationSmtp) isNotIncludeUserNotFoundErrors() bool {
 	for _, smtpRequest := range validation.smtpResults {
 		for _, err := range smtpRequest.Response.Errors {
-			if err.isRecptTo && validation.result.Configuration.SmtpErrorBodyPattern.MatchString(err.Error()) {
+			if !err.isRecptTo {
+				continue
+			}
+
+			if err.isMailboxUnknown(validation.result.Configuration.SmtpErrorBodyPattern) || smtpRequest.Provider.isMailboxUnknownError(err) {
 				return false
 			}
 		}
@@ -1,15 +1,34 @@
 package truemail
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/smtp"
+	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SMTP request configuration. Provides connection/request settings for SMTP client
+// SMTP request configuration. Provides connection/request settings for SMTP client.
+// Target server name is MX host name of target server address, uses for TLS verification.
+// Catch-all and DATA probing settings are assigned from SMTP provider profile
 type SmtpRequestConfiguration struct {
-	VerifierDomain, VerifierEmail, TargetEmail, TargetServerAddress string
-	TargetServerPortNumber, ConnectionTimeout, ResponseTimeout      int
+	VerifierDomain, VerifierEmail, TargetEmail, TargetServerAddress, TargetServerName string
+	TargetServerPortNumber, ConnectionTimeout, ResponseTimeout                        int
+	TlsPolicy, Proxy, SourceIpAddress                                                 string
+	CatchAllProbing, DataProbing                                                      bool
+	TlsConfig                                                                         *tls.Config
+	TlsaRecords                                                                       []*TlsaRecord
+	sessionPool                                                                       *smtpSessionPool
+	metricsCollector                                                                  MetricsCollector
+	eventLogger                                                                       *eventLogger
+	ctx                                                                               context.Context
 }
 
 // smtpRequestConfiguration builder. Creates SMTP request configuration with settings from configuration
@@ -22,38 +41,97 @@ func newSmtpRequestConfiguration(config *Configuration, targetEmail, targetServe
 		TargetServerPortNumber: config.SmtpPort,
 		ConnectionTimeout:      config.ConnectionTimeout,
 		ResponseTimeout:        config.ResponseTimeout,
+		TlsPolicy:              config.SmtpTlsPolicy,
+		TlsConfig:              config.SmtpTlsConfig,
+		Proxy:                  config.SmtpProxy,
+		sessionPool:            config.smtpSessionPool,
+		metricsCollector:       config.MetricsCollector,
+		eventLogger:            config.eventLogger(),
+		ctx:                    config.ctx,
 	}
 }
 
-// SMTP response structure. Includes RCPTTO successful request marker
-// and SMTP client error pointers slice
+// SMTP response structure. Includes RCPTTO successful request marker, SMTP client error
+// pointers slice, TLS details of upgraded SMTP session and transcript of all SMTP sessions.
+// CatchAll is true when target server accepted random recipient of catch-all probe
 type SmtpResponse struct {
-	Rcptto bool
-	Errors []*SmtpClientError
+	Rcptto        bool
+	RcpttoTrusted bool
+	CatchAll      bool
+	Errors        []*SmtpClientError
+	Tls           *TlsDetails
+	Transcript    SmtpTranscript
+}
+
+// TLS details structure. Includes negotiated TLS version, cipher suite, target server
+// certificate subject and expiry. Includes STARTTLS error only when SMTP session of
+// opportunistic TLS policy was not upgraded to TLS and fell back to plain text
+type TlsDetails struct {
+	Version, CipherSuite, CertificateSubject string
+	CertificateExpiry                        time.Time
+	Err                                      error
 }
 
-// SMTP request structure. Includes attempts count, target email & host address,
-// pointers to SMTP request configuration and SMTP response
+// TlsDetails builder. Creates TLS details from TLS connection state
+func newTlsDetails(connectionState tls.ConnectionState) *TlsDetails {
+	tlsDetails := &TlsDetails{
+		Version:     tls.VersionName(connectionState.Version),
+		CipherSuite: tls.CipherSuiteName(connectionState.CipherSuite),
+	}
+	if len(connectionState.PeerCertificates) > 0 {
+		certificate := connectionState.PeerCertificates[0]
+		tlsDetails.CertificateSubject, tlsDetails.CertificateExpiry = certificate.Subject.String(), certificate.NotAfter
+	}
+
+	return tlsDetails
+}
+
+// SMTP request structure. Includes attempts count, target email & host address, pointers
+// to SMTP request configuration, SMTP response, DANE result of target host and SMTP source address
 type SmtpRequest struct {
 	Attempts      int
 	Email, Host   string
 	Configuration *SmtpRequestConfiguration
 	Response      *SmtpResponse
+	Dane          *DaneResult
+	SourceAddress *SmtpSourceAddress
+	Provider      *SmtpProviderProfile
 }
 
 // SMTP validation client interface
 type client interface {
 	runSession() bool
 	sessionError() *SmtpClientError
+	sessionTls() *TlsDetails
+	sessionTranscript() SmtpTranscript
+	sessionCatchAll() bool
+	cancelSession()
 }
 
 // SMTP client structure. Provides possibility to interact with target SMTP server
 type smtpClient struct {
 	verifierDomain, verifierEmail, targetEmail, targetServerAddress, networkProtocol string
+	targetServerName                                                                 string
 	targetServerPortNumber                                                           int
 	connectionTimeout, responseTimeout                                               time.Duration
+	tlsPolicy, proxy, sourceIpAddress                                                string
+	catchAllProbing, dataProbing, isCatchAll, isDataStarted, isTlsFallback           bool
+	tlsConfig                                                                        *tls.Config
+	tlsSettingsKey                                                                   string
+	tlsDetails                                                                       *TlsDetails
+	connection                                                                       *smtpConnection
 	client                                                                           *smtp.Client
+	transcript                                                                       SmtpTranscript
 	err                                                                              *SmtpClientError
+	sessionMutex                                                                     sync.Mutex
+	sessionContext                                                                   context.Context
+	cancel                                                                           context.CancelFunc
+	stopCancellation                                                                 func() bool
+	sessionPool                                                                      *smtpSessionPool
+	recipients                                                                       int
+	metricsCollector                                                                 MetricsCollector
+	eventLogger                                                                      *eventLogger
+	ctx, spanContext                                                                 context.Context
 }
 
 // smtpClient builder. Creates SMTP client with settings from smtpRequestConfiguration
@@ -67,16 +145,122 @@ func newSmtpClient(config *SmtpRequestConfiguration) *smtpClient {
 		networkProtocol:        tcpTransportLayer,
 		connectionTimeout:      time.Duration(config.ConnectionTimeout) * time.Second,
 		responseTimeout:        time.Duration(config.ResponseTimeout) * time.Second,
+		tlsPolicy:              config.TlsPolicy,
+		tlsConfig:              newSmtpTlsConfig(config),
+		tlsSettingsKey:         smtpTlsSettingsKey(config),
+		proxy:                  config.Proxy,
+		sourceIpAddress:        config.SourceIpAddress,
+		catchAllProbing:        config.CatchAllProbing,
+		dataProbing:            config.DataProbing,
+		sessionPool:            config.sessionPool,
+		metricsCollector:       config.metricsCollector,
+		eventLogger:            config.eventLogger,
+		targetServerName:       config.TargetServerName,
+		ctx:                    config.ctx,
+	}
+}
+
+// Returns copy of custom TLS config for SMTP client with TLS server name. Certificate chain
+// is verified by DANE TLSA records instead of PKIX verification when TLSA records are specified.
+// Opportunistic TLS policy does not verify certificate chain (RFC 7435)
+func newSmtpTlsConfig(config *SmtpRequestConfiguration) *tls.Config {
+	tlsConfig := new(tls.Config)
+	if config.TlsConfig != nil {
+		tlsConfig = config.TlsConfig.Clone()
 	}
+	tlsConfig.ServerName = smtpTlsServerName(config)
+
+	switch {
+	case len(config.TlsaRecords) > 0:
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = newDaneVerifier(config.TlsaRecords, tlsConfig.ServerName, tlsConfig.RootCAs)
+	case config.TlsPolicy == smtpTlsPolicyOpportunistic:
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig
+}
+
+// Returns TLS server name for SMTP client. Uses MX host name of target server
+// when it is known, otherwise target server address
+func smtpTlsServerName(config *SmtpRequestConfiguration) string {
+	if config.TargetServerName != emptyString {
+		return config.TargetServerName
+	}
+
+	return config.TargetServerAddress
+}
+
+// Returns key of TLS settings of SMTP client: custom TLS config, TLS server name and TLSA
+// records. TLS verification is done once per SMTP session, so SMTP session pool key includes it
+func smtpTlsSettingsKey(config *SmtpRequestConfiguration) string {
+	tlsaRecords := make([]string, 0, len(config.TlsaRecords))
+	for _, tlsaRecord := range config.TlsaRecords {
+		tlsaRecords = append(
+			tlsaRecords,
+			fmt.Sprintf("%d %d %d %s", tlsaRecord.Usage, tlsaRecord.Selector, tlsaRecord.MatchingType, tlsaRecord.Certificate),
+		)
+	}
+
+	return fmt.Sprintf("%p|%s|%s", config.TlsConfig, smtpTlsServerName(config), strings.Join(tlsaRecords, ","))
 }
 
 // smtpClient methods
 
-// Initializes SMTP client connection with connection timeout
+// Initializes SMTP client connection with connection timeout directly or via SMTP proxy.
+// Connection errors via SMTP proxy are wrapped in smtpProxyError
 func (smtpClient *smtpClient) initConnection() (net.Conn, error) {
 	targetAddress := serverWithPortNumber(smtpClient.targetServerAddress, smtpClient.targetServerPortNumber)
-	connection, error := net.DialTimeout(smtpClient.networkProtocol, targetAddress, smtpClient.connectionTimeout)
-	return connection, error
+	dialer, err := newSmtpDialer(
+		smtpClient.proxy,
+		&net.Dialer{Timeout: smtpClient.connectionTimeout, LocalAddr: smtpClient.localAddress()},
+	)
+
+	var connection net.Conn
+	if err == nil {
+		ctx, cancel := smtpClient.connectionContext()
+		defer cancel()
+		connection, err = dialer.DialContext(ctx, smtpClient.networkProtocol, targetAddress)
+	}
+
+	if err != nil && smtpClient.proxy != emptyString {
+		return nil, &smtpProxyError{proxy: redactedSmtpProxy(smtpClient.proxy), err: err}
+	}
+
+	return connection, err
+}
+
+// Returns local TCP address of SMTP source IP address which SMTP client binds.
+// Returns nil when SMTP source IP address is not specified
+func (smtpClient *smtpClient) localAddress() net.Addr {
+	if smtpClient.sourceIpAddress == emptyString {
+		return nil
+	}
+
+	return &net.TCPAddr{IP: net.ParseIP(smtpClient.sourceIpAddress)}
+}
+
+// Returns context of connection with target server limited by connection timeout.
+// Zero connection timeout means no timeout
+func (smtpClient *smtpClient) connectionContext() (context.Context, context.CancelFunc) {
+	if smtpClient.connectionTimeout == 0 {
+		return context.WithCancel(smtpClient.context())
+	}
+
+	return context.WithTimeout(smtpClient.context(), smtpClient.connectionTimeout)
+}
+
+// Returns context of current SMTP session, which is done when SMTP session is cancelled
+// or when validation context is done. Context is created on first call, safe for concurrent use
+func (smtpClient *smtpClient) context() context.Context {
+	smtpClient.sessionMutex.Lock()
+	defer smtpClient.sessionMutex.Unlock()
+
+	if smtpClient.sessionContext == nil {
+		smtpClient.sessionContext, smtpClient.cancel = context.WithCancel(contextOrBackground(smtpClient.ctx))
+	}
+
+	return smtpClient.sessionContext
 }
 
 // interface implementation
@@ -86,57 +270,481 @@ func (smtpClient *smtpClient) sessionError() *SmtpClientError {
 	return smtpClient.err
 }
 
-// Runs SMTP session with target mail server. Assigns smtpClient.error
-// for failure case and return false. Otherwise returns true
+// Returns pointer to TLS details of current SMTP session. Returns nil
+// when SMTP session was not upgraded to TLS
+func (smtpClient *smtpClient) sessionTls() *TlsDetails {
+	return smtpClient.tlsDetails
+}
+
+// Returns SMTP transcript of current SMTP session. Returns transcript copy taken before
+// SMTP session was returned to SMTP session pool, or nil when connection with target
+// server was not established
+func (smtpClient *smtpClient) sessionTranscript() SmtpTranscript {
+	if smtpClient.connection == nil {
+		return smtpClient.transcript
+	}
+
+	return smtpClient.connection.sessionTranscript()
+}
+
+// Returns true if target server accepted random recipient of catch-all probe
+// during current SMTP session, otherwise returns false
+func (smtpClient *smtpClient) sessionCatchAll() bool {
+	return smtpClient.isCatchAll
+}
+
+// Cancels current SMTP session: aborts connection with target server, so running
+// SMTP session fails. Safe for concurrent use with SMTP session
+func (smtpClient *smtpClient) cancelSession() {
+	smtpClient.context()
+	smtpClient.cancel()
+}
+
+// Runs SMTP session with target mail server. Reuses idle SMTP session from SMTP session
+// pool when it is available. Runs DATA or catch-all probe after successful RCPT TO when
+// it is enabled. Assigns smtpClient.error for failure case and return false. Otherwise
+// returns true
 func (smtpClient *smtpClient) runSession() bool {
-	var err error
-	connection, err := smtpClient.initConnection()
+	var span trace.Span
+	smtpClient.spanContext, span = startSpan(
+		smtpClient.ctx,
+		spanNameSmtpSession,
+		attribute.String(attributeServerAddress, smtpClient.targetServerAddress),
+		attribute.Int(attributeServerPort, smtpClient.targetServerPortNumber),
+		attribute.String(attributeSmtpMxHost, smtpClient.targetServerName),
+	)
+	defer smtpClient.endSessionSpan(span)
+	smtpClient.addSessionsInFlight(1)
+	defer smtpClient.addSessionsInFlight(-1)
+
+	client := smtpClient.reusePooledSession()
+	if client == nil {
+		client = smtpClient.newSession()
+		if client == nil {
+			return false
+		}
+	}
+	defer smtpClient.closeSession(client)
+
+	err := smtpClient.runCommand(smtpCommandMailFrom, func() error { return client.Mail(smtpClient.verifierEmail) })
+	if err != nil {
+		smtpClient.err = &SmtpClientError{isMailFrom: true, err: err}
+		return false
+	}
 
+	smtpClient.recipients++
+	err = smtpClient.runCommand(smtpCommandRcptTo, func() error { return client.Rcpt(smtpClient.targetEmail) })
 	if err != nil {
-		smtpClient.err = &SmtpClientError{isConnection: true, err: err}
+		smtpClient.err = &SmtpClientError{isRecptTo: true, err: err}
 		return false
 	}
 
-	closeConnection := func() {
-		connection.Close()
-		smtpClient.err = &SmtpClientError{isResponseTimeout: true, err: err}
+	if smtpClient.dataProbing {
+		return smtpClient.probeData(client)
 	}
 
-	client, err := smtp.NewClient(connection, smtpClient.targetServerAddress)
+	if smtpClient.catchAllProbing {
+		smtpClient.probeCatchAll(client)
+	}
+
+	return true
+}
+
+// Runs DATA probe: sends DATA command after successful RCPT TO. Target server rejection
+// of DATA with SMTP reply is recipient rejection. Connection is closed right after 354
+// reply without message sending, so mail transaction is never completed. Assigns
+// smtpClient.error for failure case and returns false. Otherwise returns true
+func (smtpClient *smtpClient) probeData(client *smtp.Client) bool {
+	err := smtpClient.runCommand(smtpCommandData, func() error { return smtpCommand(client, 354, "DATA") })
+	if err != nil {
+		smtpClient.err = &SmtpClientError{isRecptTo: smtpClient.reply(err) != nil, isData: true, err: err}
+		return false
+	}
+	smtpClient.isDataStarted = true
+
+	return true
+}
+
+// Runs catch-all probe: sends RCPT TO with random recipient of target email domain.
+// Target server which does not reject random recipient permanently is catch-all
+func (smtpClient *smtpClient) probeCatchAll(client *smtp.Client) {
+	smtpClient.recipients++
+	err := smtpClient.runCommand(smtpCommandRcptTo, func() error { return client.Rcpt(randomSmtpRecipient(smtpClient.targetEmail)) })
+	if err == nil {
+		smtpClient.isCatchAll = true
+		return
+	}
+
+	smtpReply := smtpClient.reply(err)
+	smtpClient.isCatchAll = smtpReply != nil && !smtpReply.IsPermanent()
+}
+
+// Establishes new SMTP session with target mail server: connection, server greeting,
+// HELO/EHLO and STARTTLS. Assigns smtpClient.error for failure case and returns nil
+func (smtpClient *smtpClient) newSession() *smtp.Client {
+	var connection net.Conn
+	err := smtpClient.observeCommand(smtpCommandConnect, func() (err error) {
+		connection, err = smtpClient.initConnection()
+		return err
+	})
+	if err != nil {
+		isProxy := isSmtpProxyError(err)
+		smtpClient.err = &SmtpClientError{isConnection: !isProxy, isProxy: isProxy, err: err}
+		return nil
+	}
+
+	smtpClient.connection = newSmtpConnection(connection)
+	smtpClient.watchCancellation()
+	var client *smtp.Client
+	err = smtpClient.runCommand(smtpCommandGreeting, func() (err error) {
+		client, err = smtp.NewClient(smtpClient.connection, smtpClient.targetServerAddress)
+		return err
+	})
 	// Handle error case when SMTP server responded with non 220 status
 	if err != nil {
-		closeConnection()
+		smtpClient.stopCancellation()
+		smtpClient.connection.Close()
 		smtpClient.err = &SmtpClientError{isSmtpServiceReady: true, err: err}
-		return false
+		return nil
 	}
 
 	smtpClient.client = client
-	defer client.Close()
 
-	timerHello := time.AfterFunc(smtpClient.responseTimeout, closeConnection)
-	err = client.Hello(smtpClient.verifierDomain)
+	err = smtpClient.runCommand(smtpCommandHelo, func() error { return client.Hello(smtpClient.verifierDomain) })
 	if err != nil {
 		smtpClient.err = &SmtpClientError{isHello: true, err: err}
-		return false
+		smtpClient.closeSession(client)
+		return nil
+	}
+
+	if smtpClient.isStartTlsEnabled() {
+		err = smtpClient.runCommand(smtpCommandStartTls, func() error { return smtpClient.startTls(client) })
+		if err != nil && smtpClient.tlsPolicy == smtpTlsPolicyOpportunistic {
+			return smtpClient.fallbackSession(client, err)
+		}
+		if err != nil {
+			isCertificate := isCertificateVerificationError(err)
+			smtpClient.err = &SmtpClientError{
+				isStartTls:    !isCertificate,
+				isCertificate: isCertificate,
+				isDane:        isDaneVerificationError(err),
+				err:           err,
+			}
+			smtpClient.closeSession(client)
+			return nil
+		}
+	}
+
+	return client
+}
+
+// Ends SMTP session which was not upgraded to TLS with QUIT and establishes new SMTP
+// session without STARTTLS, uses for opportunistic TLS policy (RFC 7435). STARTTLS error
+// is recorded in TLS details of SMTP session. Returns nil when new SMTP session can not
+// be established
+func (smtpClient *smtpClient) fallbackSession(client *smtp.Client, err error) *smtp.Client {
+	smtpClient.stopCancellation()
+	if !smtpClient.connection.isFailed {
+		_ = smtpClient.runCommand(smtpCommandQuit, func() error { return smtpCommand(client, 221, "QUIT") })
 	}
-	defer timerHello.Stop()
+	_ = client.Close()
+	smtpClient.isTlsFallback, smtpClient.tlsDetails = true, &TlsDetails{Err: err}
+
+	return smtpClient.newSession()
+}
 
-	timerMailFrom := time.AfterFunc(smtpClient.responseTimeout, closeConnection)
-	err = client.Mail(smtpClient.verifierEmail)
+// Takes idle SMTP session from SMTP session pool and checks its health with NOOP.
+// Unhealthy SMTP sessions are closed. SMTP transcript of reused SMTP session starts
+// from NOOP. Returns nil when SMTP session pool is not used or healthy idle SMTP
+// session not exists
+func (smtpClient *smtpClient) reusePooledSession() *smtp.Client {
+	if smtpClient.sessionPool == nil {
+		return nil
+	}
+
+	for {
+		session := smtpClient.sessionPool.checkout(smtpClient.sessionPoolKey())
+		if session == nil {
+			return nil
+		}
+
+		session.connection.resetTranscript()
+		smtpClient.connection, smtpClient.client = session.connection, session.client
+		smtpClient.tlsDetails, smtpClient.recipients = session.tlsDetails, session.recipients
+		smtpClient.watchCancellation()
+
+		err := smtpClient.runCommand(smtpCommandNoop, func() error { return smtpCommand(session.client, 250, "NOOP") })
+		if err == nil {
+			return session.client
+		}
+
+		smtpClient.stopCancellation()
+		session.close()
+		smtpClient.connection, smtpClient.client, smtpClient.tlsDetails, smtpClient.recipients = nil, nil, nil, 0
+	}
+}
+
+// Aborts connection with target server when SMTP session is cancelled
+func (smtpClient *smtpClient) watchCancellation() {
+	smtpClient.stopCancellation = context.AfterFunc(smtpClient.context(), smtpClient.connection.abort)
+}
+
+// Ends SMTP session. Reusable SMTP session is reset with RSET and returned to SMTP session
+// pool, otherwise SMTP session is ended gracefully with QUIT and connection is closed.
+// Pooled SMTP session can be reused by other SMTP client right after check in, so SMTP
+// transcript is copied before and SMTP client does not refer to pooled connection anymore
+func (smtpClient *smtpClient) closeSession(client *smtp.Client) {
+	isNotCancelled := smtpClient.stopCancellation()
+	if smtpClient.isDataStarted {
+		smtpClient.connection.abort()
+		_ = client.Close()
+		return
+	}
+
+	if !isNotCancelled || !smtpClient.isSessionReusable() {
+		smtpClient.quit(client)
+		_ = client.Close()
+		return
+	}
+
+	err := smtpClient.runCommand(smtpCommandRset, func() error { return smtpCommand(client, 250, "RSET") })
+	if err == nil {
+		pooledSession, transcript := smtpClient.pooledSession(), smtpClient.connection.sessionTranscript()
+		if smtpClient.sessionPool.checkin(smtpClient.sessionPoolKey(), pooledSession) {
+			smtpClient.connection, smtpClient.client, smtpClient.transcript = nil, nil, transcript
+			return
+		}
+	}
+
+	if !smtpClient.connection.isFailed {
+		_ = smtpClient.runCommand(smtpCommandQuit, func() error { return smtpCommand(client, 221, "QUIT") })
+	}
+	_ = client.Close()
+}
+
+// Returns true if SMTP session pool is used and current SMTP session can be reused:
+// connection is not failed, target server accepted MAIL FROM and SMTP session did
+// not reach max recipients. Otherwise returns false
+func (smtpClient *smtpClient) isSessionReusable() bool {
+	return smtpClient.sessionPool != nil &&
+		!smtpClient.connection.isFailed &&
+		smtpClient.isMailTransactionOpened() &&
+		smtpClient.sessionPool.isReusable(smtpClient.recipients)
+}
+
+// Returns pooled SMTP session of current SMTP session
+func (smtpClient *smtpClient) pooledSession() *smtpPooledSession {
+	return &smtpPooledSession{
+		connection:      smtpClient.connection,
+		client:          smtpClient.client,
+		tlsDetails:      smtpClient.tlsDetails,
+		recipients:      smtpClient.recipients,
+		responseTimeout: smtpClient.responseTimeout,
+	}
+}
+
+// Returns SMTP session pool key: target server address with port number, SMTP source
+// IP address and settings of SMTP session which can not be changed after HELO/EHLO and
+// STARTTLS, including TLS settings
+func (smtpClient *smtpClient) sessionPoolKey() string {
+	return fmt.Sprintf(
+		"%s|%s|%s|%s|%s|%s",
+		serverWithPortNumber(smtpClient.targetServerAddress, smtpClient.targetServerPortNumber),
+		smtpClient.sourceIpAddress,
+		smtpClient.proxy,
+		smtpClient.tlsPolicy,
+		smtpClient.verifierDomain,
+		smtpClient.tlsSettingsKey,
+	)
+}
+
+// Runs SMTP command under response timeout. Aborts connection when target server does not
+// respond in time, so blocked command returns error. Abort timer goroutine only closes
+// connection, session state is changed by SMTP session goroutine only
+func (smtpClient *smtpClient) withResponseTimeout(command func() error) error {
+	abortTimer := time.AfterFunc(smtpClient.responseTimeout, smtpClient.connection.abort)
+	defer abortTimer.Stop()
+
+	return command()
+}
+
+// Runs SMTP command under response timeout
+func (smtpClient *smtpClient) runCommand(commandName string, command func() error) error {
+	return smtpClient.observeCommand(commandName, func() error { return smtpClient.withResponseTimeout(command) })
+}
+
+// Runs SMTP command within SMTP command span, child of SMTP session span. Observes
+// SMTP command latency with metrics collector when specified, logs SMTP command
+func (smtpClient *smtpClient) observeCommand(commandName string, command func() error) error {
+	_, span := startSpan(smtpClient.spanContext, spanNameSmtpCommand, attribute.String(attributeSmtpCommand, commandName))
+	startedAt := time.Now()
+	err := command()
+	duration := time.Since(startedAt)
+	if smtpClient.metricsCollector != nil {
+		smtpClient.metricsCollector.ObserveSmtpCommand(commandName, metricsStatus(err), duration)
+	}
+	smtpClient.logCommand(commandName, duration, err)
+	span.SetAttributes(smtpClient.replyAttributes(err)...)
+	endSpan(span, err)
+
+	return err
+}
+
+// Returns target server reply to last SMTP command: SMTP reply of error when target
+// server rejected SMTP command, otherwise last reply from SMTP transcript. Returns nil
+// when target server did not reply
+func (smtpClient *smtpClient) reply(err error) *SmtpReply {
 	if err != nil {
-		smtpClient.err = &SmtpClientError{isMailFrom: true, err: err}
-		return false
+		if smtpReply := (&SmtpClientError{err: err}).Reply(); smtpReply != nil {
+			return smtpReply
+		}
+	}
+
+	transcript := smtpClient.sessionTranscript()
+	if len(transcript) == 0 || transcript[len(transcript)-1].Code == 0 {
+		return nil
+	}
+
+	return &SmtpReply{Code: transcript[len(transcript)-1].Code}
+}
+
+// Returns span attributes of target server reply to last SMTP command: reply code
+// and enhanced status code. Returns nil when target server did not reply
+func (smtpClient *smtpClient) replyAttributes(err error) []attribute.KeyValue {
+	smtpReply := smtpClient.reply(err)
+	if smtpReply == nil {
+		return nil
 	}
-	defer timerMailFrom.Stop()
 
-	timerRcptTo := time.AfterFunc(smtpClient.responseTimeout, closeConnection)
-	err = client.Rcpt(smtpClient.targetEmail)
+	attributes := []attribute.KeyValue{attribute.Int(attributeSmtpReplyCode, smtpReply.Code)}
+	if smtpReply.EnhancedCode != emptyString {
+		attributes = append(attributes, attribute.String(attributeSmtpReplyEnhancedCode, smtpReply.EnhancedCode))
+	}
+
+	return attributes
+}
+
+// Logs SMTP command with target server reply: successful command with debug level,
+// failed command with info level. Target email echoed in reply text is redacted
+func (smtpClient *smtpClient) logCommand(commandName string, duration time.Duration, err error) {
+	if smtpClient.eventLogger == nil {
+		return
+	}
+
+	attributes := []slog.Attr{
+		slog.String(logAttrServerAddress, serverWithPortNumber(smtpClient.targetServerAddress, smtpClient.targetServerPortNumber)),
+		slog.String(logAttrHost, smtpClient.targetServerName),
+		slog.String(logAttrCommand, commandName),
+	}
+	if smtpReply := smtpClient.reply(err); smtpReply != nil {
+		attributes = append(
+			attributes,
+			slog.Int(logAttrReplyCode, smtpReply.Code),
+			slog.String(logAttrReplyEnhancedCode, smtpReply.EnhancedCode),
+		)
+	}
+	attributes = append(
+		attributes,
+		slog.Duration(logAttrDuration, duration),
+		redactedErrorLogAttr(err, smtpClient.targetEmail, smtpClient.eventLogger.emailRedaction),
+	)
+
+	smtpClient.eventLogger.log(
+		smtpClient.spanContext,
+		errorLogLevel(err, slog.LevelDebug, slog.LevelInfo),
+		logMessageSmtpCommand,
+		attributes...,
+	)
+}
+
+// Ends SMTP session span, records SMTP client error when SMTP session failed
+func (smtpClient *smtpClient) endSessionSpan(span trace.Span) {
+	span.SetAttributes(attribute.Bool(attributeSuccess, smtpClient.err == nil))
+	if smtpClient.err != nil {
+		endSpan(span, smtpClient.err)
+		return
+	}
+
+	endSpan(span, nil)
+}
+
+// Adds delta to SMTP sessions in flight with metrics collector when specified
+func (smtpClient *smtpClient) addSessionsInFlight(delta float64) {
+	if smtpClient.metricsCollector == nil {
+		return
+	}
+
+	smtpClient.metricsCollector.AddSmtpSessionsInFlight(delta)
+}
+
+// Ends SMTP session gracefully under response timeout: resets opened mail transaction
+// with RSET and sends QUIT. Does nothing when connection with target server failed.
+// QUIT acknowledgement is available in SMTP transcript
+func (smtpClient *smtpClient) quit(client *smtp.Client) {
+	if smtpClient.connection.isFailed {
+		return
+	}
+
+	if smtpClient.isMailTransactionOpened() {
+		_ = smtpClient.runCommand(smtpCommandRset, func() error { return smtpCommand(client, 250, "RSET") })
+		if smtpClient.connection.isFailed {
+			return
+		}
+	}
+
+	_ = smtpClient.runCommand(smtpCommandQuit, func() error { return smtpCommand(client, 221, "QUIT") })
+}
+
+// Returns true if target server accepted MAIL FROM command of current SMTP session
+func (smtpClient *smtpClient) isMailTransactionOpened() bool {
+	return smtpClient.err == nil || smtpClient.err.isRecptTo
+}
+
+// Returns true if TLS policy is opportunistic or required and SMTP session did not fall
+// back to plain text, otherwise returns false
+func (smtpClient *smtpClient) isStartTlsEnabled() bool {
+	return !smtpClient.isTlsFallback &&
+		(smtpClient.tlsPolicy == smtpTlsPolicyOpportunistic || smtpClient.tlsPolicy == smtpTlsPolicyRequired)
+}
+
+// Upgrades SMTP session to TLS when target server advertises STARTTLS extension in EHLO
+// response and records TLS details. Returns error when TLS handshake fails or when target
+// server does not advertise STARTTLS extension and TLS is required by TLS policy. TLS is
+// negotiated on SMTP connection, so SMTP transcript includes commands sent over TLS too
+func (smtpClient *smtpClient) startTls(client *smtp.Client) error {
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		if smtpClient.tlsPolicy == smtpTlsPolicyOpportunistic {
+			return nil
+		}
+		return fmt.Errorf("%s does not support STARTTLS", smtpClient.targetServerAddress)
+	}
+
+	err := smtpCommand(client, 220, "STARTTLS")
 	if err != nil {
-		smtpClient.err = &SmtpClientError{isRecptTo: true, err: err}
-		return false
+		return err
 	}
-	defer timerRcptTo.Stop()
 
-	// TODO: What about client.Quit() ?
-	return true
+	connectionState, err := smtpClient.connection.upgrade(smtpClient.tlsConfig)
+	if err != nil {
+		return err
+	}
+	smtpClient.tlsDetails = newTlsDetails(connectionState)
+
+	// SMTP session is reset after TLS negotiation, so EHLO should be sent again (RFC 3207)
+	return smtpCommand(client, 250, "EHLO %s", smtpClient.verifierDomain)
+}
+
+// Sends SMTP command to target server and reads its reply. Returns
+// error when reply code is not equal to expected reply code
+func smtpCommand(client *smtp.Client, expectedCode int, format string, args ...any) error {
+	id, err := client.Text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+
+	client.Text.StartResponse(id)
+	defer client.Text.EndResponse(id)
+	_, _, err = client.Text.ReadResponse(expectedCode)
+
+	return err
 }
@@ -1,18 +1,32 @@
 package truemail
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func TestNewSmtpRequestConfiguration(t *testing.T) {
 	t.Run("creates new smtp request configuration with settings specified in configuration", func(t *testing.T) {
 		configuration, email, server := createConfiguration(), randomEmail(), randomIpAddress()
 		configuration.SmtpPort, configuration.ConnectionTimeout, configuration.ResponseTimeout = randomPortNumber(), randomPositiveNumber(), randomPositiveNumber()
+		configuration.SmtpProxy = "http://127.0.0.1:3128"
 		smtpRequestConfiguration := newSmtpRequestConfiguration(configuration, email, server)
 
 		assert.Equal(t, configuration.VerifierDomain, smtpRequestConfiguration.VerifierDomain)
@@ -22,6 +36,10 @@ func TestNewSmtpRequestConfiguration(t *testing.T) {
 		assert.Equal(t, configuration.SmtpPort, smtpRequestConfiguration.TargetServerPortNumber)
 		assert.Equal(t, configuration.ConnectionTimeout, smtpRequestConfiguration.ConnectionTimeout)
 		assert.Equal(t, configuration.ResponseTimeout, smtpRequestConfiguration.ResponseTimeout)
+		assert.Equal(t, configuration.SmtpProxy, smtpRequestConfiguration.Proxy)
+		assert.Equal(t, configuration.smtpSessionPool, smtpRequestConfiguration.sessionPool)
+		assert.Equal(t, configuration.MetricsCollector, smtpRequestConfiguration.metricsCollector)
+		assert.Equal(t, configuration.ctx, smtpRequestConfiguration.ctx)
 	})
 }
 
@@ -35,6 +53,7 @@ func TestNewSmtpClient(t *testing.T) {
 			TargetServerPortNumber: randomPortNumber(),
 			ConnectionTimeout:      randomPositiveNumber(),
 			ResponseTimeout:        randomPositiveNumber(),
+			SourceIpAddress:        randomIpAddress(),
 		}
 		smtpClient := newSmtpClient(smtpRequestConfig)
 
@@ -46,6 +65,56 @@ func TestNewSmtpClient(t *testing.T) {
 		assert.Equal(t, tcpTransportLayer, smtpClient.networkProtocol)
 		assert.Equal(t, time.Duration(smtpRequestConfig.ConnectionTimeout)*time.Second, smtpClient.connectionTimeout)
 		assert.Equal(t, time.Duration(smtpRequestConfig.ResponseTimeout)*time.Second, smtpClient.responseTimeout)
+		assert.Empty(t, smtpClient.tlsPolicy)
+		assert.Equal(t, smtpRequestConfig.SourceIpAddress, smtpClient.sourceIpAddress)
+		assert.Equal(t, smtpRequestConfig.TargetServerAddress, smtpClient.tlsConfig.ServerName)
+	})
+
+	t.Run("creates new smtp client which requires STARTTLS with target server name", func(t *testing.T) {
+		smtpRequestConfig := &SmtpRequestConfiguration{
+			TargetServerAddress: randomIpAddress(),
+			TargetServerName:    randomDomain(),
+			TlsPolicy:           smtpTlsPolicyRequired,
+		}
+		smtpClient := newSmtpClient(smtpRequestConfig)
+
+		assert.Equal(t, smtpTlsPolicyRequired, smtpClient.tlsPolicy)
+		assert.Equal(t, smtpRequestConfig.TargetServerName, smtpClient.tlsConfig.ServerName)
+	})
+}
+
+func TestNewSmtpTlsConfig(t *testing.T) {
+	t.Run("when TLSA records not specified, uses PKIX verification", func(t *testing.T) {
+		tlsConfig := newSmtpTlsConfig(&SmtpRequestConfiguration{TargetServerAddress: randomIpAddress()})
+
+		assert.False(t, tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, tlsConfig.VerifyConnection)
+	})
+
+	t.Run("when custom TLS config specified, uses its copy with target server name", func(t *testing.T) {
+		customTlsConfig := &tls.Config{RootCAs: x509.NewCertPool(), ServerName: randomDomain()}
+		targetServerName := randomDomain()
+		tlsConfig := newSmtpTlsConfig(&SmtpRequestConfiguration{TargetServerName: targetServerName, TlsConfig: customTlsConfig})
+
+		assert.NotSame(t, customTlsConfig, tlsConfig)
+		assert.Same(t, customTlsConfig.RootCAs, tlsConfig.RootCAs)
+		assert.Equal(t, targetServerName, tlsConfig.ServerName)
+	})
+
+	t.Run("when opportunistic TLS policy, does not verify certificate chain", func(t *testing.T) {
+		tlsConfig := newSmtpTlsConfig(&SmtpRequestConfiguration{TlsPolicy: smtpTlsPolicyOpportunistic})
+
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+		assert.Nil(t, tlsConfig.VerifyConnection)
+	})
+
+	t.Run("when TLSA records specified, uses DANE verification", func(t *testing.T) {
+		tlsConfig := newSmtpTlsConfig(
+			&SmtpRequestConfiguration{TargetServerName: randomDomain(), TlsaRecords: []*TlsaRecord{{Usage: tlsaUsageDaneEe}}},
+		)
+
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+		assert.NotNil(t, tlsConfig.VerifyConnection)
 	})
 }
 
@@ -269,3 +338,931 @@ func TestSmtpClientRunSession(t *testing.T) {
 		assert.EqualError(t, client.err, msgGreeting)
 	})
 }
+
+func TestSmtpClientRunSessionWithStartTls(t *testing.T) {
+	tlsConfig, rootCAs := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+	createSmtpClient := func(serverAddress, serverName, tlsPolicy string) *smtpClient {
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         randomDomain(),
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerName:       serverName,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				TlsPolicy:              tlsPolicy,
+				TlsConfig:              &tls.Config{RootCAs: rootCAs},
+			},
+		)
+	}
+
+	t.Run("required TLS policy, successful session upgraded to TLS", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(tlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyRequired)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.Equal(t, "TLS 1.3", client.sessionTls().Version)
+		assert.Equal(t, "STARTTLS", client.sessionTranscript()[2].Command)
+		assert.False(t, client.sessionTranscript()[2].Tls)
+		assert.Equal(t, "EHLO "+client.verifierDomain, client.sessionTranscript()[3].Command)
+		assert.True(t, client.sessionTranscript()[3].Tls)
+		assert.NotEmpty(t, client.sessionTls().CipherSuite)
+		assert.Equal(t, certificate.Subject.String(), client.sessionTls().CertificateSubject)
+		assert.Equal(t, certificate.NotAfter, client.sessionTls().CertificateExpiry)
+	})
+
+	t.Run("required TLS policy, when target server does not support STARTTLS", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyRequired)
+
+		assert.False(t, client.runSession())
+		assert.EqualError(t, client.err, localhostIPv4Address+" does not support STARTTLS")
+		assert.True(t, client.err.isStartTls)
+		assert.False(t, client.err.isCertificate)
+		assert.False(t, client.err.isMailFrom)
+		assert.Nil(t, client.sessionTls())
+	})
+
+	t.Run("required TLS policy, when target server certificate does not match target server name", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(tlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress, "mx.other.com", smtpTlsPolicyRequired)
+
+		assert.False(t, client.runSession())
+		assert.Error(t, client.err)
+		assert.True(t, client.err.isCertificate)
+		assert.False(t, client.err.isStartTls)
+		assert.False(t, client.err.isDane)
+	})
+
+	t.Run("opportunistic TLS policy, upgrades session without certificate verification", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(tlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress, "mx.other.com", smtpTlsPolicyOpportunistic)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.NotNil(t, client.sessionTls())
+	})
+
+	t.Run("opportunistic TLS policy, when TLS handshake failed falls back to plain text session", func(t *testing.T) {
+		legacyTlsConfig := tlsConfig.Clone()
+		legacyTlsConfig.MaxVersion = tls.VersionTLS11
+		serverAddress, stop := startSmtpStandIn(legacyTlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyOpportunistic)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.Error(t, client.sessionTls().Err)
+		assert.Empty(t, client.sessionTls().Version)
+		assert.Equal(t, "EHLO "+client.verifierDomain, client.sessionTranscript()[1].Command)
+		assert.True(t, strings.HasPrefix(client.sessionTranscript()[2].Command, "MAIL FROM:"))
+		assert.False(t, client.sessionTranscript()[2].Tls)
+	})
+
+	t.Run("opportunistic TLS policy, when target server rejected STARTTLS falls back to plain text session", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(tlsConfig, map[string][]string{"STARTTLS": {"454 4.7.0 TLS not available"}})
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyOpportunistic)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.ErrorContains(t, client.sessionTls().Err, "TLS not available")
+		assert.Equal(t, "EHLO "+client.verifierDomain, client.sessionTranscript()[1].Command)
+		assert.True(t, strings.HasPrefix(client.sessionTranscript()[2].Command, "MAIL FROM:"))
+	})
+
+	t.Run("required TLS policy, when target server rejected STARTTLS", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(tlsConfig, map[string][]string{"STARTTLS": {"454 4.7.0 TLS not available"}})
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyRequired)
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isStartTls)
+		assert.Nil(t, client.sessionTls())
+	})
+
+	t.Run("opportunistic TLS policy, when target server does not support STARTTLS", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyOpportunistic)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.Nil(t, client.sessionTls())
+	})
+
+	t.Run("none TLS policy, does not upgrade session", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(tlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress, "example.com", smtpTlsPolicyNone)
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.sessionTls())
+	})
+}
+
+func TestSmtpClientRunSessionWithDane(t *testing.T) {
+	tlsConfig, _ := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+	serverAddress, stop := startSmtpStandIn(tlsConfig)
+	defer stop()
+	host, port, _ := net.SplitHostPort(serverAddress)
+	portNumber, _ := strconv.Atoi(port)
+	createSmtpClient := func(tlsaRecords ...*TlsaRecord) *smtpClient {
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         randomDomain(),
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerName:       randomDomain(),
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				TlsPolicy:              smtpTlsPolicyRequired,
+				TlsaRecords:            tlsaRecords,
+			},
+		)
+	}
+
+	t.Run("when target server certificate matches TLSA records", func(t *testing.T) {
+		client := createSmtpClient(createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeSha256))
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+	})
+
+	t.Run("when target server certificate does not match TLSA records", func(t *testing.T) {
+		client := createSmtpClient(&TlsaRecord{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256})
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isCertificate)
+		assert.True(t, client.err.isDane)
+		assert.EqualError(t, client.err, client.tlsConfig.ServerName+" certificate does not match dane tlsa records")
+	})
+}
+
+func TestNewTlsDetails(t *testing.T) {
+	t.Run("creates TLS details from TLS connection state", func(t *testing.T) {
+		tlsConfig, _ := createTlsConfig()
+		certificate := tlsConfigCertificate(tlsConfig)
+		tlsDetails := newTlsDetails(
+			tls.ConnectionState{
+				Version:          tls.VersionTLS12,
+				CipherSuite:      tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				PeerCertificates: []*x509.Certificate{certificate},
+			},
+		)
+
+		assert.Equal(
+			t,
+			&TlsDetails{
+				Version:            "TLS 1.2",
+				CipherSuite:        "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				CertificateSubject: certificate.Subject.String(),
+				CertificateExpiry:  certificate.NotAfter,
+			},
+			tlsDetails,
+		)
+	})
+
+	t.Run("when target server certificate not exists", func(t *testing.T) {
+		tlsDetails := newTlsDetails(tls.ConnectionState{Version: tls.VersionTLS13})
+
+		assert.Equal(t, "TLS 1.3", tlsDetails.Version)
+		assert.Empty(t, tlsDetails.CertificateSubject)
+	})
+}
+
+func TestSmtpClientSessionTranscript(t *testing.T) {
+	t.Run("returns transcript of SMTP session", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		verifierDomain, verifierEmail, targetEmail := randomDomain(), randomEmail(), randomEmail()
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         verifierDomain,
+				VerifierEmail:          verifierEmail,
+				TargetEmail:            targetEmail,
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+			},
+		)
+
+		assert.True(t, client.runSession())
+		transcript := client.sessionTranscript()
+		assert.Equal(t, 6, len(transcript))
+		assert.Empty(t, transcript[0].Command)
+		assert.Equal(t, []string{"220 stand-in ESMTP"}, transcript[0].Reply)
+		assert.Equal(t, "EHLO "+verifierDomain, transcript[1].Command)
+		assert.Equal(t, []string{"250-stand-in", "250 8BITMIME"}, transcript[1].Reply)
+		assert.Equal(t, "MAIL FROM:<"+verifierEmail+"> BODY=8BITMIME", transcript[2].Command)
+		assert.Equal(t, "RCPT TO:<"+targetEmail+">", transcript[3].Command)
+		assert.Equal(t, 250, transcript[3].Code)
+		assert.Equal(t, "RSET", transcript[4].Command)
+		assert.Equal(t, "QUIT", transcript[5].Command)
+		assert.True(t, transcript.IsQuitAcknowledged())
+	})
+
+	t.Run("when connection with target server was not established", func(t *testing.T) {
+		assert.Nil(t, new(smtpClient).sessionTranscript())
+	})
+}
+
+func TestSmtpClientRunSessionWithSessionPool(t *testing.T) {
+	serverAddress, stop := startSmtpStandIn(nil)
+	defer stop()
+	host, port, _ := net.SplitHostPort(serverAddress)
+	portNumber, _ := strconv.Atoi(port)
+	createSmtpClient := func(sessionPool *smtpSessionPool) *smtpClient {
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				sessionPool:            sessionPool,
+			},
+		)
+	}
+	transcriptCommands := func(transcript SmtpTranscript) (commands []string) {
+		for _, entry := range transcript {
+			command, _, _ := strings.Cut(entry.Command, ":")
+			commands = append(commands, command)
+		}
+
+		return commands
+	}
+
+	t.Run("reuses idle SMTP session via RSET", func(t *testing.T) {
+		sessionPool := newSmtpSessionPool(30, 20, 2)
+		firstClient, secondClient := createSmtpClient(sessionPool), createSmtpClient(sessionPool)
+
+		assert.True(t, firstClient.runSession())
+		assert.Equal(t, []string{"", "EHLO example.com", "MAIL FROM", "RCPT TO", "RSET"}, transcriptCommands(firstClient.sessionTranscript()))
+		assert.Len(t, sessionPool.sessions[firstClient.sessionPoolKey()], 1)
+		assert.True(t, secondClient.runSession())
+		assert.Equal(t, []string{"NOOP", "MAIL FROM", "RCPT TO", "RSET"}, transcriptCommands(secondClient.sessionTranscript()))
+		assert.Equal(t, []string{"", "EHLO example.com", "MAIL FROM", "RCPT TO", "RSET"}, transcriptCommands(firstClient.sessionTranscript()))
+		assert.Equal(t, 2, sessionPool.sessions[secondClient.sessionPoolKey()][0].recipients)
+	})
+
+	t.Run("ends SMTP session which reached max recipients", func(t *testing.T) {
+		sessionPool := newSmtpSessionPool(30, 1, 2)
+		client := createSmtpClient(sessionPool)
+
+		assert.True(t, client.runSession())
+		assert.True(t, client.sessionTranscript().IsQuitAcknowledged())
+		assert.Empty(t, sessionPool.sessions)
+	})
+
+	t.Run("establishes new SMTP session when idle SMTP session failed health check", func(t *testing.T) {
+		sessionPool := newSmtpSessionPool(30, 20, 2)
+		firstClient, secondClient := createSmtpClient(sessionPool), createSmtpClient(sessionPool)
+
+		assert.True(t, firstClient.runSession())
+		assert.Nil(t, firstClient.connection)
+		sessionPool.sessions[firstClient.sessionPoolKey()][0].connection.abort()
+		assert.True(t, secondClient.runSession())
+		assert.Equal(t, []string{"", "EHLO example.com", "MAIL FROM", "RCPT TO", "RSET"}, transcriptCommands(secondClient.sessionTranscript()))
+		assert.Len(t, sessionPool.sessions[secondClient.sessionPoolKey()], 1)
+	})
+
+	t.Run("keeps own SMTP transcript of concurrent SMTP sessions which share SMTP session pool", func(t *testing.T) {
+		sessionPool, sessionsCount := newSmtpSessionPool(30, 100, 1), 4
+		var waitGroup sync.WaitGroup
+		for range sessionsCount {
+			waitGroup.Add(1)
+			go func() {
+				defer waitGroup.Done()
+				for range 10 {
+					client := createSmtpClient(sessionPool)
+					assert.True(t, client.runSession())
+					var rcpttoCommands []string
+					for _, entry := range client.sessionTranscript() {
+						if strings.HasPrefix(entry.Command, "RCPT TO") {
+							rcpttoCommands = append(rcpttoCommands, entry.Command)
+						}
+					}
+					assert.Equal(t, []string{"RCPT TO:<" + client.targetEmail + ">"}, rcpttoCommands)
+				}
+			}()
+		}
+		waitGroup.Wait()
+	})
+}
+
+func TestSmtpClientRunSessionWithProviderProbing(t *testing.T) {
+	createSmtpClient := func(serverAddress string, catchAllProbing, dataProbing bool, sessionPool *smtpSessionPool) *smtpClient {
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				CatchAllProbing:        catchAllProbing,
+				DataProbing:            dataProbing,
+				sessionPool:            sessionPool,
+			},
+		)
+	}
+	transcriptCommands := func(transcript SmtpTranscript) (commands []string) {
+		for _, entry := range transcript {
+			command, _, _ := strings.Cut(entry.Command, ":")
+			commands = append(commands, command)
+		}
+
+		return commands
+	}
+
+	t.Run("catch-all probing, random recipient is accepted", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		client := createSmtpClient(serverAddress, true, false, nil)
+
+		assert.True(t, client.runSession())
+		assert.True(t, client.sessionCatchAll())
+		assert.Equal(t, 2, client.recipients)
+		transcript := client.sessionTranscript()
+		assert.Equal(t, []string{"", "EHLO example.com", "MAIL FROM", "RCPT TO", "RCPT TO", "RSET", "QUIT"}, transcriptCommands(transcript))
+		assert.Equal(t, "RCPT TO:<"+client.targetEmail+">", transcript[3].Command)
+		assert.Contains(t, transcript[4].Command, "@"+emailDomain(client.targetEmail)+">")
+	})
+
+	t.Run("catch-all probing, random recipient is rejected permanently", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(nil, map[string][]string{"RCPT": {"250 ok", "550 5.1.1 user unknown"}})
+		defer stop()
+		client := createSmtpClient(serverAddress, true, false, nil)
+
+		assert.True(t, client.runSession())
+		assert.False(t, client.sessionCatchAll())
+		assert.Nil(t, client.sessionError())
+	})
+
+	t.Run("DATA probing, connection is closed after 354 reply without message sending", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(nil, map[string][]string{"DATA": {"354 start mail input"}})
+		defer stop()
+		sessionPool := newSmtpSessionPool(30, 20, 2)
+		client := createSmtpClient(serverAddress, true, true, sessionPool)
+
+		assert.True(t, client.runSession())
+		assert.False(t, client.sessionCatchAll())
+		assert.Equal(t, []string{"", "EHLO example.com", "MAIL FROM", "RCPT TO", "DATA"}, transcriptCommands(client.sessionTranscript()))
+		assert.False(t, client.sessionTranscript().IsQuitAcknowledged())
+		assert.Empty(t, sessionPool.sessions)
+	})
+
+	t.Run("DATA probing, recipient is rejected after DATA", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(nil, map[string][]string{"DATA": {"550 5.1.10 RESOLVER.ADR.RecipientNotFound; Recipient not found"}})
+		defer stop()
+		client := createSmtpClient(serverAddress, false, true, nil)
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.sessionError().isRecptTo)
+		assert.True(t, client.sessionError().isData)
+		assert.True(t, client.sessionError().Reply().IsPermanent())
+		assert.True(t, client.sessionTranscript().IsQuitAcknowledged())
+	})
+}
+
+func TestSmtpClientRunSessionWithMetricsCollector(t *testing.T) {
+	t.Run("observes SMTP commands latency and SMTP sessions in flight with metrics collector", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		metricsCollector := new(metricsCollectorMock)
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				metricsCollector:       metricsCollector,
+			},
+		)
+		metricsCollector.On("AddSmtpSessionsInFlight", float64(1)).Once()
+		metricsCollector.On("AddSmtpSessionsInFlight", float64(-1)).Once()
+		for _, command := range []string{
+			smtpCommandConnect,
+			smtpCommandGreeting,
+			smtpCommandHelo,
+			smtpCommandMailFrom,
+			smtpCommandRcptTo,
+			smtpCommandRset,
+			smtpCommandQuit,
+		} {
+			metricsCollector.On("ObserveSmtpCommand", command, metricsStatusSuccess, mock.AnythingOfType("time.Duration")).Once()
+		}
+
+		assert.True(t, client.runSession())
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("observes failed SMTP connection", func(t *testing.T) {
+		metricsCollector := new(metricsCollectorMock)
+		client := &smtpClient{
+			targetServerAddress:    localhostIPv4Address,
+			targetServerPortNumber: 1,
+			networkProtocol:        tcpTransportLayer,
+			metricsCollector:       metricsCollector,
+		}
+		metricsCollector.On("AddSmtpSessionsInFlight", mock.AnythingOfType("float64")).Twice()
+		metricsCollector.On("ObserveSmtpCommand", smtpCommandConnect, metricsStatusFailure, mock.AnythingOfType("time.Duration")).Once()
+
+		assert.False(t, client.runSession())
+		metricsCollector.AssertExpectations(t)
+	})
+}
+
+func TestSmtpClientRunSessionWithTracing(t *testing.T) {
+	serverAddress, stop := startSmtpStandIn(nil)
+	defer stop()
+	host, port, _ := net.SplitHostPort(serverAddress)
+	portNumber, _ := strconv.Atoi(port)
+
+	t.Run("runs SMTP session within SMTP session span with SMTP commands spans", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, _ := startValidationSpan(context.Background(), tracerProvider)
+		mxHostName := randomDomain()
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerName:       mxHostName,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				ctx:                    ctx,
+			},
+		)
+
+		assert.True(t, client.runSession())
+		sessionSpans, commandSpans := endedSpans(spanRecorder, spanNameSmtpSession), endedSpans(spanRecorder, spanNameSmtpCommand)
+		assert.Len(t, sessionSpans, 1)
+		assert.Equal(t, host, spanAttribute(sessionSpans[0], attributeServerAddress).AsString())
+		assert.Equal(t, int64(portNumber), spanAttribute(sessionSpans[0], attributeServerPort).AsInt64())
+		assert.Equal(t, mxHostName, spanAttribute(sessionSpans[0], attributeSmtpMxHost).AsString())
+		assert.True(t, spanAttribute(sessionSpans[0], attributeSuccess).AsBool())
+
+		var commands []string
+		for _, commandSpan := range commandSpans {
+			assert.Equal(t, sessionSpans[0].SpanContext().SpanID(), commandSpan.Parent().SpanID())
+			commands = append(commands, spanAttribute(commandSpan, attributeSmtpCommand).AsString())
+		}
+		assert.Equal(
+			t,
+			[]string{
+				smtpCommandConnect,
+				smtpCommandGreeting,
+				smtpCommandHelo,
+				smtpCommandMailFrom,
+				smtpCommandRcptTo,
+				smtpCommandRset,
+				smtpCommandQuit,
+			},
+			commands,
+		)
+		assert.Equal(t, int64(250), spanAttribute(commandSpans[4], attributeSmtpReplyCode).AsInt64())
+	})
+}
+
+func TestSmtpClientRunSessionWithLogger(t *testing.T) {
+	t.Run("logs SMTP commands with target server replies", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		logger, buffer := createLogger()
+		mxHostName := randomDomain()
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerName:       mxHostName,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				eventLogger:            newEventLogger(logger, slog.LevelDebug),
+			},
+		)
+
+		assert.True(t, client.runSession())
+		records := logRecords(buffer, logMessageSmtpCommand)
+		var commands []string
+		for _, record := range records {
+			assert.Equal(t, slog.LevelDebug.String(), record[slog.LevelKey])
+			assert.Equal(t, serverAddress, record[logAttrServerAddress])
+			assert.Equal(t, mxHostName, record[logAttrHost])
+			commands = append(commands, record[logAttrCommand].(string))
+		}
+		assert.Equal(
+			t,
+			[]string{
+				smtpCommandConnect,
+				smtpCommandGreeting,
+				smtpCommandHelo,
+				smtpCommandMailFrom,
+				smtpCommandRcptTo,
+				smtpCommandRset,
+				smtpCommandQuit,
+			},
+			commands,
+		)
+		assert.Equal(t, float64(250), records[4][logAttrReplyCode])
+	})
+
+	t.Run("logs SMTP command error with target email redacted with email redaction", func(t *testing.T) {
+		serverAddress, stop := startScriptedSmtpStandIn(nil, map[string][]string{"RCPT": {"550 5.1.1 <john.doe@example.com>: user unknown"}})
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		logger, buffer := createLogger()
+		eventLogger := newEventLogger(logger, slog.LevelDebug)
+		eventLogger.emailRedaction = logEmailRedactionMask
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         "example.com",
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            "John.Doe@example.com",
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				eventLogger:            eventLogger,
+			},
+		)
+
+		assert.False(t, client.runSession())
+		records := logRecords(buffer, logMessageSmtpCommand)
+		assert.Equal(t, smtpCommandRcptTo, records[4][logAttrCommand])
+		assert.Contains(t, records[4][logAttrError], "<J***@example.com>: user unknown")
+		assert.NotContains(t, buffer.String(), "john.doe")
+	})
+
+	t.Run("logs failed SMTP connection with info level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		client := &smtpClient{
+			targetServerAddress:    localhostIPv4Address,
+			targetServerPortNumber: 1,
+			networkProtocol:        tcpTransportLayer,
+			eventLogger:            newEventLogger(logger, slog.LevelDebug),
+		}
+
+		assert.False(t, client.runSession())
+		records := logRecords(buffer, logMessageSmtpCommand)
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelInfo.String(), records[0][slog.LevelKey])
+		assert.Equal(t, smtpCommandConnect, records[0][logAttrCommand])
+		assert.Contains(t, records[0], logAttrError)
+	})
+}
+
+func TestSmtpClientReplyAttributes(t *testing.T) {
+	t.Run("when target server rejected SMTP command", func(t *testing.T) {
+		err := &textproto.Error{Code: 550, Msg: "5.1.1 User unknown"}
+
+		assert.Equal(
+			t,
+			[]attribute.KeyValue{
+				attribute.Int(attributeSmtpReplyCode, 550),
+				attribute.String(attributeSmtpReplyEnhancedCode, "5.1.1"),
+			},
+			new(smtpClient).replyAttributes(err),
+		)
+	})
+
+	t.Run("when target server did not reply", func(t *testing.T) {
+		assert.Nil(t, new(smtpClient).replyAttributes(errors.New("connection refused")))
+	})
+}
+
+func TestSmtpClientSessionPoolKey(t *testing.T) {
+	t.Run("returns SMTP session pool key", func(t *testing.T) {
+		client := &smtpClient{
+			targetServerAddress:    "127.0.0.1",
+			targetServerPortNumber: 25,
+			sourceIpAddress:        "10.0.0.1",
+			tlsPolicy:              smtpTlsPolicyRequired,
+			verifierDomain:         "example.com",
+			tlsSettingsKey:         "0x0|mx.example.com|",
+		}
+
+		assert.Equal(t, "127.0.0.1:25|10.0.0.1||"+smtpTlsPolicyRequired+"|example.com|0x0|mx.example.com|", client.sessionPoolKey())
+	})
+
+	t.Run("returns different SMTP session pool keys for different TLS settings", func(t *testing.T) {
+		createSmtpClient := func(tlsConfig *tls.Config, targetServerName string, tlsaRecords []*TlsaRecord) *smtpClient {
+			return newSmtpClient(
+				&SmtpRequestConfiguration{
+					VerifierDomain:         "example.com",
+					TargetServerAddress:    "127.0.0.1",
+					TargetServerName:       targetServerName,
+					TargetServerPortNumber: 25,
+					TlsPolicy:              smtpTlsPolicyRequired,
+					TlsConfig:              tlsConfig,
+					TlsaRecords:            tlsaRecords,
+				},
+			)
+		}
+		tlsConfig := new(tls.Config)
+		tlsaRecord := &TlsaRecord{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256, Certificate: "abc"}
+		sessionPoolKey := createSmtpClient(tlsConfig, "mx.example.com", nil).sessionPoolKey()
+
+		assert.Equal(t, sessionPoolKey, createSmtpClient(tlsConfig, "mx.example.com", nil).sessionPoolKey())
+		assert.NotEqual(t, sessionPoolKey, createSmtpClient(new(tls.Config), "mx.example.com", nil).sessionPoolKey())
+		assert.NotEqual(t, sessionPoolKey, createSmtpClient(tlsConfig, "mx2.example.com", nil).sessionPoolKey())
+		assert.NotEqual(t, sessionPoolKey, createSmtpClient(tlsConfig, "mx.example.com", []*TlsaRecord{tlsaRecord}).sessionPoolKey())
+	})
+}
+
+func TestSmtpClientQuit(t *testing.T) {
+	createSmtpClient := func(serverAddress string) *smtpClient {
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         randomDomain(),
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				TlsPolicy:              smtpTlsPolicyRequired,
+			},
+		)
+	}
+	commands := func(transcript SmtpTranscript) (commands []string) {
+		for _, entry := range transcript {
+			command, _, _ := strings.Cut(entry.Command, " ")
+			commands = append(commands, command)
+		}
+
+		return commands
+	}
+
+	t.Run("when mail transaction was not opened, sends QUIT without RSET", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		client := createSmtpClient(serverAddress)
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isStartTls)
+		assert.Equal(t, []string{emptyString, "EHLO", "QUIT"}, commands(client.sessionTranscript()))
+		assert.True(t, client.sessionTranscript().IsQuitAcknowledged())
+	})
+
+	t.Run("when connection with target server failed, does not send QUIT", func(t *testing.T) {
+		tlsConfig, _ := createTlsConfig()
+		serverAddress, stop := startSmtpStandIn(tlsConfig)
+		defer stop()
+		client := createSmtpClient(serverAddress)
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isCertificate)
+		assert.True(t, client.connection.isFailed)
+		assert.Equal(t, []string{emptyString, "EHLO", "STARTTLS"}, commands(client.sessionTranscript()))
+		assert.False(t, client.sessionTranscript().IsQuitAcknowledged())
+	})
+}
+
+func TestSmtpClientWithResponseTimeout(t *testing.T) {
+	t.Run("when command completed in time", func(t *testing.T) {
+		connection, server := net.Pipe()
+		defer server.Close()
+		client := &smtpClient{responseTimeout: 10 * time.Millisecond, connection: newSmtpConnection(connection)}
+
+		assert.NoError(t, client.withResponseTimeout(func() error { return nil }))
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, connection.SetDeadline(time.Time{}))
+	})
+
+	t.Run("when command was not completed in time, aborts connection", func(t *testing.T) {
+		connection, server := net.Pipe()
+		defer server.Close()
+		client := &smtpClient{responseTimeout: time.Millisecond, connection: newSmtpConnection(connection)}
+		err := client.withResponseTimeout(func() error {
+			_, err := client.connection.Read(make([]byte, 1))
+			return err
+		})
+
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+		assert.True(t, client.connection.isFailed)
+	})
+}
+
+func TestSmtpClientIsMailTransactionOpened(t *testing.T) {
+	t.Run("when successful session", func(t *testing.T) {
+		assert.True(t, new(smtpClient).isMailTransactionOpened())
+	})
+
+	t.Run("when RCPT TO error", func(t *testing.T) {
+		assert.True(t, (&smtpClient{err: &SmtpClientError{isRecptTo: true}}).isMailTransactionOpened())
+	})
+
+	t.Run("when other error", func(t *testing.T) {
+		assert.False(t, (&smtpClient{err: &SmtpClientError{isMailFrom: true}}).isMailTransactionOpened())
+	})
+}
+
+func TestSmtpClientRunSessionWithProxy(t *testing.T) {
+	serverAddress, stop := startSmtpStandIn(nil)
+	defer stop()
+	host, port, _ := net.SplitHostPort(serverAddress)
+	portNumber, _ := strconv.Atoi(port)
+	createSmtpClient := func(smtpProxy string) *smtpClient {
+		return newSmtpClient(
+			&SmtpRequestConfiguration{
+				VerifierDomain:         randomDomain(),
+				VerifierEmail:          randomEmail(),
+				TargetEmail:            randomEmail(),
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				ResponseTimeout:        1,
+				Proxy:                  smtpProxy,
+			},
+		)
+	}
+
+	t.Run("successful session via SOCKS5 proxy", func(t *testing.T) {
+		proxyStandIn := startSocks5ProxyStandIn("user", "password")
+		defer proxyStandIn.stop()
+		client := createSmtpClient("socks5://user:password@" + proxyStandIn.address())
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.Equal(t, []string{serverAddress}, proxyStandIn.requestedTargetAddresses())
+	})
+
+	t.Run("successful session via HTTP CONNECT proxy", func(t *testing.T) {
+		proxyStandIn := startHttpConnectProxyStandIn(emptyString)
+		defer proxyStandIn.stop()
+		client := createSmtpClient("http://" + proxyStandIn.address())
+
+		assert.True(t, client.runSession())
+		assert.Nil(t, client.err)
+		assert.True(t, client.sessionTranscript().IsQuitAcknowledged())
+		assert.Equal(t, []string{serverAddress}, proxyStandIn.requestedTargetAddresses())
+	})
+
+	t.Run("when SOCKS5 proxy rejected authentication", func(t *testing.T) {
+		proxyStandIn := startSocks5ProxyStandIn("user", "password")
+		defer proxyStandIn.stop()
+		client := createSmtpClient("socks5://user:wrong@" + proxyStandIn.address())
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isProxy)
+		assert.False(t, client.err.isConnection)
+		assert.Contains(t, client.err.Error(), "proxy socks5://user:xxxxx@"+proxyStandIn.address())
+		assert.Empty(t, proxyStandIn.requestedTargetAddresses())
+	})
+
+	t.Run("when proxy is unreachable", func(t *testing.T) {
+		client := createSmtpClient("socks5://" + localhostIPv4Address + ":1")
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isProxy)
+		assert.False(t, client.err.isConnection)
+	})
+
+	t.Run("when target server is unreachable via proxy", func(t *testing.T) {
+		proxyStandIn := startHttpConnectProxyStandIn(emptyString)
+		defer proxyStandIn.stop()
+		client := createSmtpClient("http://" + proxyStandIn.address())
+		client.targetServerPortNumber = 1
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.err.isProxy)
+		assert.Contains(t, client.err.Error(), "proxy responded with 502 Bad Gateway")
+	})
+}
+
+func TestSmtpClientConnectionContext(t *testing.T) {
+	t.Run("when connection timeout specified", func(t *testing.T) {
+		ctx, cancel := (&smtpClient{connectionTimeout: time.Second}).connectionContext()
+		defer cancel()
+		_, ok := ctx.Deadline()
+
+		assert.True(t, ok)
+	})
+
+	t.Run("when connection timeout is zero", func(t *testing.T) {
+		ctx, cancel := new(smtpClient).connectionContext()
+		defer cancel()
+		_, ok := ctx.Deadline()
+
+		assert.False(t, ok)
+	})
+}
+
+func TestSmtpClientLocalAddress(t *testing.T) {
+	t.Run("when SMTP source IP address specified", func(t *testing.T) {
+		sourceIpAddress := randomIpAddress()
+
+		assert.Equal(t, &net.TCPAddr{IP: net.ParseIP(sourceIpAddress)}, (&smtpClient{sourceIpAddress: sourceIpAddress}).localAddress())
+	})
+
+	t.Run("when SMTP source IP address not specified", func(t *testing.T) {
+		assert.Nil(t, new(smtpClient).localAddress())
+	})
+}
+
+func TestSmtpClientInitConnectionWithSourceIpAddress(t *testing.T) {
+	t.Run("binds SMTP source IP address", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		sourceIpAddress := "127.0.0.2"
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{
+				TargetServerAddress:    host,
+				TargetServerPortNumber: portNumber,
+				ConnectionTimeout:      1,
+				SourceIpAddress:        sourceIpAddress,
+			},
+		)
+		connection, err := client.initConnection()
+
+		assert.NoError(t, err)
+		defer connection.Close()
+		assert.Equal(t, sourceIpAddress, connection.LocalAddr().(*net.TCPAddr).IP.String())
+	})
+}
+
+func TestSmtpClientCancelSession(t *testing.T) {
+	t.Run("when SMTP session was cancelled before run", func(t *testing.T) {
+		serverAddress, stop := startSmtpStandIn(nil)
+		defer stop()
+		host, port, _ := net.SplitHostPort(serverAddress)
+		portNumber, _ := strconv.Atoi(port)
+		client := newSmtpClient(&SmtpRequestConfiguration{TargetServerAddress: host, TargetServerPortNumber: portNumber, ConnectionTimeout: 1})
+		client.cancelSession()
+
+		assert.False(t, client.runSession())
+		assert.True(t, client.sessionError().isConnection)
+	})
+
+	t.Run("when running SMTP session was cancelled", func(t *testing.T) {
+		listener, _ := net.Listen(tcpTransportLayer, localhostIPv4Address+":0")
+		defer listener.Close()
+		go func() {
+			connection, err := listener.Accept()
+			if err == nil {
+				defer connection.Close()
+				_, _ = io.Copy(io.Discard, connection)
+			}
+		}()
+		host, port, _ := net.SplitHostPort(listener.Addr().String())
+		portNumber, _ := strconv.Atoi(port)
+		client := newSmtpClient(
+			&SmtpRequestConfiguration{TargetServerAddress: host, TargetServerPortNumber: portNumber, ConnectionTimeout: 1, ResponseTimeout: 30},
+		)
+		sessionResult := make(chan bool)
+		go func() { sessionResult <- client.runSession() }()
+		time.Sleep(50 * time.Millisecond)
+		client.cancelSession()
+
+		select {
+		case isSuccessfulSession := <-sessionResult:
+			assert.False(t, isSuccessfulSession)
+			assert.True(t, client.sessionError().isSmtpServiceReady)
+		case <-time.After(5 * time.Second):
+			t.Fatal("SMTP session was not cancelled")
+		}
+	})
+}
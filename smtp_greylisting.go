@@ -0,0 +1,75 @@
+package truemail
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SMTP greylisting retry structure. Re-runs SMTP validation of greylisted email after retry
+// delay until email is not greylisted or retry attempts are exhausted, then delivers final
+// validator result to retry channel and to greylisting callback when it is specified
+type smtpGreylistingRetry struct {
+	validatorResult *ValidatorResult
+	delay           time.Duration
+	attempts        int
+	callback        func(*ValidatorResult)
+	results         chan *ValidatorResult
+	newSmtpLayer    func() smtpLayer
+}
+
+// smtpGreylistingRetry builder. Creates SMTP greylisting retry of greylisted validator
+// result with retry settings from its configuration. Retry uses own copy of validator
+// result taken before retry is scheduled, so it does not share state with validation
+func newSmtpGreylistingRetry(validatorResult *ValidatorResult) *smtpGreylistingRetry {
+	configuration := validatorResult.Configuration
+
+	return &smtpGreylistingRetry{
+		validatorResult: validatorResult.smtpRetryCopy(),
+		delay:           time.Duration(configuration.SmtpGreylistingRetryDelay) * time.Second,
+		attempts:        configuration.SmtpGreylistingRetryAttempts,
+		callback:        configuration.SmtpGreylistingCallback,
+		results:         make(chan *ValidatorResult, 1),
+		newSmtpLayer:    func() smtpLayer { return new(validationSmtp) },
+	}
+}
+
+// smtpGreylistingRetry methods
+
+// Schedules SMTP validation retry after retry delay
+func (retry *smtpGreylistingRetry) schedule() {
+	configuration := retry.validatorResult.Configuration
+	configuration.logEvent(
+		slog.LevelInfo,
+		logMessageSmtpGreylistingRetry,
+		configuration.emailLogAttr(retry.validatorResult.Email),
+		slog.Duration(logAttrRetryDelay, retry.delay),
+		slog.Int(logAttrAttemptsLeft, retry.attempts),
+	)
+	time.AfterFunc(retry.delay, retry.run)
+}
+
+// Runs SMTP validation retry with copy of validator result as it was before SMTP validation.
+// Schedules next retry when email is still greylisted and retry attempts are available,
+// otherwise delivers final validator result
+func (retry *smtpGreylistingRetry) run() {
+	retry.attempts--
+	validatorResult := retry.newSmtpLayer().check(retry.validatorResult.smtpRetryCopy())
+	if validatorResult.isGreylisted() && retry.attempts > 0 {
+		retry.validatorResult = validatorResult
+		retry.schedule()
+		return
+	}
+
+	retry.deliver(validatorResult)
+}
+
+// Delivers final validator result to retry channel and to greylisting callback.
+// Retry channel is closed after final validator result
+func (retry *smtpGreylistingRetry) deliver(validatorResult *ValidatorResult) {
+	retry.results <- validatorResult
+	close(retry.results)
+
+	if retry.callback != nil {
+		retry.callback(validatorResult)
+	}
+}
@@ -0,0 +1,95 @@
+package truemail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewSmtpGreylistingRetry(t *testing.T) {
+	t.Run("creates SMTP greylisting retry with settings from configuration", func(t *testing.T) {
+		callback := func(*ValidatorResult) {}
+		configuration := createConfiguration()
+		configuration.SmtpGreylistingRetryDelay, configuration.SmtpGreylistingRetryAttempts = 60, 3
+		configuration.SmtpGreylistingCallback = callback
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		retry := newSmtpGreylistingRetry(validatorResult)
+
+		assert.NotSame(t, validatorResult, retry.validatorResult)
+		assert.NotSame(t, configuration, retry.validatorResult.Configuration)
+		assert.Equal(t, validatorResult.Email, retry.validatorResult.Email)
+		assert.True(t, retry.validatorResult.isSmtpRetry)
+		assert.Equal(t, time.Minute, retry.delay)
+		assert.Equal(t, 3, retry.attempts)
+		assert.NotNil(t, retry.callback)
+		assert.Equal(t, 1, cap(retry.results))
+		assert.Equal(t, new(validationSmtp), retry.newSmtpLayer())
+	})
+}
+
+func TestSmtpGreylistingRetrySchedule(t *testing.T) {
+	t.Run("runs SMTP validation retry after retry delay", func(t *testing.T) {
+		validatorResult, validationSmtp := createSuccessfulValidatorResult(randomEmail(), createConfiguration()), new(validationSmtpMock)
+		retry := &smtpGreylistingRetry{
+			validatorResult: validatorResult,
+			delay:           time.Millisecond,
+			attempts:        1,
+			results:         make(chan *ValidatorResult, 1),
+			newSmtpLayer:    func() smtpLayer { return validationSmtp },
+		}
+		validationSmtp.On("check", mock.Anything).Once().Return(validatorResult)
+		retry.schedule()
+
+		assert.Same(t, validatorResult, <-retry.results)
+		validationSmtp.AssertExpectations(t)
+	})
+}
+
+func TestSmtpGreylistingRetryRun(t *testing.T) {
+	configuration := createConfiguration()
+	greylistedValidatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+	greylistedValidatorResult.Success = false
+	greylistedValidatorResult.addError(validationTypeSmtp, smtpGreylistedErrorContext)
+	successfulValidatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+	createRetry := func(validationSmtp smtpLayer, attempts int, callback func(*ValidatorResult)) *smtpGreylistingRetry {
+		return &smtpGreylistingRetry{
+			validatorResult: greylistedValidatorResult,
+			delay:           time.Millisecond,
+			attempts:        attempts,
+			callback:        callback,
+			results:         make(chan *ValidatorResult, 1),
+			newSmtpLayer:    func() smtpLayer { return validationSmtp },
+		}
+	}
+
+	t.Run("when email is not greylisted after retry, delivers final validator result", func(t *testing.T) {
+		validationSmtp, callbackResults := new(validationSmtpMock), make(chan *ValidatorResult, 1)
+		retry := createRetry(validationSmtp, 2, func(validatorResult *ValidatorResult) { callbackResults <- validatorResult })
+		validationSmtp.On("check", mock.MatchedBy(func(validatorResult *ValidatorResult) bool {
+			return validatorResult.isSmtpRetry && validatorResult.Success && validatorResult.Errors == nil
+		})).Once().Return(successfulValidatorResult)
+		retry.run()
+
+		result, isOpened := <-retry.results
+		assert.Same(t, successfulValidatorResult, result)
+		assert.True(t, isOpened)
+		_, isOpened = <-retry.results
+		assert.False(t, isOpened)
+		assert.Same(t, successfulValidatorResult, <-callbackResults)
+		assert.Equal(t, 1, retry.attempts)
+		validationSmtp.AssertExpectations(t)
+	})
+
+	t.Run("when email is still greylisted, retries until retry attempts are exhausted", func(t *testing.T) {
+		validationSmtp := new(validationSmtpMock)
+		retry := createRetry(validationSmtp, 2, nil)
+		validationSmtp.On("check", mock.Anything).Twice().Return(greylistedValidatorResult)
+		retry.run()
+
+		assert.Same(t, greylistedValidatorResult, <-retry.results)
+		assert.Equal(t, 0, retry.attempts)
+		validationSmtp.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,78 @@
+package truemail
+
+import "sync"
+
+// SMTP parallel probe structure. Tracks SMTP clients of SMTP sessions which run in
+// parallel with mail servers of one priority group, cancels running SMTP sessions
+// once definitive answer is received from one of mail servers
+type smtpParallelProbe struct {
+	mutex        sync.Mutex
+	isDefinitive bool
+	clients      map[client]bool
+}
+
+// smtpParallelProbe builder
+func newSmtpParallelProbe() *smtpParallelProbe {
+	return &smtpParallelProbe{clients: map[client]bool{}}
+}
+
+// smtpParallelProbe methods
+
+// Registers SMTP client of SMTP session which is going to run. Returns false when
+// definitive answer was already received, so SMTP session should not run. Nil probe
+// (sequential SMTP sessions) always returns true
+func (probe *smtpParallelProbe) register(smtpClient client) bool {
+	if probe == nil {
+		return true
+	}
+
+	probe.mutex.Lock()
+	defer probe.mutex.Unlock()
+
+	if probe.isDefinitive {
+		return false
+	}
+	probe.clients[smtpClient] = false
+
+	return true
+}
+
+// Unregisters SMTP client of finished SMTP session. Returns true when SMTP
+// session was cancelled, otherwise returns false
+func (probe *smtpParallelProbe) unregister(smtpClient client) bool {
+	if probe == nil {
+		return false
+	}
+
+	probe.mutex.Lock()
+	defer probe.mutex.Unlock()
+
+	isCancelled := probe.clients[smtpClient]
+	delete(probe.clients, smtpClient)
+
+	return isCancelled
+}
+
+// Marks that definitive answer was received and cancels running SMTP sessions
+func (probe *smtpParallelProbe) finish() {
+	probe.mutex.Lock()
+	defer probe.mutex.Unlock()
+
+	if probe.isDefinitive {
+		return
+	}
+	probe.isDefinitive = true
+
+	for smtpClient := range probe.clients {
+		probe.clients[smtpClient] = true
+		smtpClient.cancelSession()
+	}
+}
+
+// Returns true if definitive answer was received, otherwise returns false
+func (probe *smtpParallelProbe) isFinished() bool {
+	probe.mutex.Lock()
+	defer probe.mutex.Unlock()
+
+	return probe.isDefinitive
+}
@@ -0,0 +1,78 @@
+package truemail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSmtpParallelProbe(t *testing.T) {
+	t.Run("creates SMTP parallel probe without registered SMTP clients", func(t *testing.T) {
+		probe := newSmtpParallelProbe()
+
+		assert.Empty(t, probe.clients)
+		assert.False(t, probe.isFinished())
+	})
+}
+
+func TestSmtpParallelProbeRegister(t *testing.T) {
+	t.Run("when probe is nil", func(t *testing.T) {
+		var probe *smtpParallelProbe
+
+		assert.True(t, probe.register(new(smtpClientMock)))
+	})
+
+	t.Run("when definitive answer was not received", func(t *testing.T) {
+		probe, smtpClient := newSmtpParallelProbe(), new(smtpClientMock)
+
+		assert.True(t, probe.register(smtpClient))
+		assert.Contains(t, probe.clients, smtpClient)
+	})
+
+	t.Run("when definitive answer was received", func(t *testing.T) {
+		probe, smtpClient := newSmtpParallelProbe(), new(smtpClientMock)
+		probe.finish()
+
+		assert.False(t, probe.register(smtpClient))
+		assert.Empty(t, probe.clients)
+	})
+}
+
+func TestSmtpParallelProbeUnregister(t *testing.T) {
+	t.Run("when probe is nil", func(t *testing.T) {
+		var probe *smtpParallelProbe
+
+		assert.False(t, probe.unregister(new(smtpClientMock)))
+	})
+
+	t.Run("when SMTP session was not cancelled", func(t *testing.T) {
+		probe, smtpClient := newSmtpParallelProbe(), new(smtpClientMock)
+		probe.register(smtpClient)
+
+		assert.False(t, probe.unregister(smtpClient))
+		assert.Empty(t, probe.clients)
+	})
+
+	t.Run("when SMTP session was cancelled", func(t *testing.T) {
+		probe, smtpClient := newSmtpParallelProbe(), new(smtpClientMock)
+		smtpClient.On("cancelSession").Once()
+		probe.register(smtpClient)
+		probe.finish()
+
+		assert.True(t, probe.unregister(smtpClient))
+		assert.Empty(t, probe.clients)
+	})
+}
+
+func TestSmtpParallelProbeFinish(t *testing.T) {
+	t.Run("cancels running SMTP sessions once", func(t *testing.T) {
+		probe, smtpClient := newSmtpParallelProbe(), new(smtpClientMock)
+		smtpClient.On("cancelSession").Once()
+		probe.register(smtpClient)
+		probe.finish()
+		probe.finish()
+
+		smtpClient.AssertExpectations(t)
+		assert.True(t, probe.isFinished())
+	})
+}
@@ -0,0 +1,71 @@
+package truemail
+
+import "regexp"
+
+// SMTP provider profile structure. Identifies mail provider by MX host names (provider
+// domains, their subdomains are matched too) and defines provider SMTP behavior: whether
+// successful RCPT TO reply means that mailbox exists, reply text pattern of unknown
+// mailbox and rate limit of provider MX hosts. CatchAllProbing enables RCPT TO probe of
+// random recipient after successful RCPT TO, accepted random recipient means catch-all
+// domain. DataProbing enables DATA probe after successful RCPT TO for providers which
+// reject unknown mailbox only after DATA, connection is closed after 354 reply, so message
+// is never sent. Catch-all probing is skipped when DATA probing is enabled
+type SmtpProviderProfile struct {
+	Name                                    string
+	MxHostNames                             []string
+	TrustRcpt, CatchAllProbing, DataProbing bool
+	MailboxUnknownPattern                   *regexp.Regexp
+	RateLimit                               SmtpRateLimit
+}
+
+// Returns bundled SMTP provider profiles
+func defaultSmtpProviderProfiles() []SmtpProviderProfile {
+	gmailMailboxUnknownPattern, _ := newRegex(regexGmailMailboxUnknownPattern)
+	microsoftMailboxUnknownPattern, _ := newRegex(regexMicrosoftMailboxUnknownPattern)
+
+	return []SmtpProviderProfile{
+		{
+			Name:                  smtpProviderGmail,
+			MxHostNames:           []string{"google.com", "googlemail.com"},
+			TrustRcpt:             true,
+			CatchAllProbing:       true,
+			MailboxUnknownPattern: gmailMailboxUnknownPattern,
+		},
+		{
+			Name:        smtpProviderYahoo,
+			MxHostNames: []string{"yahoodns.net"},
+		},
+		{
+			Name:                  smtpProviderMicrosoft,
+			MxHostNames:           []string{"protection.outlook.com", "hotmail.com", "outlook.com"},
+			TrustRcpt:             true,
+			DataProbing:           true,
+			MailboxUnknownPattern: microsoftMailboxUnknownPattern,
+		},
+	}
+}
+
+// SmtpProviderProfile methods
+
+// Returns true if SMTP provider profile matches MX host name, otherwise returns false
+func (profile *SmtpProviderProfile) isMatched(mxHostName string) bool {
+	for _, providerHostName := range profile.MxHostNames {
+		if isDomainOrSubdomain(mxHostName, providerHostName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if SMTP provider profile is specified and does not trust successful
+// RCPT TO reply, otherwise returns false
+func (profile *SmtpProviderProfile) isRcptUntrusted() bool {
+	return profile != nil && !profile.TrustRcpt
+}
+
+// Returns true if SMTP client error matches mailbox unknown pattern of SMTP provider
+// profile. Returns false when profile or its pattern is not specified
+func (profile *SmtpProviderProfile) isMailboxUnknownError(err *SmtpClientError) bool {
+	return profile != nil && profile.MailboxUnknownPattern != nil && profile.MailboxUnknownPattern.MatchString(err.Error())
+}
@@ -0,0 +1,85 @@
+package truemail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSmtpProviderProfiles(t *testing.T) {
+	t.Run("returns bundled SMTP provider profiles", func(t *testing.T) {
+		profiles := defaultSmtpProviderProfiles()
+
+		assert.Len(t, profiles, 3)
+		assert.Equal(t, smtpProviderGmail, profiles[0].Name)
+		assert.True(t, profiles[0].TrustRcpt)
+		assert.True(t, profiles[0].CatchAllProbing)
+		assert.False(t, profiles[0].DataProbing)
+		assert.Equal(t, smtpProviderYahoo, profiles[1].Name)
+		assert.False(t, profiles[1].TrustRcpt)
+		assert.False(t, profiles[1].CatchAllProbing)
+		assert.False(t, profiles[1].DataProbing)
+		assert.Equal(t, smtpProviderMicrosoft, profiles[2].Name)
+		assert.True(t, profiles[2].TrustRcpt)
+		assert.False(t, profiles[2].CatchAllProbing)
+		assert.True(t, profiles[2].DataProbing)
+	})
+}
+
+func TestSmtpProviderProfileIsMatched(t *testing.T) {
+	profile := &SmtpProviderProfile{MxHostNames: []string{"google.com", "googlemail.com"}}
+
+	t.Run("when MX host name is provider domain or its subdomain", func(t *testing.T) {
+		assert.True(t, profile.isMatched("google.com"))
+		assert.True(t, profile.isMatched("ALT1.ASPMX.L.GOOGLE.COM."))
+		assert.True(t, profile.isMatched("gmail-smtp-in.l.googlemail.com"))
+	})
+
+	t.Run("when MX host name is not provider domain or its subdomain", func(t *testing.T) {
+		assert.False(t, profile.isMatched("notgoogle.com"))
+		assert.False(t, profile.isMatched(randomDomain()))
+	})
+}
+
+func TestSmtpProviderProfileIsRcptUntrusted(t *testing.T) {
+	t.Run("when SMTP provider profile is not specified", func(t *testing.T) {
+		var profile *SmtpProviderProfile
+
+		assert.False(t, profile.isRcptUntrusted())
+	})
+
+	t.Run("when SMTP provider profile trusts RCPT TO", func(t *testing.T) {
+		assert.False(t, (&SmtpProviderProfile{TrustRcpt: true}).isRcptUntrusted())
+	})
+
+	t.Run("when SMTP provider profile does not trust RCPT TO", func(t *testing.T) {
+		assert.True(t, new(SmtpProviderProfile).isRcptUntrusted())
+	})
+}
+
+func TestSmtpProviderProfileIsMailboxUnknownError(t *testing.T) {
+	gmailProfile := &defaultSmtpProviderProfiles()[0]
+	smtpClientError := &SmtpClientError{
+		isRecptTo: true,
+		err:       errors.New("550 5.1.1 The email account that you tried to reach does not exist"),
+	}
+
+	t.Run("when SMTP provider profile is not specified", func(t *testing.T) {
+		var profile *SmtpProviderProfile
+
+		assert.False(t, profile.isMailboxUnknownError(smtpClientError))
+	})
+
+	t.Run("when SMTP provider profile mailbox unknown pattern is not specified", func(t *testing.T) {
+		assert.False(t, new(SmtpProviderProfile).isMailboxUnknownError(smtpClientError))
+	})
+
+	t.Run("when SMTP client error matches mailbox unknown pattern", func(t *testing.T) {
+		assert.True(t, gmailProfile.isMailboxUnknownError(smtpClientError))
+	})
+
+	t.Run("when SMTP client error does not match mailbox unknown pattern", func(t *testing.T) {
+		assert.False(t, gmailProfile.isMailboxUnknownError(&SmtpClientError{err: errors.New("550 5.7.1 blocked")}))
+	})
+}
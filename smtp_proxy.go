@@ -0,0 +1,134 @@
+package truemail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// SMTP dialer interface. Dials target server address directly or via proxy
+type smtpDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// HTTP CONNECT proxy dialer structure
+type httpConnectDialer struct {
+	proxyAddress, proxyAuthorization string
+	dialer                           *net.Dialer
+}
+
+// Buffered connection structure. Reads data buffered during proxy handshake first
+type bufferedConnection struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// smtpDialer builder. Creates dialer which connects with target server via SOCKS5 or
+// HTTP CONNECT proxy when proxy URL is specified, otherwise connects directly
+func newSmtpDialer(smtpProxy string, dialer *net.Dialer) (smtpDialer, error) {
+	if smtpProxy == emptyString {
+		return dialer, nil
+	}
+
+	proxyUrl, err := url.Parse(smtpProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyUrl.Scheme {
+	case smtpProxySchemeSocks5, smtpProxySchemeSocks5h:
+		var auth *proxy.Auth
+		if proxyUrl.User != nil {
+			password, _ := proxyUrl.User.Password()
+			auth = &proxy.Auth{User: proxyUrl.User.Username(), Password: password}
+		}
+
+		socksDialer, err := proxy.SOCKS5(tcpTransportLayer, proxyUrl.Host, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+
+		return socksDialer.(smtpDialer), nil
+	case smtpProxySchemeHttp:
+		httpDialer := &httpConnectDialer{proxyAddress: proxyUrl.Host, dialer: dialer}
+		if proxyUrl.User != nil {
+			password, _ := proxyUrl.User.Password()
+			credentials := proxyUrl.User.Username() + ":" + password
+			httpDialer.proxyAuthorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		}
+
+		return httpDialer, nil
+	}
+
+	return nil, fmt.Errorf("%s is unsupported smtp proxy scheme", proxyUrl.Scheme)
+}
+
+// Returns SMTP proxy URL without password, uses in proxy errors
+func redactedSmtpProxy(smtpProxy string) string {
+	proxyUrl, err := url.Parse(smtpProxy)
+	if err != nil {
+		return smtpProxy
+	}
+
+	return proxyUrl.Redacted()
+}
+
+// httpConnectDialer methods
+
+// Connects with target server address via HTTP CONNECT proxy tunnel. Returns
+// error when proxy responded with not 2xx status
+func (httpDialer *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	connection, err := httpDialer.dialer.DialContext(ctx, network, httpDialer.proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = connection.SetDeadline(deadline)
+		defer func() { _ = connection.SetDeadline(time.Time{}) }()
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if httpDialer.proxyAuthorization != emptyString {
+		request.Header.Set("Proxy-Authorization", httpDialer.proxyAuthorization)
+	}
+
+	err = request.Write(connection)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(connection)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		connection.Close()
+		return nil, fmt.Errorf("http connect to %s: proxy responded with %s", address, response.Status)
+	}
+
+	return &bufferedConnection{Conn: connection, reader: reader}, nil
+}
+
+// bufferedConnection methods
+
+// Reads data buffered during proxy handshake, then data from connection
+func (connection *bufferedConnection) Read(data []byte) (int, error) {
+	return connection.reader.Read(data)
+}
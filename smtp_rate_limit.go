@@ -0,0 +1,168 @@
+package truemail
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SMTP rate limit structure. Token-bucket limit of SMTP connections: bucket is refilled
+// with Rate tokens per second up to Burst tokens, each SMTP connection takes one token.
+// Burst is equal to 1 when it is not specified. Zero rate disables rate limit
+type SmtpRateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Token bucket structure. Includes available tokens (negative when tokens are reserved
+// by waiting SMTP connections), its rate limit and time of last refill
+type tokenBucket struct {
+	tokens    float64
+	rateLimit SmtpRateLimit
+	updatedAt time.Time
+}
+
+// SMTP rate limiter structure. Token buckets by MX host ip address and recipient domain,
+// shared across validations which use the same configuration
+type smtpRateLimiter struct {
+	buckets map[string]*tokenBucket
+	mutex   sync.Mutex
+	now     func() time.Time
+	sleep   func(context.Context, time.Duration) bool
+}
+
+// smtpRateLimiter builder
+func newSmtpRateLimiter() *smtpRateLimiter {
+	return &smtpRateLimiter{buckets: map[string]*tokenBucket{}, now: time.Now, sleep: sleepContext}
+}
+
+// Waits for duration or until context is done. Returns false when
+// context is done before duration elapsed, otherwise returns true
+func sleepContext(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-contextOrBackground(ctx).Done():
+		return false
+	}
+}
+
+// SmtpRateLimit methods
+
+// Returns true if rate limit is enabled, otherwise returns false
+func (rateLimit SmtpRateLimit) isEnabled() bool {
+	return rateLimit.Rate > 0
+}
+
+// Returns token bucket capacity
+func (rateLimit SmtpRateLimit) capacity() float64 {
+	if rateLimit.Burst == 0 {
+		return 1
+	}
+
+	return float64(rateLimit.Burst)
+}
+
+// tokenBucket methods
+
+// Refills token bucket with tokens accumulated since last refill up to bucket capacity
+func (bucket *tokenBucket) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.updatedAt).Seconds() * bucket.rateLimit.Rate
+	bucket.tokens = min(bucket.tokens, bucket.rateLimit.capacity())
+	bucket.updatedAt = now
+}
+
+// Returns true if token bucket is refilled up to its capacity, otherwise returns false
+func (bucket *tokenBucket) isFull(now time.Time) bool {
+	tokens := bucket.tokens + now.Sub(bucket.updatedAt).Seconds()*bucket.rateLimit.Rate
+	return tokens >= bucket.rateLimit.capacity()
+}
+
+// smtpRateLimiter methods
+
+// Takes token from token bucket of key. When bucket is empty, reserves token and waits
+// until it is available, if wait time does not exceed max wait. Reserved token is returned
+// to token bucket when context is done during waiting. Returns false when rate limited or
+// context is done, otherwise returns true
+func (limiter *smtpRateLimiter) take(ctx context.Context, key string, rateLimit SmtpRateLimit, maxWait time.Duration) bool {
+	if !rateLimit.isEnabled() {
+		return true
+	}
+
+	wait, ok := limiter.reserve(key, rateLimit, maxWait)
+	if !ok {
+		return false
+	}
+
+	if wait > 0 && !limiter.sleep(ctx, wait) {
+		limiter.release(key)
+		return false
+	}
+
+	return true
+}
+
+// Reserves token from token bucket of key. Returns wait time until reserved
+// token is available or false when wait time exceeds max wait
+func (limiter *smtpRateLimiter) reserve(key string, rateLimit SmtpRateLimit, maxWait time.Duration) (time.Duration, bool) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	bucket := limiter.bucket(key, rateLimit, now)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / rateLimit.Rate * float64(time.Second))
+	if wait > maxWait {
+		return 0, false
+	}
+
+	bucket.tokens--
+	return wait, true
+}
+
+// Returns reserved token to token bucket of key
+func (limiter *smtpRateLimiter) release(key string) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if bucket, ok := limiter.buckets[key]; ok {
+		bucket.tokens++
+	}
+}
+
+// Returns token bucket of key with actual rate limit. Creates full token bucket when it
+// does not exist, full token buckets are removed when buckets limit is reached
+func (limiter *smtpRateLimiter) bucket(key string, rateLimit SmtpRateLimit, now time.Time) *tokenBucket {
+	bucket, ok := limiter.buckets[key]
+	if ok {
+		bucket.refill(now)
+		bucket.rateLimit = rateLimit
+		return bucket
+	}
+
+	if len(limiter.buckets) >= smtpRateLimiterMaxBuckets {
+		limiter.removeFullBuckets(now)
+	}
+
+	bucket = &tokenBucket{tokens: rateLimit.capacity(), rateLimit: rateLimit, updatedAt: now}
+	limiter.buckets[key] = bucket
+
+	return bucket
+}
+
+// Removes token buckets refilled up to its capacity, they are equal to new token buckets
+func (limiter *smtpRateLimiter) removeFullBuckets(now time.Time) {
+	for key, bucket := range limiter.buckets {
+		if bucket.isFull(now) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package truemail
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createSmtpRateLimiter(now *time.Time, waits *[]time.Duration) *smtpRateLimiter {
+	limiter := newSmtpRateLimiter()
+	limiter.now = func() time.Time { return *now }
+	limiter.sleep = func(_ context.Context, wait time.Duration) bool {
+		*waits = append(*waits, wait)
+		return true
+	}
+	return limiter
+}
+
+func TestNewSmtpRateLimiter(t *testing.T) {
+	t.Run("creates SMTP rate limiter without token buckets", func(t *testing.T) {
+		limiter := newSmtpRateLimiter()
+
+		assert.Empty(t, limiter.buckets)
+		assert.NotNil(t, limiter.now)
+		assert.NotNil(t, limiter.sleep)
+	})
+}
+
+func TestSmtpRateLimitIsEnabled(t *testing.T) {
+	t.Run("when rate is positive", func(t *testing.T) {
+		assert.True(t, SmtpRateLimit{Rate: 0.5}.isEnabled())
+	})
+
+	t.Run("when rate is not specified", func(t *testing.T) {
+		assert.False(t, SmtpRateLimit{Burst: 10}.isEnabled())
+	})
+}
+
+func TestSmtpRateLimitCapacity(t *testing.T) {
+	t.Run("when burst is specified", func(t *testing.T) {
+		assert.Equal(t, float64(5), SmtpRateLimit{Rate: 1, Burst: 5}.capacity())
+	})
+
+	t.Run("when burst is not specified", func(t *testing.T) {
+		assert.Equal(t, float64(1), SmtpRateLimit{Rate: 1}.capacity())
+	})
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	updatedAt := time.Now()
+
+	t.Run("adds tokens accumulated since last refill", func(t *testing.T) {
+		bucket := &tokenBucket{tokens: -1, rateLimit: SmtpRateLimit{Rate: 2, Burst: 5}, updatedAt: updatedAt}
+		bucket.refill(updatedAt.Add(time.Second))
+
+		assert.Equal(t, float64(1), bucket.tokens)
+		assert.Equal(t, updatedAt.Add(time.Second), bucket.updatedAt)
+	})
+
+	t.Run("does not exceed bucket capacity", func(t *testing.T) {
+		bucket := &tokenBucket{rateLimit: SmtpRateLimit{Rate: 2, Burst: 5}, updatedAt: updatedAt}
+		bucket.refill(updatedAt.Add(time.Minute))
+
+		assert.Equal(t, float64(5), bucket.tokens)
+	})
+}
+
+func TestTokenBucketIsFull(t *testing.T) {
+	updatedAt := time.Now()
+	bucket := &tokenBucket{tokens: 1, rateLimit: SmtpRateLimit{Rate: 1, Burst: 3}, updatedAt: updatedAt}
+
+	t.Run("when bucket is refilled up to its capacity", func(t *testing.T) {
+		assert.True(t, bucket.isFull(updatedAt.Add(2*time.Second)))
+	})
+
+	t.Run("when bucket is not refilled up to its capacity", func(t *testing.T) {
+		assert.False(t, bucket.isFull(updatedAt.Add(time.Second)))
+	})
+}
+
+func TestSmtpRateLimiterTake(t *testing.T) {
+	rateLimit := SmtpRateLimit{Rate: 1, Burst: 2}
+
+	t.Run("when rate limit is disabled", func(t *testing.T) {
+		limiter := newSmtpRateLimiter()
+
+		for range 10 {
+			assert.True(t, limiter.take(context.Background(), randomDomain(), SmtpRateLimit{}, 0))
+		}
+		assert.Empty(t, limiter.buckets)
+	})
+
+	t.Run("takes tokens up to burst, then rate limits without waiting", func(t *testing.T) {
+		now, waits := time.Now(), []time.Duration{}
+		limiter, key := createSmtpRateLimiter(&now, &waits), randomDomain()
+
+		assert.True(t, limiter.take(context.Background(), key, rateLimit, 0))
+		assert.True(t, limiter.take(context.Background(), key, rateLimit, 0))
+		assert.False(t, limiter.take(context.Background(), key, rateLimit, 0))
+		assert.True(t, limiter.take(context.Background(), randomDomain(), rateLimit, 0))
+		assert.Empty(t, waits)
+	})
+
+	t.Run("takes token refilled with rate", func(t *testing.T) {
+		now, waits := time.Now(), []time.Duration{}
+		limiter, key := createSmtpRateLimiter(&now, &waits), randomDomain()
+
+		assert.True(t, limiter.take(context.Background(), key, rateLimit, 0))
+		assert.True(t, limiter.take(context.Background(), key, rateLimit, 0))
+		now = now.Add(time.Second)
+		assert.True(t, limiter.take(context.Background(), key, rateLimit, 0))
+		assert.False(t, limiter.take(context.Background(), key, rateLimit, 0))
+	})
+
+	t.Run("waits for reserved tokens up to max wait", func(t *testing.T) {
+		now, waits := time.Now(), []time.Duration{}
+		limiter, key := createSmtpRateLimiter(&now, &waits), randomDomain()
+
+		for range 4 {
+			assert.True(t, limiter.take(context.Background(), key, rateLimit, 2*time.Second))
+		}
+		assert.False(t, limiter.take(context.Background(), key, rateLimit, 2*time.Second))
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, waits)
+	})
+
+	t.Run("when context is done while waiting, returns reserved token", func(t *testing.T) {
+		now, waits := time.Now(), []time.Duration{}
+		limiter, key := createSmtpRateLimiter(&now, &waits), randomDomain()
+		limiter.sleep = func(ctx context.Context, wait time.Duration) bool { return sleepContext(ctx, wait) }
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.True(t, limiter.take(ctx, key, rateLimit, 2*time.Second))
+		assert.True(t, limiter.take(ctx, key, rateLimit, 2*time.Second))
+		assert.False(t, limiter.take(ctx, key, rateLimit, 2*time.Second))
+		assert.Equal(t, float64(0), limiter.buckets[key].tokens)
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		limiter, waitGroup, key := newSmtpRateLimiter(), new(sync.WaitGroup), randomDomain()
+		takenTokens, mutex := 0, new(sync.Mutex)
+		for range 10 {
+			waitGroup.Add(1)
+			go func() {
+				defer waitGroup.Done()
+				if limiter.take(context.Background(), key, SmtpRateLimit{Rate: 0.001, Burst: 5}, 0) {
+					mutex.Lock()
+					takenTokens++
+					mutex.Unlock()
+				}
+			}()
+		}
+		waitGroup.Wait()
+
+		assert.Equal(t, 5, takenTokens)
+	})
+}
+
+func TestSleepContext(t *testing.T) {
+	t.Run("waits for duration", func(t *testing.T) {
+		assert.True(t, sleepContext(context.Background(), time.Millisecond))
+	})
+
+	t.Run("when context is done before duration elapsed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		startedAt := time.Now()
+
+		assert.False(t, sleepContext(ctx, time.Minute))
+		assert.Less(t, time.Since(startedAt), time.Second)
+	})
+
+	t.Run("when context is not specified", func(t *testing.T) {
+		assert.True(t, sleepContext(nil, time.Millisecond))
+	})
+}
+
+func TestSmtpRateLimiterBucket(t *testing.T) {
+	t.Run("creates full token bucket", func(t *testing.T) {
+		now := time.Now()
+		limiter, key, rateLimit := newSmtpRateLimiter(), randomDomain(), SmtpRateLimit{Rate: 1, Burst: 3}
+		bucket := limiter.bucket(key, rateLimit, now)
+
+		assert.Equal(t, &tokenBucket{tokens: 3, rateLimit: rateLimit, updatedAt: now}, bucket)
+		assert.Same(t, bucket, limiter.buckets[key])
+	})
+
+	t.Run("refills existing token bucket and updates its rate limit", func(t *testing.T) {
+		now := time.Now()
+		limiter, key, rateLimit := newSmtpRateLimiter(), randomDomain(), SmtpRateLimit{Rate: 2, Burst: 3}
+		limiter.buckets[key] = &tokenBucket{rateLimit: SmtpRateLimit{Rate: 1, Burst: 3}, updatedAt: now}
+		bucket := limiter.bucket(key, rateLimit, now.Add(time.Second))
+
+		assert.Equal(t, float64(1), bucket.tokens)
+		assert.Equal(t, rateLimit, bucket.rateLimit)
+	})
+
+	t.Run("removes full token buckets when buckets limit is reached", func(t *testing.T) {
+		now, rateLimit := time.Now(), SmtpRateLimit{Rate: 1}
+		limiter := newSmtpRateLimiter()
+		for index := range smtpRateLimiterMaxBuckets {
+			limiter.buckets[fmt.Sprint(index)] = &tokenBucket{tokens: 1, rateLimit: rateLimit, updatedAt: now}
+		}
+		limiter.buckets["0"].tokens = -10
+		limiter.bucket(randomDomain(), rateLimit, now)
+
+		assert.Equal(t, 2, len(limiter.buckets))
+		assert.Contains(t, limiter.buckets, "0")
+	})
+}
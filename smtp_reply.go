@@ -0,0 +1,81 @@
+package truemail
+
+import "strings"
+
+// SMTP reply structure. Includes basic reply code (RFC 5321), enhanced status code
+// (RFC 3463) when target server specified it, reply text without codes and reply
+// classification. Classification is empty when reply was not recognized
+type SmtpReply struct {
+	Code                               int
+	EnhancedCode, Text, Classification string
+}
+
+// SmtpReply builder. Parses enhanced status code from first line of reply message and
+// removes it from each reply line, classifies SMTP reply
+func newSmtpReply(code int, message string) *SmtpReply {
+	lines := strings.Split(message, "\n")
+	enhancedCode := regexCaptureGroup(lines[0], regexSmtpEnhancedCodePattern, 1)
+	if enhancedCode != emptyString && enhancedCode[0] != byte('0'+code/100) {
+		enhancedCode = emptyString
+	}
+
+	for index, line := range lines {
+		if enhancedCode != emptyString {
+			line = strings.TrimPrefix(line, enhancedCode)
+		}
+		lines[index] = strings.TrimSpace(line)
+	}
+
+	smtpReply := &SmtpReply{Code: code, EnhancedCode: enhancedCode, Text: strings.Join(lines, "\n")}
+	smtpReply.Classification = smtpReply.classification()
+
+	return smtpReply
+}
+
+// SmtpReply methods
+
+// Returns true if SMTP reply is transient negative completion reply (4xx), otherwise returns false
+func (smtpReply *SmtpReply) IsTemporary() bool {
+	return smtpReply.Code/100 == 4
+}
+
+// Returns true if SMTP reply is permanent negative completion reply (5xx), otherwise returns false
+func (smtpReply *SmtpReply) IsPermanent() bool {
+	return smtpReply.Code/100 == 5
+}
+
+// Returns SMTP reply classification. Enhanced status code classification table is checked
+// first, then reply text of IP reputation block, then enhanced status code subject and
+// basic reply code. Returns empty string when SMTP reply was not recognized
+func (smtpReply *SmtpReply) classification() string {
+	if classification, ok := smtpReplyClassificationsByEnhancedCode()[smtpReply.EnhancedCode]; ok {
+		return classification
+	}
+
+	if !smtpReply.IsTemporary() && !smtpReply.IsPermanent() {
+		return emptyString
+	}
+
+	if matchRegex(smtpReply.Text, regexSmtpIpReputationBlockPattern) {
+		return smtpReplyIpReputationBlock
+	}
+
+	if smtpReply.IsTemporary() {
+		return smtpReplyTemporaryFailure
+	}
+
+	if strings.HasPrefix(smtpReply.EnhancedCode, "5.7.") {
+		return smtpReplyPolicyBlock
+	}
+
+	switch smtpReply.Code {
+	case 552:
+		return smtpReplyMailboxFull
+	case 550, 551, 553:
+		if matchRegex(smtpReply.Text, regexSmtpMailboxUnknownPattern) {
+			return smtpReplyMailboxUnknown
+		}
+	}
+
+	return emptyString
+}
@@ -0,0 +1,85 @@
+package truemail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSmtpReply(t *testing.T) {
+	t.Run("parses enhanced status code and reply text", func(t *testing.T) {
+		assert.Equal(
+			t,
+			&SmtpReply{Code: 550, EnhancedCode: "5.1.1", Text: "User unknown", Classification: smtpReplyMailboxUnknown},
+			newSmtpReply(550, "5.1.1 User unknown"),
+		)
+	})
+
+	t.Run("removes enhanced status code from each line of multi-line reply", func(t *testing.T) {
+		smtpReply := newSmtpReply(552, "5.2.2 The email account that you tried to reach is over quota.\n5.2.2 Please try again later")
+
+		assert.Equal(t, "5.2.2", smtpReply.EnhancedCode)
+		assert.Equal(t, "The email account that you tried to reach is over quota.\nPlease try again later", smtpReply.Text)
+		assert.Equal(t, smtpReplyMailboxFull, smtpReply.Classification)
+	})
+
+	t.Run("when reply does not include enhanced status code", func(t *testing.T) {
+		smtpReply := newSmtpReply(550, "No such user here")
+
+		assert.Empty(t, smtpReply.EnhancedCode)
+		assert.Equal(t, "No such user here", smtpReply.Text)
+		assert.Equal(t, smtpReplyMailboxUnknown, smtpReply.Classification)
+	})
+
+	t.Run("when enhanced status code class does not match reply code", func(t *testing.T) {
+		smtpReply := newSmtpReply(450, "5.1.1 User unknown")
+
+		assert.Empty(t, smtpReply.EnhancedCode)
+		assert.Equal(t, "5.1.1 User unknown", smtpReply.Text)
+	})
+}
+
+func TestSmtpReplyIsTemporary(t *testing.T) {
+	t.Run("when transient negative completion reply", func(t *testing.T) {
+		assert.True(t, (&SmtpReply{Code: 451}).IsTemporary())
+	})
+
+	t.Run("when other reply", func(t *testing.T) {
+		assert.False(t, (&SmtpReply{Code: 550}).IsTemporary())
+	})
+}
+
+func TestSmtpReplyIsPermanent(t *testing.T) {
+	t.Run("when permanent negative completion reply", func(t *testing.T) {
+		assert.True(t, (&SmtpReply{Code: 554}).IsPermanent())
+	})
+
+	t.Run("when other reply", func(t *testing.T) {
+		assert.False(t, (&SmtpReply{Code: 421}).IsPermanent())
+	})
+}
+
+func TestSmtpReplyClassification(t *testing.T) {
+	for _, testCase := range []struct {
+		code                 int
+		message, expectation string
+	}{
+		{550, "5.1.1 The email account that you tried to reach does not exist", smtpReplyMailboxUnknown},
+		{550, "5.2.1 Mailbox disabled", smtpReplyMailboxUnknown},
+		{553, "sorry, that domain isn't in my list of allowed rcpthosts; no such recipient", smtpReplyMailboxUnknown},
+		{452, "4.2.2 Mailbox full", smtpReplyMailboxFull},
+		{552, "Requested mail action aborted: exceeded storage allocation", smtpReplyMailboxFull},
+		{550, "5.7.1 Service unavailable, Client host [192.0.2.1] blocked using Spamhaus", smtpReplyIpReputationBlock},
+		{554, "5.7.1 Rejected due to poor IP reputation", smtpReplyIpReputationBlock},
+		{550, "5.7.25 The IP address sending this message does not have a PTR record setup", smtpReplyIpReputationBlock},
+		{550, "5.7.1 Delivery not authorized, message refused", smtpReplyPolicyBlock},
+		{450, "4.2.0 Greylisted, please try again later", smtpReplyTemporaryFailure},
+		{421, "Service not available", smtpReplyTemporaryFailure},
+		{554, "Transaction failed", emptyString},
+		{354, "Start mail input", emptyString},
+	} {
+		t.Run(testCase.message, func(t *testing.T) {
+			assert.Equal(t, testCase.expectation, newSmtpReply(testCase.code, testCase.message).classification())
+		})
+	}
+}
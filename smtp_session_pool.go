@@ -0,0 +1,143 @@
+package truemail
+
+import (
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+// Pooled SMTP session structure. Idle SMTP session with target server which passed
+// greeting, HELO/EHLO and STARTTLS, so it can be reused for following recipients. Includes
+// number of RCPT TO commands sent over this session, time when session became idle and
+// response timeout of target server
+type smtpPooledSession struct {
+	connection      *smtpConnection
+	client          *smtp.Client
+	tlsDetails      *TlsDetails
+	recipients      int
+	idleSince       time.Time
+	responseTimeout time.Duration
+}
+
+// smtpPooledSession methods
+
+// Ends pooled SMTP session gracefully with QUIT under response timeout and closes its
+// connection. QUIT is not sent when connection with target server failed
+func (session *smtpPooledSession) close() {
+	if !session.connection.isFailed {
+		_ = session.connection.SetDeadline(time.Now().Add(session.responseTimeout))
+		_ = smtpCommand(session.client, 221, "QUIT")
+	}
+	_ = session.client.Close()
+}
+
+// SMTP session pool structure. Keeps idle SMTP sessions by SMTP session pool key (MX host,
+// source IP address and session settings), shared between validations, safe for concurrent use.
+// Closed SMTP session pool does not keep idle SMTP sessions anymore
+type smtpSessionPool struct {
+	mutex                          sync.Mutex
+	sessions                       map[string][]*smtpPooledSession
+	maxIdleTime                    time.Duration
+	maxRecipients, maxIdleSessions int
+	isClosed                       bool
+	now                            func() time.Time
+}
+
+// smtpSessionPool builder. Max idle time is specified in seconds
+func newSmtpSessionPool(maxIdleTime, maxRecipients, maxIdleSessions int) *smtpSessionPool {
+	return &smtpSessionPool{
+		sessions:        map[string][]*smtpPooledSession{},
+		maxIdleTime:     time.Duration(maxIdleTime) * time.Second,
+		maxRecipients:   maxRecipients,
+		maxIdleSessions: maxIdleSessions,
+		now:             time.Now,
+	}
+}
+
+// smtpSessionPool methods
+
+// Takes most recently used idle SMTP session by SMTP session pool key. Idle SMTP sessions
+// which exceeded max idle time are removed and ended with QUIT. Returns nil when idle SMTP
+// session not exists
+func (pool *smtpSessionPool) checkout(key string) *smtpPooledSession {
+	pool.mutex.Lock()
+	expiredSessions := pool.removeExpiredSessions()
+	var session *smtpPooledSession
+	if idleSessions := pool.sessions[key]; len(idleSessions) > 0 {
+		session = idleSessions[len(idleSessions)-1]
+		pool.sessions[key] = idleSessions[:len(idleSessions)-1]
+	}
+	pool.mutex.Unlock()
+
+	for _, expiredSession := range expiredSessions {
+		expiredSession.close()
+	}
+
+	return session
+}
+
+// Puts SMTP session to SMTP session pool. Returns false when SMTP session reached max
+// recipients, SMTP session pool key reached max idle sessions or SMTP session pool is
+// closed, otherwise returns true
+func (pool *smtpSessionPool) checkin(key string, session *smtpPooledSession) bool {
+	if !pool.isReusable(session.recipients) {
+		return false
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pool.isClosed || len(pool.sessions[key]) >= pool.maxIdleSessions {
+		return false
+	}
+	session.idleSince = pool.now()
+	pool.sessions[key] = append(pool.sessions[key], session)
+
+	return true
+}
+
+// Closes SMTP session pool: removes all idle SMTP sessions and ends them gracefully with
+// QUIT. SMTP sessions which are in use are ended with QUIT by SMTP clients. Safe for
+// concurrent use, does nothing for closed SMTP session pool
+func (pool *smtpSessionPool) Close() {
+	pool.mutex.Lock()
+	sessions := pool.sessions
+	pool.sessions, pool.isClosed = map[string][]*smtpPooledSession{}, true
+	pool.mutex.Unlock()
+
+	for _, idleSessions := range sessions {
+		for _, session := range idleSessions {
+			session.close()
+		}
+	}
+}
+
+// Returns true if SMTP session with given number of sent RCPT TO commands
+// can be reused, otherwise returns false
+func (pool *smtpSessionPool) isReusable(recipients int) bool {
+	return recipients < pool.maxRecipients
+}
+
+// Removes idle SMTP sessions which exceeded max idle time and returns them
+func (pool *smtpSessionPool) removeExpiredSessions() (expiredSessions []*smtpPooledSession) {
+	now := pool.now()
+
+	for key, idleSessions := range pool.sessions {
+		var activeSessions []*smtpPooledSession
+		for _, session := range idleSessions {
+			if now.Sub(session.idleSince) > pool.maxIdleTime {
+				expiredSessions = append(expiredSessions, session)
+				continue
+			}
+			activeSessions = append(activeSessions, session)
+		}
+
+		if len(activeSessions) == 0 {
+			delete(pool.sessions, key)
+			continue
+		}
+		pool.sessions[key] = activeSessions
+	}
+
+	return expiredSessions
+}
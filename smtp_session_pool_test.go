@@ -0,0 +1,168 @@
+package truemail
+
+import (
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createPooledSession(recipients int) *smtpPooledSession {
+	clientConnection, serverConnection := net.Pipe()
+	go func() {
+		_, _ = serverConnection.Write([]byte("220 ok\r\n"))
+	}()
+	client, _ := smtp.NewClient(clientConnection, localhostIPv4Address)
+
+	return &smtpPooledSession{connection: newSmtpConnection(clientConnection), client: client, recipients: recipients}
+}
+
+// Returns pooled SMTP session with target server stand-in which replies to QUIT.
+// Received SMTP commands are sent to returned channel
+func createPooledSessionWithQuit(recipients int) (*smtpPooledSession, chan string) {
+	clientConnection, serverConnection := net.Pipe()
+	commands := make(chan string, 1)
+	go func() {
+		textConnection := textproto.NewConn(serverConnection)
+		_ = textConnection.PrintfLine("220 ok")
+		line, _ := textConnection.ReadLine()
+		commands <- line
+		_ = textConnection.PrintfLine("221 bye")
+	}()
+	connection := newSmtpConnection(clientConnection)
+	client, _ := smtp.NewClient(connection, localhostIPv4Address)
+	session := &smtpPooledSession{connection: connection, client: client, recipients: recipients, responseTimeout: time.Second}
+
+	return session, commands
+}
+
+func TestSmtpPooledSessionClose(t *testing.T) {
+	t.Run("ends pooled SMTP session with QUIT", func(t *testing.T) {
+		session, commands := createPooledSessionWithQuit(1)
+		session.close()
+
+		assert.Equal(t, "QUIT", <-commands)
+		assert.True(t, session.connection.sessionTranscript().IsQuitAcknowledged())
+		_, err := session.connection.Write([]byte("NOOP\r\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("closes pooled SMTP session without QUIT when connection failed", func(t *testing.T) {
+		session, _ := createPooledSessionWithQuit(1)
+		session.connection.isFailed = true
+		session.close()
+
+		assert.Len(t, session.connection.sessionTranscript(), 1)
+		assert.False(t, session.connection.sessionTranscript().IsQuitAcknowledged())
+	})
+}
+
+func TestNewSmtpSessionPool(t *testing.T) {
+	t.Run("creates SMTP session pool", func(t *testing.T) {
+		pool := newSmtpSessionPool(30, 20, 2)
+
+		assert.Empty(t, pool.sessions)
+		assert.Equal(t, 30*time.Second, pool.maxIdleTime)
+		assert.Equal(t, 20, pool.maxRecipients)
+		assert.Equal(t, 2, pool.maxIdleSessions)
+		assert.NotNil(t, pool.now)
+	})
+}
+
+func TestSmtpSessionPoolCheckin(t *testing.T) {
+	key := randomIpAddress()
+
+	t.Run("puts SMTP session to SMTP session pool", func(t *testing.T) {
+		pool, session, now := newSmtpSessionPool(30, 20, 2), createPooledSession(1), time.Now()
+		pool.now = func() time.Time { return now }
+
+		assert.True(t, pool.checkin(key, session))
+		assert.Equal(t, []*smtpPooledSession{session}, pool.sessions[key])
+		assert.Equal(t, now, session.idleSince)
+	})
+
+	t.Run("when SMTP session reached max recipients", func(t *testing.T) {
+		pool := newSmtpSessionPool(30, 1, 2)
+
+		assert.False(t, pool.checkin(key, createPooledSession(1)))
+		assert.Empty(t, pool.sessions)
+	})
+
+	t.Run("when SMTP session pool key reached max idle sessions", func(t *testing.T) {
+		pool := newSmtpSessionPool(30, 20, 1)
+
+		assert.True(t, pool.checkin(key, createPooledSession(1)))
+		assert.False(t, pool.checkin(key, createPooledSession(1)))
+		assert.True(t, pool.checkin(randomIpAddress(), createPooledSession(1)))
+	})
+}
+
+func TestSmtpSessionPoolCheckout(t *testing.T) {
+	key := randomIpAddress()
+
+	t.Run("takes most recently used idle SMTP session", func(t *testing.T) {
+		pool, firstSession, secondSession := newSmtpSessionPool(30, 20, 2), createPooledSession(1), createPooledSession(2)
+		pool.checkin(key, firstSession)
+		pool.checkin(key, secondSession)
+
+		assert.Equal(t, secondSession, pool.checkout(key))
+		assert.Equal(t, firstSession, pool.checkout(key))
+		assert.Nil(t, pool.checkout(key))
+	})
+
+	t.Run("when idle SMTP session not exists", func(t *testing.T) {
+		assert.Nil(t, newSmtpSessionPool(30, 20, 2).checkout(key))
+	})
+
+	t.Run("removes and closes idle SMTP sessions which exceeded max idle time", func(t *testing.T) {
+		pool, expiredSession, now := newSmtpSessionPool(30, 20, 2), createPooledSession(1), time.Now()
+		pool.now = func() time.Time { return now }
+		pool.checkin(randomIpAddress(), expiredSession)
+		pool.now = func() time.Time { return now.Add(31 * time.Second) }
+
+		assert.Nil(t, pool.checkout(key))
+		assert.Empty(t, pool.sessions)
+		_, err := expiredSession.connection.Write([]byte("NOOP\r\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("ends idle SMTP sessions which exceeded max idle time with QUIT", func(t *testing.T) {
+		pool, now := newSmtpSessionPool(30, 20, 2), time.Now()
+		expiredSession, commands := createPooledSessionWithQuit(1)
+		pool.now = func() time.Time { return now }
+		pool.checkin(randomIpAddress(), expiredSession)
+		pool.now = func() time.Time { return now.Add(31 * time.Second) }
+
+		assert.Nil(t, pool.checkout(key))
+		assert.Equal(t, "QUIT", <-commands)
+	})
+}
+
+func TestSmtpSessionPoolClose(t *testing.T) {
+	t.Run("ends idle SMTP sessions with QUIT and does not keep SMTP sessions anymore", func(t *testing.T) {
+		pool, key := newSmtpSessionPool(30, 20, 2), randomIpAddress()
+		session, commands := createPooledSessionWithQuit(1)
+		pool.checkin(key, session)
+		pool.Close()
+
+		assert.Equal(t, "QUIT", <-commands)
+		assert.Empty(t, pool.sessions)
+		assert.False(t, pool.checkin(key, createPooledSession(1)))
+		assert.NotPanics(t, pool.Close)
+	})
+}
+
+func TestSmtpSessionPoolIsReusable(t *testing.T) {
+	pool := newSmtpSessionPool(30, 2, 2)
+
+	t.Run("when SMTP session did not reach max recipients", func(t *testing.T) {
+		assert.True(t, pool.isReusable(1))
+	})
+
+	t.Run("when SMTP session reached max recipients", func(t *testing.T) {
+		assert.False(t, pool.isReusable(2))
+	})
+}
@@ -0,0 +1,45 @@
+package truemail
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// SMTP source address structure. Includes local IP address which SMTP client binds for
+// connection with target server and optional HELO domain which matches its PTR record
+type SmtpSourceAddress struct {
+	IpAddress, HeloDomain string
+}
+
+// SMTP source addresses rotator structure. Tracks round-robin position,
+// shared across validations which use the same configuration
+type smtpSourceRotator struct {
+	position int
+	mutex    sync.Mutex
+}
+
+// smtpSourceRotator builder
+func newSmtpSourceRotator() *smtpSourceRotator {
+	return new(smtpSourceRotator)
+}
+
+// Returns source address position for target domain. The same target domain always
+// gets the same source address, so per domain throttling is applied to one source IP
+func smtpSourcePositionByDomain(domain string, sourceAddressesCount int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(domain))
+
+	return int(hash.Sum32() % uint32(sourceAddressesCount))
+}
+
+// smtpSourceRotator methods
+
+// Returns next round-robin position
+func (rotator *smtpSourceRotator) nextPosition() int {
+	rotator.mutex.Lock()
+	defer rotator.mutex.Unlock()
+
+	position := rotator.position
+	rotator.position++
+	return position
+}
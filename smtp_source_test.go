@@ -0,0 +1,52 @@
+package truemail
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSmtpSourceRotator(t *testing.T) {
+	t.Run("creates SMTP source addresses rotator with initial position", func(t *testing.T) {
+		assert.Equal(t, 0, newSmtpSourceRotator().position)
+	})
+}
+
+func TestSmtpSourcePositionByDomain(t *testing.T) {
+	t.Run("returns the same position for the same domain", func(t *testing.T) {
+		domain := randomDomain()
+		position := smtpSourcePositionByDomain(domain, 3)
+
+		assert.Equal(t, position, smtpSourcePositionByDomain(domain, 3))
+		assert.GreaterOrEqual(t, position, 0)
+		assert.Less(t, position, 3)
+	})
+
+	t.Run("when one source address", func(t *testing.T) {
+		assert.Equal(t, 0, smtpSourcePositionByDomain(randomDomain(), 1))
+	})
+}
+
+func TestSmtpSourceRotatorNextPosition(t *testing.T) {
+	t.Run("returns next round-robin position", func(t *testing.T) {
+		rotator := newSmtpSourceRotator()
+
+		assert.Equal(t, 0, rotator.nextPosition())
+		assert.Equal(t, 1, rotator.nextPosition())
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		rotator, waitGroup := newSmtpSourceRotator(), new(sync.WaitGroup)
+		for range 10 {
+			waitGroup.Add(1)
+			go func() {
+				defer waitGroup.Done()
+				rotator.nextPosition()
+			}()
+		}
+		waitGroup.Wait()
+
+		assert.Equal(t, 10, rotator.position)
+	})
+}
@@ -1,11 +1,16 @@
 package truemail
 
 import (
+	"context"
 	"errors"
+	"log/slog"
+	"net/textproto"
 	"testing"
+	"time"
 
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestValidationSmtpCheck(t *testing.T) {
@@ -28,10 +33,24 @@ func TestValidationSmtpCheck(t *testing.T) {
 
 		assert.True(t, validatorResult.Success)
 		assert.Empty(t, validatorResult.Errors)
-		assert.Empty(t, validatorResult.SmtpDebug)
+		assert.Equal(t, 1, len(validatorResult.SmtpDebug))
+		assert.True(t, validatorResult.SmtpDebug[0].Response.Rcptto)
+		assert.NotEmpty(t, validatorResult.SmtpDebug[0].Response.Transcript)
 		assert.Empty(t, validatorResult.usedValidations)
 	})
 
+	t.Run("SMTP validation: successful with DANE check, includes SMTP debug", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.DaneCheck = portNumber, true
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+
+		assert.True(t, validatorResult.Success)
+		assert.Equal(t, 1, len(validatorResult.SmtpDebug))
+		assert.True(t, validatorResult.SmtpDebug[0].Response.Rcptto)
+	})
+
 	// // TODO: add for successful case during second attempt, MailServers == 1; MailServers > 1;
 
 	t.Run("SMTP validation: failed after second attempt on second server, safe check scenario is disabled, fail fast scenario is disabled", func(t *testing.T) {
@@ -129,6 +148,164 @@ func TestValidationSmtpCheck(t *testing.T) {
 		assert.Equal(t, smtpDebug, validationSmtp.smtpResults)
 		assert.Empty(t, validatorResult.usedValidations)
 	})
+
+	t.Run("SMTP validation: not confirmed by SMTP provider which does not trust RCPT TO, safe check scenario is disabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort = portNumber
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		validatorResult.addMailServerHostName(localhostIPv4Address, "mta5.am0.yahoodns.net")
+		new(validationSmtp).check(validatorResult)
+
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{"smtp": "email existence is not confirmed by mail server"}, validatorResult.Errors)
+		assert.True(t, validatorResult.SmtpDebug[0].Response.Rcptto)
+		assert.False(t, validatorResult.SmtpDebug[0].Response.RcpttoTrusted)
+		assert.True(t, validatorResult.isUnknown())
+	})
+
+	t.Run("SMTP validation: not confirmed by catch-all mail server, safe check scenario is disabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort = portNumber
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		validatorResult.addMailServerHostName(localhostIPv4Address, "gmail-smtp-in.l.google.com")
+		new(validationSmtp).check(validatorResult)
+
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{"smtp": "email existence is not confirmed by mail server"}, validatorResult.Errors)
+		assert.True(t, validatorResult.SmtpDebug[0].Response.CatchAll)
+		assert.False(t, validatorResult.SmtpDebug[0].Response.RcpttoTrusted)
+	})
+
+	t.Run("SMTP validation: not confirmed by SMTP provider which does not trust RCPT TO, safe check scenario is enabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.SmtpSafeCheck = portNumber, true
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		validatorResult.addMailServerHostName(localhostIPv4Address, "mta5.am0.yahoodns.net")
+		new(validationSmtp).check(validatorResult)
+
+		assert.True(t, validatorResult.Success)
+		assert.Empty(t, validatorResult.Errors)
+		assert.False(t, validatorResult.SmtpDebug[0].Response.RcpttoTrusted)
+	})
+
+	t.Run("SMTP validation: rate limited recipient domain, shared across validations", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.SmtpRateLimitPerDomain = portNumber, SmtpRateLimit{Rate: 0.001}
+		email := randomEmail()
+		firstValidatorResult := createSuccessfulValidatorResult(email, configuration)
+		firstValidatorResult.MailServers = append(firstValidatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(firstValidatorResult)
+		validatorResult := createSuccessfulValidatorResult(email, configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+
+		assert.True(t, firstValidatorResult.Success)
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{"smtp": "rate limited, retry later"}, validatorResult.Errors)
+		assert.Empty(t, validatorResult.SmtpDebug)
+	})
+
+	t.Run("SMTP validation: rate limited MX host, safe check scenario is enabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.SmtpSafeCheck = portNumber, true
+		configuration.SmtpRateLimitPerHost = SmtpRateLimit{Rate: 0.001}
+		firstValidatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		firstValidatorResult.MailServers = append(firstValidatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(firstValidatorResult)
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+		smtpDebug := validatorResult.SmtpDebug
+
+		assert.True(t, firstValidatorResult.Success)
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{"smtp": "rate limited, retry later"}, validatorResult.Errors)
+		assert.Equal(t, 1, len(smtpDebug))
+		assert.Equal(t, []*SmtpClientError{newSmtpRateLimitError()}, smtpDebug[0].Response.Errors)
+		assert.Empty(t, smtpDebug[0].Response.Transcript)
+	})
+}
+
+func TestValidationSmtpCheckWithGreylisting(t *testing.T) {
+	greylistedEmail := randomEmail()
+	server := startSmtpMock(
+		smtpmock.ConfigurationAttr{
+			NotRegisteredEmails:         []string{greylistedEmail},
+			MsgRcpttoNotRegisteredEmail: "450 4.2.0 Greylisted, please try again later",
+		},
+	)
+	portNumber := server.PortNumber()
+	defer func() { _ = server.Stop() }()
+
+	t.Run("SMTP validation: greylisted, safe check scenario is disabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort = portNumber
+		validatorResult := createSuccessfulValidatorResult(greylistedEmail, configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+		smtpErrors := validatorResult.SmtpDebug[0].Response.Errors
+
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{"smtp": "greylisted, retry later"}, validatorResult.Errors)
+		assert.True(t, smtpErrors[len(smtpErrors)-1].isGreylisted)
+		assert.Nil(t, validatorResult.GreylistingRetry)
+	})
+
+	t.Run("SMTP validation: greylisted, safe check scenario is enabled", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.SmtpSafeCheck = portNumber, true
+		configuration.SmtpGreylistingRetryDelay = 1
+		validatorResult := createSuccessfulValidatorResult(greylistedEmail, configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+		smtpErrors := validatorResult.SmtpDebug[0].Response.Errors
+
+		assert.True(t, validatorResult.Success)
+		assert.Empty(t, validatorResult.Errors)
+		assert.True(t, smtpErrors[len(smtpErrors)-1].isGreylisted)
+		assert.Nil(t, validatorResult.GreylistingRetry)
+	})
+
+	t.Run("SMTP validation: greylisted, delivers final result of scheduled retry", func(t *testing.T) {
+		callbackResults := make(chan *ValidatorResult, 1)
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.ConnectionAttempts = portNumber, 1
+		configuration.SmtpGreylistingRetryDelay = 1
+		configuration.SmtpGreylistingCallback = func(validatorResult *ValidatorResult) { callbackResults <- validatorResult }
+		validatorResult := createSuccessfulValidatorResult(greylistedEmail, configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+
+		assert.True(t, validatorResult.isGreylisted())
+		assert.NotNil(t, validatorResult.GreylistingRetry)
+		finalValidatorResult := <-validatorResult.GreylistingRetry
+		assert.NotSame(t, validatorResult, finalValidatorResult)
+		assert.True(t, finalValidatorResult.isGreylisted())
+		assert.Equal(t, 1, len(finalValidatorResult.SmtpDebug))
+		assert.Nil(t, finalValidatorResult.GreylistingRetry)
+		assert.Same(t, finalValidatorResult, <-callbackResults)
+	})
+
+	t.Run("SMTP validation: greylisted, scheduled retry uses own copy of configuration with not cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		configuration := createConfiguration()
+		configuration.SmtpPort, configuration.ConnectionAttempts = portNumber, 1
+		configuration.SmtpGreylistingRetryDelay, configuration.ctx = 1, ctx
+		validatorResult := createSuccessfulValidatorResult(greylistedEmail, configuration)
+		validatorResult.MailServers = append(validatorResult.MailServers, localhostIPv4Address)
+		new(validationSmtp).check(validatorResult)
+		cancel()
+		validatorResult.Configuration.SmtpPort = 1
+
+		finalValidatorResult := <-validatorResult.GreylistingRetry
+		assert.NotSame(t, validatorResult.Configuration, finalValidatorResult.Configuration)
+		assert.NoError(t, finalValidatorResult.Configuration.ctx.Err())
+		assert.Equal(t, portNumber, finalValidatorResult.Configuration.SmtpPort)
+		assert.True(t, finalValidatorResult.isGreylisted())
+	})
 }
 
 func TestValidationSmtpInitSmtpBuilder(t *testing.T) {
@@ -163,6 +340,7 @@ func TestValidationSmtpRun(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
 		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		validation.run()
 
 		assert.True(t, validation.isIncludesSuccessfulSmtpResponse())
@@ -202,16 +380,19 @@ func TestValidationSmtpRun(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, firstTargetHostAddress, configuration).Once().Return(firstSmtpReq)
 		builder.On("newSmtpClient", firstSmtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Once().Return(sessionError)
 
 		builder.On("newSmtpRequest", attempts, targetEmail, secondTargetHostAddress, configuration).Once().Return(secondSmtpReq)
 		builder.On("newSmtpClient", secondSmtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Once().Return(sessionError)
 
 		builder.On("newSmtpRequest", attempts, targetEmail, thirdTargetHostAddress, configuration).Once().Return(thirdSmtpReq)
 		builder.On("newSmtpClient", thirdSmtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		validation.run()
 
 		assert.Equal(t, []*SmtpRequest{firstSmtpReq, secondSmtpReq, thirdSmtpReq}, validation.smtpResults)
@@ -245,11 +426,13 @@ func TestValidationSmtpRun(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, firstTargetHostAddress, configuration).Once().Return(firstSmtpReq)
 		builder.On("newSmtpClient", firstSmtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Once().Return(sessionError)
 
 		builder.On("newSmtpRequest", attempts, targetEmail, secondTargetHostAddress, configuration).Once().Return(secondSmtpReq)
 		builder.On("newSmtpClient", secondSmtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Once().Return(sessionError)
 		validation.run()
 
@@ -279,10 +462,12 @@ func TestValidationSmtpRunSmtpSession(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
 		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 
 		assert.True(t, validation.runSmtpSession(targetHostAddress))
 		assert.Equal(t, attempts-1, smtpReq.Attempts)
 		assert.True(t, smtpResponse.Rcptto)
+		assert.True(t, smtpResponse.RcpttoTrusted)
 		assert.Empty(t, smtpResponse.Errors)
 		assert.Equal(t, []*SmtpRequest{smtpReq}, validation.smtpResults)
 	})
@@ -303,8 +488,10 @@ func TestValidationSmtpRunSmtpSession(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Twice().Return(smtpReq)
 		builder.On("newSmtpClient", smtpReq.Configuration).Twice().Return(smtpClient)
 		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Once().Return(sessionError)
 		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 
 		assert.True(t, validation.runSmtpSession(targetHostAddress))
 		assert.Equal(t, attempts-2, smtpReq.Attempts)
@@ -329,6 +516,7 @@ func TestValidationSmtpRunSmtpSession(t *testing.T) {
 		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Twice().Return(smtpReq)
 		builder.On("newSmtpClient", smtpReq.Configuration).Twice().Return(smtpClient)
 		smtpClient.On("runSession").Twice().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
 		smtpClient.On("sessionError").Twice().Return(sessionError)
 
 		assert.False(t, validation.runSmtpSession(targetHostAddress))
@@ -339,6 +527,309 @@ func TestValidationSmtpRunSmtpSession(t *testing.T) {
 	})
 }
 
+func TestValidationSmtpLogSessionRetry(t *testing.T) {
+	targetEmail, targetHostAddress := randomEmail(), randomIpAddress()
+
+	t.Run("logs retry decision when SMTP request attempts are available", func(t *testing.T) {
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.Logger = logger
+		validation := &validationSmtp{result: createValidatorResult(targetEmail, configuration)}
+		smtpReq := &SmtpRequest{
+			Attempts:      1,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+		}
+		validation.logSessionRetry(smtpReq, &SmtpClientError{isConnection: true, err: errors.New("connection refused")})
+		records := logRecords(buffer, logMessageSmtpSessionRetry)
+
+		assert.Len(t, records, 1)
+		assert.Equal(t, targetHostAddress, records[0][logAttrServerAddress])
+		assert.Equal(t, float64(1), records[0][logAttrAttemptsLeft])
+		assert.Equal(t, "connection refused", records[0][logAttrError])
+	})
+
+	t.Run("does not log retry decision when SMTP request attempts are exhausted", func(t *testing.T) {
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.Logger = logger
+		validation := &validationSmtp{result: createValidatorResult(targetEmail, configuration)}
+		smtpReq := &SmtpRequest{Host: targetHostAddress, Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress)}
+		validation.logSessionRetry(smtpReq, new(SmtpClientError))
+
+		assert.Empty(t, logRecords(buffer, logMessageSmtpSessionRetry))
+	})
+}
+
+func TestValidationSmtpRunWithRateLimit(t *testing.T) {
+	t.Run("when recipient domain is rate limited, does not run SMTP sessions", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpRateLimitPerDomain = SmtpRateLimit{Rate: 0.001}
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		validatorResult.MailServers = []string{randomIpAddress()}
+		configuration.takeSmtpDomainToken(validatorResult.Domain)
+		builder := new(smtpBuilderMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		validation.run()
+
+		assert.True(t, validation.isRateLimited.Load())
+		assert.Empty(t, validation.smtpResults)
+		builder.AssertNotCalled(t, "newSmtpRequest")
+	})
+
+	t.Run("logs rate limited recipient domain", func(t *testing.T) {
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.SmtpRateLimitPerDomain, configuration.Logger = SmtpRateLimit{Rate: 0.001}, logger
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		configuration.takeSmtpDomainToken(validatorResult.Domain)
+		(&validationSmtp{result: validatorResult, builder: new(smtpBuilderMock)}).run()
+		records := logRecords(buffer, logMessageSmtpRateLimited)
+
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.Equal(t, validatorResult.Domain, records[0][logAttrDomain])
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithRateLimit(t *testing.T) {
+	t.Run("when target host is rate limited, records rate limit error without SMTP session", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		configuration.SmtpRateLimitPerHost = SmtpRateLimit{Rate: 0.001}
+		configuration.takeSmtpHostToken(targetHostAddress, emptyString)
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		builder := new(smtpBuilderMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		attempts, smtpResponse := validation.attempts(), new(SmtpResponse)
+		smtpReq := &SmtpRequest{
+			Attempts:      attempts,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      smtpResponse,
+		}
+		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+
+		assert.False(t, validation.runSmtpSession(targetHostAddress))
+		assert.True(t, validation.isRateLimited.Load())
+		assert.Equal(t, []*SmtpClientError{newSmtpRateLimitError()}, smtpResponse.Errors)
+		builder.AssertNotCalled(t, "newSmtpClient", smtpReq.Configuration)
+	})
+
+	t.Run("when validation context is done while waiting for token, records rate limit error", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		configuration.SmtpRateLimitPerHost, configuration.SmtpRateLimitMaxWait = SmtpRateLimit{Rate: 0.01}, 600
+		configuration.takeSmtpHostToken(targetHostAddress, emptyString)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		configuration.ctx = ctx
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		builder := new(smtpBuilderMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		attempts, smtpResponse := validation.attempts(), new(SmtpResponse)
+		smtpReq := &SmtpRequest{
+			Attempts:      attempts,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      smtpResponse,
+		}
+		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		startedAt := time.Now()
+
+		assert.False(t, validation.runSmtpSession(targetHostAddress))
+		assert.Less(t, time.Since(startedAt), time.Second)
+		assert.Equal(t, []*SmtpClientError{newSmtpRateLimitError()}, smtpResponse.Errors)
+		builder.AssertNotCalled(t, "newSmtpClient", smtpReq.Configuration)
+	})
+}
+
+func TestValidationSmtpIsRateLimitedOutcome(t *testing.T) {
+	configuration := createConfiguration()
+	failedSmtpRequest, successfulSmtpRequest := &SmtpRequest{Response: new(SmtpResponse)}, &SmtpRequest{Response: &SmtpResponse{Rcptto: true}}
+	userNotFoundSmtpRequest := &SmtpRequest{
+		Response: &SmtpResponse{Errors: []*SmtpClientError{{isRecptTo: true, err: errors.New("550 user not found")}}},
+	}
+
+	t.Run("when SMTP validation was not rate limited", func(t *testing.T) {
+		validation := &validationSmtp{result: &ValidatorResult{Configuration: configuration}}
+
+		assert.False(t, validation.isRateLimitedOutcome())
+	})
+
+	t.Run("when rate limited without SMTP results", func(t *testing.T) {
+		validation := &validationSmtp{result: &ValidatorResult{Configuration: configuration}}
+		validation.isRateLimited.Store(true)
+
+		assert.True(t, validation.isRateLimitedOutcome())
+	})
+
+	t.Run("when rate limited without successful SMTP response", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{failedSmtpRequest},
+		}
+		validation.isRateLimited.Store(true)
+
+		assert.True(t, validation.isRateLimitedOutcome())
+	})
+
+	t.Run("when rate limited with successful SMTP response", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{failedSmtpRequest, successfulSmtpRequest},
+		}
+		validation.isRateLimited.Store(true)
+
+		assert.False(t, validation.isRateLimitedOutcome())
+	})
+
+	t.Run("when rate limited with UserNotFound error", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{failedSmtpRequest, userNotFoundSmtpRequest},
+		}
+		validation.isRateLimited.Store(true)
+
+		assert.False(t, validation.isRateLimitedOutcome())
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithGreylisting(t *testing.T) {
+	t.Run("marks greylisting session error", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		sessionError := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 451, Msg: "4.7.1 try again later"}}
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		smtpReq := &SmtpRequest{
+			Attempts:      1,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+		builder.On("newSmtpRequest", validation.attempts(), targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		smtpClient.On("sessionError").Once().Return(sessionError)
+
+		assert.False(t, validation.runSmtpSession(targetHostAddress))
+		assert.True(t, smtpReq.Response.Errors[0].isGreylisted)
+	})
+}
+
+func TestValidationSmtpIsGreylistingError(t *testing.T) {
+	validation := &validationSmtp{result: &ValidatorResult{Configuration: createConfiguration()}}
+
+	t.Run("when RCPT TO error with 450 or 451 reply code", func(t *testing.T) {
+		for _, replyCode := range []int{450, 451} {
+			err := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: replyCode, Msg: "4.2.0 mailbox busy"}}
+
+			assert.True(t, validation.isGreylistingError(err))
+		}
+	})
+
+	t.Run("when RCPT TO error with other 4xx reply code matches greylisting pattern", func(t *testing.T) {
+		err := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 421, Msg: "4.7.0 greylisted, please try again later"}}
+
+		assert.True(t, validation.isGreylistingError(err))
+	})
+
+	t.Run("when RCPT TO error with other 4xx reply code does not match greylisting pattern", func(t *testing.T) {
+		err := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 452, Msg: "4.2.2 mailbox full"}}
+
+		assert.False(t, validation.isGreylistingError(err))
+	})
+
+	t.Run("when RCPT TO error with 5xx reply code", func(t *testing.T) {
+		err := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 550, Msg: "5.7.1 greylisted forever"}}
+
+		assert.False(t, validation.isGreylistingError(err))
+	})
+
+	t.Run("when not RCPT TO error", func(t *testing.T) {
+		err := &SmtpClientError{isMailFrom: true, err: &textproto.Error{Code: 451, Msg: "4.7.1 try again later"}}
+
+		assert.False(t, validation.isGreylistingError(err))
+	})
+}
+
+func TestValidationSmtpIsGreylistedOutcome(t *testing.T) {
+	configuration := createConfiguration()
+	greylistedSmtpRequest := &SmtpRequest{Response: &SmtpResponse{Errors: []*SmtpClientError{{isRecptTo: true, isGreylisted: true, err: errors.New("450 greylisted")}}}}
+	failedSmtpRequest, successfulSmtpRequest := &SmtpRequest{Response: new(SmtpResponse)}, &SmtpRequest{Response: &SmtpResponse{Rcptto: true}}
+	userNotFoundSmtpRequest := &SmtpRequest{
+		Response: &SmtpResponse{Errors: []*SmtpClientError{{isRecptTo: true, err: errors.New("550 user not found")}}},
+	}
+
+	t.Run("when SMTP results contain greylisting errors", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{failedSmtpRequest, greylistedSmtpRequest},
+		}
+
+		assert.True(t, validation.isGreylistedOutcome())
+	})
+
+	t.Run("when SMTP results do not contain greylisting errors", func(t *testing.T) {
+		validation := &validationSmtp{result: &ValidatorResult{Configuration: configuration}, smtpResults: []*SmtpRequest{failedSmtpRequest}}
+
+		assert.False(t, validation.isGreylistedOutcome())
+	})
+
+	t.Run("when SMTP results contain successful SMTP response", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{greylistedSmtpRequest, successfulSmtpRequest},
+		}
+
+		assert.False(t, validation.isGreylistedOutcome())
+	})
+
+	t.Run("when SMTP results contain UserNotFound errors", func(t *testing.T) {
+		validation := &validationSmtp{
+			result:      &ValidatorResult{Configuration: configuration},
+			smtpResults: []*SmtpRequest{greylistedSmtpRequest, userNotFoundSmtpRequest},
+		}
+
+		assert.False(t, validation.isGreylistedOutcome())
+	})
+}
+
+func TestValidationSmtpScheduleGreylistingRetry(t *testing.T) {
+	t.Run("when SMTP greylisting retry delay is not specified", func(t *testing.T) {
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), createConfiguration())
+		(&validationSmtp{result: validatorResult}).scheduleGreylistingRetry()
+
+		assert.Nil(t, validatorResult.GreylistingRetry)
+	})
+
+	t.Run("when validator result is SMTP validation retry", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpGreylistingRetryDelay = 1
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration).smtpRetryCopy()
+		(&validationSmtp{result: validatorResult}).scheduleGreylistingRetry()
+
+		assert.Nil(t, validatorResult.GreylistingRetry)
+	})
+
+	t.Run("schedules SMTP validation retry", func(t *testing.T) {
+		configuration := createConfiguration()
+		configuration.SmtpGreylistingRetryDelay = 60
+		validatorResult := createSuccessfulValidatorResult(randomEmail(), configuration)
+		(&validationSmtp{result: validatorResult}).scheduleGreylistingRetry()
+
+		assert.NotNil(t, validatorResult.GreylistingRetry)
+		select {
+		case <-validatorResult.GreylistingRetry:
+			assert.Fail(t, "SMTP validation retry should wait for retry delay")
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
+
 func TestValidationSmtpIsFailFastScenario(t *testing.T) {
 	t.Run("when SMTP fail fast scenario is enabled", func(t *testing.T) {
 		configuration := createConfiguration()
@@ -546,4 +1037,491 @@ func TestValidationSmtpIsNotIncludeUserNotFoundErrors(t *testing.T) {
 
 		assert.False(t, validation.isNotIncludeUserNotFoundErrors())
 	})
+
+	t.Run("when contains classified RCPT TO errors", func(t *testing.T) {
+		createValidation := func(errorMessage string) *validationSmtp {
+			return &validationSmtp{
+				result: createValidatorResult(randomEmail(), createConfiguration()),
+				smtpResults: []*SmtpRequest{
+					{
+						Response: &SmtpResponse{
+							Errors: []*SmtpClientError{{isRecptTo: true, err: &textproto.Error{Code: 550, Msg: errorMessage}}},
+						},
+					},
+				},
+			}
+		}
+
+		assert.False(t, createValidation("5.1.1 Recipient address rejected: user unknown").isNotIncludeUserNotFoundErrors())
+		assert.True(t, createValidation("5.7.1 Access denied by policy").isNotIncludeUserNotFoundErrors())
+		assert.True(t, createValidation("5.7.1 Client host blocked using Spamhaus").isNotIncludeUserNotFoundErrors())
+		assert.False(t, createValidation("5.7.1 Recipient rejected: user unknown").isNotIncludeUserNotFoundErrors())
+	})
+
+	t.Run("when contains RCPT TO errors matched by SMTP provider profile", func(t *testing.T) {
+		createValidation := func(errorMessage string) *validationSmtp {
+			return &validationSmtp{
+				result: createValidatorResult(randomEmail(), createConfiguration()),
+				smtpResults: []*SmtpRequest{
+					{
+						Provider: &defaultSmtpProviderProfiles()[2],
+						Response: &SmtpResponse{
+							Errors: []*SmtpClientError{{isRecptTo: true, err: &textproto.Error{Code: 550, Msg: errorMessage}}},
+						},
+					},
+				},
+			}
+		}
+
+		assert.False(t, createValidation("5.4.1 Recipient address rejected: Access denied").isNotIncludeUserNotFoundErrors())
+		assert.True(t, createValidation("5.7.1 Service unavailable, client host blocked").isNotIncludeUserNotFoundErrors())
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithProviderProfile(t *testing.T) {
+	t.Run("assigns SMTP provider profile and untrusted RCPT TO to SMTP response", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		validatorResult.addMailServerHostName(targetHostAddress, "mta5.am0.yahoodns.net")
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		smtpReq := &SmtpRequest{
+			Attempts:      1,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+		builder.On("newSmtpRequest", validation.attempts(), targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, smtpProviderYahoo, smtpReq.Provider.Name)
+		assert.False(t, smtpReq.Configuration.CatchAllProbing)
+		assert.False(t, smtpReq.Configuration.DataProbing)
+		assert.True(t, smtpReq.Response.Rcptto)
+		assert.False(t, smtpReq.Response.RcpttoTrusted)
+	})
+
+	t.Run("assigns catch-all probing and catch-all outcome to SMTP response", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		validatorResult.addMailServerHostName(targetHostAddress, "gmail-smtp-in.l.google.com")
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		smtpReq := &SmtpRequest{
+			Attempts:      1,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+		builder.On("newSmtpRequest", validation.attempts(), targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionCatchAll").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, smtpProviderGmail, smtpReq.Provider.Name)
+		assert.True(t, smtpReq.Configuration.CatchAllProbing)
+		assert.True(t, smtpReq.Response.Rcptto)
+		assert.True(t, smtpReq.Response.CatchAll)
+		assert.False(t, smtpReq.Response.RcpttoTrusted)
+		smtpClient.AssertExpectations(t)
+	})
+
+	t.Run("assigns DATA probing of SMTP provider profile to SMTP request configuration", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		validatorResult.addMailServerHostName(targetHostAddress, "example-com.mail.protection.outlook.com")
+		validation := &validationSmtp{result: validatorResult, builder: new(smtpBuilder)}
+		smtpReq := validation.prepareSmtpRequest(targetHostAddress)
+
+		assert.Equal(t, smtpProviderMicrosoft, smtpReq.Provider.Name)
+		assert.True(t, smtpReq.Configuration.DataProbing)
+		assert.False(t, smtpReq.Configuration.CatchAllProbing)
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithMtaStsPolicy(t *testing.T) {
+	t.Run("assigns MX host name and STARTTLS requirement to SMTP request configuration", func(t *testing.T) {
+		targetEmail, targetHostAddress, targetHostName, configuration := randomEmail(), randomIpAddress(), randomDomain(), createConfiguration()
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		validatorResult.MtaSts = &MtaStsResult{Mode: mtaStsModeEnforce}
+		validatorResult.addMailServerHostName(targetHostAddress, targetHostName)
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		attempts := validation.attempts()
+		smtpReq := &SmtpRequest{
+			Attempts:      attempts,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+
+		builder.On("newSmtpRequest", attempts, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		smtpClient.On("sessionTls").Once().Return((*TlsDetails)(nil))
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, targetHostName, smtpReq.Configuration.TargetServerName)
+		assert.Equal(t, smtpTlsPolicyRequired, smtpReq.Configuration.TlsPolicy)
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithDane(t *testing.T) {
+	targetEmail, targetHostAddress, targetHostName := randomEmail(), randomIpAddress(), randomDomain()
+	tlsaRecords := []*TlsaRecord{{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256}}
+	createValidation := func(daneResult *DaneResult, builder builder) *validationSmtp {
+		validatorResult := createValidatorResult(targetEmail, createConfiguration())
+		validatorResult.addMailServerHostName(targetHostAddress, targetHostName)
+		validatorResult.addDaneResult(daneResult)
+
+		return &validationSmtp{result: validatorResult, builder: builder}
+	}
+	createSmtpRequest := func(validation *validationSmtp) *SmtpRequest {
+		return &SmtpRequest{
+			Attempts:      1,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(validation.result.Configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+	}
+
+	t.Run("when authenticated TLSA records, requires STARTTLS and records verified session", func(t *testing.T) {
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := createValidation(&DaneResult{HostName: targetHostName, TlsaRecords: tlsaRecords, Authenticated: true}, builder)
+		validation.result.Configuration.ConnectionAttempts = 1
+		smtpReq := createSmtpRequest(validation)
+
+		builder.On("newSmtpRequest", 1, targetEmail, targetHostAddress, validation.result.Configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		smtpClient.On("sessionTls").Once().Return((*TlsDetails)(nil))
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, smtpTlsPolicyRequired, smtpReq.Configuration.TlsPolicy)
+		assert.Equal(t, tlsaRecords, smtpReq.Configuration.TlsaRecords)
+		assert.True(t, smtpReq.Dane.Verified)
+	})
+
+	t.Run("when certificate verification failed, records DANE error", func(t *testing.T) {
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		sessionError := &SmtpClientError{isCertificate: true, isDane: true, err: &daneVerificationError{hostName: targetHostName}}
+		validation := createValidation(&DaneResult{HostName: targetHostName, TlsaRecords: tlsaRecords, Authenticated: true}, builder)
+		validation.result.Configuration.ConnectionAttempts = 1
+		smtpReq := createSmtpRequest(validation)
+
+		builder.On("newSmtpRequest", 1, targetEmail, targetHostAddress, validation.result.Configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		smtpClient.On("sessionError").Once().Return(sessionError)
+		smtpClient.On("sessionTls").Once().Return((*TlsDetails)(nil))
+
+		assert.False(t, validation.runSmtpSession(targetHostAddress))
+		assert.False(t, smtpReq.Dane.Verified)
+		assert.Equal(t, sessionError, smtpReq.Dane.Err)
+	})
+
+	t.Run("when not authenticated TLSA records, does not use them", func(t *testing.T) {
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := createValidation(&DaneResult{HostName: targetHostName, TlsaRecords: tlsaRecords}, builder)
+		validation.result.Configuration.ConnectionAttempts = 1
+		smtpReq := createSmtpRequest(validation)
+
+		builder.On("newSmtpRequest", 1, targetEmail, targetHostAddress, validation.result.Configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Once().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, smtpTlsPolicyNone, smtpReq.Configuration.TlsPolicy)
+		assert.Empty(t, smtpReq.Configuration.TlsaRecords)
+		assert.False(t, smtpReq.Dane.Verified)
+		assert.Equal(t, targetHostName, smtpReq.Dane.HostName)
+	})
+}
+
+func TestValidationSmtpAssignSessionTls(t *testing.T) {
+	tlsDetails := &TlsDetails{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256"}
+
+	for _, tlsPolicy := range []string{smtpTlsPolicyOpportunistic, smtpTlsPolicyRequired} {
+		t.Run("assigns TLS details of SMTP session, "+tlsPolicy+" TLS policy", func(t *testing.T) {
+			smtpClient := new(smtpClientMock)
+			smtpReq := &SmtpRequest{Configuration: &SmtpRequestConfiguration{TlsPolicy: tlsPolicy}, Response: new(SmtpResponse)}
+			smtpClient.On("sessionTls").Once().Return(tlsDetails)
+			new(validationSmtp).assignSessionTls(smtpReq, smtpClient)
+
+			assert.Equal(t, tlsDetails, smtpReq.Response.Tls)
+			smtpClient.AssertExpectations(t)
+		})
+	}
+
+	t.Run("does not assign TLS details, none TLS policy", func(t *testing.T) {
+		smtpClient := new(smtpClientMock)
+		smtpReq := &SmtpRequest{Configuration: &SmtpRequestConfiguration{TlsPolicy: smtpTlsPolicyNone}, Response: new(SmtpResponse)}
+		new(validationSmtp).assignSessionTls(smtpReq, smtpClient)
+
+		assert.Nil(t, smtpReq.Response.Tls)
+		smtpClient.AssertNotCalled(t, "sessionTls")
+	})
+}
+
+func TestValidationSmtpAssignSourceAddress(t *testing.T) {
+	createValidation := func(sourceAddresses ...SmtpSourceAddress) *validationSmtp {
+		configuration := createConfiguration()
+		configuration.SmtpSourceAddresses = sourceAddresses
+		return &validationSmtp{result: &ValidatorResult{Domain: randomDomain(), Configuration: configuration}}
+	}
+
+	t.Run("when SMTP source addresses not specified", func(t *testing.T) {
+		verifierDomain := randomDomain()
+		smtpReq := &SmtpRequest{Configuration: &SmtpRequestConfiguration{VerifierDomain: verifierDomain}}
+		createValidation().assignSourceAddress(smtpReq)
+
+		assert.Nil(t, smtpReq.SourceAddress)
+		assert.Empty(t, smtpReq.Configuration.SourceIpAddress)
+		assert.Equal(t, verifierDomain, smtpReq.Configuration.VerifierDomain)
+	})
+
+	t.Run("assigns SMTP source IP address, keeps verifier domain", func(t *testing.T) {
+		sourceAddress, verifierDomain := SmtpSourceAddress{IpAddress: randomIpAddress()}, randomDomain()
+		smtpReq := &SmtpRequest{Configuration: &SmtpRequestConfiguration{VerifierDomain: verifierDomain}}
+		createValidation(sourceAddress).assignSourceAddress(smtpReq)
+
+		assert.Equal(t, &sourceAddress, smtpReq.SourceAddress)
+		assert.Equal(t, sourceAddress.IpAddress, smtpReq.Configuration.SourceIpAddress)
+		assert.Equal(t, verifierDomain, smtpReq.Configuration.VerifierDomain)
+	})
+
+	t.Run("assigns SMTP source IP address and HELO domain", func(t *testing.T) {
+		sourceAddress := SmtpSourceAddress{IpAddress: randomIpAddress(), HeloDomain: randomDomain()}
+		smtpReq := &SmtpRequest{Configuration: &SmtpRequestConfiguration{VerifierDomain: randomDomain()}}
+		createValidation(sourceAddress).assignSourceAddress(smtpReq)
+
+		assert.Equal(t, sourceAddress.IpAddress, smtpReq.Configuration.SourceIpAddress)
+		assert.Equal(t, sourceAddress.HeloDomain, smtpReq.Configuration.VerifierDomain)
+	})
+}
+
+func TestValidationSmtpRunSmtpSessionWithTranscript(t *testing.T) {
+	t.Run("appends SMTP transcript of each attempt to SMTP response", func(t *testing.T) {
+		targetEmail, targetHostAddress, configuration := randomEmail(), randomIpAddress(), createConfiguration()
+		configuration.ConnectionAttempts = 2
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		builder, smtpClient := new(smtpBuilderMock), new(smtpClientMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		smtpReq := &SmtpRequest{
+			Attempts:      2,
+			Email:         targetEmail,
+			Host:          targetHostAddress,
+			Configuration: newSmtpRequestConfiguration(configuration, targetEmail, targetHostAddress),
+			Response:      new(SmtpResponse),
+		}
+		firstTranscript := SmtpTranscript{{Reply: []string{"421 try later"}, Code: 421}}
+		secondTranscript := SmtpTranscript{{Reply: []string{"220 ready"}, Code: 220}, {Command: "QUIT", Code: 221}}
+
+		builder.On("newSmtpRequest", 2, targetEmail, targetHostAddress, configuration).Once().Return(smtpReq)
+		builder.On("newSmtpClient", smtpReq.Configuration).Twice().Return(smtpClient)
+		smtpClient.On("runSession").Once().Return(false)
+		smtpClient.On("sessionTranscript").Once().Return(firstTranscript)
+		smtpClient.On("sessionError").Once().Return(&SmtpClientError{isSmtpServiceReady: true})
+		smtpClient.On("runSession").Once().Return(true)
+		smtpClient.On("sessionTranscript").Once().Return(secondTranscript)
+
+		assert.True(t, validation.runSmtpSession(targetHostAddress))
+		assert.Equal(t, append(firstTranscript, secondTranscript...), smtpReq.Response.Transcript)
+	})
+}
+
+func TestValidationSmtpRunParallelProbing(t *testing.T) {
+	createParallelProbing := func(mailServers []string, priorities []uint16) (*validationSmtp, *smtpBuilderMock, []*SmtpRequest) {
+		targetEmail, configuration := randomEmail(), createConfiguration()
+		configuration.SmtpParallelProbing = true
+		validatorResult := createValidatorResult(targetEmail, configuration)
+		validatorResult.MailServers = mailServers
+		builder := new(smtpBuilderMock)
+		validation := &validationSmtp{result: validatorResult, builder: builder}
+		smtpRequests := []*SmtpRequest{}
+		for index, mailServer := range mailServers {
+			validatorResult.addMailServerPriority(mailServer, priorities[index])
+			smtpReq := &SmtpRequest{
+				Attempts:      validation.attempts(),
+				Email:         targetEmail,
+				Host:          mailServer,
+				Configuration: newSmtpRequestConfiguration(configuration, targetEmail, mailServer),
+				Response:      new(SmtpResponse),
+			}
+			builder.On("newSmtpRequest", validation.attempts(), targetEmail, mailServer, configuration).Once().Return(smtpReq)
+			smtpRequests = append(smtpRequests, smtpReq)
+		}
+
+		return validation, builder, smtpRequests
+	}
+
+	t.Run("cancels running SMTP sessions once definitive answer is received", func(t *testing.T) {
+		validation, builder, smtpRequests := createParallelProbing([]string{randomIpAddress(), randomIpAddress()}, []uint16{10, 10})
+		successfulClient, cancelledClient := new(smtpClientMock), new(smtpClientMock)
+		isSessionStarted, isSessionCancelled := make(chan struct{}), make(chan struct{})
+		builder.On("newSmtpClient", smtpRequests[0].Configuration).Once().Return(successfulClient)
+		builder.On("newSmtpClient", smtpRequests[1].Configuration).Once().Return(cancelledClient)
+		successfulClient.On("runSession").Once().Run(func(mock.Arguments) { <-isSessionStarted }).Return(true)
+		successfulClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		cancelledClient.On("runSession").Once().Run(func(mock.Arguments) {
+			close(isSessionStarted)
+			<-isSessionCancelled
+		}).Return(false)
+		cancelledClient.On("cancelSession").Once().Run(func(mock.Arguments) { close(isSessionCancelled) })
+		cancelledClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		validation.run()
+
+		cancelledClient.AssertExpectations(t)
+		assert.Equal(t, smtpRequests, validation.smtpResults)
+		assert.True(t, smtpRequests[0].Response.Rcptto)
+		assert.Equal(t, []*SmtpClientError{newSmtpCancelledError()}, smtpRequests[1].Response.Errors)
+	})
+
+	t.Run("probes next priority group when definitive answer is not received", func(t *testing.T) {
+		validation, builder, smtpRequests := createParallelProbing([]string{randomIpAddress(), randomIpAddress()}, []uint16{10, 20})
+		failedClient, successfulClient := new(smtpClientMock), new(smtpClientMock)
+		connectionError := &SmtpClientError{isConnection: true, err: errors.New("connection refused")}
+		builder.On("newSmtpClient", smtpRequests[0].Configuration).Once().Return(failedClient)
+		builder.On("newSmtpClient", smtpRequests[1].Configuration).Once().Return(successfulClient)
+		failedClient.On("runSession").Once().Return(false)
+		failedClient.On("sessionError").Once().Return(connectionError)
+		failedClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		successfulClient.On("runSession").Once().Return(true)
+		successfulClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		validation.run()
+
+		builder.AssertExpectations(t)
+		assert.Equal(t, smtpRequests, validation.smtpResults)
+		assert.Equal(t, []*SmtpClientError{connectionError}, smtpRequests[0].Response.Errors)
+		assert.True(t, smtpRequests[1].Response.Rcptto)
+	})
+
+	t.Run("does not probe next priority group when definitive answer is received", func(t *testing.T) {
+		validation, builder, smtpRequests := createParallelProbing([]string{randomIpAddress(), randomIpAddress()}, []uint16{10, 20})
+		failedClient := new(smtpClientMock)
+		userNotFoundError := &SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}}
+		builder.On("newSmtpClient", smtpRequests[0].Configuration).Once().Return(failedClient)
+		failedClient.On("runSession").Once().Return(false)
+		failedClient.On("sessionError").Once().Return(userNotFoundError)
+		failedClient.On("sessionTranscript").Return(SmtpTranscript(nil))
+		validation.run()
+
+		builder.AssertNotCalled(t, "newSmtpRequest", validation.attempts(), smtpRequests[1].Email, smtpRequests[1].Host, validation.result.Configuration)
+		assert.Equal(t, smtpRequests[:1], validation.smtpResults)
+	})
+
+	t.Run("marks SMTP validation as rate limited when parallel SMTP sessions are rate limited", func(t *testing.T) {
+		validation, builder, smtpRequests := createParallelProbing([]string{randomIpAddress(), randomIpAddress()}, []uint16{10, 10})
+		configuration := validation.result.Configuration
+		configuration.SmtpRateLimitPerHost = SmtpRateLimit{Rate: 0.001}
+		for _, smtpRequest := range smtpRequests {
+			configuration.takeSmtpHostToken(smtpRequest.Host, emptyString)
+		}
+		validation.run()
+
+		builder.AssertNotCalled(t, "newSmtpClient", smtpRequests[0].Configuration)
+		assert.True(t, validation.isRateLimited.Load())
+		for _, smtpRequest := range smtpRequests {
+			assert.Equal(t, []*SmtpClientError{newSmtpRateLimitError()}, smtpRequest.Response.Errors)
+		}
+	})
+}
+
+func TestValidationSmtpIsParallelProbingScenario(t *testing.T) {
+	configuration := createConfiguration()
+	validation := &validationSmtp{result: createValidatorResult(randomEmail(), configuration)}
+	mailServers := []string{randomIpAddress(), randomIpAddress()}
+
+	t.Run("when SMTP parallel probing is disabled", func(t *testing.T) {
+		assert.False(t, validation.isParallelProbingScenario(mailServers))
+	})
+
+	t.Run("when SMTP parallel probing is enabled", func(t *testing.T) {
+		configuration.SmtpParallelProbing = true
+
+		assert.True(t, validation.isParallelProbingScenario(mailServers))
+		assert.False(t, validation.isParallelProbingScenario(mailServers[:1]))
+	})
+}
+
+func TestValidationSmtpParallelProbingLimit(t *testing.T) {
+	configuration := createConfiguration()
+	validation := &validationSmtp{result: createValidatorResult(randomEmail(), configuration)}
+
+	t.Run("when SMTP parallel probing limit is not specified", func(t *testing.T) {
+		assert.Equal(t, 5, validation.parallelProbingLimit(5))
+	})
+
+	t.Run("when SMTP parallel probing limit is specified", func(t *testing.T) {
+		configuration.SmtpParallelProbingLimit = 2
+
+		assert.Equal(t, 2, validation.parallelProbingLimit(5))
+		assert.Equal(t, 1, validation.parallelProbingLimit(1))
+	})
+}
+
+func TestValidationSmtpMailServerPriorityGroups(t *testing.T) {
+	t.Run("groups mail servers by MX record priority", func(t *testing.T) {
+		validatorResult := createValidatorResult(randomEmail(), createConfiguration())
+		mailServers := []string{randomIpAddress(), randomIpAddress(), randomIpAddress(), randomIpAddress()}
+		for index, priority := range []uint16{10, 10, 20, 30} {
+			validatorResult.addMailServerPriority(mailServers[index], priority)
+		}
+		validation := &validationSmtp{result: validatorResult}
+
+		assert.Equal(
+			t,
+			[][]string{mailServers[:2], mailServers[2:3], mailServers[3:]},
+			validation.mailServerPriorityGroups(mailServers),
+		)
+	})
+
+	t.Run("when mail servers were not resolved by MX records", func(t *testing.T) {
+		validation := &validationSmtp{result: createValidatorResult(randomEmail(), createConfiguration())}
+		mailServers := []string{randomIpAddress(), randomIpAddress()}
+
+		assert.Equal(t, [][]string{mailServers}, validation.mailServerPriorityGroups(mailServers))
+	})
+}
+
+func TestValidationSmtpIsDefinitiveSmtpResponse(t *testing.T) {
+	validation := new(validationSmtp)
+	createSmtpResponse := func(errs ...*SmtpClientError) *SmtpResponse {
+		return &SmtpResponse{Errors: errs}
+	}
+
+	t.Run("when successful RCPT TO", func(t *testing.T) {
+		assert.True(t, validation.isDefinitiveSmtpResponse(&SmtpResponse{Rcptto: true}))
+	})
+
+	t.Run("when RCPT TO permanent failure", func(t *testing.T) {
+		smtpResponse := createSmtpResponse(&SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 550, Msg: "user unknown"}})
+
+		assert.True(t, validation.isDefinitiveSmtpResponse(smtpResponse))
+	})
+
+	t.Run("when RCPT TO temporary failure", func(t *testing.T) {
+		smtpResponse := createSmtpResponse(&SmtpClientError{isRecptTo: true, err: &textproto.Error{Code: 451, Msg: "try again later"}})
+
+		assert.False(t, validation.isDefinitiveSmtpResponse(smtpResponse))
+	})
+
+	t.Run("when connection error", func(t *testing.T) {
+		smtpResponse := createSmtpResponse(&SmtpClientError{isConnection: true, err: errors.New("connection refused")})
+
+		assert.False(t, validation.isDefinitiveSmtpResponse(smtpResponse))
+	})
 }
@@ -0,0 +1,182 @@
+package truemail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP transcript entry structure. Includes SMTP command sent to target server (empty
+// for server greeting), reply lines and reply code, TLS session marker, timestamps of
+// sent command and received reply, and latency between them
+type SmtpTranscriptEntry struct {
+	Command           string
+	Reply             []string
+	Code              int
+	Tls               bool
+	SentAt, RepliedAt time.Time
+	Latency           time.Duration
+}
+
+// SMTP transcript. Ordered SMTP transcript entries of SMTP sessions
+type SmtpTranscript []*SmtpTranscriptEntry
+
+// SMTP connection structure. Wraps target server connection, records ordered SMTP
+// transcript of commands and multi-line replies, can be upgraded to TLS. Target server
+// connection is not changed after TLS upgrade, so it can be aborted from other goroutine
+type smtpConnection struct {
+	net.Conn
+	targetConnection           net.Conn
+	transcript                 []*SmtpTranscriptEntry
+	commandBuffer, replyBuffer []byte
+	awaitingReply              int
+	isTls, isFailed            bool
+	connectedAt                time.Time
+}
+
+// smtpConnection builder. Creates SMTP connection which records transcript of target server connection
+func newSmtpConnection(connection net.Conn) *smtpConnection {
+	return &smtpConnection{Conn: connection, targetConnection: connection, connectedAt: time.Now()}
+}
+
+// Returns lines from buffer which end with line feed and rest of buffer
+func cutLines(buffer []byte) (lines []string, rest []byte) {
+	for {
+		index := bytes.IndexByte(buffer, '\n')
+		if index < 0 {
+			return lines, buffer
+		}
+
+		lines, buffer = append(lines, strings.TrimSuffix(string(buffer[:index]), "\r")), buffer[index+1:]
+	}
+}
+
+// Returns true if SMTP reply line is last line of reply. Not last lines of
+// multi-line reply have hyphen after reply code (RFC 5321)
+func isLastReplyLine(line string) bool {
+	return len(line) < 4 || line[3] != '-'
+}
+
+// SmtpTranscript methods
+
+// Returns true if target server acknowledged last QUIT command of SMTP transcript
+func (transcript SmtpTranscript) IsQuitAcknowledged() bool {
+	for index := len(transcript) - 1; index >= 0; index-- {
+		if entry := transcript[index]; strings.EqualFold(entry.Command, "QUIT") {
+			return entry.Code == 221
+		}
+	}
+
+	return false
+}
+
+// smtpConnection methods
+
+// Reads data from target server connection and records SMTP replies.
+// Connection is marked as failed when read fails
+func (connection *smtpConnection) Read(data []byte) (int, error) {
+	size, err := connection.Conn.Read(data)
+	connection.recordReply(data[:size])
+	if err != nil {
+		connection.isFailed = true
+	}
+
+	return size, err
+}
+
+// Records SMTP commands and writes data to target server connection.
+// Connection is marked as failed when write fails
+func (connection *smtpConnection) Write(data []byte) (int, error) {
+	connection.recordCommand(data)
+	size, err := connection.Conn.Write(data)
+	if err != nil {
+		connection.isFailed = true
+	}
+
+	return size, err
+}
+
+// Aborts target server connection. Safe for concurrent use with SMTP session,
+// blocked read or write of SMTP session returns error
+func (connection *smtpConnection) abort() {
+	_ = connection.targetConnection.Close()
+}
+
+// Upgrades connection to TLS, following transcript entries are marked as TLS.
+// Returns TLS connection state of successful TLS handshake
+func (connection *smtpConnection) upgrade(tlsConfig *tls.Config) (tls.ConnectionState, error) {
+	tlsConnection := tls.Client(connection.Conn, tlsConfig)
+	if err := tlsConnection.Handshake(); err != nil {
+		connection.isFailed = true
+		return tls.ConnectionState{}, err
+	}
+
+	connection.Conn, connection.isTls = tlsConnection, true
+
+	return tlsConnection.ConnectionState(), nil
+}
+
+// Resets recorded SMTP transcript, uses when idle SMTP session is reused
+func (connection *smtpConnection) resetTranscript() {
+	connection.transcript, connection.awaitingReply = nil, 0
+}
+
+// Returns copy of recorded SMTP transcript
+func (connection *smtpConnection) sessionTranscript() SmtpTranscript {
+	return append(SmtpTranscript(nil), connection.transcript...)
+}
+
+// Adds transcript entry for each SMTP command line
+func (connection *smtpConnection) recordCommand(data []byte) {
+	var lines []string
+	lines, connection.commandBuffer = cutLines(append(connection.commandBuffer, data...))
+
+	for _, line := range lines {
+		connection.transcript = append(
+			connection.transcript,
+			&SmtpTranscriptEntry{Command: line, Tls: connection.isTls, SentAt: time.Now()},
+		)
+	}
+}
+
+// Adds SMTP reply lines to transcript entry which awaits reply. Completes
+// transcript entry with reply code and latency when last reply line received
+func (connection *smtpConnection) recordReply(data []byte) {
+	var lines []string
+	lines, connection.replyBuffer = cutLines(append(connection.replyBuffer, data...))
+
+	for _, line := range lines {
+		entry := connection.awaitingReplyEntry()
+		entry.Reply = append(entry.Reply, line)
+		if !isLastReplyLine(line) {
+			continue
+		}
+
+		entry.RepliedAt = time.Now()
+		entry.Latency = entry.RepliedAt.Sub(entry.SentAt)
+		if len(line) >= 3 {
+			entry.Code, _ = strconv.Atoi(line[:3])
+		}
+		connection.awaitingReply++
+	}
+}
+
+// Returns first transcript entry which awaits reply. Adds transcript entry without
+// command for server greeting or for reply which was sent by server without command
+func (connection *smtpConnection) awaitingReplyEntry() *SmtpTranscriptEntry {
+	if connection.awaitingReply < len(connection.transcript) {
+		return connection.transcript[connection.awaitingReply]
+	}
+
+	sentAt := time.Now()
+	if len(connection.transcript) == 0 {
+		sentAt = connection.connectedAt
+	}
+	entry := &SmtpTranscriptEntry{Tls: connection.isTls, SentAt: sentAt}
+	connection.transcript = append(connection.transcript, entry)
+
+	return entry
+}
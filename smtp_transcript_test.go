@@ -0,0 +1,239 @@
+package truemail
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSmtpConnection(t *testing.T) {
+	t.Run("creates SMTP connection with empty transcript", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		connection := newSmtpConnection(client)
+
+		assert.Equal(t, client, connection.Conn)
+		assert.Equal(t, client, connection.targetConnection)
+		assert.Empty(t, connection.transcript)
+		assert.False(t, connection.isTls)
+		assert.False(t, connection.connectedAt.IsZero())
+	})
+}
+
+func TestCutLines(t *testing.T) {
+	t.Run("when buffer includes complete and incomplete lines", func(t *testing.T) {
+		lines, rest := cutLines([]byte("250-mx.example.com\r\n250 8BITMIME\r\n220 rea"))
+
+		assert.Equal(t, []string{"250-mx.example.com", "250 8BITMIME"}, lines)
+		assert.Equal(t, []byte("220 rea"), rest)
+	})
+
+	t.Run("when buffer includes no complete lines", func(t *testing.T) {
+		lines, rest := cutLines([]byte("250"))
+
+		assert.Empty(t, lines)
+		assert.Equal(t, []byte("250"), rest)
+	})
+}
+
+func TestIsLastReplyLine(t *testing.T) {
+	t.Run("when last line of reply", func(t *testing.T) {
+		assert.True(t, isLastReplyLine("250 8BITMIME"))
+		assert.True(t, isLastReplyLine("250"))
+	})
+
+	t.Run("when not last line of multi-line reply", func(t *testing.T) {
+		assert.False(t, isLastReplyLine("250-mx.example.com"))
+	})
+}
+
+func TestSmtpConnectionReadWrite(t *testing.T) {
+	t.Run("records SMTP commands and replies via target server connection", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		connection := newSmtpConnection(client)
+		go func() {
+			_, _ = server.Write([]byte("220 mx.example.com ESMTP\r\n"))
+			_, _ = server.Read(make([]byte, 1024))
+			_, _ = server.Write([]byte("250-mx.example.com\r\n"))
+			_, _ = server.Write([]byte("250 8BITMIME\r\n"))
+		}()
+
+		buffer := make([]byte, 1024)
+		_, _ = connection.Read(buffer)
+		_, _ = connection.Write([]byte("EHLO example.com\r\n"))
+		_, _ = connection.Read(buffer)
+		_, _ = connection.Read(buffer)
+		transcript := connection.sessionTranscript()
+
+		assert.Equal(t, 2, len(transcript))
+		assert.Empty(t, transcript[0].Command)
+		assert.Equal(t, []string{"220 mx.example.com ESMTP"}, transcript[0].Reply)
+		assert.Equal(t, 220, transcript[0].Code)
+		assert.Equal(t, connection.connectedAt, transcript[0].SentAt)
+		assert.Equal(t, "EHLO example.com", transcript[1].Command)
+		assert.Equal(t, []string{"250-mx.example.com", "250 8BITMIME"}, transcript[1].Reply)
+		assert.Equal(t, 250, transcript[1].Code)
+		assert.Equal(t, transcript[1].RepliedAt.Sub(transcript[1].SentAt), transcript[1].Latency)
+	})
+}
+
+func TestSmtpConnectionUpgrade(t *testing.T) {
+	tlsConfig, rootCAs := createTlsConfig()
+
+	t.Run("when successful TLS handshake, marks following transcript entries as TLS", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		go func() { _ = tls.Server(server, tlsConfig).Handshake() }()
+		connection := newSmtpConnection(client)
+		connectionState, err := connection.upgrade(&tls.Config{ServerName: "example.com", RootCAs: rootCAs})
+		connection.recordCommand([]byte("EHLO example.com\r\n"))
+
+		assert.NoError(t, err)
+		assert.True(t, connectionState.HandshakeComplete)
+		assert.True(t, connection.isTls)
+		assert.IsType(t, new(tls.Conn), connection.Conn)
+		assert.Equal(t, client, connection.targetConnection)
+		assert.True(t, connection.transcript[0].Tls)
+	})
+
+	t.Run("when failed TLS handshake", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		go func() { _ = tls.Server(server, tlsConfig).Handshake() }()
+		connection := newSmtpConnection(client)
+		_, err := connection.upgrade(&tls.Config{ServerName: "example.com"})
+
+		assert.Error(t, err)
+		assert.True(t, connection.isFailed)
+		assert.False(t, connection.isTls)
+		assert.Equal(t, client, connection.Conn)
+	})
+}
+
+func TestSmtpConnectionRecordCommand(t *testing.T) {
+	t.Run("adds transcript entry for each complete command line", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordCommand([]byte("MAIL FROM:<verifier@example.com>\r\nRCPT "))
+		connection.recordCommand([]byte("TO:<email@example.com>\r\n"))
+
+		assert.Equal(t, 2, len(connection.transcript))
+		assert.Equal(t, "MAIL FROM:<verifier@example.com>", connection.transcript[0].Command)
+		assert.Equal(t, "RCPT TO:<email@example.com>", connection.transcript[1].Command)
+		assert.False(t, connection.transcript[1].SentAt.IsZero())
+		assert.Empty(t, connection.commandBuffer)
+	})
+}
+
+func TestSmtpConnectionRecordReply(t *testing.T) {
+	t.Run("adds reply lines to transcript entries in order of sent commands", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordCommand([]byte("MAIL FROM:<verifier@example.com>\r\n"))
+		connection.recordCommand([]byte("RCPT TO:<email@example.com>\r\n"))
+		connection.recordReply([]byte("250 ok\r\n550 5.1.1 us"))
+		connection.recordReply([]byte("er unknown\r\n"))
+
+		assert.Equal(t, []string{"250 ok"}, connection.transcript[0].Reply)
+		assert.Equal(t, 250, connection.transcript[0].Code)
+		assert.Equal(t, []string{"550 5.1.1 user unknown"}, connection.transcript[1].Reply)
+		assert.Equal(t, 550, connection.transcript[1].Code)
+		assert.False(t, connection.transcript[1].RepliedAt.IsZero())
+	})
+
+	t.Run("adds transcript entry without command for reply sent by server without command", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordReply([]byte("220 ready\r\n421 timeout\r\n"))
+
+		assert.Equal(t, 2, len(connection.transcript))
+		assert.Equal(t, connection.connectedAt, connection.transcript[0].SentAt)
+		assert.Empty(t, connection.transcript[1].Command)
+		assert.Equal(t, 421, connection.transcript[1].Code)
+	})
+
+	t.Run("does not complete transcript entry until last line of multi-line reply", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordCommand([]byte("EHLO example.com\r\n"))
+		connection.recordReply([]byte("250-mx.example.com\r\n"))
+
+		assert.Zero(t, connection.transcript[0].Code)
+		assert.True(t, connection.transcript[0].RepliedAt.IsZero())
+		assert.Equal(t, 0, connection.awaitingReply)
+	})
+}
+
+func TestSmtpConnectionSessionTranscript(t *testing.T) {
+	t.Run("returns copy of recorded transcript", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordCommand([]byte("NOOP\r\n"))
+		transcript := connection.sessionTranscript()
+		connection.recordCommand([]byte("QUIT\r\n"))
+
+		assert.Equal(t, 1, len(transcript))
+		assert.Equal(t, 2, len(connection.transcript))
+	})
+}
+
+func TestSmtpConnectionResetTranscript(t *testing.T) {
+	t.Run("resets recorded transcript", func(t *testing.T) {
+		connection := newSmtpConnection(nil)
+		connection.recordCommand([]byte("NOOP\r\n"))
+		connection.recordReply([]byte("250 ok\r\n"))
+		connection.resetTranscript()
+		connection.recordCommand([]byte("MAIL FROM:<a@b.com>\r\n"))
+		connection.recordReply([]byte("250 ok\r\n"))
+
+		assert.Equal(t, 1, len(connection.transcript))
+		assert.Equal(t, 250, connection.transcript[0].Code)
+		assert.Equal(t, 1, connection.awaitingReply)
+	})
+}
+
+func TestSmtpConnectionAbort(t *testing.T) {
+	t.Run("aborts target server connection, marks connection as failed after read error", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer server.Close()
+		connection := newSmtpConnection(client)
+		connection.abort()
+		_, err := connection.Read(make([]byte, 1))
+
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+		assert.True(t, connection.isFailed)
+	})
+
+	t.Run("marks connection as failed after write error", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer server.Close()
+		connection := newSmtpConnection(client)
+		connection.abort()
+		_, err := connection.Write([]byte("QUIT\r\n"))
+
+		assert.Error(t, err)
+		assert.True(t, connection.isFailed)
+		assert.Equal(t, "QUIT", connection.transcript[0].Command)
+	})
+}
+
+func TestSmtpTranscriptIsQuitAcknowledged(t *testing.T) {
+	t.Run("when target server acknowledged QUIT", func(t *testing.T) {
+		transcript := SmtpTranscript{{Command: "QUIT", Code: 221}, {Command: "RSET", Code: 250}}
+
+		assert.True(t, transcript.IsQuitAcknowledged())
+	})
+
+	t.Run("when target server did not acknowledge last QUIT", func(t *testing.T) {
+		transcript := SmtpTranscript{{Command: "QUIT", Code: 221}, {Reply: []string{"220 ready"}, Code: 220}, {Command: "QUIT"}}
+
+		assert.False(t, transcript.IsQuitAcknowledged())
+	})
+
+	t.Run("when QUIT was not sent", func(t *testing.T) {
+		assert.False(t, SmtpTranscript{{Command: "RSET", Code: 250}}.IsQuitAcknowledged())
+	})
+}
@@ -0,0 +1,511 @@
+package truemail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// truemail test fixtures: DNS, SMTP, MTA-STS, proxy stand-in servers, tracing and logging helpers
+
+// Returns raw DNS response message with A answers with given TTLs
+func createDnsMessage(hostName string, ttls ...uint32) []byte {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	_ = builder.StartQuestions()
+	name := dnsmessage.MustNewName(toDnsHostName(hostName))
+	_ = builder.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	_ = builder.StartAnswers()
+	for _, ttl := range ttls {
+		header := dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl}
+		_ = builder.AResource(header, dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+	}
+	message, _ := builder.Finish()
+
+	return message
+}
+
+// Runs DNS mock server. Returns running mock server address and server stop function
+func startMockDnsServer(dnsRecords map[string]mockdns.Zone) (string, func()) {
+	srv, _ := mockdns.NewServer(dnsRecords, false)
+	return srv.LocalAddr().String(), func() { _ = srv.Close() }
+}
+
+// Runs DNS-over-TLS stand-in server, which terminates TLS and proxies DNS stream
+// to DNS server. Returns stand-in address, TLS root CAs and server stop function.
+// Stand-in certificate is valid for 127.0.0.1 and example.com
+func startDnsOverTlsStandIn(dnsServerAddress string) (string, *x509.CertPool, func()) {
+	tlsConfig, rootCAs := createTlsConfig()
+	listener, _ := tls.Listen(tcpTransportLayer, localhostIPv4Address+":0", tlsConfig)
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer connection.Close()
+				dnsConnection, err := net.Dial(tcpTransportLayer, dnsServerAddress)
+				if err != nil {
+					return
+				}
+				defer dnsConnection.Close()
+				go func() { _, _ = io.Copy(dnsConnection, connection) }()
+				_, _ = io.Copy(connection, dnsConnection)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), rootCAs, func() { _ = listener.Close() }
+}
+
+// Runs DNS-over-HTTPS stand-in server, which proxies DNS messages from HTTPS POST requests
+// to DNS server via UDP. Returns stand-in server
+func startDnsOverHttpsStandIn(dnsServerAddress string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost || request.Header.Get("Content-Type") != dnsMessageMediaType {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		query, _ := io.ReadAll(request.Body)
+		dnsConnection, err := net.Dial("udp", dnsServerAddress)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer dnsConnection.Close()
+		_, _ = dnsConnection.Write(query)
+		message := make([]byte, dnsMessageMaxSize)
+		n, _ := dnsConnection.Read(message)
+
+		writer.Header().Set("Content-Type", dnsMessageMediaType)
+		_, _ = writer.Write(message[:n])
+	}))
+}
+
+// Runs raw DNS server with custom DNS handler on UDP and TCP with the same port.
+// Picks another port when TCP port is already taken. Returns running server address
+// and server stop function
+func startRawDnsServer(handler dns.HandlerFunc) (string, func()) {
+	var packetConnection net.PacketConn
+	var listener net.Listener
+	var address string
+	for listener == nil {
+		packetConnection, _ = net.ListenPacket(udpTransportLayer, localhostIPv4Address+":0")
+		address = packetConnection.LocalAddr().String()
+		if tcpListener, err := net.Listen(tcpTransportLayer, address); err == nil {
+			listener = tcpListener
+			continue
+		}
+		_ = packetConnection.Close()
+	}
+	servers := []*dns.Server{
+		{PacketConn: packetConnection, Handler: handler},
+		{Listener: listener, Handler: handler},
+	}
+
+	for _, server := range servers {
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = server.ActivateAndServe() }()
+		<-started
+	}
+
+	return address, func() {
+		for _, server := range servers {
+			_ = server.Shutdown()
+		}
+	}
+}
+
+// Returns raw DNS handler which responds with response code, answer records and DNSSEC
+// authenticated data flag
+func createRawDnsHandler(rcode int, authenticated bool, answers ...dns.RR) dns.HandlerFunc {
+	return func(writer dns.ResponseWriter, request *dns.Msg) {
+		response := new(dns.Msg)
+		response.SetRcode(request, rcode)
+		response.AuthenticatedData = authenticated
+		response.Answer = answers
+		_ = writer.WriteMsg(response)
+	}
+}
+
+// Returns DNS resource record from zone file representation
+func createDnsRecord(record string) dns.RR {
+	resourceRecord, _ := dns.NewRR(record)
+	return resourceRecord
+}
+
+// HTTP client stand-in, sends all requests to HTTP test server and records requested URLs
+type httpClientStandIn struct {
+	server *httptest.Server
+	urls   []string
+}
+
+func (httpClient *httpClientStandIn) Do(request *http.Request) (*http.Response, error) {
+	httpClient.urls = append(httpClient.urls, request.URL.String())
+	request.URL.Host = httpClient.server.Listener.Addr().String()
+
+	return httpClient.server.Client().Do(request)
+}
+
+// Runs MTA-STS policy host stand-in which responds with MTA-STS policy.
+// Returns HTTP client stand-in and server stop function
+func startMtaStsPolicyHostStandIn(policy string) (*httpClientStandIn, func()) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/.well-known/mta-sts.txt" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = writer.Write([]byte(policy))
+	}))
+
+	return &httpClientStandIn{server: server}, server.Close
+}
+
+// Returns MTA-STS policy with mode and mx patterns
+func createMtaStsPolicy(mode string, mxPatterns ...string) string {
+	policy := "version: STSv1\r\nmode: " + mode + "\r\nmax_age: 86400\r\n"
+	for _, mxPattern := range mxPatterns {
+		policy += "mx: " + mxPattern + "\r\n"
+	}
+
+	return policy
+}
+
+// Runs SMTP stand-in server, which accepts any sender and recipient. Advertises and
+// supports STARTTLS when TLS config is specified. Returns server address and stop function
+func startSmtpStandIn(tlsConfig *tls.Config) (string, func()) {
+	return startScriptedSmtpStandIn(tlsConfig, nil)
+}
+
+// Runs SMTP stand-in server with scripted replies. Scripted replies of SMTP command are
+// sent in order within each SMTP session, default reply is sent when scripted replies of
+// SMTP command are over. Returns server address and stop function
+func startScriptedSmtpStandIn(tlsConfig *tls.Config, replies map[string][]string) (string, func()) {
+	listener, _ := net.Listen(tcpTransportLayer, localhostIPv4Address+":0")
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			sessionReplies := make(map[string][]string, len(replies))
+			for command, commandReplies := range replies {
+				sessionReplies[command] = append([]string(nil), commandReplies...)
+			}
+			go runSmtpStandInSession(connection, tlsConfig, sessionReplies)
+		}
+	}()
+
+	return listener.Addr().String(), func() { _ = listener.Close() }
+}
+
+// Runs SMTP stand-in session
+func runSmtpStandInSession(connection net.Conn, tlsConfig *tls.Config, replies map[string][]string) {
+	defer func() { _ = connection.Close() }()
+	textConnection := textproto.NewConn(connection)
+	_ = textConnection.PrintfLine("220 stand-in ESMTP")
+
+	for {
+		line, err := textConnection.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		if commandReplies := replies[command]; len(commandReplies) > 0 {
+			replies[command] = commandReplies[1:]
+			_ = textConnection.PrintfLine("%s", commandReplies[0])
+			continue
+		}
+
+		switch command {
+		case "EHLO":
+			_ = textConnection.PrintfLine("250-stand-in")
+			if _, isTls := connection.(*tls.Conn); tlsConfig != nil && !isTls {
+				_ = textConnection.PrintfLine("250-STARTTLS")
+			}
+			_ = textConnection.PrintfLine("250 8BITMIME")
+		case "STARTTLS":
+			if tlsConfig == nil {
+				_ = textConnection.PrintfLine("502 command not implemented")
+				continue
+			}
+			_ = textConnection.PrintfLine("220 ready to start TLS")
+			tlsConnection := tls.Server(connection, tlsConfig)
+			if tlsConnection.Handshake() != nil {
+				return
+			}
+			connection, textConnection = tlsConnection, textproto.NewConn(tlsConnection)
+		case "QUIT":
+			_ = textConnection.PrintfLine("221 bye")
+			return
+		case "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			_ = textConnection.PrintfLine("250 ok")
+		default:
+			_ = textConnection.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// Returns TLS config with certificate valid for 127.0.0.1 and example.com, and its root CAs
+func createTlsConfig() (*tls.Config, *x509.CertPool) {
+	certificateServer := httptest.NewUnstartedServer(nil)
+	certificateServer.StartTLS()
+	defer certificateServer.Close()
+	tlsConfig, rootCAs := certificateServer.TLS.Clone(), x509.NewCertPool()
+	rootCAs.AddCert(certificateServer.Certificate())
+	tlsConfig.NextProtos = nil
+
+	return tlsConfig, rootCAs
+}
+
+// Returns server certificate of TLS config
+func tlsConfigCertificate(tlsConfig *tls.Config) *x509.Certificate {
+	certificate, _ := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	return certificate
+}
+
+// Returns TLSA record for certificate with certificate usage, selector and matching type
+func createTlsaRecord(certificate *x509.Certificate, usage, selector, matchingType uint8) *TlsaRecord {
+	data := certificate.Raw
+	if selector == tlsaSelectorPublicKey {
+		data = certificate.RawSubjectPublicKeyInfo
+	}
+
+	switch matchingType {
+	case tlsaMatchingTypeSha256:
+		digest := sha256.Sum256(data)
+		data = digest[:]
+	case tlsaMatchingTypeSha512:
+		digest := sha512.Sum512(data)
+		data = digest[:]
+	}
+
+	return &TlsaRecord{Usage: usage, Selector: selector, MatchingType: matchingType, Certificate: hex.EncodeToString(data)}
+}
+
+// TLSA resolver stand-in, returns TLSA records and records requested names
+type tlsaResolverStandIn struct {
+	*net.Resolver
+	tlsaRecords []*TlsaRecord
+	names       []string
+}
+
+func (resolver *tlsaResolverStandIn) lookupTLSA(ctx context.Context, name string) ([]*TlsaRecord, error) {
+	resolver.names = append(resolver.names, name)
+	return resolver.tlsaRecords, nil
+}
+
+// Proxy stand-in structure. Tunnels connections to requested target addresses
+type proxyStandIn struct {
+	listener        net.Listener
+	mutex           sync.Mutex
+	targetAddresses []string
+}
+
+// Starts SOCKS5 proxy stand-in. Requires username/password authentication when username is specified
+func startSocks5ProxyStandIn(username, password string) *proxyStandIn {
+	proxy := new(proxyStandIn)
+	proxy.listener, _ = net.Listen(tcpTransportLayer, localhostIPv4Address+":0")
+	go proxy.serve(func(connection net.Conn) { proxy.runSocks5Session(connection, username, password) })
+
+	return proxy
+}
+
+// Starts HTTP CONNECT proxy stand-in. Requires Proxy-Authorization header when it is specified
+func startHttpConnectProxyStandIn(proxyAuthorization string) *proxyStandIn {
+	proxy := new(proxyStandIn)
+	proxy.listener, _ = net.Listen(tcpTransportLayer, localhostIPv4Address+":0")
+	go proxy.serve(func(connection net.Conn) { proxy.runHttpConnectSession(connection, proxyAuthorization) })
+
+	return proxy
+}
+
+// Returns proxy stand-in address
+func (proxy *proxyStandIn) address() string {
+	return proxy.listener.Addr().String()
+}
+
+// Returns target addresses requested via proxy stand-in
+func (proxy *proxyStandIn) requestedTargetAddresses() []string {
+	proxy.mutex.Lock()
+	defer proxy.mutex.Unlock()
+
+	return append([]string(nil), proxy.targetAddresses...)
+}
+
+// Stops proxy stand-in
+func (proxy *proxyStandIn) stop() {
+	_ = proxy.listener.Close()
+}
+
+// Accepts proxy stand-in connections
+func (proxy *proxyStandIn) serve(runSession func(net.Conn)) {
+	for {
+		connection, err := proxy.listener.Accept()
+		if err != nil {
+			return
+		}
+		go runSession(connection)
+	}
+}
+
+// Dials requested target address and records it
+func (proxy *proxyStandIn) dialTarget(targetAddress string) (net.Conn, error) {
+	proxy.mutex.Lock()
+	proxy.targetAddresses = append(proxy.targetAddresses, targetAddress)
+	proxy.mutex.Unlock()
+
+	return net.Dial(tcpTransportLayer, targetAddress)
+}
+
+// Runs SOCKS5 proxy stand-in session (RFC 1928, RFC 1929)
+func (proxy *proxyStandIn) runSocks5Session(connection net.Conn, username, password string) {
+	defer func() { _ = connection.Close() }()
+	readBytes := func(size int) []byte {
+		data := make([]byte, size)
+		_, _ = io.ReadFull(connection, data)
+		return data
+	}
+
+	greeting := readBytes(2)
+	readBytes(int(greeting[1]))
+	if username == emptyString {
+		_, _ = connection.Write([]byte{5, 0})
+	} else {
+		_, _ = connection.Write([]byte{5, 2})
+		readBytes(1)
+		requestedUsername := string(readBytes(int(readBytes(1)[0])))
+		requestedPassword := string(readBytes(int(readBytes(1)[0])))
+		if requestedUsername != username || requestedPassword != password {
+			_, _ = connection.Write([]byte{1, 1})
+			return
+		}
+		_, _ = connection.Write([]byte{1, 0})
+	}
+
+	request := readBytes(4)
+	var host string
+	switch request[3] {
+	case 1:
+		host = net.IP(readBytes(4)).String()
+	case 3:
+		host = string(readBytes(int(readBytes(1)[0])))
+	case 4:
+		host = net.IP(readBytes(16)).String()
+	}
+	port := readBytes(2)
+	targetConnection, err := proxy.dialTarget(serverWithPortNumber(host, int(port[0])<<8|int(port[1])))
+	if err != nil {
+		_, _ = connection.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer func() { _ = targetConnection.Close() }()
+
+	_, _ = connection.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	tunnelConnections(connection, connection, targetConnection)
+}
+
+// Runs HTTP CONNECT proxy stand-in session
+func (proxy *proxyStandIn) runHttpConnectSession(connection net.Conn, proxyAuthorization string) {
+	defer func() { _ = connection.Close() }()
+	reader := bufio.NewReader(connection)
+	request, err := http.ReadRequest(reader)
+	if err != nil || request.Method != http.MethodConnect {
+		return
+	}
+
+	if request.Header.Get("Proxy-Authorization") != proxyAuthorization {
+		_, _ = connection.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+		return
+	}
+
+	targetConnection, err := proxy.dialTarget(request.Host)
+	if err != nil {
+		_, _ = connection.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+		return
+	}
+	defer func() { _ = targetConnection.Close() }()
+
+	_, _ = connection.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	tunnelConnections(reader, connection, targetConnection)
+}
+
+// Copies data between proxy client and target server connections until one of them closed
+func tunnelConnections(clientReader io.Reader, clientConnection, targetConnection net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(targetConnection, clientReader); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(clientConnection, targetConnection); done <- struct{}{} }()
+	<-done
+}
+
+// Creates tracer provider which records ended spans into span recorder
+func createTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)), spanRecorder
+}
+
+// Returns ended spans with span name from span recorder
+func endedSpans(spanRecorder *tracetest.SpanRecorder, spanName string) (spans []sdktrace.ReadOnlySpan) {
+	for _, span := range spanRecorder.Ended() {
+		if span.Name() == spanName {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// Returns span attribute value by attribute key. Returns empty value when attribute not exists
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, spanAttribute := range span.Attributes() {
+		if string(spanAttribute.Key) == key {
+			return spanAttribute.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+// Creates JSON logger with debug level which writes log records into buffer
+func createLogger() (*slog.Logger, *bytes.Buffer) {
+	buffer := new(bytes.Buffer)
+	return slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelDebug})), buffer
+}
+
+// Returns log records with log message from buffer of JSON logger
+func logRecords(buffer *bytes.Buffer, message string) (records []map[string]interface{}) {
+	for _, line := range bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n")) {
+		record := make(map[string]interface{})
+		if json.Unmarshal(line, &record) == nil && record[slog.MessageKey] == message {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
@@ -1,6 +1,12 @@
 package truemail
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
 
 // Testing mocks
 
@@ -84,6 +90,20 @@ func (resolver *dnsResolverMock) ptrRecords(hostName string) ([]string, error) {
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (resolver *dnsResolverMock) txtRecords(hostName string) ([]string, error) {
+	args := resolver.Called(hostName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (resolver *dnsResolverMock) tlsaRecords(name string) ([]*TlsaRecord, bool, error) {
+	args := resolver.Called(name)
+	return args.Get(0).([]*TlsaRecord), args.Bool(1), args.Error(2)
+}
+
+func (resolver *dnsResolverMock) dnsQueries() []*DnsQuery {
+	return resolver.Called().Get(0).([]*DnsQuery)
+}
+
 // smtpClientMock structure mock
 type smtpClientMock struct {
 	mock.Mock
@@ -97,6 +117,22 @@ func (client *smtpClientMock) runSession() bool {
 	return client.Called().Bool(0)
 }
 
+func (client *smtpClientMock) sessionTls() *TlsDetails {
+	return client.Called().Get(0).(*TlsDetails)
+}
+
+func (client *smtpClientMock) sessionTranscript() SmtpTranscript {
+	return client.Called().Get(0).(SmtpTranscript)
+}
+
+func (client *smtpClientMock) sessionCatchAll() bool {
+	return client.Called().Bool(0)
+}
+
+func (client *smtpClientMock) cancelSession() {
+	client.Called()
+}
+
 // smtpBuilderMock structure mock
 type smtpBuilderMock struct {
 	mock.Mock
@@ -111,3 +147,54 @@ func (builder *smtpBuilderMock) newSmtpClient(configuration *SmtpRequestConfigur
 	args := builder.Called(configuration)
 	return args.Get(0).(client)
 }
+
+// gatewayMock structure mock
+type gatewayMock struct {
+	mock.Mock
+}
+
+func (gateway *gatewayMock) LookupHost(ctx context.Context, hostName string) ([]string, error) {
+	args := gateway.Called(hostName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (gateway *gatewayMock) LookupCNAME(ctx context.Context, hostName string) (string, error) {
+	args := gateway.Called(hostName)
+	return args.String(0), args.Error(1)
+}
+
+func (gateway *gatewayMock) LookupMX(ctx context.Context, hostName string) ([]*net.MX, error) {
+	args := gateway.Called(hostName)
+	return args.Get(0).([]*net.MX), args.Error(1)
+}
+
+func (gateway *gatewayMock) LookupAddr(ctx context.Context, hostAddress string) ([]string, error) {
+	args := gateway.Called(hostAddress)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (gateway *gatewayMock) LookupTXT(ctx context.Context, hostName string) ([]string, error) {
+	args := gateway.Called(hostName)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// metricsCollectorMock structure mock
+type metricsCollectorMock struct {
+	mock.Mock
+}
+
+func (metricsCollector *metricsCollectorMock) ObserveValidation(validationType, layer, outcome string, fromCache bool) {
+	metricsCollector.Called(validationType, layer, outcome, fromCache)
+}
+
+func (metricsCollector *metricsCollectorMock) ObserveDnsQuery(queryType, status string, duration time.Duration) {
+	metricsCollector.Called(queryType, status, duration)
+}
+
+func (metricsCollector *metricsCollectorMock) ObserveSmtpCommand(command, status string, duration time.Duration) {
+	metricsCollector.Called(command, status, duration)
+}
+
+func (metricsCollector *metricsCollectorMock) AddSmtpSessionsInFlight(delta float64) {
+	metricsCollector.Called(delta)
+}
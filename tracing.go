@@ -0,0 +1,48 @@
+package truemail
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Starts validation span with tracer of tracer provider. Uses global
+// tracer provider when tracer provider is not specified
+func startValidationSpan(ctx context.Context, tracerProvider trace.TracerProvider, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	return tracerProvider.Tracer(tracerName).Start(contextOrBackground(ctx), spanNameValidate, trace.WithAttributes(attributes...))
+}
+
+// Starts child span of span from context with tracer of the same tracer provider, so spans
+// of validation layers, DNS queries and SMTP sessions are recorded by tracer provider of
+// validation span. Span is not recording when context has no span
+func startSpan(ctx context.Context, spanName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = contextOrBackground(ctx)
+
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, spanName, trace.WithAttributes(attributes...))
+}
+
+// Records error into span and sets span error status when error is not nil, ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Returns context, returns background context when context is nil
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+
+	return ctx
+}
@@ -0,0 +1,101 @@
+package truemail
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartValidationSpan(t *testing.T) {
+	t.Run("starts validation span with tracer of tracer provider", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, span := startValidationSpan(context.Background(), tracerProvider, attribute.String(attributeValidationType, validationTypeMx))
+		span.End()
+		spans := endedSpans(spanRecorder, spanNameValidate)
+
+		assert.Len(t, spans, 1)
+		assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(ctx))
+		assert.Equal(t, validationTypeMx, spanAttribute(spans[0], attributeValidationType).AsString())
+	})
+
+	t.Run("starts validation span as child of span from context", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, parentSpan := tracerProvider.Tracer(tracerName).Start(context.Background(), "parent")
+		_, span := startValidationSpan(ctx, tracerProvider)
+		span.End()
+
+		assert.Equal(t, parentSpan.SpanContext().SpanID(), endedSpans(spanRecorder, spanNameValidate)[0].Parent().SpanID())
+	})
+
+	t.Run("uses global tracer provider when tracer provider is not specified", func(t *testing.T) {
+		var nilContext context.Context
+		ctx, span := startValidationSpan(nilContext, nil)
+
+		assert.NotNil(t, ctx)
+		assert.False(t, span.IsRecording())
+	})
+}
+
+func TestStartSpan(t *testing.T) {
+	t.Run("starts child span with tracer provider of span from context", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, parentSpan := startValidationSpan(context.Background(), tracerProvider)
+		_, span := startSpan(ctx, spanNameDnsQuery, attribute.String(attributeDnsQueryType, "MX"))
+		span.End()
+		spans := endedSpans(spanRecorder, spanNameDnsQuery)
+
+		assert.Len(t, spans, 1)
+		assert.Equal(t, parentSpan.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, "MX", spanAttribute(spans[0], attributeDnsQueryType).AsString())
+	})
+
+	t.Run("when context has no span", func(t *testing.T) {
+		var nilContext context.Context
+		ctx, span := startSpan(nilContext, spanNameDnsQuery)
+
+		assert.NotNil(t, ctx)
+		assert.False(t, span.IsRecording())
+	})
+}
+
+func TestEndSpan(t *testing.T) {
+	t.Run("ends span without error", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		_, span := startValidationSpan(context.Background(), tracerProvider)
+		endSpan(span, nil)
+		endedSpan := spanRecorder.Ended()[0]
+
+		assert.Equal(t, codes.Unset, endedSpan.Status().Code)
+		assert.Empty(t, endedSpan.Events())
+	})
+
+	t.Run("records error and sets span error status", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		_, span := startValidationSpan(context.Background(), tracerProvider)
+		endSpan(span, errors.New("error"))
+		endedSpan := spanRecorder.Ended()[0]
+
+		assert.Equal(t, codes.Error, endedSpan.Status().Code)
+		assert.Equal(t, "error", endedSpan.Status().Description)
+		assert.Len(t, endedSpan.Events(), 1)
+	})
+}
+
+func TestContextOrBackground(t *testing.T) {
+	t.Run("when context is specified", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), dnsQueryKey{}, nil)
+
+		assert.Equal(t, ctx, contextOrBackground(ctx))
+	})
+
+	t.Run("when context is nil", func(t *testing.T) {
+		var nilContext context.Context
+
+		assert.Equal(t, context.Background(), contextOrBackground(nilContext))
+	})
+}
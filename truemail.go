@@ -1,16 +1,26 @@
 package truemail
 
+import "context"
+
 // Validate is main truemail entrypoint. Accepts validation type as option.
 // Available types are: regex, mx, mx_blacklist, smtp. By default uses
-// validation layer specified in Configuration.ValidationTypeDefault
+// validation layer specified in Configuration.ValidationTypeDefault. Returns
+// result from Configuration.ResultCache when it is configured and includes result
 func Validate(email string, configuration *Configuration, options ...string) (*ValidatorResult, error) {
+	return ValidateContext(context.Background(), email, configuration, options...)
+}
+
+// ValidateContext is Validate() function with context. Context is propagated to validation
+// layers, DNS queries and SMTP sessions: validation spans are children of span from context,
+// DNS queries and SMTP sessions are cancelled when context is done
+func ValidateContext(ctx context.Context, email string, configuration *Configuration, options ...string) (*ValidatorResult, error) {
 	validationType, err := variadicValidationType(options, configuration.ValidationTypeDefault)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return newValidator(email, validationType, configuration).run(), err
+	return newValidator(email, validationType, configuration).runWithContext(ctx), err
 }
 
 // IsValid is shortcut for Validate() function. Returns boolean as email validation result.
@@ -23,5 +33,5 @@ func IsValid(email string, configuration *Configuration, options ...string) bool
 		return false
 	}
 
-	return newValidator(email, validationType, configuration).run().Success
+	return newValidator(email, validationType, configuration).runWithContext(context.Background()).Success
 }
@@ -1,13 +1,18 @@
 package truemail
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/foxcpp/go-mockdns"
+	"github.com/miekg/dns"
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestValidate(t *testing.T) {
@@ -61,6 +66,23 @@ func TestValidate(t *testing.T) {
 		})
 	}
 
+	t.Run("successful validation, custom resolver specified in configuration", func(t *testing.T) {
+		email, domain := pairRandomEmailDomain()
+		mxHostName, mxHostAddress := randomDnsHostName(), randomIpAddress()
+		resolver := &mockdns.Resolver{
+			Zones: map[string]mockdns.Zone{
+				toDnsHostName(punycodeDomain(domain)): {MX: []net.MX{{Host: mxHostName, Pref: uint16(5)}}},
+				mxHostName:                            {A: []string{mxHostAddress}},
+			},
+		}
+		configuration, _ := NewConfiguration(ConfigurationAttr{VerifierEmail: randomEmail(), Resolver: resolver})
+		validatorResult, err := Validate(email, configuration, validationTypeMx)
+
+		assert.NoError(t, err)
+		assert.True(t, validatorResult.Success)
+		assert.Equal(t, []string{mxHostAddress}, validatorResult.MailServers)
+	})
+
 	t.Run("successful validation, default validation type specified in configuration", func(t *testing.T) {
 		email, specifiedValidationTypeByDefault := randomEmail(), validationTypeRegex
 		configuration, _ := NewConfiguration(
@@ -158,6 +180,65 @@ func TestValidate(t *testing.T) {
 	})
 }
 
+func TestValidateWithMtaStsCheck(t *testing.T) {
+	email, domain := pairRandomEmailDomain()
+	mxHostName, mxHostAddress := "mx."+domain, randomIpAddress()
+	resolver := &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			toDnsHostName(domain):               {MX: []net.MX{{Host: toDnsHostName(mxHostName), Pref: uint16(5)}}},
+			toDnsHostName(mxHostName):           {A: []string{mxHostAddress}},
+			toDnsHostName("_mta-sts." + domain): {TXT: []string{"v=STSv1; id=20240101T000000"}},
+		},
+	}
+
+	t.Run("successful validation, MX hosts match MTA-STS policy", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "*."+domain))
+		defer stop()
+		configuration, _ := NewConfiguration(
+			ConfigurationAttr{VerifierEmail: randomEmail(), Resolver: resolver, MtaStsCheck: true, MtaStsHttpClient: httpClient},
+		)
+		validatorResult, err := Validate(email, configuration, validationTypeMx)
+
+		assert.NoError(t, err)
+		assert.True(t, validatorResult.Success)
+		assert.Equal(t, []string{mxHostAddress}, validatorResult.MailServers)
+		assert.Equal(t, mtaStsModeEnforce, validatorResult.MtaSts.Mode)
+		assert.Equal(t, "20240101T000000", validatorResult.MtaSts.Id)
+		assert.Empty(t, validatorResult.MtaSts.MismatchedMxs)
+	})
+
+	t.Run("failure validation, MX hosts do not match MTA-STS policy in enforce mode", func(t *testing.T) {
+		httpClient, stop := startMtaStsPolicyHostStandIn(createMtaStsPolicy(mtaStsModeEnforce, "mx.other.com"))
+		defer stop()
+		configuration, _ := NewConfiguration(
+			ConfigurationAttr{VerifierEmail: randomEmail(), Resolver: resolver, MtaStsCheck: true, MtaStsHttpClient: httpClient},
+		)
+		validatorResult, err := Validate(email, configuration, validationTypeMx)
+
+		assert.NoError(t, err)
+		assert.False(t, validatorResult.Success)
+		assert.Empty(t, validatorResult.MailServers)
+		assert.Equal(t, []string{mxHostName}, validatorResult.MtaSts.MismatchedMxs)
+		assert.Equal(t, map[string]string{validationTypeMx: mxMtaStsErrorContext}, validatorResult.Errors)
+	})
+}
+
+func TestValidateWithRawDnsClient(t *testing.T) {
+	t.Run("failure validation, raw DNS client, DNS server responded with SERVFAIL", func(t *testing.T) {
+		dnsServer, stop := startRawDnsServer(createRawDnsHandler(dns.RcodeServerFailure, false))
+		defer stop()
+		configuration, _ := NewConfiguration(
+			ConfigurationAttr{VerifierEmail: randomEmail(), Dns: dnsServer, DnsClient: dnsClientRaw, ConnectionAttempts: 1},
+		)
+		validatorResult, err := Validate(randomEmail(), configuration, validationTypeMx)
+
+		assert.NoError(t, err)
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, map[string]string{validationTypeMx: mxDnsFailureErrorContext}, validatorResult.Errors)
+		assert.Equal(t, "SERVFAIL", validatorResult.DnsDebug[0].Rcode)
+	})
+}
+
 func TestIsValid(t *testing.T) {
 	email, domain := pairRandomEmailDomain()
 	resolvedHostNameByMxReord := randomDnsHostName()
@@ -217,3 +298,167 @@ func TestIsValid(t *testing.T) {
 		assert.False(t, IsValid(randomEmail(), createConfiguration(), "invalidValidationType"))
 	})
 }
+
+func TestValidateWithDaneCheck(t *testing.T) {
+	tlsConfig, _ := createTlsConfig()
+	certificate := tlsConfigCertificate(tlsConfig)
+	smtpServer, stopSmtpServer := startSmtpStandIn(tlsConfig)
+	defer stopSmtpServer()
+	_, smtpPort, _ := net.SplitHostPort(smtpServer)
+	smtpPortNumber, _ := strconv.Atoi(smtpPort)
+	email, domain := pairRandomEmailDomain()
+	mxHostName := "mx." + domain
+	startDnsServer := func(tlsaRecord *TlsaRecord) (string, func()) {
+		records := map[uint16]dns.RR{
+			dns.TypeMX: createDnsRecord(toDnsHostName(domain) + " 42 IN MX 10 " + toDnsHostName(mxHostName)),
+			dns.TypeA:  createDnsRecord(toDnsHostName(mxHostName) + " 42 IN A " + localhostIPv4Address),
+			dns.TypeTLSA: createDnsRecord(
+				fmt.Sprintf(
+					"%s 42 IN TLSA %d %d %d %s",
+					toDnsHostName(daneTlsaName(smtpPortNumber, mxHostName)),
+					tlsaRecord.Usage,
+					tlsaRecord.Selector,
+					tlsaRecord.MatchingType,
+					tlsaRecord.Certificate,
+				),
+			),
+		}
+
+		return startRawDnsServer(func(writer dns.ResponseWriter, request *dns.Msg) {
+			record, ok := records[request.Question[0].Qtype]
+			if !ok || record.Header().Name != request.Question[0].Name {
+				createRawDnsHandler(dns.RcodeNameError, true)(writer, request)
+				return
+			}
+			createRawDnsHandler(dns.RcodeSuccess, true, record)(writer, request)
+		})
+	}
+	createConfiguration := func(dnsServer string) *Configuration {
+		configuration, _ := NewConfiguration(
+			ConfigurationAttr{
+				VerifierEmail: randomEmail(),
+				Dns:           dnsServer,
+				DnsClient:     dnsClientRaw,
+				DaneCheck:     true,
+				SmtpPort:      smtpPortNumber,
+			},
+		)
+
+		return configuration
+	}
+
+	t.Run("successful validation, MX host certificate matches DANE TLSA record", func(t *testing.T) {
+		dnsServer, stop := startDnsServer(createTlsaRecord(certificate, tlsaUsageDaneEe, tlsaSelectorPublicKey, tlsaMatchingTypeSha256))
+		defer stop()
+		validatorResult, err := Validate(email, createConfiguration(dnsServer), validationTypeSmtp)
+		daneResult := validatorResult.SmtpDebug[0].Dane
+
+		assert.NoError(t, err)
+		assert.True(t, validatorResult.Success)
+		assert.Equal(t, mxHostName, daneResult.HostName)
+		assert.True(t, daneResult.Authenticated)
+		assert.True(t, daneResult.Verified)
+		assert.NoError(t, daneResult.Err)
+	})
+
+	t.Run("failure validation, MX host certificate does not match DANE TLSA record", func(t *testing.T) {
+		dnsServer, stop := startDnsServer(&TlsaRecord{Usage: tlsaUsageDaneEe, Selector: tlsaSelectorPublicKey, MatchingType: tlsaMatchingTypeSha256, Certificate: "abcdef"})
+		defer stop()
+		validatorResult, err := Validate(email, createConfiguration(dnsServer), validationTypeSmtp)
+		daneResult := validatorResult.SmtpDebug[0].Dane
+
+		assert.NoError(t, err)
+		assert.False(t, validatorResult.Success)
+		assert.False(t, daneResult.Verified)
+		assert.True(t, daneResult.Err.(*SmtpClientError).isDane)
+	})
+}
+
+func TestValidateWithResultCache(t *testing.T) {
+	t.Run("returns validator result from result cache for normalized email", func(t *testing.T) {
+		configuration, _ := NewConfiguration(ConfigurationAttr{VerifierEmail: randomEmail(), ResultCacheSize: 10})
+		validatorResult, _ := Validate("email@example.com", configuration, validationTypeRegex)
+		cachedValidatorResult, err := Validate("email@Example.com", configuration, validationTypeRegex)
+
+		assert.NoError(t, err)
+		assert.False(t, validatorResult.FromCache)
+		assert.True(t, cachedValidatorResult.FromCache)
+		assert.True(t, cachedValidatorResult.Success)
+		assert.Equal(t, "email@Example.com", cachedValidatorResult.Email)
+		assert.True(t, IsValid("email@example.com", configuration, validationTypeRegex))
+	})
+}
+
+func TestValidateContext(t *testing.T) {
+	serverAddress, stop := startSmtpStandIn(nil)
+	defer stop()
+	_, port, _ := net.SplitHostPort(serverAddress)
+	portNumber, _ := strconv.Atoi(port)
+	email, domain := pairRandomEmailDomain()
+	mxHostName := randomDnsHostName()
+	resolver := &mockdns.Resolver{
+		Zones: map[string]mockdns.Zone{
+			toDnsHostName(punycodeDomain(domain)): {MX: []net.MX{{Host: mxHostName, Pref: uint16(10)}}},
+			mxHostName:                            {A: []string{localhostIPv4Address}},
+		},
+	}
+
+	t.Run("records validation spans as children of span from context", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		configuration, _ := NewConfiguration(
+			ConfigurationAttr{
+				VerifierEmail:  randomEmail(),
+				Resolver:       resolver,
+				SmtpPort:       portNumber,
+				TracerProvider: tracerProvider,
+			},
+		)
+		ctx, parentSpan := tracerProvider.Tracer(tracerName).Start(context.Background(), "parent")
+		validatorResult, err := ValidateContext(ctx, email, configuration)
+		parentSpan.End()
+
+		assert.NoError(t, err)
+		assert.True(t, validatorResult.Success)
+
+		validateSpans := endedSpans(spanRecorder, spanNameValidate)
+		assert.Len(t, validateSpans, 1)
+		assert.Equal(t, parentSpan.SpanContext().SpanID(), validateSpans[0].Parent().SpanID())
+
+		layerSpans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, validationType := range []string{validationTypeRegex, validationTypeMx, validationTypeMxBlacklist, validationTypeSmtp} {
+			spans := endedSpans(spanRecorder, spanNamePrefix+validationType)
+			assert.Len(t, spans, 1)
+			assert.Equal(t, validateSpans[0].SpanContext().SpanID(), spans[0].Parent().SpanID())
+			layerSpans[validationType] = spans[0]
+		}
+
+		dnsQuerySpans := endedSpans(spanRecorder, spanNameDnsQuery)
+		assert.NotEmpty(t, dnsQuerySpans)
+		for _, dnsQuerySpan := range dnsQuerySpans {
+			assert.Equal(t, layerSpans[validationTypeMx].SpanContext().SpanID(), dnsQuerySpan.Parent().SpanID())
+		}
+
+		sessionSpans := endedSpans(spanRecorder, spanNameSmtpSession)
+		assert.Len(t, sessionSpans, 1)
+		assert.Equal(t, layerSpans[validationTypeSmtp].SpanContext().SpanID(), sessionSpans[0].Parent().SpanID())
+		assert.Equal(t, strings.TrimSuffix(mxHostName, "."), spanAttribute(sessionSpans[0], attributeSmtpMxHost).AsString())
+	})
+
+	t.Run("cancels SMTP sessions when context is done", func(t *testing.T) {
+		configuration, _ := NewConfiguration(ConfigurationAttr{VerifierEmail: randomEmail(), Resolver: resolver, SmtpPort: portNumber})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		validatorResult, err := ValidateContext(ctx, email, configuration)
+
+		assert.NoError(t, err)
+		assert.False(t, validatorResult.Success)
+		assert.Equal(t, smtpErrorContext, validatorResult.Errors[validationTypeSmtp])
+	})
+
+	t.Run("when invalid validation type", func(t *testing.T) {
+		validatorResult, err := ValidateContext(context.Background(), email, createConfiguration(), "invalid")
+
+		assert.Nil(t, validatorResult)
+		assert.Error(t, err)
+	})
+}
@@ -1,5 +1,13 @@
 package truemail
 
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
 // Validator result mutable structure. Each validation
 // layer write something into ValidatorResult
 type ValidatorResult struct {
@@ -9,6 +17,14 @@ type ValidatorResult struct {
 	Errors                                                       map[string]string
 	Configuration                                                *Configuration
 	SmtpDebug                                                    []*SmtpRequest
+	DnsDebug                                                     []*DnsQuery
+	MtaSts                                                       *MtaStsResult
+	mailServerHostNames                                          map[string]string
+	mailServerPriorities                                         map[string]uint16
+	daneResults                                                  map[string]*DaneResult
+	GreylistingRetry                                             <-chan *ValidatorResult
+	isSmtpRetry                                                  bool
+	FromCache                                                    bool
 }
 
 // ValidatorResult methods
@@ -26,10 +42,180 @@ func (validatorResult *ValidatorResult) addError(key, value string) {
 	validatorResult.Errors[key] = value
 }
 
+// Addes MX host name of mail server ip address. Keeps first added MX host name
+func (validatorResult *ValidatorResult) addMailServerHostName(ipAddress, hostName string) {
+	if validatorResult.mailServerHostNames == nil {
+		validatorResult.mailServerHostNames = map[string]string{}
+	}
+	if _, ok := validatorResult.mailServerHostNames[ipAddress]; !ok {
+		validatorResult.mailServerHostNames[ipAddress] = hostName
+	}
+}
+
+// Addes MX record priority of mail server ip address. Keeps first added priority
+func (validatorResult *ValidatorResult) addMailServerPriority(ipAddress string, priority uint16) {
+	if validatorResult.mailServerPriorities == nil {
+		validatorResult.mailServerPriorities = map[string]uint16{}
+	}
+	if _, ok := validatorResult.mailServerPriorities[ipAddress]; !ok {
+		validatorResult.mailServerPriorities[ipAddress] = priority
+	}
+}
+
+// Returns MX record priority of mail server ip address. Returns 0 when
+// mail server was not resolved by MX records
+func (validatorResult *ValidatorResult) mailServerPriority(ipAddress string) uint16 {
+	return validatorResult.mailServerPriorities[ipAddress]
+}
+
+// Returns MX host name of mail server ip address. Returns empty string when
+// mail server was not resolved by MX records
+func (validatorResult *ValidatorResult) mailServerHostName(ipAddress string) string {
+	return validatorResult.mailServerHostNames[ipAddress]
+}
+
+// Addes DANE result of MX host
+func (validatorResult *ValidatorResult) addDaneResult(daneResult *DaneResult) {
+	if validatorResult.daneResults == nil {
+		validatorResult.daneResults = map[string]*DaneResult{}
+	}
+	validatorResult.daneResults[daneResult.HostName] = daneResult
+}
+
+// Returns copy of DANE result of mail server ip address MX host, so certificate
+// verification outcome is recorded per SMTP request. Returns nil when DANE result not exists
+func (validatorResult *ValidatorResult) daneResult(ipAddress string) *DaneResult {
+	daneResult, ok := validatorResult.daneResults[validatorResult.mailServerHostName(ipAddress)]
+	if !ok {
+		return nil
+	}
+
+	copiedDaneResult := *daneResult
+	return &copiedDaneResult
+}
+
+// Returns true if SMTP validation failed because target server greylisted email, otherwise returns false
+func (validatorResult *ValidatorResult) isGreylisted() bool {
+	return validatorResult.Errors[validationTypeSmtp] == smtpGreylistedErrorContext
+}
+
+// Returns copy of validator result as it was before SMTP validation, uses for SMTP validation retry.
+// SMTP validation retry runs in other goroutine after validation was finished, so it uses own copy
+// of configuration with context which is not cancelled when validation context is done
+func (validatorResult *ValidatorResult) smtpRetryCopy() *ValidatorResult {
+	copiedValidatorResult := *validatorResult
+	copiedValidatorResult.Success, copiedValidatorResult.Errors = true, nil
+	copiedValidatorResult.SmtpDebug, copiedValidatorResult.GreylistingRetry = nil, nil
+	copiedValidatorResult.isSmtpRetry = true
+	if configuration := validatorResult.Configuration; configuration != nil {
+		copiedValidatorResult.Configuration = copyConfigurationByPointer(configuration)
+		copiedValidatorResult.Configuration.eventLogBuffer = nil
+		if configuration.ctx != nil {
+			copiedValidatorResult.Configuration.ctx = context.WithoutCancel(configuration.ctx)
+		}
+	}
+
+	return &copiedValidatorResult
+}
+
+// Returns true if STARTTLS is required by MTA-STS policy in enforce mode, otherwise returns false
+func (validatorResult *ValidatorResult) isStartTlsRequired() bool {
+	mtaSts := validatorResult.MtaSts
+	return mtaSts != nil && mtaSts.Err == nil && mtaSts.Mode == mtaStsModeEnforce
+}
+
+// Returns true if validation outcome is unknown: SMTP validation was rate limited or greylisted,
+// email existence was not confirmed by mail server, or DNS lookup failed, otherwise returns false
+func (validatorResult *ValidatorResult) isUnknown() bool {
+	switch validatorResult.Errors[validationTypeSmtp] {
+	case smtpRateLimitedErrorContext, smtpGreylistedErrorContext, smtpNotConfirmedErrorContext:
+		return true
+	}
+
+	return validatorResult.Errors[validationTypeMx] == mxDnsFailureErrorContext
+}
+
+// Returns last used validation layer. Returns domain list match validation layer
+// when validation was finished by whitelist/blacklist validation
+func (validatorResult *ValidatorResult) validationLayer() string {
+	usedValidations := validatorResult.usedValidations
+	if len(usedValidations) == 0 {
+		return validationTypeDomainListMatch
+	}
+
+	return usedValidations[len(usedValidations)-1]
+}
+
+// Returns validation outcome: unknown, valid or invalid
+func (validatorResult *ValidatorResult) validationOutcome() string {
+	switch {
+	case validatorResult.isUnknown():
+		return metricsOutcomeUnknown
+	case validatorResult.Success:
+		return metricsOutcomeValid
+	default:
+		return metricsOutcomeInvalid
+	}
+}
+
+// Logs validation started event. Event is not logged when only failed validations should be logged
+func (validatorResult *ValidatorResult) logValidationStarted() {
+	configuration := validatorResult.Configuration
+	if configuration.Logger == nil || configuration.LogFailedValidationsOnly {
+		return
+	}
+
+	configuration.logEvent(
+		slog.LevelDebug,
+		logMessageValidationStarted,
+		configuration.emailLogAttr(validatorResult.Email),
+		slog.String(logAttrValidationType, validatorResult.ValidationType),
+	)
+}
+
+// Logs validation finished event: successful validation with info level, failed validation
+// with warn level. Successful validation and its DNS and SMTP events are not logged when only
+// failed validations should be logged, events of failed validation are logged before it
+func (validatorResult *ValidatorResult) logValidationFinished(duration time.Duration) {
+	configuration := validatorResult.Configuration
+	if configuration.Logger == nil {
+		return
+	}
+
+	eventLogBuffer := configuration.eventLogBuffer
+	configuration.eventLogBuffer = nil
+	if validatorResult.Success && configuration.LogFailedValidationsOnly {
+		return
+	}
+	if eventLogBuffer != nil {
+		eventLogBuffer.flush(configuration.Logger)
+	}
+
+	level := slog.LevelInfo
+	if !validatorResult.Success {
+		level = slog.LevelWarn
+	}
+
+	configuration.logEvent(
+		level,
+		logMessageValidationFinished,
+		configuration.emailLogAttr(validatorResult.Email),
+		slog.String(logAttrDomain, validatorResult.Domain),
+		slog.String(logAttrValidationType, validatorResult.ValidationType),
+		slog.Bool(logAttrSuccess, validatorResult.Success),
+		slog.String(logAttrOutcome, validatorResult.validationOutcome()),
+		slog.String(logAttrLayer, validatorResult.validationLayer()),
+		slog.Any(logAttrErrors, validatorResult.Errors),
+		slog.Bool(logAttrFromCache, validatorResult.FromCache),
+		slog.Duration(logAttrDuration, duration),
+	)
+}
+
 // Structure with behavior. Responsible for the
 // logic of calling the validation layers sequence
 type validator struct {
 	result *ValidatorResult
+	ctx    context.Context
 	domainListMatchLayer
 	regexLayer
 	mxLayer
@@ -55,6 +241,57 @@ func newValidator(email, validationType string, configuration *Configuration) *v
 	return validator
 }
 
+// Runs validation within validation span. Context passed by caller is propagated to validation
+// layers, DNS queries and SMTP sessions via validator result configuration
+func (validator *validator) runWithContext(ctx context.Context) *ValidatorResult {
+	validatorResult := validator.result
+	ctx, span := startValidationSpan(
+		ctx,
+		validatorResult.Configuration.TracerProvider,
+		attribute.String(attributeValidationType, validatorResult.ValidationType),
+	)
+	defer span.End()
+	validator.ctx, validatorResult.Configuration.ctx = ctx, ctx
+	validatorResult.Configuration.initEventLogBuffer()
+	validatorResult.logValidationStarted()
+	startedAt := time.Now()
+
+	validatorResult = validator.runWithResultCache()
+	validatorResult.logValidationFinished(time.Since(startedAt))
+	span.SetAttributes(
+		attribute.String(attributeDomain, validatorResult.Domain),
+		attribute.Bool(attributeSuccess, validatorResult.Success),
+		attribute.Bool(attributeFromCache, validatorResult.FromCache),
+	)
+
+	return validatorResult
+}
+
+// Returns validator result from result cache when result cache is configured and
+// includes result of email, otherwise runs validation and stores validator result
+// into result cache with TTL of validation verdict. Result cache hit is observed
+// by metrics collector, result cache error is logged
+func (validator *validator) runWithResultCache() *ValidatorResult {
+	validatorResult := validator.result
+	configuration := validatorResult.Configuration
+	if configuration.ResultCache == nil {
+		return validator.run()
+	}
+
+	key := resultCacheKey(validatorResult.Email, validatorResult.ValidationType, configuration)
+	if entry, ok := configuration.ResultCache.Get(key); ok {
+		validatorResult = entry.validatorResult(validatorResult.Email, configuration)
+		observeValidation(validatorResult)
+		return validatorResult
+	}
+
+	validatorResult = validator.run()
+	err := configuration.ResultCache.Set(key, newResultCacheEntry(validatorResult), configuration.resultCacheTtl(validatorResult))
+	configuration.logResultCacheError(err)
+
+	return validatorResult
+}
+
 // validation layers interfaces
 
 type domainListMatchLayer interface {
@@ -79,16 +316,39 @@ type smtpLayer interface {
 
 // validator methods
 
+// Runs validation layer check within validation layer span. Validation layer span context
+// is propagated to DNS queries and SMTP sessions of validation layer, validation context
+// is restored when validation layer check is finished
+func (validator *validator) check(validationType string, layerCheck func(*ValidatorResult) *ValidatorResult) *ValidatorResult {
+	validatorResult := validator.result
+	ctx, span := startSpan(validator.ctx, spanNamePrefix+validationType)
+	defer span.End()
+	configuration := validatorResult.Configuration
+	configuration.ctx = ctx
+	defer func() { configuration.ctx = validator.ctx }()
+
+	validatorResult = layerCheck(validatorResult)
+	span.SetAttributes(attribute.Bool(attributeSuccess, validatorResult.Success))
+	if errorContext, ok := validatorResult.Errors[validationType]; ok {
+		span.SetAttributes(attribute.String(attributeError, errorContext))
+	}
+	if validationType == validationTypeMx {
+		span.SetAttributes(attribute.StringSlice(attributeMailServers, validatorResult.MailServers))
+	}
+
+	return validatorResult
+}
+
 // Runs Whitelist/Blacklist validation
 func (validator *validator) validateDomainListMatch() {
-	validator.domainListMatchLayer.check(validator.result)
+	validator.check(validationTypeDomainListMatch, validator.domainListMatchLayer.check)
 }
 
 // Runs Regex validation
 func (validator *validator) validateRegex() {
 	validatorResult := validator.result
 	validatorResult.addUsedValidationType(validationTypeRegex)
-	validator.regexLayer.check(validatorResult)
+	validator.check(validationTypeRegex, validator.regexLayer.check)
 }
 
 // Runs validations chain: Regex -> Mx
@@ -96,12 +356,12 @@ func (validator *validator) validateMx() {
 	validatorResult := validator.result
 
 	validatorResult.addUsedValidationType(validationTypeRegex)
-	if !validator.regexLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeRegex, validator.regexLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeMx)
-	validator.mxLayer.check(validatorResult)
+	validator.check(validationTypeMx, validator.mxLayer.check)
 }
 
 // Runs validations chain: Regex -> Mx -> MxBlacklist
@@ -109,17 +369,17 @@ func (validator *validator) validateMxBlacklist() {
 	validatorResult := validator.result
 
 	validatorResult.addUsedValidationType(validationTypeRegex)
-	if !validator.regexLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeRegex, validator.regexLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeMx)
-	if !validator.mxLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeMx, validator.mxLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeMxBlacklist)
-	validator.mxBlacklistLayer.check(validatorResult)
+	validator.check(validationTypeMxBlacklist, validator.mxBlacklistLayer.check)
 }
 
 // Runs validations chain: Regex -> Mx -> MxBlacklist -> SMTP
@@ -127,22 +387,22 @@ func (validator *validator) validateSMTP() {
 	validatorResult := validator.result
 
 	validatorResult.addUsedValidationType(validationTypeRegex)
-	if !validator.regexLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeRegex, validator.regexLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeMx)
-	if !validator.mxLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeMx, validator.mxLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeMxBlacklist)
-	if !validator.mxBlacklistLayer.check(validatorResult).Success {
+	if !validator.check(validationTypeMxBlacklist, validator.mxBlacklistLayer.check).Success {
 		return
 	}
 
 	validatorResult.addUsedValidationType(validationTypeSmtp)
-	validator.smtpLayer.check(validatorResult)
+	validator.check(validationTypeSmtp, validator.smtpLayer.check)
 }
 
 // validator entrypoint. This method triggers chain of validation layers
@@ -153,6 +413,7 @@ func (validator *validator) run() *ValidatorResult {
 	// preparing for running
 	validatorResult := validator.result
 	validatorResult.usedValidations = []string{}
+	defer observeValidation(validatorResult)
 
 	// Whitelist/Blacklist validation
 	validator.validateDomainListMatch()
@@ -1,9 +1,16 @@
 package truemail
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
 	"testing"
 
+	"github.com/foxcpp/go-mockdns"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNewValidator(t *testing.T) {
@@ -437,3 +444,409 @@ func TestValidatorResultAddError(t *testing.T) {
 		assert.Equal(t, value, result.Errors[key])
 	})
 }
+
+func TestValidatorResultAddMailServerHostName(t *testing.T) {
+	t.Run("validatorResult#addMailServerHostName, keeps first added MX host name", func(t *testing.T) {
+		ipAddress, hostName := randomIpAddress(), randomDomain()
+		result := new(ValidatorResult)
+		result.addMailServerHostName(ipAddress, hostName)
+		result.addMailServerHostName(ipAddress, randomDomain())
+
+		assert.Equal(t, hostName, result.mailServerHostName(ipAddress))
+		assert.Empty(t, result.mailServerHostName(randomIpAddress()))
+	})
+}
+
+func TestValidatorResultAddMailServerPriority(t *testing.T) {
+	t.Run("validatorResult#addMailServerPriority, keeps first added MX record priority", func(t *testing.T) {
+		ipAddress := randomIpAddress()
+		result := new(ValidatorResult)
+		result.addMailServerPriority(ipAddress, 10)
+		result.addMailServerPriority(ipAddress, 20)
+
+		assert.Equal(t, uint16(10), result.mailServerPriority(ipAddress))
+		assert.Equal(t, uint16(0), result.mailServerPriority(randomIpAddress()))
+	})
+}
+
+func TestValidatorResultDaneResult(t *testing.T) {
+	ipAddress, hostName := randomIpAddress(), randomDomain()
+	result := new(ValidatorResult)
+	result.addMailServerHostName(ipAddress, hostName)
+	daneResult := &DaneResult{HostName: hostName, Authenticated: true}
+	result.addDaneResult(daneResult)
+
+	t.Run("validatorResult#daneResult, returns copy of DANE result of MX host", func(t *testing.T) {
+		copiedDaneResult := result.daneResult(ipAddress)
+		copiedDaneResult.Verified = true
+
+		assert.Equal(t, &DaneResult{HostName: hostName, Authenticated: true, Verified: true}, copiedDaneResult)
+		assert.False(t, daneResult.Verified)
+	})
+
+	t.Run("validatorResult#daneResult, when DANE result not exists", func(t *testing.T) {
+		assert.Nil(t, result.daneResult(randomIpAddress()))
+	})
+}
+
+func TestValidatorResultIsStartTlsRequired(t *testing.T) {
+	t.Run("when MTA-STS policy in enforce mode", func(t *testing.T) {
+		result := &ValidatorResult{MtaSts: &MtaStsResult{Mode: mtaStsModeEnforce}}
+
+		assert.True(t, result.isStartTlsRequired())
+	})
+
+	t.Run("when MTA-STS policy in testing mode", func(t *testing.T) {
+		result := &ValidatorResult{MtaSts: &MtaStsResult{Mode: mtaStsModeTesting}}
+
+		assert.False(t, result.isStartTlsRequired())
+	})
+
+	t.Run("when MTA-STS policy discovery failed", func(t *testing.T) {
+		result := &ValidatorResult{MtaSts: &MtaStsResult{Err: errors.New("error")}}
+
+		assert.False(t, result.isStartTlsRequired())
+	})
+
+	t.Run("when MTA-STS policy not found", func(t *testing.T) {
+		assert.False(t, new(ValidatorResult).isStartTlsRequired())
+	})
+}
+
+func TestValidatorResultIsGreylisted(t *testing.T) {
+	t.Run("when SMTP validation failed because of greylisting", func(t *testing.T) {
+		validatorResult := &ValidatorResult{Errors: map[string]string{validationTypeSmtp: smtpGreylistedErrorContext}}
+
+		assert.True(t, validatorResult.isGreylisted())
+	})
+
+	t.Run("when SMTP validation failed because of other error", func(t *testing.T) {
+		validatorResult := &ValidatorResult{Errors: map[string]string{validationTypeSmtp: smtpErrorContext}}
+
+		assert.False(t, validatorResult.isGreylisted())
+	})
+
+	t.Run("when validation is successful", func(t *testing.T) {
+		assert.False(t, new(ValidatorResult).isGreylisted())
+	})
+}
+
+func TestValidatorResultIsUnknown(t *testing.T) {
+	t.Run("when SMTP validation was rate limited, greylisted or not confirmed", func(t *testing.T) {
+		for _, errorContext := range []string{smtpRateLimitedErrorContext, smtpGreylistedErrorContext, smtpNotConfirmedErrorContext} {
+			assert.True(t, (&ValidatorResult{Errors: map[string]string{validationTypeSmtp: errorContext}}).isUnknown())
+		}
+	})
+
+	t.Run("when DNS lookup failed", func(t *testing.T) {
+		assert.True(t, (&ValidatorResult{Errors: map[string]string{validationTypeMx: mxDnsFailureErrorContext}}).isUnknown())
+	})
+
+	t.Run("when validation outcome is definitive", func(t *testing.T) {
+		assert.False(t, new(ValidatorResult).isUnknown())
+		assert.False(t, (&ValidatorResult{Errors: map[string]string{validationTypeSmtp: smtpErrorContext}}).isUnknown())
+	})
+}
+
+func TestValidatorRunWithContext(t *testing.T) {
+	t.Run("runs validation within validation span, child of span from context", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		configuration := createConfiguration()
+		configuration.TracerProvider = tracerProvider
+		ctx, parentSpan := tracerProvider.Tracer(tracerName).Start(context.Background(), "parent")
+		email := randomEmail()
+		validatorResult := newValidator(email, validationTypeRegex, configuration).runWithContext(ctx)
+		validateSpans, regexSpans := endedSpans(spanRecorder, spanNameValidate), endedSpans(spanRecorder, spanNamePrefix+validationTypeRegex)
+
+		assert.True(t, validatorResult.Success)
+		assert.Len(t, validateSpans, 1)
+		assert.Equal(t, parentSpan.SpanContext().SpanID(), validateSpans[0].Parent().SpanID())
+		assert.Equal(t, validationTypeRegex, spanAttribute(validateSpans[0], attributeValidationType).AsString())
+		assert.Equal(t, emailDomain(email), spanAttribute(validateSpans[0], attributeDomain).AsString())
+		assert.True(t, spanAttribute(validateSpans[0], attributeSuccess).AsBool())
+		assert.False(t, spanAttribute(validateSpans[0], attributeFromCache).AsBool())
+		assert.Len(t, regexSpans, 1)
+		assert.Equal(t, validateSpans[0].SpanContext().SpanID(), regexSpans[0].Parent().SpanID())
+		assert.NotSame(t, configuration, validatorResult.Configuration)
+		assert.Nil(t, configuration.ctx)
+	})
+}
+
+func TestValidatorCheck(t *testing.T) {
+	t.Run("runs validation layer check within validation layer span", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		ctx, validateSpan := startValidationSpan(context.Background(), tracerProvider)
+		validator := createValidator(randomEmail(), createConfiguration())
+		validator.ctx = ctx
+		var layerContext context.Context
+		result, mailServer := validator.result, randomIpAddress()
+		validator.check(validationTypeMx, func(validatorResult *ValidatorResult) *ValidatorResult {
+			validatorResult.Success, validatorResult.MailServers = true, []string{mailServer}
+			layerContext = validatorResult.Configuration.ctx
+			return validatorResult
+		})
+		spans := endedSpans(spanRecorder, spanNamePrefix+validationTypeMx)
+
+		assert.True(t, result.Success)
+		assert.Len(t, spans, 1)
+		assert.Equal(t, validateSpan.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Equal(t, spans[0].SpanContext(), trace.SpanContextFromContext(layerContext))
+		assert.True(t, spanAttribute(spans[0], attributeSuccess).AsBool())
+		assert.Equal(t, []string{mailServer}, spanAttribute(spans[0], attributeMailServers).AsStringSlice())
+	})
+
+	t.Run("records validation layer error into validation layer span", func(t *testing.T) {
+		tracerProvider, spanRecorder := createTracerProvider()
+		validator := createValidator(randomEmail(), createConfiguration())
+		validator.ctx, _ = startValidationSpan(context.Background(), tracerProvider)
+		validator.check(validationTypeRegex, func(validatorResult *ValidatorResult) *ValidatorResult {
+			validatorResult.Success = false
+			validatorResult.addError(validationTypeRegex, regexErrorContext)
+			return validatorResult
+		})
+		span := endedSpans(spanRecorder, spanNamePrefix+validationTypeRegex)[0]
+
+		assert.False(t, spanAttribute(span, attributeSuccess).AsBool())
+		assert.Equal(t, regexErrorContext, spanAttribute(span, attributeError).AsString())
+	})
+}
+
+func TestValidatorResultValidationLayer(t *testing.T) {
+	t.Run("when validation was finished by whitelist/blacklist validation", func(t *testing.T) {
+		assert.Equal(t, validationTypeDomainListMatch, new(ValidatorResult).validationLayer())
+	})
+
+	t.Run("returns last used validation layer", func(t *testing.T) {
+		validatorResult := &ValidatorResult{usedValidations: []string{validationTypeRegex, validationTypeMx}}
+
+		assert.Equal(t, validationTypeMx, validatorResult.validationLayer())
+	})
+}
+
+func TestValidatorResultValidationOutcome(t *testing.T) {
+	t.Run("when validation outcome is unknown", func(t *testing.T) {
+		validatorResult := &ValidatorResult{Errors: map[string]string{validationTypeSmtp: smtpGreylistedErrorContext}}
+
+		assert.Equal(t, metricsOutcomeUnknown, validatorResult.validationOutcome())
+	})
+
+	t.Run("when validation is successful", func(t *testing.T) {
+		assert.Equal(t, metricsOutcomeValid, (&ValidatorResult{Success: true}).validationOutcome())
+	})
+
+	t.Run("when validation is failed", func(t *testing.T) {
+		validatorResult := &ValidatorResult{Errors: map[string]string{validationTypeRegex: regexErrorContext}}
+
+		assert.Equal(t, metricsOutcomeInvalid, validatorResult.validationOutcome())
+	})
+}
+
+func TestValidatorRunWithMetricsCollector(t *testing.T) {
+	t.Run("observes validation outcome with metrics collector", func(t *testing.T) {
+		metricsCollector, configuration := new(metricsCollectorMock), createConfiguration()
+		configuration.MetricsCollector = metricsCollector
+		metricsCollector.On("ObserveValidation", validationTypeRegex, validationTypeRegex, metricsOutcomeValid, false).Once()
+		newValidator(randomEmail(), validationTypeRegex, configuration).run()
+
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("observes validation outcome of blacklisted email", func(t *testing.T) {
+		email, domain := pairRandomEmailDomain()
+		metricsCollector, configuration := new(metricsCollectorMock), createConfiguration()
+		configuration.MetricsCollector, configuration.BlacklistedDomains = metricsCollector, []string{domain}
+		metricsCollector.On("ObserveValidation", domainListMatchBlacklist, validationTypeDomainListMatch, metricsOutcomeInvalid, false).Once()
+		newValidator(email, validationTypeRegex, configuration).run()
+
+		metricsCollector.AssertExpectations(t)
+	})
+}
+
+func TestValidatorRunWithContextWithLogger(t *testing.T) {
+	t.Run("logs validation started and finished events", func(t *testing.T) {
+		logger, buffer := createLogger()
+		email, configuration := randomEmail(), createConfiguration()
+		configuration.Logger, configuration.LogLevel = logger, slog.LevelDebug
+		newValidator(email, validationTypeRegex, configuration).runWithContext(context.Background())
+		startedRecords, finishedRecords := logRecords(buffer, logMessageValidationStarted), logRecords(buffer, logMessageValidationFinished)
+
+		assert.Len(t, startedRecords, 1)
+		assert.Equal(t, email, startedRecords[0][logAttrEmail])
+		assert.Equal(t, validationTypeRegex, startedRecords[0][logAttrValidationType])
+		assert.Len(t, finishedRecords, 1)
+		assert.Equal(t, slog.LevelInfo.String(), finishedRecords[0][slog.LevelKey])
+		assert.Equal(t, true, finishedRecords[0][logAttrSuccess])
+		assert.Equal(t, metricsOutcomeValid, finishedRecords[0][logAttrOutcome])
+		assert.Equal(t, validationTypeRegex, finishedRecords[0][logAttrLayer])
+		assert.Equal(t, false, finishedRecords[0][logAttrFromCache])
+	})
+
+	t.Run("logs failed validation with warn level", func(t *testing.T) {
+		logger, buffer := createLogger()
+		email, domain := pairRandomEmailDomain()
+		configuration := createConfiguration()
+		configuration.Logger, configuration.BlacklistedDomains = logger, []string{domain}
+		newValidator(email, validationTypeRegex, configuration).runWithContext(context.Background())
+		records := logRecords(buffer, logMessageValidationFinished)
+
+		assert.Empty(t, logRecords(buffer, logMessageValidationStarted))
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.Equal(t, false, records[0][logAttrSuccess])
+		assert.Equal(t, domain, records[0][logAttrDomain])
+		assert.Equal(t, validationTypeDomainListMatch, records[0][logAttrLayer])
+	})
+
+	t.Run("logs only failed validations", func(t *testing.T) {
+		logger, buffer := createLogger()
+		email, domain := pairRandomEmailDomain()
+		configuration := createConfiguration()
+		configuration.Logger, configuration.LogLevel, configuration.LogFailedValidationsOnly = logger, slog.LevelDebug, true
+		newValidator(randomEmail(), validationTypeRegex, configuration).runWithContext(context.Background())
+		configuration.BlacklistedDomains = []string{domain}
+		newValidator(email, validationTypeRegex, configuration).runWithContext(context.Background())
+		records := logRecords(buffer, logMessageValidationFinished)
+
+		assert.Empty(t, logRecords(buffer, logMessageValidationStarted))
+		assert.Len(t, records, 1)
+		assert.Equal(t, email, records[0][logAttrEmail])
+	})
+
+	t.Run("logs DNS events of failed validations only", func(t *testing.T) {
+		validDomain, invalidDomain, mxHostName := randomDomain(), randomDomain(), randomDomain()
+		dns, stop := startMockDnsServer(
+			map[string]mockdns.Zone{
+				toDnsHostName(validDomain): {MX: []net.MX{{Host: toDnsHostName(mxHostName), Pref: 10}}},
+				toDnsHostName(mxHostName):  {A: []string{randomIpAddress()}},
+			},
+		)
+		defer stop()
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.Logger, configuration.LogLevel, configuration.LogFailedValidationsOnly = logger, slog.LevelDebug, true
+		configuration.Dns, configuration.DnsCache = dns, nil
+		validatorResult := newValidator("email@"+validDomain, validationTypeMx, configuration).runWithContext(context.Background())
+
+		assert.True(t, validatorResult.Success)
+		assert.Empty(t, logRecords(buffer, logMessageDnsQuery))
+		assert.Empty(t, logRecords(buffer, logMessageValidationFinished))
+
+		validatorResult = newValidator("email@"+invalidDomain, validationTypeMx, configuration).runWithContext(context.Background())
+
+		assert.False(t, validatorResult.Success)
+		assert.Nil(t, validatorResult.Configuration.eventLogBuffer)
+		assert.NotEmpty(t, logRecords(buffer, logMessageDnsQuery))
+		for _, record := range logRecords(buffer, logMessageDnsQuery) {
+			assert.Contains(t, record[logAttrQueryName], invalidDomain)
+		}
+		assert.Len(t, logRecords(buffer, logMessageValidationFinished), 1)
+	})
+
+	t.Run("logs email redacted with log email redaction", func(t *testing.T) {
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.Logger, configuration.LogEmailRedaction = logger, logEmailRedactionMask
+		newValidator("email@example.com", validationTypeRegex, configuration).runWithContext(context.Background())
+
+		assert.Equal(t, "e***@example.com", logRecords(buffer, logMessageValidationFinished)[0][logAttrEmail])
+	})
+}
+
+func TestValidatorRunWithResultCache(t *testing.T) {
+	t.Run("when result cache is not configured", func(t *testing.T) {
+		validatorResult := newValidator(randomEmail(), validationTypeRegex, createConfiguration()).runWithResultCache()
+
+		assert.True(t, validatorResult.Success)
+		assert.False(t, validatorResult.FromCache)
+	})
+
+	t.Run("stores validator result into result cache and returns it from cache", func(t *testing.T) {
+		email, configuration := randomEmail(), createConfiguration()
+		configuration.ResultCache, configuration.ResultCacheValidTtl = NewResultMemoryCache(1), 60
+		validatorResult := newValidator(email, validationTypeRegex, configuration).runWithResultCache()
+		cachedValidatorResult := newValidator(email, validationTypeRegex, configuration).runWithResultCache()
+
+		assert.False(t, validatorResult.FromCache)
+		assert.True(t, cachedValidatorResult.FromCache)
+		assert.Equal(t, validatorResult.Success, cachedValidatorResult.Success)
+		assert.Equal(t, validatorResult.Email, cachedValidatorResult.Email)
+		assert.Equal(t, validationTypeRegex, cachedValidatorResult.ValidationType)
+	})
+
+	t.Run("does not return validator result of other validation type from cache", func(t *testing.T) {
+		email, configuration := randomEmail(), createConfiguration()
+		configuration.ResultCache, configuration.ResultCacheValidTtl = NewResultMemoryCache(2), 60
+		newValidator(email, validationTypeRegex, configuration).runWithResultCache()
+		validatorResult := newValidator(email, validationTypeDomainListMatch, configuration).runWithResultCache()
+
+		assert.False(t, validatorResult.FromCache)
+	})
+
+	t.Run("observes validation outcome of result cache hit with metrics collector", func(t *testing.T) {
+		email, metricsCollector, configuration := randomEmail(), new(metricsCollectorMock), createConfiguration()
+		configuration.ResultCache, configuration.ResultCacheValidTtl = NewResultMemoryCache(1), 60
+		configuration.MetricsCollector = metricsCollector
+		metricsCollector.On("ObserveValidation", validationTypeRegex, validationTypeRegex, metricsOutcomeValid, false).Once()
+		metricsCollector.On("ObserveValidation", validationTypeRegex, validationTypeRegex, metricsOutcomeValid, true).Once()
+		newValidator(email, validationTypeRegex, configuration).runWithResultCache()
+		newValidator(email, validationTypeRegex, configuration).runWithResultCache()
+
+		metricsCollector.AssertExpectations(t)
+	})
+
+	t.Run("when result cache error occurs logs it", func(t *testing.T) {
+		logger, buffer := createLogger()
+		configuration := createConfiguration()
+		configuration.ResultCache, _ = NewResultFileCache(filepath.Join(t.TempDir(), "missing", "results.json"), 1, 0)
+		configuration.ResultCacheValidTtl, configuration.Logger = 60, logger
+		configuration.LogFailedValidationsOnly, configuration.eventLogBuffer = true, new(eventLogBuffer)
+		validatorResult := newValidator(randomEmail(), validationTypeRegex, configuration).runWithResultCache()
+		records := logRecords(buffer, logMessageResultCacheError)
+
+		assert.True(t, validatorResult.Success)
+		assert.Len(t, records, 1)
+		assert.Equal(t, slog.LevelWarn.String(), records[0][slog.LevelKey])
+		assert.NotEmpty(t, records[0][logAttrError])
+	})
+}
+
+func TestValidatorResultSmtpRetryCopy(t *testing.T) {
+	t.Run("returns copy of validator result as it was before SMTP validation", func(t *testing.T) {
+		configuration := createConfiguration()
+		validatorResult := &ValidatorResult{
+			Email:            randomEmail(),
+			MailServers:      []string{randomIpAddress()},
+			Configuration:    configuration,
+			Errors:           map[string]string{validationTypeSmtp: smtpGreylistedErrorContext},
+			SmtpDebug:        []*SmtpRequest{new(SmtpRequest)},
+			GreylistingRetry: make(chan *ValidatorResult),
+		}
+		copiedValidatorResult := validatorResult.smtpRetryCopy()
+
+		assert.NotSame(t, validatorResult, copiedValidatorResult)
+		assert.True(t, copiedValidatorResult.Success)
+		assert.True(t, copiedValidatorResult.isSmtpRetry)
+		assert.Nil(t, copiedValidatorResult.Errors)
+		assert.Nil(t, copiedValidatorResult.SmtpDebug)
+		assert.Nil(t, copiedValidatorResult.GreylistingRetry)
+		assert.Equal(t, validatorResult.Email, copiedValidatorResult.Email)
+		assert.Equal(t, validatorResult.MailServers, copiedValidatorResult.MailServers)
+		assert.NotSame(t, configuration, copiedValidatorResult.Configuration)
+		assert.Equal(t, configuration.VerifierEmail, copiedValidatorResult.Configuration.VerifierEmail)
+		assert.NotNil(t, validatorResult.Errors)
+	})
+
+	t.Run("copies configuration with context which is not cancelled with validation context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		configuration := createConfiguration()
+		configuration.ctx = ctx
+		validatorResult := &ValidatorResult{Email: randomEmail(), Configuration: configuration}
+		cancel()
+		copiedValidatorResult := validatorResult.smtpRetryCopy()
+
+		assert.NotSame(t, configuration, copiedValidatorResult.Configuration)
+		assert.Equal(t, configuration.VerifierEmail, copiedValidatorResult.Configuration.VerifierEmail)
+		assert.NoError(t, copiedValidatorResult.Configuration.ctx.Err())
+		assert.Same(t, ctx, configuration.ctx)
+	})
+}